@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"strings"
+
+	"github.com/michaelmass/github-settings/pkg/github"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(newAdopt())
+}
+
+func newAdopt() *cobra.Command {
+	flags := struct {
+		clientFlags
+		resources []string
+		state     string
+		config    string
+	}{}
+
+	cmd := &cobra.Command{
+		Use:   "adopt owner/repo",
+		Short: "Adopt marks the live resources of a github repository as managed without changing them.",
+		Long: `Adopt records the live resources of the resource kinds of a github repository as managed in the state store, without changing them.
+Plan and apply given the same --state only delete the managed resources missing from the config, the resources the tool did not create nor adopt are left alone.
+--config appends the adopted resources missing from the config file to the sections of the repository. Webhook secrets are redacted.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			owner, name, err := splitFullName(args[0])
+
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			if flags.state == "" {
+				log.Fatal("Adopt requires a state store file, set it with --state")
+			}
+
+			state := loadState(flags.state)
+			settings, err := flags.newClient(github.WithStateStore(state)).Adopt(commandContext, owner, name, flags.resources)
+
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			if flags.config != "" {
+				err = github.AppendAdopted(flags.config, owner, name, settings, flags.resources)
+
+				if err != nil {
+					log.Fatal(err)
+				}
+			}
+
+			if err := state.Save(); err != nil {
+				log.Fatal(err)
+			}
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&flags.resources, "resources", nil, "Resource kinds adopted ("+strings.Join(github.AdoptSections, ", ")+")")
+	cmd.Flags().StringVar(&flags.state, "state", "", "State store file the adopted resources are recorded in, the same file is given to plan and apply")
+	cmd.Flags().StringVarP(&flags.config, "config", "c", "", "Configuration file the adopted resources are appended to (disabled when empty)")
+	flags.register(cmd)
+
+	return cmd
+}
@@ -1,6 +1,9 @@
 package cmd
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/michaelmass/github-settings/pkg/github"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -12,33 +15,265 @@ func init() {
 
 func newApply() *cobra.Command {
 	flags := struct {
-		token  string
-		config string
+		clientFlags
+		statusFlags
+		config      string
+		dryRun      bool
+		concurrency int
+		secretsFile string
+		create      bool
+		prune       bool
+		yes         bool
+		force       bool
+		output      string
+		verify      bool
+		cache       string
+		history     string
+		canary      int
+		canaryRepos []string
+		soak        time.Duration
+		archive     bool
+		state       string
 	}{}
 
 	cmd := &cobra.Command{
 		Use:   "apply",
 		Short: "Apply applies the config settings to the github repository.",
-		Long:  `Apply applies the config settings to the github repository.`,
+		Long: `Apply applies the config settings to the github repository or to every repository targeted by the config.
+It exits with 0 when nothing changed, 2 when changes were applied and 1 on error.`,
 		Run: func(cmd *cobra.Command, args []string) {
-			client := github.New(flags.token)
+			secretValues := map[string]string{}
+
+			if flags.secretsFile != "" {
+				values, err := github.LoadSecretValues(flags.secretsFile)
+
+				if err != nil {
+					log.Fatal(err)
+				}
+
+				secretValues = values
+			}
 
-			settings, err := github.GetSettingsFromFile(flags.config)
+			renderer, err := newRenderer(flags.output, flags.dryRun, flags.config)
 
 			if err != nil {
 				log.Fatal(err)
 			}
 
-			err = client.Apply(settings)
+			var cache *github.ApplyCache
 
-			if err != nil {
+			if flags.cache != "" && !flags.dryRun {
+				cache, err = github.LoadApplyCache(flags.cache)
+
+				if err != nil {
+					log.Fatal(err)
+				}
+			}
+
+			state := loadState(flags.state)
+			var history *github.History
+
+			if flags.history != "" {
+				history, err = github.OpenHistory(flags.history)
+
+				if err != nil {
+					log.Fatal(err)
+				}
+
+				defer history.Close()
+			}
+
+			client := flags.newClient(github.WithSecretValues(secretValues), github.WithCreateRepositories(flags.create), github.WithPrune(flags.prune), github.WithForce(flags.force), github.WithVerify(flags.verify), github.WithApplyCache(cache), github.WithArchiveInactive(flags.archive), github.WithStateStore(state))
+
+			settings, settingsErrors := client.StreamAllSettingsFromFile(commandContext, flags.config)
+
+			canary := github.Canary{Count: flags.canary, Repositories: flags.canaryRepos}
+
+			// Without confirmation each repository is applied as soon as it is resolved, while the repositories of an org are still listed
+			// A canary needs every plan to select the repositories applied first
+			if flags.yes && !flags.dryRun && !canary.Enabled() {
+				succeeded := true
+
+				results := github.CollectResults(client.ApplyAllStream(commandContext, settings, flags.concurrency), func(result github.RepositoryResult) {
+					printDeletions([]github.RepositoryResult{result})
+					succeeded = renderer.Result(result) && succeeded
+				})
+
+				if err := <-settingsErrors; err != nil {
+					log.Fatal(err)
+				}
+
+				printOpenCircuits(client)
+				saveCache(cache)
+				saveState(state)
+				recordHistory(history, results)
+				flags.post(client, results)
+				succeeded = renderer.Finish(results) && succeeded
+
+				exit(results, false, succeeded, "Error applying some repositories")
+
+				return
+			}
+
+			planned := github.CollectResults(client.PlanStream(commandContext, settings, flags.concurrency), nil)
+
+			if err := <-settingsErrors; err != nil {
 				log.Fatal(err)
 			}
+
+			if flags.dryRun {
+				recordHistory(history, planned)
+				flags.post(client, planned)
+				exit(planned, true, render(renderer, planned), "Error planning some repositories")
+
+				return
+			}
+
+			deletions := printDeletions(planned)
+
+			if deletions != 0 && !flags.yes && !confirm(fmt.Sprintf("Apply %d deletions?", deletions)) {
+				log.Fatal("Apply cancelled, use --yes to apply deletions without confirmation")
+			}
+
+			// The repositories that failed are reported by exit from their results
+			var results []github.RepositoryResult
+
+			if canary.Enabled() {
+				results = applyCanary(client, canary, planned, flags.concurrency, flags.soak, flags.yes)
+			} else {
+				results, _ = client.ApplyPlans(commandContext, planned, flags.concurrency)
+			}
+
+			printOpenCircuits(client)
+			saveCache(cache)
+			saveState(state)
+			recordHistory(history, results)
+			flags.post(client, results)
+			exit(results, false, render(renderer, results), "Error applying some repositories")
 		},
 	}
 
 	cmd.Flags().StringVarP(&flags.config, "config", "c", "settings.yml", "Configuration file path")
-	cmd.Flags().StringVarP(&flags.token, "token", "t", "", "Github personnal token")
+	cmd.Flags().BoolVar(&flags.dryRun, "dry-run", false, "Print the changes without applying them")
+	cmd.Flags().IntVar(&flags.concurrency, "concurrency", github.DefaultConcurrency, "Number of repositories applied in parallel")
+	cmd.Flags().StringVar(&flags.secretsFile, "secrets-file", "", "Yaml file mapping actions secret names to their values (defaults to environment variables)")
+	cmd.Flags().BoolVar(&flags.create, "create", false, "Create the repositories that do not exist")
+	cmd.Flags().BoolVar(&flags.prune, "prune", true, "Delete the resources missing from the config (the prune section of the config overrides it)")
+	cmd.Flags().BoolVarP(&flags.yes, "yes", "y", false, "Apply deletions without asking for confirmation")
+	cmd.Flags().BoolVar(&flags.force, "force", false, "Apply to repositories declared as managed by another tool (managed-by topic or custom property) and plan the repositories skipped by the cache")
+	cmd.Flags().StringVarP(&flags.output, "output", "o", outputText, "Output format (text, json, markdown or sarif), exits with 2 when changes were applied")
+	cmd.Flags().BoolVar(&flags.verify, "verify", false, "Fetch the settings again after apply and fail when github does not reflect the applied changes")
+	cmd.Flags().StringVar(&flags.cache, "cache", "", "Apply cache file, repositories unchanged since their last successful apply are skipped (disabled when empty)")
+	cmd.Flags().StringVar(&flags.history, "history", "", "Sqlite database recording the outcome of every repository, queried with the history command (disabled when empty)")
+	cmd.Flags().IntVar(&flags.canary, "canary", 0, "Apply the first N repositories with changes, then the others once confirmed or after the soak period (disabled when 0)")
+	cmd.Flags().StringSliceVar(&flags.canaryRepos, "canary-repos", nil, "Repositories (owner/name) applied first, then the others once confirmed or after the soak period")
+	cmd.Flags().DurationVar(&flags.soak, "soak", 0, "Time waited after a successful canary before applying the others without confirmation")
+	cmd.Flags().StringVar(&flags.state, "state", "", "State store file recording the managed resources, only they are deleted when missing from the config (disabled when empty)")
+	cmd.Flags().BoolVar(&flags.archive, "archive-inactive", false, "Archive the repositories inactive for the days of an inactivity policy that archives, they are only reported otherwise")
+	flags.clientFlags.register(cmd)
+	flags.statusFlags.register(cmd)
 
 	return cmd
 }
+
+// printOpenCircuits warns about the resource kinds skipped after repeated server failures
+func printOpenCircuits(client *github.Client) {
+	for _, resource := range client.OpenCircuits() {
+		log.Warnf("Skipped %s for the remainder of the run after repeated server failures", resource)
+	}
+}
+
+// saveCache writes the apply cache, the apply already happened so a failure is only logged
+func saveCache(cache *github.ApplyCache) {
+	if cache == nil {
+		return
+	}
+
+	if err := cache.Save(); err != nil {
+		log.Error(err)
+	}
+}
+
+// loadState reads the state store of a file, nil without file
+func loadState(path string) *github.StateStore {
+	if path == "" {
+		return nil
+	}
+
+	state, err := github.LoadStateStore(path)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return state
+}
+
+// saveState writes the state store, the apply already happened so a failure is only logged
+func saveState(state *github.StateStore) {
+	if state == nil {
+		return
+	}
+
+	if err := state.Save(); err != nil {
+		log.Error(err)
+	}
+}
+
+// recordHistory adds the results to the history, the apply already happened so a failure is only logged
+func recordHistory(history *github.History, results []github.RepositoryResult) {
+	if history == nil {
+		return
+	}
+
+	if err := history.Record(commandContext, results, time.Now()); err != nil {
+		log.Error(err)
+	}
+}
+
+// applyCanary applies the repositories selected by the canary, then the others once confirmed or after the soak period
+// The others are planned again before being applied since their live settings may have changed while waiting, the rollout
+// stops after the canary when one of its repositories failed or the rollout was not confirmed
+func applyCanary(client *github.Client, canary github.Canary, planned []github.RepositoryResult, concurrency int, soak time.Duration, yes bool) []github.RepositoryResult {
+	first, rest, err := canary.Split(planned)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Infof("Applying the canary to %d repositories", len(first))
+
+	results, _ := client.ApplyPlans(commandContext, first, concurrency)
+
+	for _, result := range results {
+		if !resultSucceeded(result) {
+			log.Errorf("Canary failed on %s, the %d remaining repositories were not applied", result.Repository, len(rest))
+			return results
+		}
+	}
+
+	if len(rest) == 0 {
+		return results
+	}
+
+	switch {
+	case soak > 0:
+		log.Infof("Canary succeeded, soaking %s before applying the %d remaining repositories", soak, len(rest))
+
+		select {
+		case <-commandContext.Done():
+			log.Warnf("Rollout interrupted while soaking, the %d remaining repositories were not applied", len(rest))
+			return results
+		case <-time.After(soak):
+		}
+	case !yes && !confirm(fmt.Sprintf("Canary succeeded, apply the %d remaining repositories?", len(rest))):
+		log.Warnf("Rollout stopped after the canary, the %d remaining repositories were not applied", len(rest))
+		return results
+	}
+
+	// The repositories that failed planning again are reported by exit from their results
+	replanned, _ := client.Replan(commandContext, rest, concurrency)
+	applied, _ := client.ApplyPlans(commandContext, replanned, concurrency)
+
+	return append(results, applied...)
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+func init() {
+	rootCmd.AddCommand(newChecks())
+}
+
+func newChecks() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "checks",
+		Short: "Checks helps writing required status checks.",
+		Long:  `Checks helps writing required status checks.`,
+	}
+
+	cmd.AddCommand(newChecksDiscover())
+
+	return cmd
+}
+
+func newChecksDiscover() *cobra.Command {
+	flags := struct {
+		clientFlags
+		branch  string
+		commits int
+	}{}
+
+	cmd := &cobra.Command{
+		Use:   "discover owner/repo",
+		Short: "Discover lists the status check contexts reported on the last commits of a branch.",
+		Long:  `Discover lists the status check contexts reported on the last commits of a branch, formatted for the requiredstatuschecks contexts setting.`,
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			owner, name, err := splitFullName(args[0])
+
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			contexts, err := flags.newClient().DiscoverStatusChecks(commandContext, owner, name, flags.branch, flags.commits)
+
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			content, err := yaml.Marshal(map[string][]string{"contexts": contexts})
+
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			fmt.Print(string(content))
+		},
+	}
+
+	cmd.Flags().StringVarP(&flags.branch, "branch", "b", "main", "Branch to inspect")
+	cmd.Flags().IntVarP(&flags.commits, "commits", "n", 10, "Number of recent commits to inspect")
+	flags.register(cmd)
+
+	return cmd
+}
+
+// splitFullName splits an owner/repo argument
+func splitFullName(fullName string) (string, string, error) {
+	parts := strings.Split(fullName, "/")
+
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", errors.Errorf("Invalid repository %s, expected owner/repo", fullName)
+	}
+
+	return parts[0], parts[1], nil
+}
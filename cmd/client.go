@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/michaelmass/github-settings/pkg/github"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// clientFlags holds the flags shared by the commands calling the github api
+type clientFlags struct {
+	token           string
+	tokenFile       string
+	baseURL         string
+	uploadURL       string
+	appID           int64
+	appInstall      int64
+	appKey          string
+	authCmd         string
+	oidcURL         string
+	oidcAud         string
+	oidcFile        string
+	apiVersion      string
+	previews        []string
+	endpointVersion []string
+	endpointPreview []string
+	userAgentSuffix string
+	timeout         time.Duration
+	breaker         int
+	retries         int
+	remoteEnv       []string
+}
+
+func (flags *clientFlags) register(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&flags.token, "token", "t", "", "Github personnal token")
+	cmd.Flags().StringVar(&flags.tokenFile, "token-file", "", "File holding the github token, read again when serve receives SIGHUP")
+	cmd.Flags().StringVar(&flags.baseURL, "base-url", github.DefaultBaseURL, "Github rest api url (ex: https://github.example.com/api/v3/ for github enterprise server)")
+	cmd.Flags().StringVar(&flags.uploadURL, "upload-url", "", "Github enterprise server upload url (defaults to the base url)")
+	cmd.Flags().Int64Var(&flags.appID, "app-id", 0, "Github app id, authenticates as the app installation instead of the token")
+	cmd.Flags().Int64Var(&flags.appInstall, "app-installation-id", 0, "Github app installation id")
+	cmd.Flags().StringVar(&flags.appKey, "app-private-key", "", "Github app private key file (pem)")
+	cmd.Flags().StringVar(&flags.authCmd, "auth-command", "", "Command printing the github token (alone or as json with its expiry), split on spaces, authenticates with its tokens instead of the token")
+	cmd.Flags().StringVar(&flags.oidcURL, "oidc-exchange-url", "", "Url exchanging the oidc token of the workload for a github token, authenticates with its tokens instead of the token")
+	cmd.Flags().StringVar(&flags.oidcAud, "oidc-audience", "", "Audience of the oidc token requested from github actions")
+	cmd.Flags().StringVar(&flags.oidcFile, "oidc-token-file", "", "File holding the oidc token (defaults to the token of the github actions job)")
+	cmd.Flags().StringVar(&flags.apiVersion, "api-version", github.DefaultAPIVersion, "Github rest api version sent with every request")
+	cmd.Flags().StringSliceVar(&flags.previews, "preview", nil, "Additional preview media types sent in the Accept header")
+	cmd.Flags().StringSliceVar(&flags.endpointVersion, "endpoint-api-version", nil, "Api version sent to the endpoints matching a path pattern (ex: /repos/*/*/rulesets/**=2022-11-28)")
+	cmd.Flags().StringSliceVar(&flags.endpointPreview, "endpoint-preview", nil, "Preview media type sent to the endpoints matching a path pattern (ex: /repos/*/*/topics=application/vnd.github.mercy-preview+json)")
+	cmd.Flags().StringVar(&flags.userAgentSuffix, "user-agent-suffix", "", "Identification appended to the User-Agent (ex: pipeline id)")
+	cmd.Flags().DurationVar(&flags.timeout, "resource-timeout", 0, "Maximum time spent applying a single resource change (0 for no limit)")
+	cmd.Flags().IntVar(&flags.retries, "max-retries", github.DefaultMaxRetries, "Number of times a request rejected by a github rate limit is retried")
+	cmd.Flags().StringSliceVar(&flags.remoteEnv, "remote-env", nil, "Environment variables the remote files extended by the settings can reference (ex: DOMAIN)")
+	cmd.Flags().IntVar(&flags.breaker, "breaker-threshold", github.DefaultBreakerThreshold, "Consecutive server failures after which a resource kind is skipped (0 to disable)")
+}
+
+func (flags *clientFlags) newClient(opts ...github.Option) *github.Client {
+	clientOpts := []github.Option{
+		github.WithToken(flags.token),
+		github.WithTokenFile(flags.tokenFile),
+		github.WithBaseURL(flags.baseURL),
+		github.WithUploadURL(flags.uploadURL),
+		github.WithAPIVersion(flags.apiVersion),
+		github.WithPreviews(flags.previews...),
+		github.WithUserAgent(userAgent()),
+		github.WithUserAgentSuffix(flags.userAgentSuffix),
+		github.WithResourceTimeout(flags.timeout),
+		github.WithCircuitBreaker(flags.breaker),
+		github.WithMaxRetries(flags.retries),
+		github.WithRemoteEnv(flags.remoteEnv...),
+	}
+
+	for _, endpoint := range flags.endpointVersion {
+		pattern, version, ok := strings.Cut(endpoint, "=")
+
+		if !ok {
+			log.Fatalf("Invalid endpoint api version %q, expected pattern=version", endpoint)
+		}
+
+		clientOpts = append(clientOpts, github.WithEndpointHeaders(pattern, version))
+	}
+
+	for _, endpoint := range flags.endpointPreview {
+		pattern, preview, ok := strings.Cut(endpoint, "=")
+
+		if !ok {
+			log.Fatalf("Invalid endpoint preview %q, expected pattern=media type", endpoint)
+		}
+
+		clientOpts = append(clientOpts, github.WithEndpointHeaders(pattern, "", preview))
+	}
+
+	command := strings.Fields(flags.authCmd)
+
+	switch {
+	case len(command) != 0:
+		clientOpts = append(clientOpts, github.WithAuthProvider(github.NewCommandProvider(command[0], command[1:]...)))
+	case flags.oidcURL != "":
+		clientOpts = append(clientOpts, github.WithAuthProvider(github.NewOIDCProvider(flags.oidcURL, flags.oidcAud, flags.oidcFile)))
+	case flags.appID != 0:
+		privateKey, err := ioutil.ReadFile(flags.appKey)
+
+		if err != nil {
+			log.Fatalf("Error reading the app private key: %s", err)
+		}
+
+		clientOpts = append(clientOpts, github.WithAppAuth(flags.appID, flags.appInstall, privateKey))
+	}
+
+	client, err := github.New(append(clientOpts, opts...)...)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return client
+}
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/michaelmass/github-settings/pkg/github"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(newCloneSettings())
+}
+
+func newCloneSettings() *cobra.Command {
+	flags := struct {
+		clientFlags
+		from   string
+		to     string
+		only   []string
+		prune  bool
+		dryRun bool
+		yes    bool
+	}{}
+
+	cmd := &cobra.Command{
+		Use:   "clone-settings",
+		Short: "Clone-settings copies the live settings of a repository to another repository.",
+		Long: `Clone-settings reads the live settings of a repository and applies them to another repository without a config file.
+The sections cloned are selected with --only (protection is an alias of branches), every section is cloned when none is selected.
+The target repository keeps its name, description, homepage, default branch and visibility. Secrets and files are never cloned,
+webhooks are cloned without their secret. The resources of the target missing from the source are kept unless --prune is set.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			fromOwner, fromName, err := splitFullName(flags.from)
+
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			toOwner, toName, err := splitFullName(flags.to)
+
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			client := flags.newClient(github.WithPrune(flags.prune))
+			settings, warnings, err := client.CloneSettings(commandContext, fromOwner, fromName, toOwner, toName, flags.only)
+
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			for _, warning := range warnings {
+				log.Warn(warning)
+			}
+
+			plan, err := client.Plan(commandContext, settings)
+
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			fmt.Print(plan)
+
+			if flags.dryRun || plan.Empty() {
+				return
+			}
+
+			planned := github.RepositoryResult{Repository: flags.to, Plan: plan}
+			deletions := printDeletions([]github.RepositoryResult{planned})
+
+			if deletions != 0 && !flags.yes && !confirm(fmt.Sprintf("Apply %d deletions?", deletions)) {
+				log.Fatal("Clone cancelled, use --yes to apply deletions without confirmation")
+			}
+
+			start := time.Now()
+			result, err := client.ApplyPlan(commandContext, plan)
+			applied := github.RepositoryResult{Repository: flags.to, Plan: plan, Result: result, Err: err, Duration: time.Since(start)}
+
+			if !printApplied([]github.RepositoryResult{applied}) {
+				log.Fatalf("Error cloning the settings of %s to %s", flags.from, flags.to)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&flags.from, "from", "", "Repository whose settings are cloned (owner/repo)")
+	cmd.Flags().StringVar(&flags.to, "to", "", "Repository the settings are applied to (owner/repo)")
+	cmd.Flags().StringSliceVar(&flags.only, "only", nil, "Sections cloned (ex: labels,webhooks,protection), every section when empty")
+	cmd.Flags().BoolVar(&flags.prune, "prune", false, "Delete the resources of the target missing from the source")
+	cmd.Flags().BoolVar(&flags.dryRun, "dry-run", false, "Print the changes without applying them")
+	cmd.Flags().BoolVarP(&flags.yes, "yes", "y", false, "Apply deletions without asking for confirmation")
+	flags.register(cmd)
+
+	return cmd
+}
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/michaelmass/github-settings/pkg/github"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(newConvert())
+}
+
+func newConvert() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "convert",
+		Short: "Convert translates settings between equivalent formats.",
+		Long:  `Convert translates settings between equivalent formats.`,
+	}
+
+	cmd.AddCommand(newConvertProtectionsToRulesets())
+
+	return cmd
+}
+
+func newConvertProtectionsToRulesets() *cobra.Command {
+	flags := struct {
+		clientFlags
+		config string
+		mode   string
+	}{}
+
+	cmd := &cobra.Command{
+		Use:   "protections-to-rulesets [owner/repo]",
+		Short: "Protections-to-rulesets prints the rulesets equivalent to the classic branch protections.",
+		Long: `Protections-to-rulesets reads the classic branch protections of a repository, live when owner/repo is given or from the config
+otherwise, and prints the rulesets section of a config file holding one equivalent ruleset per protected branch.
+The options rulesets can't express or express differently are logged as warnings, they need a manual review.`,
+		Args: cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			client := flags.newClient()
+			allSettings := []*github.Settings{}
+
+			if len(args) == 1 {
+				owner, name, err := splitFullName(args[0])
+
+				if err != nil {
+					log.Fatal(err)
+				}
+
+				settings, err := client.GetSettingsFromGithub(commandContext, owner, name)
+
+				if err != nil {
+					log.Fatal(err)
+				}
+
+				allSettings = append(allSettings, settings)
+			} else {
+				settings, err := client.GetAllSettingsFromFile(commandContext, flags.config)
+
+				if err != nil {
+					log.Fatal(err)
+				}
+
+				allSettings = settings
+			}
+
+			for i, settings := range allSettings {
+				converted, warnings, err := github.ProtectionsToRulesets(settings, flags.mode)
+
+				if err != nil {
+					log.Fatal(err)
+				}
+
+				for _, warning := range warnings {
+					log.Warn(warning)
+				}
+
+				content, err := github.MarshalSections(converted, []string{github.ResourceRulesets}, nil)
+
+				if err != nil {
+					log.Fatal(err)
+				}
+
+				if i > 0 {
+					fmt.Println("---")
+				}
+
+				if len(allSettings) > 1 {
+					fmt.Printf("# %s/%s\n", settings.Repository.Owner, settings.Repository.Name)
+				}
+
+				fmt.Print(string(content))
+			}
+		},
+	}
+
+	cmd.Flags().StringVarP(&flags.config, "config", "c", "settings.yml", "Configuration file path, read when no repository is given")
+	cmd.Flags().StringVar(&flags.mode, "mode", "active", "Enforcement of the rulesets (active, evaluate or disabled)")
+	flags.register(cmd)
+
+	return cmd
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(newE2E())
+}
+
+func newE2E() *cobra.Command {
+	flags := struct {
+		clientFlags
+	}{}
+
+	cmd := &cobra.Command{
+		Use:    "e2e owner/repo",
+		Short:  "E2e runs the end to end test against a sandbox repository.",
+		Long:   `E2e creates, updates and deletes every kind of resource on a sandbox repository, checking each step converged, then restores the sandbox. It is meant for release qualification and to check compatibility with a github enterprise server.`,
+		Args:   cobra.ExactArgs(1),
+		Hidden: true,
+		Run: func(cmd *cobra.Command, args []string) {
+			owner, name, err := splitFullName(args[0])
+
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			steps, err := flags.newClient().EndToEnd(commandContext, owner, name)
+
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			succeeded := true
+
+			for _, step := range steps {
+				if step.Err != nil {
+					log.Errorf("%s: %s", step.Name, step.Err)
+					succeeded = false
+
+					continue
+				}
+
+				if step.Result == nil {
+					fmt.Printf("%s: ok\n", step.Name)
+					continue
+				}
+
+				fmt.Printf("%s: ok, %d changes applied\n", step.Name, len(step.Result.Applied))
+			}
+
+			if !succeeded {
+				log.Fatalf("End to end test failed on %s", args[0])
+			}
+		},
+	}
+
+	flags.register(cmd)
+
+	return cmd
+}
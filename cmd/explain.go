@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(newExplain())
+}
+
+func newExplain() *cobra.Command {
+	flags := struct {
+		clientFlags
+		config string
+	}{}
+
+	cmd := &cobra.Command{
+		Use:   "explain owner/repo [field.path]",
+		Short: "Explain prints the effective settings of a repository and where each value comes from.",
+		Long: `Explain prints the effective settings of a repository once the files extended and included by the config and the defaults
+of a multi repository config are merged, with the file, and the section (org, defaults or repositories), that set each value.
+The values set by no file are marked default. A field path (ex: branches.main.protection) only prints the values under it,
+the resources are named by their key (ex: labels.bug.color).`,
+		Args: cobra.RangeArgs(1, 2),
+		Run: func(cmd *cobra.Command, args []string) {
+			owner, name, err := splitFullName(args[0])
+
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			explanations, err := flags.newClient().Explain(commandContext, flags.config, owner, name)
+
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			field := ""
+
+			if len(args) == 2 {
+				field = args[1]
+			}
+
+			writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(writer, "FIELD\tVALUE\tSOURCE")
+			found := false
+
+			for _, explanation := range explanations {
+				if field != "" && explanation.Path != field && !strings.HasPrefix(explanation.Path, field+".") {
+					continue
+				}
+
+				found = true
+				fmt.Fprintf(writer, "%s\t%s\t%s\n", explanation.Path, explanation.ValueString(), explanation.SourceString())
+			}
+
+			if !found {
+				log.Fatalf("Unknown field %s, the fields are named after the keys of the config (ex: branches.main.protection)", field)
+			}
+
+			writer.Flush()
+		},
+	}
+
+	cmd.Flags().StringVarP(&flags.config, "config", "c", "settings.yml", "Configuration file path")
+	flags.register(cmd)
+
+	return cmd
+}
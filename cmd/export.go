@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/michaelmass/github-settings/pkg/github"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(newExport())
+}
+
+func newExport() *cobra.Command {
+	flags := struct {
+		clientFlags
+		output  string
+		only    []string
+		exclude []string
+	}{}
+
+	cmd := &cobra.Command{
+		Use:   "export owner/repo",
+		Short: "Export dumps the live settings of a github repository to a config file.",
+		Long: `Export dumps the live settings of a github repository to a config file accepted by apply. Webhook secrets are redacted.
+--only and --exclude keep some sections (ex: --only labels,branches) to build a shared file extended by other settings files.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			owner, name, err := splitFullName(args[0])
+
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			settings, err := flags.newClient().Export(commandContext, owner, name)
+
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			content, err := github.MarshalSections(settings, flags.only, flags.exclude)
+
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			if flags.output == "" {
+				fmt.Print(string(content))
+				return
+			}
+
+			err = ioutil.WriteFile(flags.output, content, defaultFilePermission)
+
+			if err != nil {
+				log.Fatal(err)
+			}
+		},
+	}
+
+	cmd.Flags().StringVarP(&flags.output, "output", "o", "", "Configuration file path, the settings are printed when empty")
+	cmd.Flags().StringSliceVar(&flags.only, "only", nil, "Sections exported, every section when empty ("+strings.Join(github.ExportSections, ", ")+")")
+	cmd.Flags().StringSliceVar(&flags.exclude, "exclude", nil, "Sections left out of the export")
+	flags.register(cmd)
+
+	return cmd
+}
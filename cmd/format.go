@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Output formats of the commands printing reports
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+// timeLayout renders timestamps in reports with their timezone abbreviation
+const timeLayout = "2006-01-02 15:04 MST"
+
+// location returns the timezone timestamps are rendered in
+func location() *time.Location {
+	loc, err := time.LoadLocation(timezone)
+
+	if err != nil {
+		log.Fatalf("Invalid timezone %s: %s", timezone, err)
+	}
+
+	return loc
+}
+
+// formatTimestamp renders a RFC3339 timestamp in the configured timezone followed by its age (ex: 2024-03-01 10:00 CET, 2 months ago)
+func formatTimestamp(timestamp string, now time.Time) string {
+	if timestamp == "" {
+		return "never"
+	}
+
+	parsed, err := time.Parse(time.RFC3339, timestamp)
+
+	if err != nil {
+		return timestamp
+	}
+
+	return fmt.Sprintf("%s, %s ago", parsed.In(location()).Format(timeLayout), humanizeDuration(now.Sub(parsed)))
+}
+
+// humanizeDuration renders a duration with its largest unit (ex: 3 days, 1 hour, 12 seconds)
+func humanizeDuration(duration time.Duration) string {
+	units := []struct {
+		name     string
+		duration time.Duration
+	}{
+		{"year", 365 * 24 * time.Hour},
+		{"month", 30 * 24 * time.Hour},
+		{"day", 24 * time.Hour},
+		{"hour", time.Hour},
+		{"minute", time.Minute},
+		{"second", time.Second},
+	}
+
+	duration = time.Duration(math.Abs(float64(duration)))
+
+	for _, unit := range units {
+		if duration < unit.duration {
+			continue
+		}
+
+		count := int(duration / unit.duration)
+
+		if count == 1 {
+			return fmt.Sprintf("1 %s", unit.name)
+		}
+
+		return fmt.Sprintf("%d %ss", count, unit.name)
+	}
+
+	return fmt.Sprintf("%d milliseconds", duration.Milliseconds())
+}
+
+// printJSON prints a value as indented json
+func printJSON(value interface{}) {
+	content, err := json.MarshalIndent(value, "", "  ")
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println(string(content))
+}
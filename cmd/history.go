@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/michaelmass/github-settings/pkg/github"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(newHistory())
+}
+
+func newHistory() *cobra.Command {
+	flags := struct {
+		history string
+		since   time.Duration
+		drift   bool
+		limit   int
+		output  string
+	}{}
+
+	cmd := &cobra.Command{
+		Use:   "history [owner/repo]",
+		Short: "History prints the past reconciliations of the repositories recorded by serve and apply.",
+		Long: `History prints the outcome of the past reconciliations recorded with --history by serve and apply, the most recent
+first, with the drift planned and the changes applied. It answers when a repository last drifted and what changed.`,
+		Args: cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if _, err := os.Stat(flags.history); err != nil {
+				log.Fatalf("Error opening history %s, record it with the --history flag of serve or apply: %s", flags.history, err)
+			}
+
+			history, err := github.OpenHistory(flags.history)
+
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			defer history.Close()
+
+			query := github.HistoryQuery{Drift: flags.drift, Limit: flags.limit}
+
+			if len(args) == 1 {
+				query.Repository = args[0]
+			}
+
+			if flags.since != 0 {
+				query.Since = time.Now().Add(-flags.since)
+			}
+
+			entries, err := history.Query(commandContext, query)
+
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			switch flags.output {
+			case outputJSON:
+				printJSON(entries)
+			case outputText:
+				for _, entry := range entries {
+					fmt.Print(entry)
+				}
+			default:
+				log.Fatalf("Invalid output format %s (text or json)", flags.output)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&flags.history, "history", "history.db", "Sqlite database recorded by serve and apply")
+	cmd.Flags().DurationVar(&flags.since, "since", 0, "Only print the reconciliations of this last period (ex: 168h, all of them when 0)")
+	cmd.Flags().BoolVar(&flags.drift, "drift", false, "Only print the reconciliations with drift or an error")
+	cmd.Flags().IntVar(&flags.limit, "limit", github.DefaultHistoryLimit, "Maximum number of reconciliations printed")
+	cmd.Flags().StringVarP(&flags.output, "output", "o", outputText, "Output format (text or json)")
+
+	return cmd
+}
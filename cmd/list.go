@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/michaelmass/github-settings/pkg/github"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(newList())
+}
+
+func newList() *cobra.Command {
+	flags := struct {
+		clientFlags
+		configs []string
+		cache   string
+		history string
+	}{}
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the github repositories targeted by the config files.",
+		Long: `List the github repositories targeted by the config files along with the config file they come from.
+With --cache or --history it also prints when each repository was last applied and its latest status, read from the apply
+cache and the history recorded by apply and serve without calling github.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			files, err := expandConfigs(flags.configs)
+
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			var cache *github.ApplyCache
+
+			if flags.cache != "" {
+				cache, err = github.LoadApplyCache(flags.cache)
+
+				if err != nil {
+					log.Fatal(err)
+				}
+			}
+
+			statuses := map[string]github.RepositoryStatus{}
+
+			if flags.history != "" {
+				statuses = historyStatuses(flags.history)
+			}
+
+			client := flags.newClient(github.WithApplyCache(cache))
+			withStatus := flags.cache != "" || flags.history != ""
+			writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+			if withStatus {
+				fmt.Fprintln(writer, "REPOSITORY\tCONFIG\tLAST APPLIED\tSTATUS")
+			} else {
+				fmt.Fprintln(writer, "REPOSITORY\tCONFIG")
+			}
+
+			now := time.Now()
+
+			for _, file := range files {
+				allSettings, err := client.GetAllSettingsFromFile(commandContext, file)
+
+				if err != nil {
+					log.Fatal(err)
+				}
+
+				for _, settings := range allSettings {
+					fullName := settings.Repository.Owner + "/" + settings.Repository.Name
+
+					if !withStatus {
+						fmt.Fprintf(writer, "%s\t%s\n", fullName, file)
+						continue
+					}
+
+					lastApplied, status := repositoryStatus(client, settings, statuses[fullName], now)
+					fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", fullName, file, lastApplied, status)
+				}
+			}
+
+			writer.Flush()
+		},
+	}
+
+	cmd.Flags().StringSliceVarP(&flags.configs, "config", "c", []string{"settings.yml"}, "Configuration file paths or glob patterns")
+	cmd.Flags().StringVar(&flags.cache, "cache", "", "Apply cache file written by apply --cache, prints when each repository was last applied successfully")
+	cmd.Flags().StringVar(&flags.history, "history", "", "Sqlite database recorded by apply and serve, prints the latest status of each repository")
+	flags.register(cmd)
+
+	return cmd
+}
+
+// historyStatuses returns the latest status of every repository of a history keyed by full name
+func historyStatuses(path string) map[string]github.RepositoryStatus {
+	if _, err := os.Stat(path); err != nil {
+		log.Fatalf("Error opening history %s, record it with the --history flag of serve or apply: %s", path, err)
+	}
+
+	history, err := github.OpenHistory(path)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	defer history.Close()
+
+	statuses, err := history.Statuses(commandContext)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	byRepository := map[string]github.RepositoryStatus{}
+
+	for _, status := range statuses {
+		byRepository[status.Repository] = status
+	}
+
+	return byRepository
+}
+
+// repositoryStatus renders the last apply of a repository, the latest of the apply cache and the history, and its latest
+// status in the history, flagged when the settings changed since the last successful apply
+func repositoryStatus(client *github.Client, settings *github.Settings, status github.RepositoryStatus, now time.Time) (string, string) {
+	lastApplied := status.LastApplied
+	state := "unknown"
+
+	if status.Repository != "" {
+		state = fmt.Sprintf("%s (%s)", status.State(), formatTimestamp(status.Time.Format(time.RFC3339), now))
+	}
+
+	if cached, ok := client.CachedApply(settings); ok {
+		if cached.AppliedAt.After(lastApplied) {
+			lastApplied = cached.AppliedAt
+		}
+
+		if cached.ConfigChanged {
+			state += ", config changed since the last apply"
+		}
+	}
+
+	if lastApplied.IsZero() {
+		return "never", state
+	}
+
+	return formatTimestamp(lastApplied.Format(time.RFC3339), now), state
+}
+
+// expandConfigs resolves glob patterns into a list of configuration files
+func expandConfigs(patterns []string) ([]string, error) {
+	files := []string{}
+
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+
+		if err != nil {
+			return nil, errors.Wrapf(err, "Error matching configuration pattern %s", pattern)
+		}
+
+		if len(matches) == 0 {
+			return nil, errors.Errorf("No configuration file matches %s", pattern)
+		}
+
+		files = append(files, matches...)
+	}
+
+	return files, nil
+}
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/michaelmass/github-settings/pkg/github"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(newOrg())
+}
+
+func newOrg() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "org",
+		Short: "Org manages the settings of the organization declared by the org of a config file.",
+		Long: `Org manages the settings of the organization declared by the org of a config file, the repositories are left alone.
+The org is then a mapping with its name and its settings (ex: the teams granted the security manager role in security.managerteams).`,
+	}
+
+	cmd.AddCommand(newOrgPlan())
+	cmd.AddCommand(newOrgApply())
+
+	return cmd
+}
+
+func newOrgPlan() *cobra.Command {
+	flags := struct {
+		clientFlags
+		config string
+	}{}
+
+	cmd := &cobra.Command{
+		Use:   "plan",
+		Short: "Plan prints the changes apply would make to the organization.",
+		Long: `Plan prints the changes apply would make to the settings of the organization without modifying them.
+It exits with 0 when nothing would change, 2 when changes are planned and 1 on error.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			client := flags.newClient()
+			plan := planOrg(client, flags.config)
+
+			fmt.Print(plan)
+
+			if !plan.Empty() {
+				os.Exit(exitChanges)
+			}
+		},
+	}
+
+	cmd.Flags().StringVarP(&flags.config, "config", "c", "settings.yml", "Configuration file path")
+	flags.register(cmd)
+
+	return cmd
+}
+
+func newOrgApply() *cobra.Command {
+	flags := struct {
+		clientFlags
+		config string
+		dryRun bool
+	}{}
+
+	cmd := &cobra.Command{
+		Use:   "apply",
+		Short: "Apply applies the settings of the organization.",
+		Long: `Apply applies the settings of the organization declared by the org of the config file.
+It exits with 0 when nothing changed, 2 when changes were applied and 1 on error.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			client := flags.newClient()
+			plan := planOrg(client, flags.config)
+
+			fmt.Print(plan)
+
+			if flags.dryRun || plan.Empty() {
+				return
+			}
+
+			start := time.Now()
+			result, err := client.ApplyPlan(commandContext, plan)
+			applied := github.RepositoryResult{Repository: plan.Owner, Plan: plan, Result: result, Err: err, Duration: time.Since(start)}
+
+			exit([]github.RepositoryResult{applied}, false, printApplied([]github.RepositoryResult{applied}), "Error applying the settings of "+plan.Owner)
+		},
+	}
+
+	cmd.Flags().StringVarP(&flags.config, "config", "c", "settings.yml", "Configuration file path")
+	cmd.Flags().BoolVar(&flags.dryRun, "dry-run", false, "Print the changes without applying them")
+	flags.register(cmd)
+
+	return cmd
+}
+
+// planOrg plans the settings of the organization declared by a config file
+func planOrg(client *github.Client, file string) *github.Plan {
+	org, err := client.GetOrgSettingsFromFile(file)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	plan, err := client.PlanOrg(commandContext, org)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return plan
+}
@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/michaelmass/github-settings/pkg/github"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(newPlan())
+}
+
+func newPlan() *cobra.Command {
+	flags := struct {
+		clientFlags
+		statusFlags
+		config      string
+		concurrency int
+		create      bool
+		prune       bool
+		summaryOnly bool
+		output      string
+		snapshot    string
+		against     string
+		webhooks    int
+		archive     bool
+		state       string
+	}{}
+
+	cmd := &cobra.Command{
+		Use:   "plan",
+		Short: "Plan prints the changes apply would make to the github repository.",
+		Long: `Plan prints the changes apply would make to the github repositories without modifying them.
+It exits with 0 when nothing would change, 2 when changes are planned and 1 on error.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			renderer, err := newRenderer(flags.output, true, flags.config)
+
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			if text, ok := renderer.(*textRenderer); ok {
+				text.summaryOnly = flags.summaryOnly
+			}
+
+			opts := []github.Option{github.WithCreateRepositories(flags.create), github.WithPrune(flags.prune), github.WithWebhookHealth(flags.webhooks), github.WithArchiveInactive(flags.archive), github.WithStateStore(loadState(flags.state))}
+			var snapshot *github.Snapshot
+
+			if flags.snapshot != "" {
+				snapshot = github.NewSnapshot(time.Now())
+				opts = append(opts, github.WithSnapshot(snapshot))
+			}
+
+			if flags.against != "" {
+				against, err := github.LoadSnapshot(flags.against)
+
+				if err != nil {
+					log.Fatal(err)
+				}
+
+				opts = append(opts, github.WithAgainst(against))
+			}
+
+			client := flags.newClient(opts...)
+
+			// Repositories are planned while the repositories of an org are still listed, plans are printed as they complete
+			settings, settingsErrors := client.StreamAllSettingsFromFile(commandContext, flags.config)
+			succeeded := true
+
+			results := github.CollectResults(client.PlanStream(commandContext, settings, flags.concurrency), func(result github.RepositoryResult) {
+				succeeded = renderer.Result(result) && succeeded
+			})
+
+			if err := <-settingsErrors; err != nil {
+				log.Fatal(err)
+			}
+
+			if snapshot != nil {
+				if err := snapshot.Save(flags.snapshot); err != nil {
+					log.Fatal(err)
+				}
+			}
+
+			flags.post(client, results)
+			succeeded = renderer.Finish(results) && succeeded
+
+			exit(results, true, succeeded, "Error planning some repositories")
+		},
+	}
+
+	cmd.Flags().StringVarP(&flags.config, "config", "c", "settings.yml", "Configuration file path")
+	cmd.Flags().IntVar(&flags.concurrency, "concurrency", github.DefaultConcurrency, "Number of repositories planned in parallel")
+	cmd.Flags().BoolVar(&flags.create, "create", false, "Plan the creation of the repositories that do not exist")
+	cmd.Flags().BoolVar(&flags.prune, "prune", true, "Plan the deletion of the resources missing from the config (the prune section of the config overrides it)")
+	cmd.Flags().IntVar(&flags.webhooks, "webhook-health", 0, "Report the webhooks whose last N deliveries all failed as drift (disabled when 0)")
+	cmd.Flags().StringVar(&flags.state, "state", "", "State store file recording the managed resources, only they are planned for deletion when missing from the config (disabled when empty)")
+	cmd.Flags().BoolVar(&flags.archive, "archive-inactive", false, "Plan the archival of the repositories inactive for the days of an inactivity policy that archives, they are only reported otherwise")
+	cmd.Flags().BoolVar(&flags.summaryOnly, "summary-only", false, "Only print the changes grouped by kind across repositories")
+	cmd.Flags().StringVarP(&flags.output, "output", "o", outputText, "Output format (text, json, markdown or sarif), exits with 2 when changes are planned")
+	cmd.Flags().StringVar(&flags.snapshot, "save-snapshot", "", "Json file the live settings of the repositories are saved to, to plan against them later with --against")
+	cmd.Flags().StringVar(&flags.against, "against", "", "Plan against the live settings saved by --save-snapshot instead of github")
+	flags.clientFlags.register(cmd)
+	flags.statusFlags.register(cmd)
+
+	return cmd
+}
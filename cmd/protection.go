@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/michaelmass/github-settings/pkg/github"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(newProtection())
+}
+
+func newProtection() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "protection",
+		Short: "Protection manages branch protections without a config file.",
+		Long:  `Protection manages branch protections without a config file.`,
+	}
+
+	cmd.AddCommand(newProtectionCopy())
+
+	return cmd
+}
+
+func newProtectionCopy() *cobra.Command {
+	flags := struct {
+		clientFlags
+		from   string
+		to     string
+		export bool
+		dryRun bool
+	}{}
+
+	cmd := &cobra.Command{
+		Use:   "copy",
+		Short: "Copy applies the live protection of a branch to another branch.",
+		Long: `Copy reads the live protection of a branch and applies it to another branch, of the same or another repository.
+Only the target branch is changed, it is created when missing. --export prints the protection as a branches section of a config file instead.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			from, err := splitBranchRef(flags.from)
+
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			to, err := splitBranchRef(flags.to)
+
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			client := flags.newClient()
+			settings, err := client.CopyProtection(commandContext, from, to)
+
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			if flags.export {
+				content, err := github.MarshalSections(settings, []string{github.ResourceBranches}, nil)
+
+				if err != nil {
+					log.Fatal(err)
+				}
+
+				fmt.Print(string(content))
+
+				return
+			}
+
+			plan, err := client.Plan(commandContext, settings)
+
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			fmt.Print(plan)
+
+			if flags.dryRun || plan.Empty() {
+				return
+			}
+
+			start := time.Now()
+			result, err := client.ApplyPlan(commandContext, plan)
+			applied := github.RepositoryResult{Repository: to.Owner + "/" + to.Name, Plan: plan, Result: result, Err: err, Duration: time.Since(start)}
+
+			if !printApplied([]github.RepositoryResult{applied}) {
+				log.Fatalf("Error copying the protection of %s to %s", from, to)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&flags.from, "from", "", "Branch whose protection is copied (owner/repo:branch)")
+	cmd.Flags().StringVar(&flags.to, "to", "", "Branch the protection is applied to (owner/repo:branch)")
+	cmd.Flags().BoolVar(&flags.export, "export", false, "Print the protection as config instead of applying it")
+	cmd.Flags().BoolVar(&flags.dryRun, "dry-run", false, "Print the changes without applying them")
+	flags.register(cmd)
+
+	return cmd
+}
+
+// splitBranchRef parses a branch of a repository written owner/repo:branch
+func splitBranchRef(value string) (github.BranchRef, error) {
+	fullName, branch, ok := strings.Cut(value, ":")
+
+	if !ok || branch == "" {
+		return github.BranchRef{}, errors.Errorf("Invalid branch %s, expected owner/repo:branch", value)
+	}
+
+	owner, name, err := splitFullName(fullName)
+
+	if err != nil {
+		return github.BranchRef{}, err
+	}
+
+	return github.BranchRef{Owner: owner, Name: name, Branch: branch}, nil
+}
@@ -0,0 +1,223 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/michaelmass/github-settings/pkg/github"
+	"github.com/pkg/errors"
+)
+
+// Output formats of plan and apply only, the other commands print text or json
+const (
+	outputMarkdown = "markdown"
+	outputSARIF    = "sarif"
+)
+
+// Renderer prints the results of plan and apply in an output format
+type Renderer interface {
+	// Result is called as each repository completes and returns false when the repository failed, renderers printing a single document print nothing
+	Result(result github.RepositoryResult) bool
+	// Finish is called once every repository completed and returns false when a repository failed
+	Finish(results []github.RepositoryResult) bool
+}
+
+// newRenderer returns the renderer of an output format, the config file is referenced by the sarif results
+func newRenderer(output string, planOnly bool, config string) (Renderer, error) {
+	switch output {
+	case outputText:
+		return &textRenderer{planOnly: planOnly}, nil
+	case outputJSON:
+		return &jsonRenderer{planOnly: planOnly}, nil
+	case outputMarkdown:
+		return &markdownRenderer{planOnly: planOnly}, nil
+	case outputSARIF:
+		return &sarifRenderer{planOnly: planOnly, config: config}, nil
+	default:
+		return nil, errors.Errorf("Invalid output format %s (text, json, markdown or sarif)", output)
+	}
+}
+
+// render prints results collected at once with a renderer and returns false when a repository failed
+func render(renderer Renderer, results []github.RepositoryResult) bool {
+	succeeded := true
+
+	for _, result := range results {
+		succeeded = renderer.Result(result) && succeeded
+	}
+
+	return renderer.Finish(results) && succeeded
+}
+
+// resultSucceeded returns false when the repository failed or when some of its changes were skipped or not verified
+func resultSucceeded(result github.RepositoryResult) bool {
+	if result.Err != nil {
+		return false
+	}
+
+	return result.Result == nil || (len(result.Result.Skipped) == 0 && len(result.Result.Unverified) == 0)
+}
+
+// textRenderer prints the plan or the outcome of each repository as it completes
+type textRenderer struct {
+	planOnly bool
+	// summaryOnly only prints the changes grouped by kind once every repository is planned
+	summaryOnly bool
+}
+
+func (renderer *textRenderer) Result(result github.RepositoryResult) bool {
+	switch {
+	case renderer.summaryOnly:
+		return true
+	case renderer.planOnly:
+		return printPlans([]github.RepositoryResult{result})
+	default:
+		return printApplied([]github.RepositoryResult{result})
+	}
+}
+
+func (renderer *textRenderer) Finish(results []github.RepositoryResult) bool {
+	if !renderer.planOnly {
+		return true
+	}
+
+	succeeded := true
+
+	if renderer.summaryOnly {
+		succeeded = printErrors(results)
+	}
+
+	// A summary grouping the changes by kind makes plans of many repositories reviewable
+	if renderer.summaryOnly || len(results) > 1 {
+		fmt.Print(github.SummaryString(github.Summarize(results)))
+	}
+
+	return succeeded
+}
+
+// jsonRenderer prints the results of every repository as a single json array
+type jsonRenderer struct {
+	planOnly bool
+}
+
+func (renderer *jsonRenderer) Result(result github.RepositoryResult) bool {
+	return true
+}
+
+func (renderer *jsonRenderer) Finish(results []github.RepositoryResult) bool {
+	return printJSONResults(results, renderer.planOnly)
+}
+
+// markdownRenderer prints a section per repository with a table of its changes, to be posted as a pull request comment or a chat message
+type markdownRenderer struct {
+	planOnly bool
+}
+
+func (renderer *markdownRenderer) Result(result github.RepositoryResult) bool {
+	return true
+}
+
+func (renderer *markdownRenderer) Finish(results []github.RepositoryResult) bool {
+	succeeded := true
+	builder := &strings.Builder{}
+
+	for _, result := range results {
+		succeeded = resultSucceeded(result) && succeeded
+
+		fmt.Fprintf(builder, "### %s\n\n", result.Repository)
+
+		if result.Err != nil {
+			fmt.Fprintf(builder, "**Error:** %s\n\n", markdownEscape(result.Err.Error()))
+		}
+
+		if result.Plan != nil {
+			if result.Plan.ManagedBy != "" {
+				fmt.Fprintf(builder, "Managed by %s\n\n", markdownEscape(result.Plan.ManagedBy))
+			}
+
+			for _, warning := range result.Plan.Warnings {
+				fmt.Fprintf(builder, "> **Warning:** %s\n\n", markdownEscape(warning))
+			}
+		}
+
+		switch {
+		case result.Cached:
+			builder.WriteString("Unchanged since the last apply, skipped.\n\n")
+		case renderer.planOnly && result.Plan != nil && result.Plan.Empty():
+			builder.WriteString("No changes.\n\n")
+		case renderer.planOnly && result.Plan != nil:
+			writeMarkdownChanges(builder, "Planned", result.Plan.BySeverity())
+		case result.Result != nil:
+			writeMarkdownChanges(builder, "Applied", result.Result.Applied)
+			writeMarkdownChanges(builder, "Failed", result.Result.Failed)
+			writeMarkdownChanges(builder, "Skipped", result.Result.Skipped)
+			writeMarkdownChanges(builder, "Not verified", result.Result.Unverified)
+			writeMarkdownChanges(builder, "Reported only", result.Result.Reported)
+		}
+	}
+
+	if renderer.planOnly && len(results) > 1 {
+		fmt.Fprintf(builder, "### Summary\n\n```\n%s```\n", github.SummaryString(github.Summarize(results)))
+	}
+
+	fmt.Print(builder.String())
+
+	return succeeded
+}
+
+// writeMarkdownChanges writes a table of changes under a title, nothing is written without changes
+func writeMarkdownChanges(builder *strings.Builder, title string, changes []github.Change) {
+	if len(changes) == 0 {
+		return
+	}
+
+	// The reason column is only shown when a change references why it is made
+	withRationale := false
+
+	for _, change := range changes {
+		withRationale = withRationale || change.Rationale() != ""
+	}
+
+	fmt.Fprintf(builder, "%s changes (%d)\n\n", title, len(changes))
+
+	if withRationale {
+		builder.WriteString("| Action | Resource | Name | Fields | Reason |\n")
+		builder.WriteString("| --- | --- | --- | --- | --- |\n")
+	} else {
+		builder.WriteString("| Action | Resource | Name | Fields |\n")
+		builder.WriteString("| --- | --- | --- | --- |\n")
+	}
+
+	for _, change := range changes {
+		action := string(change.Action)
+
+		if change.ReportOnly {
+			action += " (report only)"
+		}
+
+		if len(change.Impacts) != 0 {
+			action += " **" + change.ImpactsString() + "**"
+		}
+
+		fields := make([]string, 0, len(change.Fields))
+
+		for _, line := range change.FieldLines() {
+			fields = append(fields, markdownEscape(line))
+		}
+
+		fmt.Fprintf(builder, "| %s | %s | %s | %s |", action, change.Resource, markdownEscape(change.Name), strings.Join(fields, "<br>"))
+
+		if withRationale {
+			fmt.Fprintf(builder, " %s |", markdownEscape(change.Rationale()))
+		}
+
+		builder.WriteString("\n")
+	}
+
+	builder.WriteString("\n")
+}
+
+// markdownEscape escapes the characters breaking a table cell or rendered as markup
+func markdownEscape(value string) string {
+	return strings.NewReplacer("|", "\\|", "\n", " ", "<", "&lt;", "`", "\\`", "*", "\\*", "_", "\\_").Replace(value)
+}
@@ -0,0 +1,320 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/michaelmass/github-settings/pkg/github"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// maxDriftExamples is the number of repositories listed for each drift of the heatmap
+const maxDriftExamples = 3
+
+func init() {
+	rootCmd.AddCommand(newReport())
+}
+
+func newReport() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "report",
+		Short: "Report prints read-only reports about the github repository.",
+		Long:  `Report prints read-only reports about the github repository.`,
+	}
+
+	cmd.AddCommand(newReportOrphans())
+	cmd.AddCommand(newReportStatus())
+	cmd.AddCommand(newReportDrift())
+	cmd.AddCommand(newReportSeats())
+
+	return cmd
+}
+
+func newReportOrphans() *cobra.Command {
+	flags := struct {
+		clientFlags
+		config string
+	}{}
+
+	cmd := &cobra.Command{
+		Use:   "orphans",
+		Short: "Orphans lists the live resources that are not declared in the config.",
+		Long:  `Orphans lists the live resources that are not declared in the config, nothing is deleted.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			client := flags.newClient()
+
+			allSettings, err := client.GetAllSettingsFromFile(commandContext, flags.config)
+
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			for _, settings := range allSettings {
+				orphans, err := client.Orphans(commandContext, settings)
+
+				if err != nil {
+					log.Fatal(err)
+				}
+
+				fmt.Printf("%s/%s orphans\n", settings.Repository.Owner, settings.Repository.Name)
+
+				for _, kind := range []string{github.ResourceLabels, github.ResourceBranches, github.ResourceWebhooks, github.ResourceTopics, github.ResourceCollaborators, github.ResourceTeams} {
+					if len(orphans[kind]) != 0 {
+						fmt.Printf("  %s: %s\n", kind, strings.Join(orphans[kind], ", "))
+					}
+				}
+			}
+		},
+	}
+
+	cmd.Flags().StringVarP(&flags.config, "config", "c", "settings.yml", "Configuration file path")
+	flags.register(cmd)
+
+	return cmd
+}
+
+func newReportStatus() *cobra.Command {
+	flags := struct {
+		clientFlags
+		config string
+		output string
+	}{}
+
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Status prints the read-only status of the repositories targeted by the config.",
+		Long:  `Status prints the read-only status (visibility, creation and last push, counts) of the repositories targeted by the config.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			client := flags.newClient()
+
+			allSettings, err := client.GetAllSettingsFromFile(commandContext, flags.config)
+
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			statuses := []map[string]interface{}{}
+			now := time.Now()
+
+			for _, settings := range allSettings {
+				githubSettings, err := client.GetSettingsFromGithub(commandContext, settings.Repository.Owner, settings.Repository.Name)
+
+				if err != nil {
+					log.Fatal(err)
+				}
+
+				fullName := settings.Repository.Owner + "/" + settings.Repository.Name
+				status := githubSettings.Status
+
+				if flags.output == outputJSON {
+					statuses = append(statuses, map[string]interface{}{"Repository": fullName, "Status": status})
+					continue
+				}
+
+				fmt.Printf("%s status\n", fullName)
+				fmt.Printf("  visibility: %s\n", status.Visibility)
+				fmt.Printf("  created: %s\n", formatTimestamp(status.CreatedAt, now))
+				fmt.Printf("  pushed: %s\n", formatTimestamp(status.PushedAt, now))
+				fmt.Printf("  stars: %d, forks: %d, open issues: %d\n", status.Stars, status.Forks, status.OpenIssues)
+			}
+
+			if flags.output == outputJSON {
+				printJSON(statuses)
+			}
+		},
+	}
+
+	cmd.Flags().StringVarP(&flags.config, "config", "c", "settings.yml", "Configuration file path")
+	cmd.Flags().StringVarP(&flags.output, "output", "o", outputText, "Output format (text or json), json timestamps are RFC3339 in UTC")
+	flags.register(cmd)
+
+	return cmd
+}
+
+func newReportDrift() *cobra.Command {
+	flags := struct {
+		clientFlags
+		config      string
+		concurrency int
+		output      string
+	}{}
+
+	cmd := &cobra.Command{
+		Use:   "drift",
+		Short: "Drift prints how often each setting drifts from the config across the repositories.",
+		Long: `Drift plans every repository targeted by the config and aggregates the drift per setting (ex: required reviews lowered in 14 repositories),
+rendered as markdown tables per resource kind so systemic gaps stand out from the drift of single repositories. Nothing is applied.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if flags.output != outputMarkdown && flags.output != outputJSON {
+				log.Fatalf("Invalid output format %s (markdown or json)", flags.output)
+			}
+
+			client := flags.newClient()
+			settings, settingsErrors := client.StreamAllSettingsFromFile(commandContext, flags.config)
+			results := github.CollectResults(client.PlanStream(commandContext, settings, flags.concurrency), nil)
+
+			if err := <-settingsErrors; err != nil {
+				log.Fatal(err)
+			}
+
+			failed := 0
+
+			for _, result := range results {
+				if result.Err != nil {
+					log.Errorf("%s: %s", result.Repository, result.Err)
+					failed++
+				}
+			}
+
+			heatmap := github.DriftHeatmap(results)
+
+			if flags.output == outputJSON {
+				printJSON(map[string]interface{}{"Repositories": len(results), "Failed": failed, "Drift": heatmap})
+				return
+			}
+
+			fmt.Print(driftMarkdown(heatmap, len(results), failed))
+		},
+	}
+
+	cmd.Flags().StringVarP(&flags.config, "config", "c", "settings.yml", "Configuration file path")
+	cmd.Flags().IntVar(&flags.concurrency, "concurrency", github.DefaultConcurrency, "Number of repositories planned in parallel")
+	cmd.Flags().StringVarP(&flags.output, "output", "o", outputMarkdown, "Output format (markdown or json)")
+	flags.register(cmd)
+
+	return cmd
+}
+
+func newReportSeats() *cobra.Command {
+	flags := struct {
+		clientFlags
+		config      string
+		concurrency int
+		output      string
+	}{}
+
+	cmd := &cobra.Command{
+		Use:   "seats",
+		Short: "Seats prints the github advanced security seats consumed and added by the config.",
+		Long: `Seats prints, for each org targeted by the config, the github advanced security seats consumed and purchased and the
+repositories each security feature is enabled on by the config. The repositories the config enables advanced security on are
+planned and their committers of the last 90 days consuming no seat yet are listed, each would consume a seat. Nothing is applied.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if flags.output != outputText && flags.output != outputJSON {
+				log.Fatalf("Invalid output format %s (text or json)", flags.output)
+			}
+
+			client := flags.newClient()
+
+			allSettings, err := client.GetAllSettingsFromFile(commandContext, flags.config)
+
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			usages, err := client.SeatUsage(commandContext, allSettings, flags.concurrency)
+
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			if flags.output == outputJSON {
+				printJSON(usages)
+				return
+			}
+
+			for _, usage := range usages {
+				fmt.Printf("%s advanced security seats\n", usage.Org)
+				fmt.Printf("  consumed: %d, purchased: %d, maximum this cycle: %d\n", usage.Committers, usage.Purchased, usage.Maximum)
+
+				for _, feature := range github.SecurityFeatures {
+					fmt.Printf("  %s: enabled on %d repositories\n", feature, usage.Features[feature])
+				}
+
+				if len(usage.Enabling) != 0 {
+					fmt.Printf("  enabling advanced security on %s adds %d seats", strings.Join(usage.Enabling, ", "), len(usage.NewCommitters))
+
+					if len(usage.NewCommitters) != 0 {
+						fmt.Printf(" (%s)", strings.Join(usage.NewCommitters, ", "))
+					}
+
+					fmt.Println()
+				}
+			}
+		},
+	}
+
+	cmd.Flags().StringVarP(&flags.config, "config", "c", "settings.yml", "Configuration file path")
+	cmd.Flags().IntVar(&flags.concurrency, "concurrency", github.DefaultConcurrency, "Number of repositories planned in parallel")
+	cmd.Flags().StringVarP(&flags.output, "output", "o", outputText, "Output format (text or json)")
+	flags.register(cmd)
+
+	return cmd
+}
+
+// driftMarkdown renders the drift heatmap as a table per resource kind, the share is the part of the planned repositories with the drift
+func driftMarkdown(heatmap []github.DriftEntry, total, failed int) string {
+	builder := &strings.Builder{}
+	drifting := map[string]bool{}
+	resources := []string{}
+	entries := map[string][]github.DriftEntry{}
+
+	for _, entry := range heatmap {
+		for _, repository := range entry.Repositories {
+			drifting[repository] = true
+		}
+
+		if _, ok := entries[entry.Resource]; !ok {
+			resources = append(resources, entry.Resource)
+		}
+
+		entries[entry.Resource] = append(entries[entry.Resource], entry)
+	}
+
+	planned := total - failed
+
+	builder.WriteString("## Drift heatmap\n\n")
+	fmt.Fprintf(builder, "%d of %d repositories drift from the config", len(drifting), planned)
+
+	if failed != 0 {
+		fmt.Fprintf(builder, ", %d failed planning", failed)
+	}
+
+	builder.WriteString("\n\n")
+
+	// Resource kinds are ordered by their most frequent drift
+	for _, resource := range resources {
+		fmt.Fprintf(builder, "### %s\n\n", resource)
+		builder.WriteString("| Setting | Live | Config | Repositories | Share | Examples |\n")
+		builder.WriteString("| --- | --- | --- | ---: | ---: | --- |\n")
+
+		for _, entry := range entries[resource] {
+			live, desired := entry.Live, entry.Desired
+
+			switch entry.Action {
+			case github.ActionCreate:
+				live, desired = "missing", "declared"
+			case github.ActionDelete:
+				live, desired = "present", "not declared"
+			}
+
+			fmt.Fprintf(builder, "| %s | %s | %s | %d | %d%% | %s |\n", markdownEscape(entry.Setting), markdownEscape(live), markdownEscape(desired),
+				len(entry.Repositories), len(entry.Repositories)*100/planned, markdownEscape(driftExamples(entry.Repositories)))
+		}
+
+		builder.WriteString("\n")
+	}
+
+	return builder.String()
+}
+
+// driftExamples lists the first repositories with a drift, the others are counted
+func driftExamples(repositories []string) string {
+	if len(repositories) > maxDriftExamples {
+		return strings.Join(repositories[:maxDriftExamples], ", ") + fmt.Sprintf(" and %d more", len(repositories)-maxDriftExamples)
+	}
+
+	return strings.Join(repositories, ", ")
+}
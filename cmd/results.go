@@ -0,0 +1,195 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/michaelmass/github-settings/pkg/github"
+	log "github.com/sirupsen/logrus"
+)
+
+// Exit codes of plan and apply, they exit with 0 when nothing changed and with a distinct code otherwise so ci pipelines can detect drift
+const (
+	exitError   = 1
+	exitChanges = 2
+)
+
+// printPlans prints the plan of each repository and returns false when a repository failed
+func printPlans(results []github.RepositoryResult) bool {
+	succeeded := true
+
+	for _, result := range results {
+		if result.Err != nil {
+			log.Errorf("%s: %s", result.Repository, result.Err)
+			succeeded = false
+			continue
+		}
+
+		fmt.Print(result.Plan)
+	}
+
+	return succeeded
+}
+
+// printErrors logs the repositories that failed and returns false when there is any
+func printErrors(results []github.RepositoryResult) bool {
+	succeeded := true
+
+	for _, result := range results {
+		if result.Err != nil {
+			log.Errorf("%s: %s", result.Repository, result.Err)
+			succeeded = false
+		}
+	}
+
+	return succeeded
+}
+
+// printApplied prints the outcome of each repository and returns false when a repository failed
+func printApplied(results []github.RepositoryResult) bool {
+	succeeded := true
+
+	for _, result := range results {
+		if result.Err != nil {
+			log.Errorf("%s: %s", result.Repository, result.Err)
+			succeeded = false
+		}
+
+		if result.Cached {
+			fmt.Printf("%s: unchanged since the last apply, skipped\n", result.Repository)
+			continue
+		}
+
+		if result.Result == nil {
+			continue
+		}
+
+		if len(result.Result.Skipped) != 0 {
+			log.Warnf("%s: %d changes skipped", result.Repository, len(result.Result.Skipped))
+			succeeded = false
+		}
+
+		if len(result.Result.Unverified) != 0 {
+			log.Warnf("%s: %d changes not verified", result.Repository, len(result.Result.Unverified))
+			succeeded = false
+		}
+
+		if len(result.Result.Reported) != 0 {
+			fmt.Printf("%s: %d changes reported only\n", result.Repository, len(result.Result.Reported))
+		}
+
+		fmt.Printf("%s: %d changes applied in %s\n", result.Repository, len(result.Result.Applied), humanizeDuration(result.Duration))
+	}
+
+	return succeeded
+}
+
+// repositoryOutput is the json output of plan and apply for a repository
+type repositoryOutput struct {
+	Repository string
+	// Changes are the planned changes, they are only printed by plan
+	Changes  []github.Change `json:",omitempty"`
+	Applied  []github.Change `json:",omitempty"`
+	Skipped  []github.Change `json:",omitempty"`
+	Failed   []github.Change `json:",omitempty"`
+	Reported []github.Change `json:",omitempty"`
+	// Unverified are the applied changes github did not reflect when verified with --verify
+	Unverified []github.Change `json:",omitempty"`
+	ManagedBy  string          `json:",omitempty"`
+	// Cached is set when apply skipped the repository, unchanged since its last successful apply
+	Cached   bool     `json:",omitempty"`
+	Warnings []string `json:",omitempty"`
+	Error    string   `json:",omitempty"`
+	Duration string
+}
+
+// printJSONResults prints the results as json and returns false when a repository failed
+func printJSONResults(results []github.RepositoryResult, planOnly bool) bool {
+	succeeded := true
+	outputs := make([]repositoryOutput, 0, len(results))
+
+	for _, result := range results {
+		output := repositoryOutput{Repository: result.Repository, Cached: result.Cached, Duration: result.Duration.String()}
+
+		if result.Err != nil {
+			output.Error = result.Err.Error()
+			succeeded = false
+		}
+
+		if result.Plan != nil {
+			output.ManagedBy = result.Plan.ManagedBy
+			output.Warnings = result.Plan.Warnings
+
+			if planOnly {
+				output.Changes = result.Plan.Changes
+			}
+		}
+
+		if result.Result != nil && !planOnly {
+			output.Applied = result.Result.Applied
+			output.Skipped = result.Result.Skipped
+			output.Failed = result.Result.Failed
+			output.Reported = result.Result.Reported
+			output.Unverified = result.Result.Unverified
+			succeeded = succeeded && len(result.Result.Skipped) == 0 && len(result.Result.Unverified) == 0
+		}
+
+		outputs = append(outputs, output)
+	}
+
+	printJSON(outputs)
+
+	return succeeded
+}
+
+// exit exits with the error code when a repository failed or with the changes code when a repository was (or would be) changed
+func exit(results []github.RepositoryResult, planOnly bool, succeeded bool, failure string) {
+	if !succeeded {
+		log.Error(failure)
+		os.Exit(exitError)
+	}
+
+	for _, result := range results {
+		planned := result.Plan != nil && !result.Plan.Empty()
+
+		if (planOnly && planned) || (!planOnly && result.Result.Changed()) {
+			os.Exit(exitChanges)
+		}
+	}
+}
+
+// printDeletions lists the deletions planned on each repository and returns their count
+func printDeletions(results []github.RepositoryResult) int {
+	count := 0
+
+	for _, result := range results {
+		if result.Err != nil || result.Plan == nil {
+			continue
+		}
+
+		for _, deletion := range result.Plan.Deletions() {
+			log.Warnf("%s: deleting %s %s", result.Repository, deletion.Resource, deletion.Name)
+			count++
+		}
+	}
+
+	return count
+}
+
+// confirm asks a yes or no question on the terminal, anything but yes (or no input) is a no
+func confirm(question string) bool {
+	fmt.Fprintf(os.Stderr, "%s [y/N] ", question)
+
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+
+	if err != nil && answer == "" {
+		fmt.Fprintln(os.Stderr)
+		return false
+	}
+
+	answer = strings.ToLower(strings.TrimSpace(answer))
+
+	return answer == "y" || answer == "yes"
+}
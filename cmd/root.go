@@ -1,8 +1,13 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
 
+	"github.com/michaelmass/github-settings/pkg/github"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
@@ -11,6 +16,14 @@ import (
 // nolint:gochecknoglobals
 var VERSION string
 
+// commandContext is cancelled on interrupt so the commands stop sending requests
+// nolint:gochecknoglobals
+var commandContext = context.Background()
+
+// timezone reports render timestamps in
+// nolint:gochecknoglobals
+var timezone string
+
 const (
 	defaultFolderPermission = 0755
 	defaultFilePermission   = 0644
@@ -25,8 +38,26 @@ var rootCmd = &cobra.Command{
 	},
 }
 
+func init() {
+	rootCmd.PersistentFlags().StringVar(&timezone, "timezone", "Local", "Timezone of the timestamps in reports (ex: UTC, Europe/Paris), json output is always RFC3339")
+}
+
+// userAgent returns the User-Agent identifying this version of github-settings
+func userAgent() string {
+	if VERSION == "" {
+		return github.DefaultUserAgent + "/dev"
+	}
+
+	return github.DefaultUserAgent + "/" + VERSION
+}
+
 // Execute the cli
 func Execute() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	commandContext = ctx
+
 	if err := rootCmd.Execute(); err != nil {
 		log.Fatal(err)
 	}
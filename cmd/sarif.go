@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/michaelmass/github-settings/pkg/github"
+)
+
+// sarifSchema is the schema of the sarif 2.1.0 logs uploaded to code scanning
+const sarifSchema = "https://json.schemastore.org/sarif-2.1.0.json"
+
+// Rules of the sarif results, each drifted resource, plan warning and repository error is a result
+const (
+	sarifRuleDrift   = "settings/drift"
+	sarifRuleWarning = "settings/warning"
+	sarifRuleError   = "settings/error"
+)
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version,omitempty"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID              string            `json:"ruleId"`
+	Level               string            `json:"level"`
+	Message             sarifMessage      `json:"message"`
+	Locations           []sarifLocation   `json:"locations"`
+	PartialFingerprints map[string]string `json:"partialFingerprints"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation  `json:"physicalLocation"`
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+type sarifLogicalLocation struct {
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+	Kind               string `json:"kind"`
+}
+
+// sarifRenderer prints the drift of the repositories as a sarif log, so code scanning lists each drifted resource as an alert on the config file
+type sarifRenderer struct {
+	planOnly bool
+	config   string
+}
+
+func (renderer *sarifRenderer) Result(result github.RepositoryResult) bool {
+	return true
+}
+
+func (renderer *sarifRenderer) Finish(results []github.RepositoryResult) bool {
+	succeeded := true
+	sarifResults := []sarifResult{}
+
+	for _, result := range results {
+		succeeded = resultSucceeded(result) && succeeded
+
+		if result.Err != nil {
+			sarifResults = append(sarifResults, renderer.newResult(sarifRuleError, "error", result.Repository, result.Repository, result.Err.Error()))
+		}
+
+		if result.Plan != nil {
+			for _, warning := range result.Plan.Warnings {
+				sarifResults = append(sarifResults, renderer.newResult(sarifRuleWarning, "note", result.Repository, result.Repository+"/"+warning, warning))
+			}
+		}
+
+		// The drift left once apply completed is made of the changes it did not make
+		drift := []github.Change{}
+
+		switch {
+		case renderer.planOnly && result.Plan != nil:
+			drift = result.Plan.Changes
+		case result.Result != nil:
+			drift = append(drift, result.Result.Failed...)
+			drift = append(drift, result.Result.Skipped...)
+			drift = append(drift, result.Result.Unverified...)
+			drift = append(drift, result.Result.Reported...)
+		}
+
+		for _, change := range drift {
+			sarifResults = append(sarifResults, renderer.newResult(sarifRuleDrift, "warning", result.Repository, result.Repository+"/"+change.Resource+"/"+change.Name, sarifChangeMessage(result.Repository, change)))
+		}
+	}
+
+	printJSON(sarifLog{
+		Schema:  sarifSchema,
+		Version: "2.1.0",
+		Runs: []sarifRun{{
+			Tool: sarifTool{Driver: sarifDriver{
+				Name:           "github-settings",
+				Version:        VERSION,
+				InformationURI: "https://github.com/michaelmass/github-settings",
+				Rules: []sarifRule{
+					{ID: sarifRuleDrift, ShortDescription: sarifMessage{Text: "The repository settings differ from the config"}},
+					{ID: sarifRuleWarning, ShortDescription: sarifMessage{Text: "The config leaves a risk uncovered"}},
+					{ID: sarifRuleError, ShortDescription: sarifMessage{Text: "The repository could not be planned or applied"}},
+				},
+			}},
+			Results: sarifResults,
+		}},
+	})
+
+	return succeeded
+}
+
+// newResult returns a result located on the config file, the fingerprint keeps the alert of a resource the same across runs
+func (renderer *sarifRenderer) newResult(rule, level, repository, fingerprint, message string) sarifResult {
+	return sarifResult{
+		RuleID:  rule,
+		Level:   level,
+		Message: sarifMessage{Text: message},
+		Locations: []sarifLocation{{
+			PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: renderer.config},
+				Region:           sarifRegion{StartLine: 1},
+			},
+			LogicalLocations: []sarifLogicalLocation{{FullyQualifiedName: repository, Kind: "module"}},
+		}},
+		PartialFingerprints: map[string]string{"settings/v1": fingerprint},
+	}
+}
+
+// sarifChangeMessage describes a drifted resource with the fields the change sets
+func sarifChangeMessage(repository string, change github.Change) string {
+	message := strings.TrimSpace(fmt.Sprintf("%s: %s %s %s", repository, change.Action, change.Resource, change.Name))
+
+	if change.ReportOnly {
+		message += " (report only)"
+	}
+
+	if lines := change.FieldLines(); len(lines) != 0 {
+		message += " (" + strings.Join(lines, ", ") + ")"
+	}
+
+	if rationale := change.Rationale(); rationale != "" {
+		message += ", reason: " + rationale
+	}
+
+	return message
+}
@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/michaelmass/github-settings/pkg/github"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// webhookSecretEnv is the environment variable holding the webhook secret when the flag is not set
+const webhookSecretEnv = "GITHUB_SETTINGS_WEBHOOK_SECRET"
+
+func init() {
+	rootCmd.AddCommand(newServe())
+}
+
+func newServe() *cobra.Command {
+	flags := struct {
+		clientFlags
+		config         string
+		addr           string
+		webhookSecret  string
+		interval       time.Duration
+		sweepInterval  time.Duration
+		enforce        bool
+		enforceCreated bool
+		windows        []string
+		history        string
+		dashboard      bool
+		concurrency    int
+		secretsFile    string
+		prune          bool
+		force          bool
+		notifier       string
+		notifierURL    string
+		verify         bool
+		webhooks       int
+	}{}
+
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve continuously detects and corrects the drift of the repositories settings.",
+		Long: `Serve runs until interrupted and compares the live settings of the repositories with the config on every interval and,
+with the webhook server of --addr, when github delivers a repository, label, branch protection, ruleset, member, team, push,
+create or delete event to its webhook endpoint (/webhook). With the webhook server, only the repositories of the deliveries
+are reconciled in between and every repository is reconciled at most every --sweep-interval.
+The drift is sent to the notifier (log, webhook or slack) and applied when --enforce is set. The config is loaded again on
+every reconciliation. --enforce-created applies the settings of a repository as soon as github delivers its creation to an
+organization webhook, so a new repository does not wait for the interval without its labels and protections.
+With --maintenance-window, the drift is only enforced during the windows and reported the rest of the time.
+SIGHUP reloads the credentials (--token-file, --auth-command or the oidc exchange) without interrupting the reconciliations.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			secretValues := map[string]string{}
+
+			if flags.secretsFile != "" {
+				values, err := github.LoadSecretValues(flags.secretsFile)
+
+				if err != nil {
+					log.Fatal(err)
+				}
+
+				secretValues = values
+			}
+
+			notifier, err := github.NewNotifier(flags.notifier, flags.notifierURL)
+
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			if flags.webhookSecret == "" {
+				flags.webhookSecret = os.Getenv(webhookSecretEnv)
+			}
+
+			if err := checkWebhookSecret(flags.addr, flags.webhookSecret); err != nil {
+				log.Fatal(err)
+			}
+
+			windows := make([]github.MaintenanceWindow, 0, len(flags.windows))
+
+			for _, value := range flags.windows {
+				window, err := github.ParseMaintenanceWindow(value)
+
+				if err != nil {
+					log.Fatal(err)
+				}
+
+				windows = append(windows, window)
+			}
+
+			client := flags.newClient(github.WithSecretValues(secretValues), github.WithPrune(flags.prune), github.WithForce(flags.force), github.WithVerify(flags.verify), github.WithWebhookHealth(flags.webhooks))
+
+			if flags.dashboard && (flags.history == "" || flags.addr == "") {
+				log.Fatal("The dashboard is served from the --history database on the --addr webhook server, set both")
+			}
+
+			var history *github.History
+
+			if flags.history != "" {
+				history, err = github.OpenHistory(flags.history)
+
+				if err != nil {
+					log.Fatal(err)
+				}
+
+				defer history.Close()
+			}
+
+			reloads := make(chan os.Signal, 1)
+			signal.Notify(reloads, syscall.SIGHUP)
+			defer signal.Stop(reloads)
+
+			go reloadCredentials(client, reloads)
+
+			err = client.Serve(commandContext, github.ServeOptions{
+				Config:             flags.config,
+				Addr:               flags.addr,
+				WebhookSecret:      flags.webhookSecret,
+				Interval:           flags.interval,
+				SweepInterval:      flags.sweepInterval,
+				Enforce:            flags.enforce,
+				EnforceCreated:     flags.enforceCreated,
+				MaintenanceWindows: windows,
+				History:            history,
+				Dashboard:          flags.dashboard,
+				Concurrency:        flags.concurrency,
+				Notifier:           notifier,
+			})
+
+			if err != nil {
+				log.Fatal(err)
+			}
+		},
+	}
+
+	cmd.Flags().StringVarP(&flags.config, "config", "c", "settings.yml", "Configuration file path")
+	cmd.Flags().StringVar(&flags.addr, "addr", "", "Address of the webhook server (ex: :8080), the repositories are only reconciled on the interval when empty")
+	cmd.Flags().StringVar(&flags.webhookSecret, "webhook-secret", "", "Secret validating the webhook deliveries, required with --addr (defaults to "+webhookSecretEnv+")")
+	cmd.Flags().DurationVar(&flags.interval, "interval", github.DefaultServeInterval, "Time between two reconciliations of every repository (0 to only reconcile on webhook deliveries)")
+	cmd.Flags().DurationVar(&flags.sweepInterval, "sweep-interval", github.DefaultSweepInterval, "Minimum time between two reconciliations of every repository while the webhook deliveries reconcile the repositories that changed (0 to reconcile every repository on every interval)")
+	cmd.Flags().BoolVar(&flags.enforce, "enforce", false, "Apply the drift instead of only notifying it")
+	cmd.Flags().BoolVar(&flags.enforceCreated, "enforce-created", false, "Apply the settings of the repositories created in the organization as soon as their creation is delivered")
+	cmd.Flags().StringArrayVar(&flags.windows, "maintenance-window", nil, "Cron expression, duration and timezone of a period during which the drift is enforced (ex: '0 22 * * 1-5 2h Europe/Paris'), repeat for several windows")
+	cmd.Flags().StringVar(&flags.history, "history", "", "Sqlite database recording the outcome of every repository reconciled, queried with the history command (disabled when empty)")
+	cmd.Flags().BoolVar(&flags.dashboard, "dashboard", false, "Serve a read-only page with the status, last apply and recent drift of the repositories of the history on /")
+	cmd.Flags().IntVar(&flags.concurrency, "concurrency", github.DefaultConcurrency, "Number of repositories reconciled in parallel")
+	cmd.Flags().StringVar(&flags.secretsFile, "secrets-file", "", "Yaml file mapping actions secret names to their values (defaults to environment variables)")
+	cmd.Flags().BoolVar(&flags.prune, "prune", true, "Delete the resources missing from the config (the prune section of the config overrides it)")
+	cmd.Flags().BoolVar(&flags.force, "force", false, "Apply to repositories declared as managed by another tool (managed-by topic or custom property)")
+	cmd.Flags().StringVar(&flags.notifier, "notifier", github.NotifierLog, "Where the drift is sent (log, webhook or slack)")
+	cmd.Flags().StringVar(&flags.notifierURL, "notifier-url", "", "Url the webhook and slack notifiers post to")
+	cmd.Flags().BoolVar(&flags.verify, "verify", false, "Fetch the settings again after enforcing the drift and notify the changes github does not reflect")
+	cmd.Flags().IntVar(&flags.webhooks, "webhook-health", 0, "Notify the webhooks whose last N deliveries all failed as drift (disabled when 0)")
+	flags.register(cmd)
+
+	return cmd
+}
+
+// checkWebhookSecret fails when the webhook server would accept deliveries without validating their signature
+func checkWebhookSecret(addr, webhookSecret string) error {
+	if addr != "" && webhookSecret == "" {
+		return errors.Errorf("Refusing to serve unvalidated webhook deliveries on %s, set --webhook-secret or %s, or leave --addr unset to only reconcile on the interval", addr, webhookSecretEnv)
+	}
+
+	return nil
+}
+
+// reloadCredentials reloads the credentials of the client on every signal, the reconciliations in progress complete with the previous ones
+func reloadCredentials(client *github.Client, signals <-chan os.Signal) {
+	for range signals {
+		err := client.ReloadCredentials()
+
+		if err != nil {
+			log.Warnf("Keeping the previous github credentials, %s", err)
+			continue
+		}
+
+		log.Info("Reloaded the github credentials")
+	}
+}
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestServeOnlyRequiresAWebhookSecretWithAnAddr(t *testing.T) {
+	tests := []struct {
+		args    []string
+		wantErr bool
+	}{
+		{nil, false},
+		{[]string{"--addr", ":8080"}, true},
+		{[]string{"--addr", ":8080", "--webhook-secret", "delivery-secret"}, false},
+	}
+
+	for _, test := range tests {
+		cmd := newServe()
+
+		if err := cmd.ParseFlags(test.args); err != nil {
+			t.Fatal(err)
+		}
+
+		addr, _ := cmd.Flags().GetString("addr")
+		webhookSecret, _ := cmd.Flags().GetString("webhook-secret")
+		err := checkWebhookSecret(addr, webhookSecret)
+
+		if (err != nil) != test.wantErr {
+			t.Errorf("serve %v: expected an error %t, got %v", test.args, test.wantErr, err)
+		}
+
+		if err != nil && !strings.Contains(err.Error(), "--webhook-secret") {
+			t.Errorf("serve %v: expected the error to name --webhook-secret, got %v", test.args, err)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/michaelmass/github-settings/pkg/github"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// Environment variables set by github actions, they default the commit receiving the statuses
+const (
+	actionsRepositoryEnv = "GITHUB_REPOSITORY"
+	actionsSHAEnv        = "GITHUB_SHA"
+	actionsServerURLEnv  = "GITHUB_SERVER_URL"
+	actionsRunIDEnv      = "GITHUB_RUN_ID"
+)
+
+// statusFlags holds the flags posting the outcome of each repository as a commit status on the config repository
+type statusFlags struct {
+	statuses   bool
+	repository string
+	sha        string
+	targetURL  string
+}
+
+func (flags *statusFlags) register(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&flags.statuses, "statuses", false, "Post a commit status per repository (settings/owner/repo) on the commit of the config repository")
+	cmd.Flags().StringVar(&flags.repository, "status-repository", "", "Config repository (owner/repo) receiving the statuses (defaults to "+actionsRepositoryEnv+")")
+	cmd.Flags().StringVar(&flags.sha, "status-sha", "", "Commit of the config repository receiving the statuses (defaults to "+actionsSHAEnv+")")
+	cmd.Flags().StringVar(&flags.targetURL, "status-url", "", "Url the statuses link to (defaults to the github actions run)")
+}
+
+// post posts the statuses of the results when enabled, a failure is logged without failing the command
+func (flags *statusFlags) post(client *github.Client, results []github.RepositoryResult) {
+	if !flags.statuses {
+		return
+	}
+
+	options, err := flags.options()
+
+	if err != nil {
+		log.Error(err)
+		return
+	}
+
+	err = client.PostStatuses(commandContext, options, results)
+
+	if err != nil {
+		log.Error(err)
+	}
+}
+
+// options resolves the commit receiving the statuses from the flags and the github actions environment
+func (flags *statusFlags) options() (github.StatusOptions, error) {
+	repository := flags.repository
+
+	if repository == "" {
+		repository = os.Getenv(actionsRepositoryEnv)
+	}
+
+	owner, name, err := splitFullName(repository)
+
+	if err != nil {
+		return github.StatusOptions{}, err
+	}
+
+	options := github.StatusOptions{Owner: owner, Name: name, SHA: flags.sha, TargetURL: flags.targetURL}
+
+	if options.SHA == "" {
+		options.SHA = os.Getenv(actionsSHAEnv)
+	}
+
+	if options.SHA == "" {
+		return github.StatusOptions{}, errors.Errorf("Missing the commit receiving the statuses, set --status-sha or %s", actionsSHAEnv)
+	}
+
+	if options.TargetURL == "" && os.Getenv(actionsRunIDEnv) != "" {
+		options.TargetURL = os.Getenv(actionsServerURLEnv) + "/" + os.Getenv(actionsRepositoryEnv) + "/actions/runs/" + os.Getenv(actionsRunIDEnv)
+	}
+
+	return options, nil
+}
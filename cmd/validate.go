@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/michaelmass/github-settings/pkg/github"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(newValidate())
+	rootCmd.AddCommand(newSchema())
+}
+
+func newValidate() *cobra.Command {
+	flags := struct {
+		config string
+	}{}
+
+	cmd := &cobra.Command{
+		Use:   "validate",
+		Short: "Validate checks a config file without calling github.",
+		Long: `Validate strictly parses a config file and reports every problem with its line: unknown fields, invalid values
+and branch protection options github ignores. The files it extends are validated separately.
+Literal values looking like secrets (tokens, passwords, random strings) are reported too.
+It exits with 1 when the file has problems.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			content, err := ioutil.ReadFile(flags.config)
+
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			problems, err := github.Validate(content)
+
+			if err != nil {
+				log.Fatalf("%s: %s", flags.config, err)
+			}
+
+			for _, problem := range problems {
+				fmt.Printf("%s:%d:%d: %s\n", flags.config, problem.Line, problem.Column, problemMessage(problem))
+			}
+
+			if len(problems) != 0 {
+				log.Fatalf("%s has %d problems", flags.config, len(problems))
+			}
+
+			fmt.Printf("%s is valid\n", flags.config)
+		},
+	}
+
+	cmd.Flags().StringVarP(&flags.config, "config", "c", "settings.yml", "Configuration file path")
+
+	return cmd
+}
+
+func newSchema() *cobra.Command {
+	return &cobra.Command{
+		Use:   "schema",
+		Short: "Schema prints the JSON Schema of the config files.",
+		Long:  `Schema prints the JSON Schema of the config files so editors can complete and check them.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			printJSON(github.Schema())
+		},
+	}
+}
+
+func problemMessage(problem github.Problem) string {
+	if problem.Path == "" {
+		return problem.Message
+	}
+
+	return problem.Path + ": " + problem.Message
+}
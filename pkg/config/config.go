@@ -0,0 +1,313 @@
+// Package config loads settings files and resolves their composition before they are parsed by the github package
+//
+// A settings file can extend other settings files, local paths or http urls, with the extends and include keys:
+//
+//	extends: https://raw.githubusercontent.com/acme/.github/main/settings.yml
+//	include: [labels.yml, branches.yml]
+//
+// The extended files are merged first, then the included files and finally the file itself. Maps are merged
+// recursively, lists of labels, branches, webhooks, collaborators, teams, secrets and variables are merged by
+// their key and other lists are replaced. A repository settings file extended by a multi repository file is
+// merged in its defaults. Variables written ${NAME} are substituted in each file before it is merged, remote files
+// only read the environment variables allowed with WithRemoteEnv. The repository variables are substituted once the
+// files are merged. Trace also returns the file that set each value of the merged document.
+package config
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// Directives listing the files merged under a settings file
+const (
+	ExtendsKey = "extends"
+	IncludeKey = "include"
+)
+
+// Option configures the loader
+type Option func(*loader)
+
+// WithVariables provides variables substituted before the environment variables
+func WithVariables(variables map[string]string) Option {
+	return func(l *loader) {
+		for name, value := range variables {
+			l.variables[name] = value
+		}
+	}
+}
+
+// WithRemoteEnv allows remote files to reference environment variables, they only read the variables given to the loader otherwise
+// A shared file fetched from an url could otherwise copy any secret of the environment in the settings (ex: a webhook url)
+func WithRemoteEnv(names ...string) Option {
+	return func(l *loader) {
+		for _, name := range names {
+			l.remoteEnv[name] = true
+		}
+	}
+}
+
+// WithToken authenticates the requests fetching files from github hosts over https
+func WithToken(token string) Option {
+	return func(l *loader) {
+		l.token = token
+	}
+}
+
+// WithGithubHosts adds hosts receiving the token, like a github enterprise server
+func WithGithubHosts(hosts ...string) Option {
+	return func(l *loader) {
+		l.hosts = append(l.hosts, hosts...)
+	}
+}
+
+// WithHTTPClient replaces the http client fetching remote files
+func WithHTTPClient(client *http.Client) Option {
+	return func(l *loader) {
+		l.client = client
+	}
+}
+
+type loader struct {
+	variables map[string]string
+	// remoteEnv are the environment variables remote files can reference
+	remoteEnv map[string]bool
+	token     string
+	hosts     []string
+	client    *http.Client
+}
+
+// Load reads a settings file, merges the files it extends or includes and substitutes its variables
+// The result is a single yaml document
+func Load(path string, opts ...Option) ([]byte, error) {
+	content, _, err := newLoader(opts).loadFile(path)
+
+	return content, err
+}
+
+func newLoader(opts []Option) *loader {
+	l := &loader{
+		variables: map[string]string{},
+		remoteEnv: map[string]bool{},
+		hosts:     []string{"github.com", "api.github.com", "raw.githubusercontent.com"},
+		client:    http.DefaultClient,
+	}
+
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	return l
+}
+
+// loadFile loads a settings file and substitutes the variables of the merged document, it returns the provenance document too
+func (l *loader) loadFile(path string) ([]byte, map[string]interface{}, error) {
+	document, provenance, err := l.load(path, nil)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	substituted, err := Substitute(document, l.lookup(document))
+
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "Error substituting variables of %s", path)
+	}
+
+	content, err := yaml.Marshal(substituted)
+
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "Error while marshal settings")
+	}
+
+	return content, provenance, nil
+}
+
+// load reads a document and merges the documents it references, visited holds the sources being loaded to detect cycles
+// The provenance document mirrors the merged document with the source of each value, see Trace
+func (l *loader) load(source string, visited []string) (map[string]interface{}, map[string]interface{}, error) {
+	for _, visitedSource := range visited {
+		if visitedSource == source {
+			return nil, nil, errors.Errorf("Settings file %s extends itself (%s)", source, strings.Join(append(visited, source), " -> "))
+		}
+	}
+
+	visited = append(visited, source)
+
+	content, err := l.read(source)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	document := map[string]interface{}{}
+	err = yaml.Unmarshal(content, &document)
+
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "Error while unmarshal %s", source)
+	}
+
+	references := []string{}
+
+	for _, key := range []string{ExtendsKey, IncludeKey} {
+		keyReferences, err := directive(document[key])
+
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "Invalid %s in %s", key, source)
+		}
+
+		references = append(references, keyReferences...)
+		delete(document, key)
+	}
+
+	err = l.substituteSource(source, document)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	merged, provenance := map[string]interface{}{}, map[string]interface{}{}
+
+	for _, reference := range references {
+		base, baseProvenance, err := l.load(resolve(source, reference), visited)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// A repository settings file extended by a multi repository file provides its defaults
+		if isMulti(document) && !isMulti(base) {
+			base = map[string]interface{}{"defaults": base}
+			baseProvenance = map[string]interface{}{"defaults": baseProvenance}
+		}
+
+		merged = Merge(merged, base)
+		provenance = Merge(provenance, baseProvenance)
+	}
+
+	return Merge(merged, document), Merge(provenance, annotate(document, Source(source))), nil
+}
+
+func (l *loader) read(source string) ([]byte, error) {
+	if !isURL(source) {
+		content, err := ioutil.ReadFile(source)
+
+		if err != nil {
+			return nil, errors.Wrapf(err, "Error while reading settings file %s", source)
+		}
+
+		return content, nil
+	}
+
+	request, err := http.NewRequest(http.MethodGet, source, nil)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "Invalid settings url %s", source)
+	}
+
+	// The token is only sent to github over https to avoid leaking it to other hosts or on the network
+	if l.token != "" && request.URL.Scheme == "https" && l.isGithubHost(request.URL.Hostname()) {
+		request.Header.Set("Authorization", "token "+l.token)
+	}
+
+	response, err := l.client.Do(request)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error while fetching settings file %s", source)
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("Error while fetching settings file %s: %s", source, response.Status)
+	}
+
+	content, err := ioutil.ReadAll(response.Body)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error while fetching settings file %s", source)
+	}
+
+	return content, nil
+}
+
+// directive returns the files referenced by an extends or include value, a single file or a list
+func directive(value interface{}) ([]string, error) {
+	switch typed := value.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{typed}, nil
+	case []interface{}:
+		references := make([]string, 0, len(typed))
+
+		for _, item := range typed {
+			reference, ok := item.(string)
+
+			if !ok {
+				return nil, errors.Errorf("Expected a file path or url, got %v", item)
+			}
+
+			references = append(references, reference)
+		}
+
+		return references, nil
+	default:
+		return nil, errors.Errorf("Expected a file path, an url or a list of them, got %v", value)
+	}
+}
+
+// resolve returns the reference relative to the file or url referencing it
+func resolve(source, reference string) string {
+	if isURL(reference) || filepath.IsAbs(reference) {
+		return reference
+	}
+
+	if isURL(source) {
+		base, err := url.Parse(source)
+
+		if err != nil {
+			return reference
+		}
+
+		relative, err := url.Parse(reference)
+
+		if err != nil {
+			return reference
+		}
+
+		return base.ResolveReference(relative).String()
+	}
+
+	return filepath.Join(filepath.Dir(source), reference)
+}
+
+// isMulti returns true when the document targets many repositories
+func isMulti(document map[string]interface{}) bool {
+	for _, key := range []string{"org", "defaults", "repositories"} {
+		if _, ok := document[key]; ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isURL(source string) bool {
+	return strings.HasPrefix(source, "https://") || strings.HasPrefix(source, "http://")
+}
+
+func (l *loader) isGithubHost(host string) bool {
+	for _, githubHost := range l.hosts {
+		if strings.EqualFold(host, githubHost) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,151 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFile writes a settings file in a temporary directory and returns its path
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	err := ioutil.WriteFile(path, []byte(content), 0600)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+// serveFile serves a settings file and records the authorization header of each request
+func serveFile(content string, authorizations *[]string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		*authorizations = append(*authorizations, r.Header.Get("Authorization"))
+		fmt.Fprint(w, content)
+	}
+}
+
+func TestLoadSubstitutesLocalFiles(t *testing.T) {
+	t.Setenv("DOMAIN", "acme.dev")
+
+	content, err := Load(writeFile(t, "settings.yml", "repository: {owner: acme, name: api, homepage: 'https://${DOMAIN}/${REPO_NAME}', description: '$${KEPT}'}\n"))
+
+	if err != nil {
+		t.Fatalf("Error loading settings: %v", err)
+	}
+
+	for _, want := range []string{"homepage: https://acme.dev/api", "description: ${KEPT}"} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("Loaded settings miss %q:\n%s", want, content)
+		}
+	}
+}
+
+func TestLoadKeepsMetadataVariables(t *testing.T) {
+	content, err := Load(writeFile(t, "settings.yml", "repository: {owner: acme, name: api, description: '${REPO_LANGUAGE} ${REPO_PROPERTY_COST_CENTER}'}\n"))
+
+	if err != nil {
+		t.Fatalf("Error loading settings: %v", err)
+	}
+
+	if !strings.Contains(string(content), "description: ${REPO_LANGUAGE} ${REPO_PROPERTY_COST_CENTER}") {
+		t.Errorf("Loaded settings substituted the metadata variables:\n%s", content)
+	}
+
+	if variable := PropertyVariable("cost-center"); variable != "REPO_PROPERTY_COST_CENTER" {
+		t.Errorf("Variable of the cost-center property is %s", variable)
+	}
+}
+
+func TestLoadRestrictsRemoteFilesEnvironment(t *testing.T) {
+	t.Setenv("GITHUB_TOKEN", "ghp_secret")
+	t.Setenv("DOMAIN", "acme.dev")
+
+	authorizations := []string{}
+	server := httptest.NewServer(serveFile("webhooks: [{url: 'https://${DOMAIN}/hook?token=${GITHUB_TOKEN}'}]\n", &authorizations))
+	defer server.Close()
+
+	path := writeFile(t, "settings.yml", "extends: "+server.URL+"/base.yml\nrepository: {owner: acme, name: api}\n")
+
+	_, err := Load(path)
+
+	if err == nil || !strings.Contains(err.Error(), "Undefined variables DOMAIN, GITHUB_TOKEN") {
+		t.Fatalf("Loading a remote file reading the environment failed with %v, want undefined variables", err)
+	}
+
+	_, err = Load(path, WithRemoteEnv("DOMAIN"))
+
+	if err == nil || !strings.Contains(err.Error(), "Undefined variables GITHUB_TOKEN") {
+		t.Fatalf("Loading a remote file reading a variable not allowed failed with %v, want undefined variables", err)
+	}
+
+	content, err := Load(path, WithRemoteEnv("DOMAIN", "GITHUB_TOKEN"))
+
+	if err != nil {
+		t.Fatalf("Error loading settings with allowed variables: %v", err)
+	}
+
+	if !strings.Contains(string(content), "https://acme.dev/hook?token=ghp_secret") {
+		t.Errorf("Allowed variables are not substituted:\n%s", content)
+	}
+}
+
+func TestLoadSendsTokenOnlyOverHTTPS(t *testing.T) {
+	authorizations := []string{}
+
+	plain := httptest.NewServer(serveFile("labels: [{name: bug}]\n", &authorizations))
+	defer plain.Close()
+
+	secure := httptest.NewTLSServer(serveFile("labels: [{name: bug}]\n", &authorizations))
+	defer secure.Close()
+
+	for _, server := range []*httptest.Server{plain, secure} {
+		serverURL, _ := url.Parse(server.URL)
+		path := writeFile(t, "settings.yml", "extends: "+server.URL+"/base.yml\nrepository: {owner: acme, name: api}\n")
+
+		_, err := Load(path, WithToken("ghp_secret"), WithGithubHosts(serverURL.Hostname()), WithHTTPClient(secure.Client()))
+
+		if err != nil {
+			t.Fatalf("Error loading settings extending %s: %v", server.URL, err)
+		}
+	}
+
+	if want := []string{"", "token ghp_secret"}; strings.Join(authorizations, ",") != strings.Join(want, ",") {
+		t.Errorf("Authorizations sent are %q, want %q", authorizations, want)
+	}
+}
+
+func TestTraceRecordsTheFileSettingEachValue(t *testing.T) {
+	base := writeFile(t, "base.yml", "labels: [{name: bug, color: d73a4a, description: Broken}]\ntopics: [go]\n")
+	path := writeFile(t, "settings.yml", "extends: "+base+"\nlabels: [{name: bug, color: ff0000}]\ntopics: [api]\n")
+
+	_, provenance, err := Trace(path)
+
+	if err != nil {
+		t.Fatalf("Error tracing settings: %v", err)
+	}
+
+	labels, _ := provenance["labels"].([]interface{})
+
+	if len(labels) != 1 {
+		t.Fatalf("Expected the labels merged by name, got %v", provenance["labels"])
+	}
+
+	bug := labels[0].(map[string]interface{})
+
+	if bug["name"] != "bug" || bug["color"] != Source(path) || bug["description"] != Source(base) {
+		t.Errorf("Expected the color from the file and the description from its base, got %v", bug)
+	}
+
+	if provenance["topics"] != Source(path) {
+		t.Errorf("Expected the topics replaced by the file, got %v", provenance["topics"])
+	}
+}
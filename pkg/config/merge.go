@@ -0,0 +1,90 @@
+package config
+
+// nolint:gochecknoglobals
+var listKeys = map[string]string{
+	"labels":            "name",
+	"branches":          "name",
+	"webhooks":          "url",
+	"collaborators":     "username",
+	"teams":             "slug",
+	"secrets":           "name",
+	"variables":         "name",
+	"dependabotsecrets": "name",
+	"environments":      "name",
+	"files":             "path",
+	"rulesets":          "name",
+}
+
+// Merge returns the override merged over the base without modifying them
+// Maps are merged recursively, lists of resources are merged by their key and other values are replaced
+func Merge(base, override map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(base)+len(override))
+
+	for key, value := range base {
+		merged[key] = value
+	}
+
+	for key, value := range override {
+		baseMap, baseIsMap := merged[key].(map[string]interface{})
+		overrideMap, overrideIsMap := value.(map[string]interface{})
+
+		if baseIsMap && overrideIsMap {
+			merged[key] = Merge(baseMap, overrideMap)
+			continue
+		}
+
+		baseList, baseIsList := merged[key].([]interface{})
+		overrideList, overrideIsList := value.([]interface{})
+		itemKey, keyed := listKeys[key]
+
+		if baseIsList && overrideIsList && keyed {
+			merged[key] = mergeList(baseList, overrideList, itemKey)
+			continue
+		}
+
+		merged[key] = value
+	}
+
+	return merged
+}
+
+// mergeList merges the items sharing the same key in place and appends the new items
+func mergeList(base, override []interface{}, itemKey string) []interface{} {
+	merged := append([]interface{}{}, base...)
+	positions := map[interface{}]int{}
+
+	for i, item := range merged {
+		if key, ok := listItemKey(item, itemKey); ok {
+			positions[key] = i
+		}
+	}
+
+	for _, item := range override {
+		key, ok := listItemKey(item, itemKey)
+		position, exists := positions[key]
+
+		if !ok || !exists {
+			merged = append(merged, item)
+			continue
+		}
+
+		merged[position] = Merge(merged[position].(map[string]interface{}), item.(map[string]interface{}))
+	}
+
+	return merged
+}
+
+func listItemKey(item interface{}, itemKey string) (interface{}, bool) {
+	itemMap, ok := item.(map[string]interface{})
+
+	if !ok {
+		return nil, false
+	}
+
+	switch key := itemMap[itemKey].(type) {
+	case string, int, float64, bool:
+		return key, true
+	default:
+		return nil, false
+	}
+}
@@ -0,0 +1,83 @@
+package config
+
+// Source is the settings file, path or url, that set a value
+type Source string
+
+// Trace loads a settings file like Load and returns its provenance document too, the merged document with every value replaced
+// by the Source of the file that set it. The items of the lists merged by their key keep their key (ex: the name of a label),
+// the other lists are replaced as a whole so they are replaced by their Source
+func Trace(path string, opts ...Option) ([]byte, map[string]interface{}, error) {
+	return newLoader(opts).loadFile(path)
+}
+
+// ListKey returns the field identifying the items of a list of resources merged by their key (ex: name for labels)
+func ListKey(section string) (string, bool) {
+	key, ok := listKeys[section]
+
+	return key, ok
+}
+
+// annotate replaces the values of a document by their source, the repositories of a multi repository document are annotated
+// one by one since they are resolved separately
+func annotate(document map[string]interface{}, source Source) map[string]interface{} {
+	annotated := make(map[string]interface{}, len(document))
+
+	for key, value := range document {
+		annotated[key] = annotateValue(key, value, source)
+	}
+
+	if repositories, ok := document["repositories"].([]interface{}); ok {
+		annotatedRepositories := make([]interface{}, 0, len(repositories))
+
+		for _, overrides := range repositories {
+			annotatedRepositories = append(annotatedRepositories, annotateValue("", overrides, source))
+		}
+
+		annotated["repositories"] = annotatedRepositories
+	}
+
+	return annotated
+}
+
+// annotateValue replaces a value by its source, the maps and the items of the lists merged by their key are annotated field by field
+func annotateValue(key string, value interface{}, source Source) interface{} {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		annotated := make(map[string]interface{}, len(typed))
+
+		for fieldKey, fieldValue := range typed {
+			annotated[fieldKey] = annotateValue(fieldKey, fieldValue, source)
+		}
+
+		return annotated
+	case []interface{}:
+		itemKey, keyed := listKeys[key]
+
+		if !keyed {
+			return source
+		}
+
+		annotated := make([]interface{}, 0, len(typed))
+
+		for _, item := range typed {
+			itemMap, ok := item.(map[string]interface{})
+
+			if !ok {
+				annotated = append(annotated, source)
+				continue
+			}
+
+			annotatedItem := annotateValue("", itemMap, source).(map[string]interface{})
+
+			if _, ok := itemMap[itemKey]; ok {
+				annotatedItem[itemKey] = itemMap[itemKey]
+			}
+
+			annotated = append(annotated, annotatedItem)
+		}
+
+		return annotated
+	default:
+		return source
+	}
+}
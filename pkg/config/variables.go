@@ -0,0 +1,199 @@
+package config
+
+import (
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Variables describing the repository the settings are applied to
+const (
+	RepoNameVariable  = "REPO_NAME"
+	RepoOwnerVariable = "REPO_OWNER"
+)
+
+// Variables describing the live repository, they are kept by the loader and substituted when the repository is planned
+const (
+	RepoLanguageVariable   = "REPO_LANGUAGE"
+	RepoVisibilityVariable = "REPO_VISIBILITY"
+	// RepoTopicsVariable is the topics of the repository separated by commas
+	RepoTopicsVariable = "REPO_TOPICS"
+	// RepoPropertyPrefix prefixes the variables of the custom properties, uppercased with underscores (ex: REPO_PROPERTY_COST_CENTER)
+	RepoPropertyPrefix = "REPO_PROPERTY_"
+)
+
+// IsMetadataVariable returns true for the variables describing the live repository
+func IsMetadataVariable(name string) bool {
+	return name == RepoLanguageVariable || name == RepoVisibilityVariable || name == RepoTopicsVariable || strings.HasPrefix(name, RepoPropertyPrefix)
+}
+
+// PropertyVariable returns the variable of a custom property (ex: REPO_PROPERTY_COST_CENTER for cost-center)
+func PropertyVariable(property string) string {
+	return RepoPropertyPrefix + strings.ToUpper(nonVariablePattern.ReplaceAllString(property, "_"))
+}
+
+// nolint:gochecknoglobals
+var nonVariablePattern = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// nolint:gochecknoglobals
+var variablePattern = regexp.MustCompile(`\$?\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// Substitute replaces the ${NAME} variables of every string of a document, $${NAME} is kept as ${NAME}
+// An error is returned for the variables unknown to the lookup
+func Substitute(document interface{}, lookup func(name string) (string, bool)) (interface{}, error) {
+	return substitute(document, lookup, false)
+}
+
+// substitute replaces the variables of a document, the escaped variables are kept escaped for a later substitution when keepEscaped is set
+func substitute(document interface{}, lookup func(name string) (string, bool), keepEscaped bool) (interface{}, error) {
+	switch typed := document.(type) {
+	case string:
+		return substituteString(typed, lookup, keepEscaped)
+	case map[string]interface{}:
+		substituted := make(map[string]interface{}, len(typed))
+
+		for key, value := range typed {
+			substitutedValue, err := substitute(value, lookup, keepEscaped)
+
+			if err != nil {
+				return nil, err
+			}
+
+			substituted[key] = substitutedValue
+		}
+
+		return substituted, nil
+	case []interface{}:
+		substituted := make([]interface{}, 0, len(typed))
+
+		for _, value := range typed {
+			substitutedValue, err := substitute(value, lookup, keepEscaped)
+
+			if err != nil {
+				return nil, err
+			}
+
+			substituted = append(substituted, substitutedValue)
+		}
+
+		return substituted, nil
+	default:
+		return document, nil
+	}
+}
+
+func substituteString(value string, lookup func(name string) (string, bool), keepEscaped bool) (string, error) {
+	var missing []string
+
+	substituted := variablePattern.ReplaceAllStringFunc(value, func(match string) string {
+		if strings.HasPrefix(match, "$$") && keepEscaped {
+			return match
+		}
+
+		if strings.HasPrefix(match, "$$") {
+			return match[1:]
+		}
+
+		name := variablePattern.FindStringSubmatch(match)[1]
+		variable, ok := lookup(name)
+
+		if !ok {
+			missing = append(missing, name)
+			return match
+		}
+
+		return variable
+	})
+
+	if len(missing) != 0 {
+		return "", errors.Errorf("Undefined variables %s", strings.Join(missing, ", "))
+	}
+
+	return substituted, nil
+}
+
+// nolint:gochecknoglobals
+var repositoryKeys = map[string]string{
+	RepoNameVariable:  "name",
+	RepoOwnerVariable: "owner",
+}
+
+// substituteSource replaces the variables of a file before it is merged with the variables given to the loader and the environment
+// Remote files only read the allowed environment variables, the repository variables are kept for the merged document
+func (l *loader) substituteSource(source string, document map[string]interface{}) error {
+	remote := isURL(source)
+
+	lookup := func(name string) (string, bool) {
+		if value, ok := l.variables[name]; ok {
+			return value, true
+		}
+
+		if _, ok := repositoryKeys[name]; ok || IsMetadataVariable(name) {
+			return "${" + name + "}", true
+		}
+
+		if remote && !l.remoteEnv[name] {
+			return "", false
+		}
+
+		return os.LookupEnv(name)
+	}
+
+	for key, value := range document {
+		substituted, err := substitute(value, lookup, true)
+
+		if err != nil && remote {
+			return errors.Wrapf(err, "Error substituting variables of %s, remote files only read the environment variables allowed for them", source)
+		}
+
+		if err != nil {
+			return errors.Wrapf(err, "Error substituting variables of %s", source)
+		}
+
+		document[key] = substituted
+	}
+
+	return nil
+}
+
+// lookup resolves the repository variables of the merged document, the other variables were substituted in each file
+// The repository variables of a multi repository document are kept to be substituted for each repository
+func (l *loader) lookup(document map[string]interface{}) func(string) (string, bool) {
+	repository, _ := document["repository"].(map[string]interface{})
+	multi := isMulti(document)
+
+	return func(name string) (string, bool) {
+		key, ok := repositoryKeys[name]
+
+		// The values substituted in the files are not substituted again
+		if !ok {
+			return "${" + name + "}", true
+		}
+
+		if multi {
+			return "${" + name + "}", true
+		}
+
+		if value, ok := repository[key].(string); ok {
+			return value, true
+		}
+
+		return os.LookupEnv(name)
+	}
+}
+
+// RepositoryLookup resolves the repository variables and keeps the others unchanged
+func RepositoryLookup(owner, name string) func(string) (string, bool) {
+	return func(variable string) (string, bool) {
+		switch variable {
+		case RepoNameVariable:
+			return name, true
+		case RepoOwnerVariable:
+			return owner, true
+		default:
+			return "${" + variable + "}", true
+		}
+	}
+}
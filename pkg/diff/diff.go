@@ -0,0 +1,116 @@
+// Package diff compares settings field by field and explains what differs
+//
+// Structs are compared field by field, nested structs are walked and their paths joined with dots. Other values,
+// pointers, slices and maps included, are compared as a whole with reflect.DeepEqual. Fields are named like their yaml
+// key: the name of the yaml tag or the lowercased field name.
+//
+// The diff tag tunes the comparison of a field:
+//
+//	Secret  string `diff:"sensitive"` // compared but reported as sensitive so its values are not printed
+//	MatchBy string `diff:"-"`         // ignored
+package diff
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Tag is the struct tag tuning the comparison of a field
+const Tag = "diff"
+
+// Tag values
+const (
+	Ignore    = "-"
+	Sensitive = "sensitive"
+)
+
+// Difference is a field whose value differs between two values
+type Difference struct {
+	// Path is the dot separated yaml path of the field (ex: protection.requiredstatuschecks.contexts), empty when the compared values are not structs
+	Path string
+	Old  interface{}
+	New  interface{}
+	// Sensitive is true when the field is tagged sensitive, its values should not be printed
+	Sensitive bool
+}
+
+// Compare returns the differences between two values of the same type
+// A nil value is compared as the zero value of the type of the other
+func Compare(oldValue, newValue interface{}) []Difference {
+	before := reflect.ValueOf(oldValue)
+	after := reflect.ValueOf(newValue)
+
+	switch {
+	case !before.IsValid() && !after.IsValid():
+		return nil
+	case !before.IsValid():
+		before = reflect.Zero(after.Type())
+	case !after.IsValid():
+		after = reflect.Zero(before.Type())
+	}
+
+	if before.Type() != after.Type() {
+		return []Difference{{Old: oldValue, New: newValue}}
+	}
+
+	return compare("", before, after, false)
+}
+
+// Equal returns true when two values have no difference, ignored fields excepted
+func Equal(oldValue, newValue interface{}) bool {
+	return len(Compare(oldValue, newValue)) == 0
+}
+
+// Paths returns the path of each difference
+func Paths(differences []Difference) []string {
+	paths := make([]string, 0, len(differences))
+
+	for _, difference := range differences {
+		paths = append(paths, difference.Path)
+	}
+
+	return paths
+}
+
+func compare(path string, before, after reflect.Value, sensitive bool) []Difference {
+	if before.Kind() != reflect.Struct {
+		if reflect.DeepEqual(before.Interface(), after.Interface()) {
+			return nil
+		}
+
+		return []Difference{{Path: path, Old: before.Interface(), New: after.Interface(), Sensitive: sensitive}}
+	}
+
+	differences := []Difference{}
+
+	for i := 0; i < before.NumField(); i++ {
+		field := before.Type().Field(i)
+		tag := field.Tag.Get(Tag)
+
+		// Unexported fields are internal state, not settings
+		if tag == Ignore || field.PkgPath != "" {
+			continue
+		}
+
+		fieldPath := fieldName(field)
+
+		if path != "" {
+			fieldPath = path + "." + fieldPath
+		}
+
+		differences = append(differences, compare(fieldPath, before.Field(i), after.Field(i), sensitive || tag == Sensitive)...)
+	}
+
+	return differences
+}
+
+// fieldName returns the yaml key of a field
+func fieldName(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+
+	if name != "" && name != "-" {
+		return name
+	}
+
+	return strings.ToLower(field.Name)
+}
@@ -0,0 +1,258 @@
+package github
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"github.com/google/go-github/v75/github"
+	"github.com/michaelmass/github-settings/pkg/diff"
+	"github.com/pkg/errors"
+)
+
+// Resource kinds granting access to the repository
+const (
+	ResourceCollaborators = "collaborators"
+	ResourceTeams         = "teams"
+)
+
+type collaborator struct {
+	Username    string
+	Permission  permission
+	annotations `yaml:",inline" diff:"-"`
+	// invitation is the id of the pending invitation of a user who did not accept it yet
+	invitation int64
+}
+
+type team struct {
+	Slug        string
+	Permission  permission
+	annotations `yaml:",inline" diff:"-"`
+}
+
+func (client *Client) getCollaborators(ctx context.Context, owner, name string) ([]collaborator, error) {
+	users, err := listAll(func(opts github.ListOptions) ([]*github.User, *github.Response, error) {
+		return client.github.Repositories.ListCollaborators(ctx, owner, name, &github.ListCollaboratorsOptions{
+			Affiliation: "direct",
+			ListOptions: opts,
+		})
+	})
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Error while listing collaborators")
+	}
+
+	collaborators := make([]collaborator, 0, len(users))
+
+	for _, user := range users {
+		collaborators = append(collaborators, collaborator{
+			Username:   user.GetLogin(),
+			Permission: toPermission(user.GetRoleName()),
+		})
+	}
+
+	invitations, err := listAll(func(opts github.ListOptions) ([]*github.RepositoryInvitation, *github.Response, error) {
+		return client.github.Repositories.ListInvitations(ctx, owner, name, &opts)
+	})
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Error while listing invitations")
+	}
+
+	for _, invitation := range invitations {
+		collaborators = append(collaborators, collaborator{
+			Username:   invitation.GetInvitee().GetLogin(),
+			Permission: toPermission(invitation.GetPermissions()),
+			invitation: invitation.GetID(),
+		})
+	}
+
+	return collaborators, nil
+}
+
+func (client *Client) getTeams(ctx context.Context, owner, name string) ([]team, error) {
+	githubTeams, err := listAll(func(opts github.ListOptions) ([]*github.Team, *github.Response, error) {
+		return client.github.Repositories.ListTeams(ctx, owner, name, &opts)
+	})
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Error while listing teams")
+	}
+
+	teams := make([]team, 0, len(githubTeams))
+
+	for _, githubTeam := range githubTeams {
+		teams = append(teams, team{
+			Slug:       githubTeam.GetSlug(),
+			Permission: toPermission(githubTeam.GetPermission()),
+		})
+	}
+
+	return client.getTeamRoles(ctx, owner, name, teams)
+}
+
+func planCollaborators(disabled bool, githubCollaborators, collaboratorsSettings []collaborator) []Change {
+	if disabled {
+		log.Print("[INFO] Skipping disabled repository collaborators\n")
+		return nil
+	}
+
+	// Without a collaborators section the collaborators are left alone, an empty list removes them all
+	if collaboratorsSettings == nil {
+		log.Print("[INFO] Skipping unmanaged repository collaborators\n")
+		return nil
+	}
+
+	changes := []Change{}
+	deleteCollaboratorsMap := map[string]collaborator{}
+
+	// Usernames are case insensitive
+	for _, githubCollaborator := range githubCollaborators {
+		deleteCollaboratorsMap[strings.ToLower(githubCollaborator.Username)] = githubCollaborator
+	}
+
+	for _, collaboratorSettings := range collaboratorsSettings {
+		key := strings.ToLower(collaboratorSettings.Username)
+		githubCollaborator, ok := deleteCollaboratorsMap[key]
+
+		if !ok {
+			changes = append(changes, newChange(ResourceCollaborators, collaboratorSettings.Username, ActionCreate, collaborator{}, collaboratorSettings))
+			continue
+		}
+
+		delete(deleteCollaboratorsMap, key)
+
+		if githubCollaborator.Permission != collaboratorSettings.Permission {
+			collaboratorSettings.invitation = githubCollaborator.invitation
+			changes = append(changes, newChange(ResourceCollaborators, collaboratorSettings.Username, ActionUpdate, githubCollaborator, collaboratorSettings))
+		}
+	}
+
+	for _, collaboratorToDeleteName := range sortedKeys(deleteCollaboratorsMap) {
+		collaboratorToDelete := deleteCollaboratorsMap[collaboratorToDeleteName]
+		changes = append(changes, newChange(ResourceCollaborators, collaboratorToDelete.Username, ActionDelete, collaboratorToDelete, collaborator{}))
+	}
+
+	return changes
+}
+
+func planTeams(disabled bool, githubTeams, teamsSettings []team) []Change {
+	if disabled {
+		log.Print("[INFO] Skipping disabled repository teams\n")
+		return nil
+	}
+
+	// Without a teams section the teams are left alone, an empty list removes them all
+	if teamsSettings == nil {
+		log.Print("[INFO] Skipping unmanaged repository teams\n")
+		return nil
+	}
+
+	changes := []Change{}
+	deleteTeamsMap := map[string]team{}
+
+	for _, githubTeam := range githubTeams {
+		deleteTeamsMap[githubTeam.Slug] = githubTeam
+	}
+
+	for _, teamSettings := range teamsSettings {
+		githubTeam, ok := deleteTeamsMap[teamSettings.Slug]
+
+		if !ok {
+			changes = append(changes, newChange(ResourceTeams, teamSettings.Slug, ActionCreate, team{}, teamSettings))
+			continue
+		}
+
+		delete(deleteTeamsMap, teamSettings.Slug)
+
+		if !diff.Equal(githubTeam, teamSettings) {
+			changes = append(changes, newChange(ResourceTeams, teamSettings.Slug, ActionUpdate, githubTeam, teamSettings))
+		}
+	}
+
+	for _, teamToDeleteName := range sortedKeys(deleteTeamsMap) {
+		teamToDelete := deleteTeamsMap[teamToDeleteName]
+		changes = append(changes, newChange(ResourceTeams, teamToDelete.Slug, ActionDelete, teamToDelete, team{}))
+	}
+
+	return changes
+}
+
+func (client *Client) updateCollaborator(ctx context.Context, report reporter, owner, name string, action Action, githubCollaborator, collaboratorSettings collaborator) error {
+	switch {
+	case action == ActionDelete && githubCollaborator.invitation != 0:
+		report.changed(ResourceCollaborators, "Cancelling invitation of collaborator %s\n", githubCollaborator.Username)
+
+		_, err := client.github.Repositories.DeleteInvitation(ctx, owner, name, githubCollaborator.invitation)
+
+		if err != nil {
+			return errors.Wrap(err, "Error cancelling a collaborator invitation\n")
+		}
+	case action == ActionDelete:
+		report.changed(ResourceCollaborators, "Removing collaborator %s\n", githubCollaborator.Username)
+
+		_, err := client.github.Repositories.RemoveCollaborator(ctx, owner, name, githubCollaborator.Username)
+
+		if err != nil {
+			return errors.Wrap(err, "Error removing a collaborator\n")
+		}
+	case action == ActionUpdate && collaboratorSettings.invitation != 0:
+		report.changed(ResourceCollaborators, "Updating invitation of collaborator %s\n", collaboratorSettings.Username)
+
+		_, _, err := client.github.Repositories.UpdateInvitation(ctx, owner, name, collaboratorSettings.invitation, invitationPermission(collaboratorSettings.Permission))
+
+		if err != nil {
+			return errors.Wrap(err, "Error updating a collaborator invitation\n")
+		}
+	default:
+		report.changed(ResourceCollaborators, "Setting %s permission for collaborator %s\n", collaboratorSettings.Permission, collaboratorSettings.Username)
+
+		_, _, err := client.github.Repositories.AddCollaborator(ctx, owner, name, collaboratorSettings.Username, &github.RepositoryAddCollaboratorOptions{
+			Permission: string(collaboratorSettings.Permission),
+		})
+
+		if err != nil {
+			return errors.Wrap(err, "Error setting a collaborator permission\n")
+		}
+	}
+
+	return nil
+}
+
+func (client *Client) updateTeam(ctx context.Context, report reporter, owner, name string, action Action, githubTeam, teamSettings team) error {
+	if action == ActionDelete {
+		report.changed(ResourceTeams, "Removing team %s\n", githubTeam.Slug)
+
+		_, err := client.github.Teams.RemoveTeamRepoBySlug(ctx, owner, githubTeam.Slug, owner, name)
+
+		if err != nil {
+			return errors.Wrap(err, "Error removing a team\n")
+		}
+
+		return nil
+	}
+
+	report.changed(ResourceTeams, "Setting %s permission for team %s\n", teamSettings.Permission, teamSettings.Slug)
+
+	_, err := client.github.Teams.AddTeamRepoBySlug(ctx, owner, teamSettings.Slug, owner, name, &github.TeamAddTeamRepoOptions{
+		Permission: string(teamSettings.Permission),
+	})
+
+	if err != nil {
+		return errors.Wrap(err, "Error setting a team permission\n")
+	}
+
+	return nil
+}
+
+// invitationPermission converts a permission to the names used by invitations (read, write)
+func invitationPermission(value permission) string {
+	switch value {
+	case permissionPull:
+		return "read"
+	case permissionPush:
+		return "write"
+	default:
+		return string(value)
+	}
+}
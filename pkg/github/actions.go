@@ -0,0 +1,396 @@
+package github
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v75/github"
+	"github.com/michaelmass/github-settings/pkg/diff"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/nacl/box"
+	"gopkg.in/yaml.v3"
+)
+
+// Resource kinds of the github actions configuration
+const (
+	ResourceSecrets   = "secrets"
+	ResourceVariables = "variables"
+)
+
+// secret is an actions secret, its value can't be read back from github so it is taken from the secret values or the environment
+type secret struct {
+	Name string
+	// Env is the environment variable holding the value (defaults to the secret name)
+	Env string `yaml:",omitempty" diff:"-"`
+	// Overwrite rewrites an existing secret on every apply since its value can't be compared
+	Overwrite bool `yaml:",omitempty" diff:"-"`
+	// UpdatedAfter is when the value was last rotated (ex: 2026-03-01), a live secret last written before is written again
+	// instead of on every apply like Overwrite
+	UpdatedAfter timestamp `yaml:",omitempty" diff:"-"`
+	annotations  `yaml:",inline" diff:"-"`
+
+	// updatedAt is when github last wrote the live secret
+	updatedAt time.Time
+}
+
+// stale returns true when the live secret was last written before the value of the settings was rotated
+func (secretSettings secret) stale(githubSecret secret) bool {
+	return secretSettings.UpdatedAfter != "" && githubSecret.updatedAt.Before(secretSettings.UpdatedAfter.time())
+}
+
+// timestamp is a date (2006-01-02) or a time (2006-01-02T15:04:05Z) of the settings
+type timestamp string
+
+// UnmarshalYAML rejects the values that are neither a date nor a time when parsing the settings
+func (value *timestamp) UnmarshalYAML(node *yaml.Node) error {
+	var parsed string
+	err := node.Decode(&parsed)
+
+	if err != nil {
+		return errors.Wrap(err, "Error while unmarshal timestamp")
+	}
+
+	if timestamp(parsed).time().IsZero() {
+		return errors.Errorf("Invalid timestamp %q, expected a date (2006-01-02) or a time (2006-01-02T15:04:05Z)", parsed)
+	}
+
+	*value = timestamp(parsed)
+
+	return nil
+}
+
+// time returns the timestamp, a date is midnight utc, an invalid timestamp is the zero time
+func (value timestamp) time() time.Time {
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		parsed, err := time.Parse(layout, string(value))
+
+		if err == nil {
+			return parsed
+		}
+	}
+
+	return time.Time{}
+}
+
+type variable struct {
+	Name        string
+	Value       string
+	annotations `yaml:",inline" diff:"-"`
+}
+
+// LoadSecretValues reads a yaml file mapping secret names to their values
+// The file is meant to stay out of the settings repository (ex: decrypted by the pipeline before running)
+func LoadSecretValues(path string) (map[string]string, error) {
+	content, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error while reading secret values file %s", path)
+	}
+
+	values := map[string]string{}
+	err = yaml.Unmarshal(content, &values)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error while unmarshal secret values file %s", path)
+	}
+
+	return values, nil
+}
+
+// withServerDefaults uppercases the secret name like github does
+func (secretSettings secret) withServerDefaults() secret {
+	secretSettings.Name = strings.ToUpper(secretSettings.Name)
+	return secretSettings
+}
+
+// withServerDefaults uppercases the variable name like github does
+func (variableSettings variable) withServerDefaults() variable {
+	variableSettings.Name = strings.ToUpper(variableSettings.Name)
+	return variableSettings
+}
+
+func (client *Client) getSecrets(ctx context.Context, owner, name string) ([]secret, error) {
+	githubSecrets, err := listAll(func(opts github.ListOptions) ([]*github.Secret, *github.Response, error) {
+		page, response, err := client.github.Actions.ListRepoSecrets(ctx, owner, name, &opts)
+
+		if err != nil {
+			return nil, response, err
+		}
+
+		return page.Secrets, response, nil
+	})
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Error while listing actions secrets")
+	}
+
+	secrets := make([]secret, 0, len(githubSecrets))
+
+	for _, githubSecret := range githubSecrets {
+		secrets = append(secrets, secret{Name: githubSecret.Name, updatedAt: githubSecret.UpdatedAt.Time})
+	}
+
+	return secrets, nil
+}
+
+func (client *Client) getVariables(ctx context.Context, owner, name string) ([]variable, error) {
+	githubVariables, err := listAll(func(opts github.ListOptions) ([]*github.ActionsVariable, *github.Response, error) {
+		page, response, err := client.github.Actions.ListRepoVariables(ctx, owner, name, &opts)
+
+		if err != nil {
+			return nil, response, err
+		}
+
+		return page.Variables, response, nil
+	})
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Error while listing actions variables")
+	}
+
+	variables := make([]variable, 0, len(githubVariables))
+
+	for _, githubVariable := range githubVariables {
+		variables = append(variables, variable{Name: githubVariable.Name, Value: githubVariable.Value})
+	}
+
+	return variables, nil
+}
+
+func planSecrets(disabled bool, githubSecrets, secretsSettings []secret) []Change {
+	if disabled {
+		log.Print("[INFO] Skipping disabled repository secrets\n")
+		return nil
+	}
+
+	// Without a secrets section the secrets are left alone, an empty list removes them all
+	if secretsSettings == nil {
+		log.Print("[INFO] Skipping unmanaged secrets\n")
+		return nil
+	}
+
+	changes := []Change{}
+	deleteSecretsMap := map[string]secret{}
+
+	for _, githubSecret := range githubSecrets {
+		deleteSecretsMap[githubSecret.Name] = githubSecret
+	}
+
+	for _, secretSettings := range secretsSettings {
+		secretSettings = secretSettings.withServerDefaults()
+		githubSecret, ok := deleteSecretsMap[secretSettings.Name]
+
+		if !ok {
+			changes = append(changes, newChange(ResourceSecrets, secretSettings.Name, ActionCreate, secret{}, secretSettings))
+			continue
+		}
+
+		delete(deleteSecretsMap, secretSettings.Name)
+
+		switch {
+		case secretSettings.stale(githubSecret):
+			change := newChange(ResourceSecrets, secretSettings.Name, ActionUpdate, githubSecret, secretSettings)
+			change.Fields = []FieldChange{{Field: "updatedat", Before: githubSecret.updatedAt.UTC().Format(time.RFC3339), After: "after " + string(secretSettings.UpdatedAfter)}}
+			changes = append(changes, change)
+		case secretSettings.Overwrite && secretSettings.UpdatedAfter == "":
+			changes = append(changes, newChange(ResourceSecrets, secretSettings.Name, ActionUpdate, githubSecret, secretSettings))
+		}
+	}
+
+	for _, secretToDeleteName := range sortedKeys(deleteSecretsMap) {
+		secretToDelete := deleteSecretsMap[secretToDeleteName]
+		changes = append(changes, newChange(ResourceSecrets, secretToDelete.Name, ActionDelete, secretToDelete, secret{}))
+	}
+
+	return changes
+}
+
+// unexpectedSecrets warns about the live secrets missing from the settings that are kept since their section is not pruned
+// Their values can't be read, the plan is the only place they show up
+func unexpectedSecrets(changes []Change, prune Prune, fallback bool) []string {
+	warnings := []string{}
+
+	for _, change := range changes {
+		secretChange := change.Resource == ResourceSecrets || change.Resource == ResourceEnvironmentSecrets || change.Resource == ResourceDependabotSecrets
+
+		if secretChange && change.Action == ActionDelete && !prune.enabled(change.Resource, fallback) {
+			warnings = append(warnings, fmt.Sprintf("secret %s is not in the settings, it is kept since %s are not pruned", change.Name, change.Resource))
+		}
+	}
+
+	return warnings
+}
+
+func planVariables(disabled bool, githubVariables, variablesSettings []variable) []Change {
+	if disabled {
+		log.Print("[INFO] Skipping disabled repository variables\n")
+		return nil
+	}
+
+	// Without a variables section the variables are left alone, an empty list removes them all
+	if variablesSettings == nil {
+		log.Print("[INFO] Skipping unmanaged variables\n")
+		return nil
+	}
+
+	changes := []Change{}
+	deleteVariablesMap := map[string]variable{}
+
+	for _, githubVariable := range githubVariables {
+		deleteVariablesMap[githubVariable.Name] = githubVariable
+	}
+
+	for _, variableSettings := range variablesSettings {
+		variableSettings = variableSettings.withServerDefaults()
+		githubVariable, ok := deleteVariablesMap[variableSettings.Name]
+
+		if !ok {
+			changes = append(changes, newChange(ResourceVariables, variableSettings.Name, ActionCreate, variable{}, variableSettings))
+			continue
+		}
+
+		delete(deleteVariablesMap, variableSettings.Name)
+
+		if !diff.Equal(githubVariable, variableSettings) {
+			changes = append(changes, newChange(ResourceVariables, variableSettings.Name, ActionUpdate, githubVariable, variableSettings))
+		}
+	}
+
+	for _, variableToDeleteName := range sortedKeys(deleteVariablesMap) {
+		variableToDelete := deleteVariablesMap[variableToDeleteName]
+		changes = append(changes, newChange(ResourceVariables, variableToDelete.Name, ActionDelete, variableToDelete, variable{}))
+	}
+
+	return changes
+}
+
+// secretValue returns the value of a secret from the secret values or its environment variable
+func (client *Client) secretValue(secretSettings secret) (string, error) {
+	if value, ok := client.secretValues[secretSettings.Name]; ok {
+		return value, nil
+	}
+
+	env := secretSettings.Env
+
+	if env == "" {
+		env = secretSettings.Name
+	}
+
+	value, ok := os.LookupEnv(env)
+
+	if !ok {
+		return "", errors.Errorf("Missing value for secret %s, set the %s environment variable or add it to the secret values", secretSettings.Name, env)
+	}
+
+	return value, nil
+}
+
+func (client *Client) updateSecret(ctx context.Context, report reporter, owner, name string, action Action, githubSecret, secretSettings secret) error {
+	if action == ActionDelete {
+		report.changed(ResourceSecrets, "Deleting secret %s\n", githubSecret.Name)
+
+		_, err := client.github.Actions.DeleteRepoSecret(ctx, owner, name, githubSecret.Name)
+
+		if err != nil {
+			return errors.Wrap(err, "Error deleting a secret\n")
+		}
+
+		return nil
+	}
+
+	value, err := client.secretValue(secretSettings)
+
+	if err != nil {
+		return err
+	}
+
+	report.changed(ResourceSecrets, "Writing secret %s\n", secretSettings.Name)
+
+	publicKey, _, err := client.github.Actions.GetRepoPublicKey(ctx, owner, name)
+
+	if err != nil {
+		return errors.Wrap(err, "Error getting the repository public key\n")
+	}
+
+	encryptedValue, err := encryptSecret(publicKey.GetKey(), value)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = client.github.Actions.CreateOrUpdateRepoSecret(ctx, owner, name, &github.EncryptedSecret{
+		Name:           secretSettings.Name,
+		KeyID:          publicKey.GetKeyID(),
+		EncryptedValue: encryptedValue,
+	})
+
+	if err != nil {
+		return errors.Wrap(err, "Error writing a secret\n")
+	}
+
+	return nil
+}
+
+// encryptSecret seals a value with the base64 encoded public key of the repository as required by the secrets api
+func encryptSecret(publicKey, value string) (string, error) {
+	decodedKey, err := base64.StdEncoding.DecodeString(publicKey)
+
+	if err != nil || len(decodedKey) != 32 {
+		return "", errors.New("Invalid repository public key")
+	}
+
+	var key [32]byte
+	copy(key[:], decodedKey)
+
+	sealed, err := box.SealAnonymous(nil, []byte(value), &key, rand.Reader)
+
+	if err != nil {
+		return "", errors.Wrap(err, "Error encrypting a secret")
+	}
+
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (client *Client) updateVariable(ctx context.Context, report reporter, owner, name string, action Action, githubVariable, variableSettings variable) error {
+	switch action {
+	case ActionDelete:
+		report.changed(ResourceVariables, "Deleting variable %s\n", githubVariable.Name)
+
+		_, err := client.github.Actions.DeleteRepoVariable(ctx, owner, name, githubVariable.Name)
+
+		if err != nil {
+			return errors.Wrap(err, "Error deleting a variable\n")
+		}
+	case ActionCreate:
+		report.changed(ResourceVariables, "Creating variable %s\n", variableSettings.Name)
+
+		_, err := client.github.Actions.CreateRepoVariable(ctx, owner, name, &github.ActionsVariable{
+			Name:  variableSettings.Name,
+			Value: variableSettings.Value,
+		})
+
+		if err != nil {
+			return errors.Wrap(err, "Error creating a variable\n")
+		}
+	case ActionUpdate:
+		report.changed(ResourceVariables, "Updating variable %s\n", variableSettings.Name)
+
+		_, err := client.github.Actions.UpdateRepoVariable(ctx, owner, name, &github.ActionsVariable{
+			Name:  variableSettings.Name,
+			Value: variableSettings.Value,
+		})
+
+		if err != nil {
+			return errors.Wrap(err, "Error updating a variable\n")
+		}
+	}
+
+	return nil
+}
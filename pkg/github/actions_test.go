@@ -0,0 +1,67 @@
+package github
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestPlanRewritesSecretsUpdatedBeforeTheirRotation(t *testing.T) {
+	server, client := newTestClient(t, WithSecretValues(map[string]string{"DEPLOY_TOKEN": "rotated"}))
+	repo := server.AddRepository("acme", "api")
+	repo.Secrets["DEPLOY_TOKEN"] = "old"
+	repo.Secrets["NPM_TOKEN"] = "old"
+
+	content := `
+repository: {owner: acme, name: api}
+disable: {repository: true}
+secrets:
+  - name: deploy_token
+    updatedafter: 2026-03-01
+  - name: npm_token
+    updatedafter: 2019-06-01T12:00:00Z
+`
+	plan := planOf(t, client, settingsFromYAML(t, content))
+
+	if strings.Join(changeNames(plan), ",") != "secrets update DEPLOY_TOKEN" {
+		t.Fatalf("Plan changes are %v, want [secrets update DEPLOY_TOKEN]", changeNames(plan))
+	}
+
+	if field := plan.Changes[0].Fields; len(field) != 1 || field[0].Before != "2020-01-01T00:00:00Z" || field[0].After != "after 2026-03-01" {
+		t.Errorf("Stale secret fields are %+v", field)
+	}
+
+	_, err := client.Apply(context.Background(), settingsFromYAML(t, content))
+
+	if err != nil {
+		t.Fatalf("Error applying settings: %v", err)
+	}
+
+	if repo.Secrets["DEPLOY_TOKEN"] != "rotated" || repo.Secrets["NPM_TOKEN"] != "old" {
+		t.Errorf("Secret values are %v, want only DEPLOY_TOKEN rotated", repo.Secrets)
+	}
+}
+
+func TestPlanWarnsAboutUnexpectedSecretsKept(t *testing.T) {
+	server, client := newTestClient(t)
+	repo := server.AddRepository("acme", "api")
+	repo.Secrets["LEGACY_TOKEN"] = "old"
+
+	plan := planOf(t, client, settingsFromYAML(t, "repository: {owner: acme, name: api}\ndisable: {repository: true}\nprune: {secrets: false}\nsecrets: []\n"))
+
+	if !plan.Empty() {
+		t.Errorf("Plan without pruning has changes %v", changeNames(plan))
+	}
+
+	if len(plan.Warnings) != 1 || !strings.Contains(plan.Warnings[0], "secret LEGACY_TOKEN is not in the settings") {
+		t.Errorf("Plan warnings are %v, want the unexpected secret", plan.Warnings)
+	}
+}
+
+func TestGetSettingsRejectsInvalidTimestamps(t *testing.T) {
+	_, err := GetSettingsFromBytes([]byte("repository: {owner: acme, name: api}\nsecrets: [{name: token, updatedafter: last week}]\n"))
+
+	if err == nil || !strings.Contains(err.Error(), `Invalid timestamp "last week"`) {
+		t.Errorf("Parsing an invalid timestamp failed with %v", err)
+	}
+}
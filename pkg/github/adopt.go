@@ -0,0 +1,250 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// adoptKeys are the fields identifying the resources of each section of a settings file, adopted resources already listed are not appended
+// nolint:gochecknoglobals
+var adoptKeys = map[string]string{
+	ResourceLabels:        "name",
+	ResourceBranches:      "name",
+	ResourceWebhooks:      "url",
+	ResourceCollaborators: "username",
+	ResourceTeams:         "slug",
+	ResourceSecrets:       "name",
+	ResourceVariables:     "name",
+	ResourceEnvironments:  "name",
+	ResourceRulesets:      "name",
+}
+
+// Adopt records the live resources of the resource kinds of a repository as managed in the state store of the client without
+// changing them, so the next applies prune them like the resources created from the settings. The live settings of the
+// adopted sections are returned to be appended to the settings file (webhook secrets are redacted like Export)
+func (client *Client) Adopt(ctx context.Context, owner, name string, resources []string) (*Settings, error) {
+	if client.state == nil {
+		return nil, errors.New("Adopt requires a state store (see WithStateStore)")
+	}
+
+	if len(resources) == 0 {
+		return nil, errors.Errorf("No resource kind to adopt (allowed values: %s)", strings.Join(AdoptSections, ", "))
+	}
+
+	for _, resource := range resources {
+		if !isAdoptable(strings.ToLower(resource)) {
+			return nil, errors.Errorf("Unknown resource kind %q to adopt (allowed values: %s)", resource, strings.Join(AdoptSections, ", "))
+		}
+	}
+
+	settings, err := client.Export(ctx, owner, name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	names := resourceNames(settings)
+
+	for _, resource := range resources {
+		resource = strings.ToLower(resource)
+		client.state.add(owner+"/"+name, resource, names[resource]...)
+		log.Printf("[INFO] Adopted %d %s of %s/%s\n", len(names[resource]), resource, owner, name)
+	}
+
+	for _, webhookSettings := range settings.Webhooks {
+		if webhookSettings.Secret == RedactedSecret {
+			log.Printf("[WARN] The secret of webhook %s can't be read back from github, replace %s in the settings before applying\n", webhookSettings.URL, RedactedSecret)
+		}
+	}
+
+	return settings, nil
+}
+
+// AppendAdopted appends the adopted resources of the resource kinds missing from a settings file to the sections of the repository,
+// the file is the settings of the repository or lists it in its repositories (an entry is added when missing)
+// The resources already listed are kept as written
+func AppendAdopted(path, owner, name string, adopted *Settings, resources []string) error {
+	content, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		return errors.Wrapf(err, "Error while reading settings file %s", path)
+	}
+
+	var document yaml.Node
+	err = yaml.Unmarshal(content, &document)
+
+	if err != nil {
+		return errors.Wrapf(err, "Error while unmarshal settings file %s", path)
+	}
+
+	if len(document.Content) == 0 || resolveAlias(document.Content[0]).Kind != yaml.MappingNode {
+		return errors.Errorf("The settings file %s is not a mapping", path)
+	}
+
+	target, err := adoptionTarget(resolveAlias(document.Content[0]), owner, name)
+
+	if err != nil {
+		return errors.Wrapf(err, "Error while finding %s/%s in %s", owner, name, path)
+	}
+
+	var sections yaml.Node
+	err = sections.Encode(adopted)
+
+	if err != nil {
+		return errors.Wrap(err, "Error while marshal adopted settings")
+	}
+
+	for _, resource := range resources {
+		resource = strings.ToLower(resource)
+		err = appendSection(target, mappingEntry(&sections, resource), resource)
+
+		if err != nil {
+			return errors.Wrapf(err, "Error while appending the adopted %s to %s", resource, path)
+		}
+	}
+
+	buffer := &bytes.Buffer{}
+	encoder := yaml.NewEncoder(buffer)
+	encoder.SetIndent(2)
+
+	err = encoder.Encode(&document)
+
+	if err == nil {
+		err = encoder.Close()
+	}
+
+	if err != nil {
+		return errors.Wrap(err, "Error while marshal settings file")
+	}
+
+	mode := os.FileMode(cacheFilePermission)
+
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode()
+	}
+
+	err = ioutil.WriteFile(path, buffer.Bytes(), mode)
+
+	if err != nil {
+		return errors.Wrapf(err, "Error while writing settings file %s", path)
+	}
+
+	return nil
+}
+
+// adoptionTarget returns the mapping holding the sections of a repository in a settings file
+func adoptionTarget(root *yaml.Node, owner, name string) (*yaml.Node, error) {
+	if !isMultiNode(root) {
+		repository := mappingEntry(root, "repository")
+
+		if !strings.EqualFold(mappingEntry(repository, "owner").Value, owner) || !strings.EqualFold(mappingEntry(repository, "name").Value, name) {
+			return nil, errors.New("The settings file targets another repository")
+		}
+
+		return root, nil
+	}
+
+	org := mappingEntry(root, "org")
+
+	if org.Kind == yaml.MappingNode {
+		org = mappingEntry(org, "name")
+	}
+
+	repositories := mappingEntry(root, "repositories")
+
+	if repositories.Kind == 0 {
+		repositories = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "repositories"}, repositories)
+	}
+
+	if repositories.Kind != yaml.SequenceNode {
+		return nil, errors.New("The repositories of the settings file are not a list")
+	}
+
+	for _, entry := range repositories.Content {
+		entry = resolveAlias(entry)
+		repository := mappingEntry(entry, "repository")
+		entryOwner := mappingEntry(repository, "owner").Value
+
+		if entryOwner == "" {
+			entryOwner = org.Value
+		}
+
+		if strings.EqualFold(entryOwner, owner) && strings.EqualFold(mappingEntry(repository, "name").Value, name) {
+			return entry, nil
+		}
+	}
+
+	repository := map[string]string{"name": name}
+
+	if !strings.EqualFold(org.Value, owner) {
+		repository["owner"] = owner
+	}
+
+	entry := &yaml.Node{}
+	err := entry.Encode(map[string]interface{}{"repository": repository})
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Error while marshal repository entry")
+	}
+
+	repositories.Content = append(repositories.Content, entry)
+
+	return entry, nil
+}
+
+// appendSection appends the items of an adopted section whose key is not listed yet by the section of the target
+func appendSection(target, adopted *yaml.Node, resource string) error {
+	if adopted.Kind != yaml.SequenceNode || len(adopted.Content) == 0 {
+		return nil
+	}
+
+	existing := mappingEntry(target, resource)
+
+	if existing.Kind == 0 {
+		target.Content = append(target.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: resource}, adopted)
+		return nil
+	}
+
+	if existing.Kind != yaml.SequenceNode {
+		return errors.New("The section is not a list")
+	}
+
+	key := adoptKeys[resource]
+
+	for _, item := range adopted.Content {
+		value := mappingEntry(item, key).Value
+		found := false
+
+		for _, listed := range existing.Content {
+			found = found || strings.EqualFold(mappingEntry(resolveAlias(listed), key).Value, value)
+		}
+
+		if found {
+			log.Printf("[INFO] Keeping %s %s listed in the settings\n", resource, value)
+			continue
+		}
+
+		existing.Content = append(existing.Content, item)
+	}
+
+	return nil
+}
+
+// mappingEntry returns the value of a key of a mapping, an empty node when the key is missing
+func mappingEntry(node *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(node.Content) && node.Kind == yaml.MappingNode; i += 2 {
+		if node.Content[i].Value == key {
+			return resolveAlias(node.Content[i+1])
+		}
+	}
+
+	return &yaml.Node{}
+}
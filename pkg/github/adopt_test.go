@@ -0,0 +1,240 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestApplyWithStateStoreOnlyDeletesTheManagedResources(t *testing.T) {
+	state, err := LoadStateStore(filepath.Join(t.TempDir(), "state.json"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server, client := newTestClient(t, WithStateStore(state))
+	repo := server.AddRepository("acme", "api")
+	repo.SetLabel("manual", "ffffff", "")
+
+	declared := `
+repository: {owner: acme, name: api}
+labels:
+  - name: bug
+    color: d73a4a
+`
+
+	if _, err := client.Apply(context.Background(), settingsFromYAML(t, declared)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := repo.Label("manual"); !ok || !state.Managed("acme/api", ResourceLabels, "bug") {
+		t.Fatalf("Expected the manual label kept and the bug label managed, got %v", repo.LabelNames())
+	}
+
+	if _, err := client.Apply(context.Background(), settingsFromYAML(t, "repository: {owner: acme, name: api}\nlabels: []\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(repo.LabelNames(), []string{"manual"}) || state.Managed("acme/api", ResourceLabels, "bug") {
+		t.Errorf("Expected only the managed bug label deleted and forgotten, got %v", repo.LabelNames())
+	}
+}
+
+func TestAdoptRecordsAndAppendsTheLiveResources(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	state, err := LoadStateStore(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server, client := newTestClient(t, WithStateStore(state))
+	repo := server.AddRepository("acme", "api")
+	repo.SetLabel("manual", "ffffff", "")
+	repo.SetLabel("bug", "d73a4a", "")
+	repo.AddHook("https://ci.example.com/hook", "push")
+
+	if _, err := client.Adopt(context.Background(), "acme", "api", []string{"teams", "unknown"}); err == nil {
+		t.Error("Expected an error for an unknown resource kind")
+	}
+
+	settings, err := client.Adopt(context.Background(), "acme", "api", []string{"labels", "webhooks"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := writeSettings(t, `org: acme
+# the repositories adopted
+repositories:
+  - repository:
+      name: api
+    labels:
+      - name: bug
+        color: b60205
+`)
+
+	if err := AppendAdopted(config, "acme", "api", settings, []string{"labels", "webhooks"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := state.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded, err := LoadStateStore(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reloaded.Managed("acme/api", ResourceLabels, "manual") || !reloaded.Managed("acme/api", ResourceWebhooks, "https://ci.example.com/hook") {
+		t.Errorf("Expected the labels and the webhook adopted, got %v", reloaded.repositories)
+	}
+
+	allSettings, err := client.GetAllSettingsFromFile(context.Background(), config)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	labels := []string{}
+
+	for _, labelSettings := range allSettings[0].Labels {
+		labels = append(labels, labelSettings.Name+" "+labelSettings.Color)
+	}
+
+	if !slices.Equal(labels, []string{"bug b60205", "manual ffffff"}) || len(allSettings[0].Webhooks) != 1 {
+		t.Errorf("Expected the manual label and the webhook appended and the bug label kept, got %v %v", labels, allSettings[0].Webhooks)
+	}
+
+	content, err := os.ReadFile(config)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(content), "# the repositories adopted") {
+		t.Errorf("Expected the comments of the settings file kept, got %s", content)
+	}
+
+	names := slices.DeleteFunc(changeNames(planOf(t, client, allSettings[0])), func(name string) bool {
+		return strings.HasPrefix(name, ResourceRepository+" ")
+	})
+
+	if !slices.Equal(names, []string{"labels update bug"}) {
+		t.Errorf("Expected only the color of the bug label planned among the labels and webhooks, got %v", names)
+	}
+}
+
+func TestApplyEditsTheURLOfAWebhookMatchedByNameInPlace(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	state, err := LoadStateStore(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server, client := newTestClient(t, WithStateStore(state))
+	id := server.AddRepository("acme", "api").AddHook("https://ci.example.com/hook", "push")
+	webhookSettings := "repository: {owner: acme, name: api}\nwebhooks:\n  - {name: ci, matchby: name, url: %q, contenttype: json, events: [push]}\n"
+
+	// The webhook of the url is matched the first time, its name is recorded although it has no changes
+	if _, err := client.Apply(context.Background(), settingsFromYAML(t, fmt.Sprintf(webhookSettings, "https://ci.example.com/hook"))); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := state.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded, err := LoadStateStore(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client = newServerClient(t, server, WithStateStore(reloaded))
+	renamed := settingsFromYAML(t, fmt.Sprintf(webhookSettings, "https://ci.example.com/v2"))
+
+	if names := changeNames(planOf(t, client, renamed)); !slices.Equal(names, []string{"webhooks update https://ci.example.com/v2"}) {
+		t.Fatalf("Expected the url of the webhook updated in place, got %v", names)
+	}
+
+	if _, err := client.Apply(context.Background(), renamed); err != nil {
+		t.Fatal(err)
+	}
+
+	if hooks := server.Repository("acme", "api").Hooks(); len(hooks) != 1 || hooks[0].ID != id || hooks[0].URL != "https://ci.example.com/v2" {
+		t.Errorf("Expected webhook %d edited to the new url, got %+v", id, hooks)
+	}
+
+	for _, request := range server.Requests() {
+		if strings.HasPrefix(request, "DELETE ") || strings.HasPrefix(request, "POST /repos/acme/api/hooks") {
+			t.Errorf("Expected the webhook edited in place, got %s", request)
+		}
+	}
+
+	if names := changeNames(planOf(t, client, renamed)); len(names) != 0 {
+		t.Errorf("Expected no changes once applied, got %v", names)
+	}
+}
+
+func TestApplyRecordsTheWebhooksCreatedWithAName(t *testing.T) {
+	state, err := LoadStateStore(filepath.Join(t.TempDir(), "state.json"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server, client := newTestClient(t, WithStateStore(state))
+	server.AddRepository("acme", "api")
+	webhookSettings := "repository: {owner: acme, name: api}\nwebhooks:\n  - {name: ci, matchby: name, url: %q, contenttype: json, events: [push]}\n"
+
+	if _, err := client.Apply(context.Background(), settingsFromYAML(t, fmt.Sprintf(webhookSettings, "https://ci.example.com/hook"))); err != nil {
+		t.Fatal(err)
+	}
+
+	hooks := server.Repository("acme", "api").Hooks()
+
+	if len(hooks) != 1 || state.webhookID("acme/api", "ci") != hooks[0].ID {
+		t.Fatalf("Expected the created webhook recorded under its name, got %+v and id %d", hooks, state.webhookID("acme/api", "ci"))
+	}
+
+	if names := changeNames(planOf(t, client, settingsFromYAML(t, fmt.Sprintf(webhookSettings, "https://ci.example.com/v2")))); !slices.Equal(names, []string{"webhooks update https://ci.example.com/v2"}) {
+		t.Errorf("Expected the url of the created webhook updated in place, got %v", names)
+	}
+}
+
+func TestPlanOfAWebhookMatchedByNameRequiresAStateStore(t *testing.T) {
+	server, client := newTestClient(t)
+	server.AddRepository("acme", "api")
+
+	_, err := client.Plan(context.Background(), settingsFromYAML(t, "repository: {owner: acme, name: api}\nwebhooks:\n  - {name: ci, matchby: name, url: https://ci.example.com/hook}\n"))
+
+	if err == nil || !strings.Contains(err.Error(), "requires a state store") {
+		t.Errorf("Expected an error asking for a state store, got %v", err)
+	}
+}
+
+func TestValidateWebhooksMatchedByName(t *testing.T) {
+	problems, err := Validate([]byte(`
+repository: {owner: acme, name: api}
+webhooks:
+  - {name: ci, matchby: name, url: "https://ci.example.com/hook"}
+  - {matchby: name, url: "https://deploy.example.com/hook"}
+`))
+
+	if err != nil {
+		t.Fatalf("Error validating settings: %v", err)
+	}
+
+	if len(problems) != 1 || !strings.Contains(problems[0].String(), "requires the name of the webhook") {
+		t.Errorf("Problems are %v, want the webhook without name", problems)
+	}
+}
@@ -0,0 +1,190 @@
+package github
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/google/go-github/v75/github"
+)
+
+// githubAPI is the layer between the client and go-github, the client only calls the github api through it
+// Its interfaces pin the go-github methods the client calls. Upgrading go-github to a new major version only changes the
+// import path, the methods whose signature changed are adapted by newGithubAPI so the rest of the package and the public
+// Settings and Client api are left as they are, except Raw and NewFromGithubClient which are the only ones exposing go-github types
+type githubAPI struct {
+	restAPI
+	// client is the go-github client behind the interfaces, it is only returned by Client.Raw
+	client *github.Client
+	// BaseURL is the url of the rest api of github.com or of the github enterprise server
+	BaseURL       *url.URL
+	Actions       actionsAPI
+	Apps          appsAPI
+	Billing       billingAPI
+	Checks        checksAPI
+	Dependabot    dependabotAPI
+	Git           gitAPI
+	Issues        issuesAPI
+	Organizations organizationsAPI
+	PullRequests  pullRequestsAPI
+	Repositories  repositoriesAPI
+	Teams         teamsAPI
+	Users         usersAPI
+}
+
+// restAPI sends the requests of the endpoints go-github does not cover (ex: /meta) or that need custom headers (ex: conditional requests)
+type restAPI interface {
+	NewRequest(method, urlStr string, body interface{}, opts ...github.RequestOption) (*http.Request, error)
+	Do(ctx context.Context, req *http.Request, v interface{}) (*github.Response, error)
+}
+
+// actionsAPI calls the actions secrets and variables of the repositories and their environments
+type actionsAPI interface {
+	CreateEnvVariable(ctx context.Context, owner, repo, env string, variable *github.ActionsVariable) (*github.Response, error)
+	CreateOrUpdateEnvSecret(ctx context.Context, repoID int, env string, eSecret *github.EncryptedSecret) (*github.Response, error)
+	CreateOrUpdateRepoSecret(ctx context.Context, owner, repo string, eSecret *github.EncryptedSecret) (*github.Response, error)
+	CreateRepoVariable(ctx context.Context, owner, repo string, variable *github.ActionsVariable) (*github.Response, error)
+	DeleteEnvSecret(ctx context.Context, repoID int, env, secretName string) (*github.Response, error)
+	DeleteEnvVariable(ctx context.Context, owner, repo, env, variableName string) (*github.Response, error)
+	DeleteRepoSecret(ctx context.Context, owner, repo, name string) (*github.Response, error)
+	DeleteRepoVariable(ctx context.Context, owner, repo, name string) (*github.Response, error)
+	GetEnvPublicKey(ctx context.Context, repoID int, env string) (*github.PublicKey, *github.Response, error)
+	GetRepoPublicKey(ctx context.Context, owner, repo string) (*github.PublicKey, *github.Response, error)
+	ListEnvSecrets(ctx context.Context, repoID int, env string, opts *github.ListOptions) (*github.Secrets, *github.Response, error)
+	ListEnvVariables(ctx context.Context, owner, repo, env string, opts *github.ListOptions) (*github.ActionsVariables, *github.Response, error)
+	ListRepoSecrets(ctx context.Context, owner, repo string, opts *github.ListOptions) (*github.Secrets, *github.Response, error)
+	ListRepoVariables(ctx context.Context, owner, repo string, opts *github.ListOptions) (*github.ActionsVariables, *github.Response, error)
+	UpdateEnvVariable(ctx context.Context, owner, repo, env string, variable *github.ActionsVariable) (*github.Response, error)
+	UpdateRepoVariable(ctx context.Context, owner, repo string, variable *github.ActionsVariable) (*github.Response, error)
+}
+
+// appsAPI calls the github apps referenced by slug
+type appsAPI interface {
+	Get(ctx context.Context, appSlug string) (*github.App, *github.Response, error)
+}
+
+// billingAPI calls the advanced security committers of the organizations
+type billingAPI interface {
+	GetAdvancedSecurityActiveCommittersOrg(ctx context.Context, org string, opts *github.ListOptions) (*github.ActiveCommitters, *github.Response, error)
+}
+
+// checksAPI calls the check runs of the commits
+type checksAPI interface {
+	ListCheckRunsForRef(ctx context.Context, owner, repo, ref string, opts *github.ListCheckRunsOptions) (*github.ListCheckRunsResults, *github.Response, error)
+}
+
+// dependabotAPI calls the dependabot secrets of the repositories
+type dependabotAPI interface {
+	CreateOrUpdateRepoSecret(ctx context.Context, owner, repo string, eSecret *github.DependabotEncryptedSecret) (*github.Response, error)
+	DeleteRepoSecret(ctx context.Context, owner, repo, name string) (*github.Response, error)
+	GetRepoPublicKey(ctx context.Context, owner, repo string) (*github.PublicKey, *github.Response, error)
+	ListRepoSecrets(ctx context.Context, owner, repo string, opts *github.ListOptions) (*github.Secrets, *github.Response, error)
+}
+
+// gitAPI calls the git references
+type gitAPI interface {
+	DeleteRef(ctx context.Context, owner, repo, ref string) (*github.Response, error)
+}
+
+// issuesAPI calls the labels and the issues of the repositories
+type issuesAPI interface {
+	CreateLabel(ctx context.Context, owner, repo string, label *github.Label) (*github.Label, *github.Response, error)
+	DeleteLabel(ctx context.Context, owner, repo, name string) (*github.Response, error)
+	EditLabel(ctx context.Context, owner, repo, name string, label *github.Label) (*github.Label, *github.Response, error)
+	ListByRepo(ctx context.Context, owner, repo string, opts *github.IssueListByRepoOptions) ([]*github.Issue, *github.Response, error)
+	ListLabels(ctx context.Context, owner, repo string, opts *github.ListOptions) ([]*github.Label, *github.Response, error)
+}
+
+// organizationsAPI calls the custom roles and the security managers of the organizations
+type organizationsAPI interface {
+	AddSecurityManagerTeam(ctx context.Context, org, team string) (*github.Response, error)
+	ListCustomRepoRoles(ctx context.Context, org string) (*github.OrganizationCustomRepoRoles, *github.Response, error)
+	ListSecurityManagerTeams(ctx context.Context, org string) ([]*github.Team, *github.Response, error)
+	RemoveSecurityManagerTeam(ctx context.Context, org, team string) (*github.Response, error)
+}
+
+// pullRequestsAPI calls the pull requests opened by the files of the settings
+type pullRequestsAPI interface {
+	Create(ctx context.Context, owner, repo string, pull *github.NewPullRequest) (*github.PullRequest, *github.Response, error)
+}
+
+// repositoriesAPI calls the repositories and their branches, webhooks, collaborators, environments, rulesets and contents
+type repositoriesAPI interface {
+	AddCollaborator(ctx context.Context, owner, repo, user string, opts *github.RepositoryAddCollaboratorOptions) (*github.CollaboratorInvitation, *github.Response, error)
+	Create(ctx context.Context, org string, repo *github.Repository) (*github.Repository, *github.Response, error)
+	CreateDeploymentBranchPolicy(ctx context.Context, owner, repo, environment string, request *github.DeploymentBranchPolicyRequest) (*github.DeploymentBranchPolicy, *github.Response, error)
+	CreateFromTemplate(ctx context.Context, templateOwner, templateRepo string, templateRepoReq *github.TemplateRepoRequest) (*github.Repository, *github.Response, error)
+	CreateHook(ctx context.Context, owner, repo string, hook *github.Hook) (*github.Hook, *github.Response, error)
+	CreateRuleset(ctx context.Context, owner, repo string, ruleset github.RepositoryRuleset) (*github.RepositoryRuleset, *github.Response, error)
+	CreateStatus(ctx context.Context, owner, repo, ref string, status *github.RepoStatus) (*github.RepoStatus, *github.Response, error)
+	CreateUpdateEnvironment(ctx context.Context, owner, repo, name string, environment *github.CreateUpdateEnvironment) (*github.Environment, *github.Response, error)
+	DeleteDeploymentBranchPolicy(ctx context.Context, owner, repo, environment string, branchPolicyID int64) (*github.Response, error)
+	DeleteEnvironment(ctx context.Context, owner, repo, name string) (*github.Response, error)
+	DeleteHook(ctx context.Context, owner, repo string, id int64) (*github.Response, error)
+	DeleteInvitation(ctx context.Context, owner, repo string, invitationID int64) (*github.Response, error)
+	DeleteRuleset(ctx context.Context, owner, repo string, rulesetID int64) (*github.Response, error)
+	Edit(ctx context.Context, owner, repo string, repository *github.Repository) (*github.Repository, *github.Response, error)
+	EditHook(ctx context.Context, owner, repo string, id int64, hook *github.Hook) (*github.Hook, *github.Response, error)
+	Get(ctx context.Context, owner, repo string) (*github.Repository, *github.Response, error)
+	GetAllRulesets(ctx context.Context, owner, repo string, opts *github.RepositoryListRulesetsOptions) ([]*github.RepositoryRuleset, *github.Response, error)
+	GetBranchProtection(ctx context.Context, owner, repo, branch string) (*github.Protection, *github.Response, error)
+	GetContents(ctx context.Context, owner, repo, path string, opts *github.RepositoryContentGetOptions) (fileContent *github.RepositoryContent, directoryContent []*github.RepositoryContent, resp *github.Response, err error)
+	GetRuleset(ctx context.Context, owner, repo string, rulesetID int64, includesParents bool) (*github.RepositoryRuleset, *github.Response, error)
+	ListBranches(ctx context.Context, owner, repo string, opts *github.BranchListOptions) ([]*github.Branch, *github.Response, error)
+	ListByAuthenticatedUser(ctx context.Context, opts *github.RepositoryListByAuthenticatedUserOptions) ([]*github.Repository, *github.Response, error)
+	ListByOrg(ctx context.Context, org string, opts *github.RepositoryListByOrgOptions) ([]*github.Repository, *github.Response, error)
+	ListByUser(ctx context.Context, user string, opts *github.RepositoryListByUserOptions) ([]*github.Repository, *github.Response, error)
+	ListCollaborators(ctx context.Context, owner, repo string, opts *github.ListCollaboratorsOptions) ([]*github.User, *github.Response, error)
+	ListCommits(ctx context.Context, owner, repo string, opts *github.CommitsListOptions) ([]*github.RepositoryCommit, *github.Response, error)
+	ListDeploymentBranchPolicies(ctx context.Context, owner, repo, environment string) (*github.DeploymentBranchPolicyResponse, *github.Response, error)
+	ListEnvironments(ctx context.Context, owner, repo string, opts *github.EnvironmentListOptions) (*github.EnvResponse, *github.Response, error)
+	ListHookDeliveries(ctx context.Context, owner, repo string, id int64, opts *github.ListCursorOptions) ([]*github.HookDelivery, *github.Response, error)
+	ListHooks(ctx context.Context, owner, repo string, opts *github.ListOptions) ([]*github.Hook, *github.Response, error)
+	ListInvitations(ctx context.Context, owner, repo string, opts *github.ListOptions) ([]*github.RepositoryInvitation, *github.Response, error)
+	ListStatuses(ctx context.Context, owner, repo, ref string, opts *github.ListOptions) ([]*github.RepoStatus, *github.Response, error)
+	ListTeams(ctx context.Context, owner, repo string, opts *github.ListOptions) ([]*github.Team, *github.Response, error)
+	OptionalSignaturesOnProtectedBranch(ctx context.Context, owner, repo, branch string) (*github.Response, error)
+	RemoveBranchProtection(ctx context.Context, owner, repo, branch string) (*github.Response, error)
+	RemoveCollaborator(ctx context.Context, owner, repo, user string) (*github.Response, error)
+	RenameBranch(ctx context.Context, owner, repo, branch, newName string) (*github.Branch, *github.Response, error)
+	ReplaceAllTopics(ctx context.Context, owner, repo string, topics []string) ([]string, *github.Response, error)
+	RequireSignaturesOnProtectedBranch(ctx context.Context, owner, repo, branch string) (*github.SignaturesProtectedBranch, *github.Response, error)
+	UpdateBranchProtection(ctx context.Context, owner, repo, branch string, preq *github.ProtectionRequest) (*github.Protection, *github.Response, error)
+	UpdateInvitation(ctx context.Context, owner, repo string, invitationID int64, permissions string) (*github.RepositoryInvitation, *github.Response, error)
+	UpdateRuleset(ctx context.Context, owner, repo string, rulesetID int64, ruleset github.RepositoryRuleset) (*github.RepositoryRuleset, *github.Response, error)
+	UpdateRulesetNoBypassActor(ctx context.Context, owner, repo string, rulesetID int64, ruleset github.RepositoryRuleset) (*github.RepositoryRuleset, *github.Response, error)
+}
+
+// teamsAPI calls the teams and their access to the repositories
+type teamsAPI interface {
+	AddTeamRepoBySlug(ctx context.Context, org, slug, owner, repo string, opts *github.TeamAddTeamRepoOptions) (*github.Response, error)
+	GetTeamBySlug(ctx context.Context, org, slug string) (*github.Team, *github.Response, error)
+	IsTeamRepoBySlug(ctx context.Context, org, slug, owner, repo string) (*github.Repository, *github.Response, error)
+	RemoveTeamRepoBySlug(ctx context.Context, org, slug, owner, repo string) (*github.Response, error)
+}
+
+// usersAPI calls the users and the organizations referenced by login
+type usersAPI interface {
+	Get(ctx context.Context, user string) (*github.User, *github.Response, error)
+}
+
+// newGithubAPI adapts a go-github client, the services of the pinned go-github version implement the interfaces as they are
+func newGithubAPI(githubClient *github.Client) *githubAPI {
+	return &githubAPI{
+		restAPI:       githubClient,
+		client:        githubClient,
+		BaseURL:       githubClient.BaseURL,
+		Actions:       githubClient.Actions,
+		Apps:          githubClient.Apps,
+		Billing:       githubClient.Billing,
+		Checks:        githubClient.Checks,
+		Dependabot:    githubClient.Dependabot,
+		Git:           githubClient.Git,
+		Issues:        githubClient.Issues,
+		Organizations: githubClient.Organizations,
+		PullRequests:  githubClient.PullRequests,
+		Repositories:  githubClient.Repositories,
+		Teams:         githubClient.Teams,
+		Users:         githubClient.Users,
+	}
+}
@@ -0,0 +1,101 @@
+package github
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/google/go-github/v75/github"
+)
+
+func TestDoCallsTheRestAPIWithoutGoGithubTypes(t *testing.T) {
+	server, client := newTestClient(t)
+	server.AddRepository("acme", "api")
+
+	repository := struct {
+		FullName string `json:"full_name"`
+	}{}
+
+	response, err := client.Do(context.Background(), http.MethodGet, "repos/acme/api", nil, &repository)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if response.StatusCode != http.StatusOK || repository.FullName != "acme/api" {
+		t.Errorf("Expected acme/api, got %d %+v", response.StatusCode, repository)
+	}
+
+	response, err = client.Do(context.Background(), http.MethodGet, "repos/acme/missing", nil, nil)
+
+	if err == nil || response == nil || response.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected a not found error, got %v %v", response, err)
+	}
+}
+
+func TestNewFromHTTPClientRejectsAnInvalidBaseURL(t *testing.T) {
+	if _, err := NewFromHTTPClient(http.DefaultClient, "", WithBaseURL("://github")); err == nil {
+		t.Error("Expected an error for an invalid base url")
+	}
+}
+
+func TestNewFromHTTPClientSendsTheHeadersOfTheOptions(t *testing.T) {
+	headers := http.Header{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header.Clone()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte("{}"))
+	}))
+	defer server.Close()
+
+	client, err := NewFromHTTPClient(server.Client(), "", WithBaseURL(server.URL), WithAPIVersion("2026-03-10"), WithUserAgentSuffix("pipeline-42"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.Do(context.Background(), http.MethodGet, "meta", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if version := headers.Get("X-GitHub-Api-Version"); version != "2026-03-10" {
+		t.Errorf("Expected the api version 2026-03-10, got %q", version)
+	}
+
+	if agent := headers.Get("User-Agent"); agent != DefaultUserAgent+" pipeline-42" {
+		t.Errorf("Expected the user agent %q, got %q", DefaultUserAgent+" pipeline-42", agent)
+	}
+}
+
+func TestNewFromGithubClientKeepsItsURLsAndRawReturnsIt(t *testing.T) {
+	agents := make(chan string, 1)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		agents <- r.Header.Get("User-Agent")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"full_name": "acme/api"}`))
+	}))
+	defer server.Close()
+
+	githubClient := github.NewClient(server.Client())
+	githubClient.BaseURL, _ = url.Parse(server.URL + "/")
+
+	client := NewFromGithubClient(githubClient, "", WithUserAgent("settings-bot/1.0"))
+
+	if client.Raw().BaseURL.String() != server.URL+"/" {
+		t.Errorf("Expected the base url %s/, got %s", server.URL, client.Raw().BaseURL)
+	}
+
+	repository, _, err := client.Raw().Repositories.Get(context.Background(), "acme", "api")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if agent := <-agents; repository.GetFullName() != "acme/api" || agent != "settings-bot/1.0" {
+		t.Errorf("Expected acme/api with the user agent settings-bot/1.0, got %s with %q", repository.GetFullName(), agent)
+	}
+}
@@ -0,0 +1,331 @@
+package github
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v75/github"
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+)
+
+// DefaultBaseURL is the rest api url of github.com
+const DefaultBaseURL = "https://api.github.com/"
+
+// tokenRefreshMargin is the time before their expiry the tokens are refreshed, so a token is never sent as it expires
+const tokenRefreshMargin = time.Minute
+
+// appJWTLifetime is the lifetime of the jwt authenticating the app, github accepts at most 10 minutes
+const appJWTLifetime = 9 * time.Minute
+
+// AuthProvider provides the tokens authenticating the requests to github, enterprises plug their own credential broker with it
+// A token is reused until its expiry, a token without expiry is requested once
+type AuthProvider interface {
+	Token(ctx context.Context) (*oauth2.Token, error)
+}
+
+// StaticTokenProvider authenticates with a token that never changes (ex: a personal access token)
+func StaticTokenProvider(token string) AuthProvider {
+	return staticProvider{token: &oauth2.Token{AccessToken: token}}
+}
+
+type staticProvider struct {
+	token *oauth2.Token
+}
+
+func (provider staticProvider) Token(ctx context.Context) (*oauth2.Token, error) {
+	return provider.token, nil
+}
+
+// providerTokenSource adapts an auth provider to the oauth2 transport, it is wrapped in an oauth2.ReuseTokenSource refreshing the tokens
+type providerTokenSource struct {
+	provider AuthProvider
+}
+
+func (source providerTokenSource) Token() (*oauth2.Token, error) {
+	token, err := source.provider.Token(context.Background())
+
+	if err != nil || token.Expiry.IsZero() {
+		return token, err
+	}
+
+	refreshed := *token
+	refreshed.Expiry = token.Expiry.Add(-tokenRefreshMargin)
+
+	return &refreshed, nil
+}
+
+// reloadableAuth authenticates the requests with the oauth2 transport of the current token source. Reloading builds a new
+// token source and transport from the options, the requests in flight complete with the previous transport
+type reloadableAuth struct {
+	mu        sync.RWMutex
+	tokens    oauth2.TokenSource
+	transport http.RoundTripper
+	load      func() (oauth2.TokenSource, error)
+}
+
+func newReloadableAuth(load func() (oauth2.TokenSource, error)) (*reloadableAuth, error) {
+	tokens, err := load()
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Error configuring github authentication")
+	}
+
+	auth := &reloadableAuth{load: load}
+	auth.swap(tokens)
+
+	return auth, nil
+}
+
+// reload replaces the token source and its transport, the previous ones are kept when the new source provides no token
+func (auth *reloadableAuth) reload() error {
+	tokens, err := auth.load()
+
+	if err != nil {
+		return errors.Wrap(err, "Error configuring github authentication")
+	}
+
+	if tokens != nil {
+		_, err = tokens.Token()
+
+		if err != nil {
+			return errors.Wrap(err, "Error getting a github token")
+		}
+	}
+
+	auth.swap(tokens)
+
+	return nil
+}
+
+func (auth *reloadableAuth) swap(tokens oauth2.TokenSource) {
+	var transport http.RoundTripper = http.DefaultTransport
+
+	if tokens != nil {
+		transport = &oauth2.Transport{Source: tokens, Base: http.DefaultTransport}
+	}
+
+	auth.mu.Lock()
+	defer auth.mu.Unlock()
+
+	auth.tokens = tokens
+	auth.transport = transport
+}
+
+func (auth *reloadableAuth) RoundTrip(req *http.Request) (*http.Response, error) {
+	auth.mu.RLock()
+	transport := auth.transport
+	auth.mu.RUnlock()
+
+	return transport.RoundTrip(req)
+}
+
+// anonymous returns true when the requests are not authenticated
+func (auth *reloadableAuth) anonymous() bool {
+	auth.mu.RLock()
+	defer auth.mu.RUnlock()
+
+	return auth.tokens == nil
+}
+
+// Token returns the token of the current source, it authenticates git pushes and the settings files fetched from github
+func (auth *reloadableAuth) Token() (*oauth2.Token, error) {
+	auth.mu.RLock()
+	tokens := auth.tokens
+	auth.mu.RUnlock()
+
+	if tokens == nil {
+		return &oauth2.Token{}, nil
+	}
+
+	return tokens.Token()
+}
+
+// ReloadCredentials reads the credentials again (ex: a token file rotated by a secret manager) and authenticates the next
+// requests with them, the requests in flight complete with the previous credentials. The previous credentials are kept
+// when the new ones can not be read
+func (client *Client) ReloadCredentials() error {
+	if client.auth == nil {
+		return errors.New("The credentials of a client created from an http client can not be reloaded")
+	}
+
+	return client.auth.reload()
+}
+
+// appProvider creates installation access tokens of a github app
+type appProvider struct {
+	appID          int64
+	installationID int64
+	privateKey     *rsa.PrivateKey
+	baseURL        string
+	uploadURL      string
+	clock          Clock
+	// options set the headers, user agent and retries of the installation token requests, as for the requests of the client
+	options *options
+}
+
+// NewAppProvider authenticates as a github app installation with the pem encoded private key of the app
+func NewAppProvider(appID, installationID int64, privateKey []byte, baseURL, uploadURL string) (AuthProvider, error) {
+	key, err := parsePrivateKey(privateKey)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &appProvider{
+		appID:          appID,
+		installationID: installationID,
+		privateKey:     key,
+		baseURL:        baseURL,
+		uploadURL:      uploadURL,
+		clock:          systemClock{},
+		options:        newOptions([]Option{WithBaseURL(baseURL)}),
+	}, nil
+}
+
+// Token exchanges a jwt signed with the app private key for an installation access token
+func (provider *appProvider) Token(ctx context.Context) (*oauth2.Token, error) {
+	jwt, err := provider.jwt(provider.clock.Now())
+
+	if err != nil {
+		return nil, err
+	}
+
+	auth := &oauth2.Transport{Source: oauth2.StaticTokenSource(&oauth2.Token{AccessToken: jwt})}
+	httpClient := &http.Client{Transport: newTransport(auth, provider.options, provider.options.basePath())}
+	appClient, err := newGithubClient(httpClient, provider.baseURL, provider.uploadURL)
+
+	if err != nil {
+		return nil, err
+	}
+
+	appClient.UserAgent = provider.options.fullUserAgent()
+
+	installationToken, _, err := appClient.Apps.CreateInstallationToken(ctx, provider.installationID, nil)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error creating an access token for the app installation %d", provider.installationID)
+	}
+
+	return &oauth2.Token{
+		AccessToken: installationToken.GetToken(),
+		Expiry:      installationToken.GetExpiresAt().Time,
+	}, nil
+}
+
+// jwt returns the RS256 json web token authenticating the app itself
+func (provider *appProvider) jwt(now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+
+	if err != nil {
+		return "", errors.Wrap(err, "Error encoding the app jwt")
+	}
+
+	// The issue time is set in the past to allow for clock drift with github
+	claims, err := json.Marshal(map[string]interface{}{
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(appJWTLifetime).Unix(),
+		"iss": strconv.FormatInt(provider.appID, 10),
+	})
+
+	if err != nil {
+		return "", errors.Wrap(err, "Error encoding the app jwt")
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+
+	signature, err := rsa.SignPKCS1v15(rand.Reader, provider.privateKey, crypto.SHA256, digest[:])
+
+	if err != nil {
+		return "", errors.Wrap(err, "Error signing the app jwt")
+	}
+
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// parsePrivateKey reads a pem encoded rsa private key as downloaded from the app settings
+func parsePrivateKey(privateKey []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(privateKey)
+
+	if block == nil {
+		return nil, errors.New("Invalid app private key, expected a pem encoded key")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Invalid app private key")
+	}
+
+	rsaKey, ok := key.(*rsa.PrivateKey)
+
+	if !ok {
+		return nil, errors.New("Invalid app private key, expected a rsa key")
+	}
+
+	return rsaKey, nil
+}
+
+// newGithubClient creates a go-github client calling github.com or a github enterprise server
+func newGithubClient(httpClient *http.Client, baseURL, uploadURL string) (*github.Client, error) {
+	githubClient := github.NewClient(httpClient)
+
+	if baseURL == "" || baseURL == DefaultBaseURL {
+		return githubClient, nil
+	}
+
+	if uploadURL == "" {
+		uploadURL = baseURL
+	}
+
+	enterpriseClient, err := githubClient.WithEnterpriseURLs(baseURL, uploadURL)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "Invalid github enterprise url %s", baseURL)
+	}
+
+	return enterpriseClient, nil
+}
+
+// webHost returns the host serving the git repositories of a rest api url (api.github.com is served by github.com)
+func webHost(baseURL *url.URL) string {
+	if baseURL == nil || baseURL.Host == "api.github.com" {
+		return "github.com"
+	}
+
+	return strings.TrimPrefix(baseURL.Host, "api.")
+}
+
+// currentToken returns the token authenticating git pushes and settings files fetched from github
+// A token with an expiry (ex: an app installation token) is refreshed when it expired
+func (client *Client) currentToken() (string, error) {
+	if client.tokens == nil || client.auth != nil && client.auth.anonymous() {
+		return "", nil
+	}
+
+	token, err := client.tokens.Token()
+
+	if err != nil {
+		return "", errors.Wrap(err, "Error getting a github token")
+	}
+
+	return token.AccessToken, nil
+}
@@ -0,0 +1,87 @@
+package github
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/google/go-github/v75/github"
+	"github.com/pkg/errors"
+)
+
+// DefaultBreakerThreshold is the number of consecutive server failures after which a resource kind is skipped
+const DefaultBreakerThreshold = 5
+
+// breaker stops calling a resource kind after repeated server failures for the lifetime of the client
+type breaker struct {
+	mutex     sync.Mutex
+	threshold int
+	failures  map[string]int
+}
+
+func newBreaker(threshold int) *breaker {
+	return &breaker{
+		threshold: threshold,
+		failures:  map[string]int{},
+	}
+}
+
+// open returns true when the resource kind failed too many times and must be skipped
+func (breaker *breaker) open(resource string) bool {
+	breaker.mutex.Lock()
+	defer breaker.mutex.Unlock()
+
+	return breaker.threshold > 0 && breaker.failures[resource] >= breaker.threshold
+}
+
+// record counts consecutive server failures, client errors (4xx) do not trip the breaker
+func (breaker *breaker) record(resource string, err error) {
+	breaker.mutex.Lock()
+	defer breaker.mutex.Unlock()
+
+	if err == nil {
+		breaker.failures[resource] = 0
+		return
+	}
+
+	if isServerError(err) {
+		breaker.failures[resource]++
+	}
+}
+
+// OpenCircuits returns the resource kinds skipped after repeated server failures
+func (client *Client) OpenCircuits() []string {
+	client.breaker.mutex.Lock()
+	defer client.breaker.mutex.Unlock()
+
+	resources := []string{}
+
+	for resource, failures := range client.breaker.failures {
+		if client.breaker.threshold > 0 && failures >= client.breaker.threshold {
+			resources = append(resources, resource)
+		}
+	}
+
+	sort.Strings(resources)
+
+	return resources
+}
+
+// isServerError returns true for 5xx responses and timeouts
+func isServerError(err error) bool {
+	cause := errors.Cause(err)
+
+	if errorResponse, ok := cause.(*github.ErrorResponse); ok {
+		return errorResponse.Response != nil && errorResponse.Response.StatusCode >= http.StatusInternalServerError
+	}
+
+	if cause == context.DeadlineExceeded {
+		return true
+	}
+
+	netError, ok := cause.(net.Error)
+
+	return ok && netError.Timeout()
+}
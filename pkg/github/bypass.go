@@ -0,0 +1,195 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/v75/github"
+	"github.com/pkg/errors"
+)
+
+// bypassActor is an app, a team or the organization admins allowed to bypass the rules of a ruleset
+type bypassActor struct {
+	// App is the slug of a github app (ex: merge-bot), its id is looked up when planning
+	App string `yaml:",omitempty"`
+	// Team is the slug of a team of the organization, its id is looked up when planning
+	Team string `yaml:",omitempty"`
+	// OrganizationAdmin lets the owners of the organization bypass the rules
+	OrganizationAdmin bool `yaml:",omitempty"`
+	// Mode is always (the default) or pull_request to only bypass the rules when merging a pull request
+	Mode bypassMode `yaml:",omitempty"`
+
+	id int64
+}
+
+// key identifies the actor to sort the actors the same way on both sides of a comparison
+func (actor bypassActor) key() string {
+	switch {
+	case actor.App != "":
+		return "app/" + actor.App
+	case actor.Team != "":
+		return "team/" + actor.Team
+	default:
+		return "organizationadmin"
+	}
+}
+
+func (actor bypassActor) String() string {
+	return fmt.Sprintf("%s (%s)", actor.key(), actor.Mode)
+}
+
+// sortedBypassActors returns a copy of the actors with their default mode, sorted by kind and slug
+func sortedBypassActors(actors []bypassActor) []bypassActor {
+	if len(actors) == 0 {
+		return nil
+	}
+
+	sorted := make([]bypassActor, 0, len(actors))
+
+	for _, actor := range actors {
+		actor.App = strings.ToLower(actor.App)
+		actor.Team = strings.ToLower(actor.Team)
+
+		if actor.Mode == "" {
+			actor.Mode = bypassModeAlways
+		}
+
+		sorted = append(sorted, actor)
+	}
+
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].key() < sorted[j].key() })
+
+	return sorted
+}
+
+// newBypassActors reads the bypass actors of a ruleset, the apps and teams are named by their id until resolveBypassActors names them
+func newBypassActors(githubActors []*github.BypassActor) []bypassActor {
+	actors := []bypassActor{}
+
+	for _, githubActor := range githubActors {
+		actor := bypassActor{id: githubActor.GetActorID()}
+
+		if githubActor.BypassMode != nil {
+			actor.Mode = bypassMode(*githubActor.BypassMode)
+		}
+
+		if githubActor.ActorType == nil {
+			continue
+		}
+
+		switch *githubActor.ActorType {
+		case github.BypassActorTypeIntegration:
+			actor.App = strconv.FormatInt(actor.id, 10)
+		case github.BypassActorTypeTeam:
+			actor.Team = strconv.FormatInt(actor.id, 10)
+		case github.BypassActorTypeOrganizationAdmin:
+			actor.OrganizationAdmin = true
+			actor.id = 0
+		default:
+			// Repository roles and deploy keys are not managed
+			continue
+		}
+
+		actors = append(actors, actor)
+	}
+
+	return emptyToNilActors(actors)
+}
+
+func emptyToNilActors(actors []bypassActor) []bypassActor {
+	if len(actors) == 0 {
+		return nil
+	}
+
+	return actors
+}
+
+// toGithubBypassActors converts the actors resolved by resolveBypassActors to the payload of the github api
+func toGithubBypassActors(actors []bypassActor) []*github.BypassActor {
+	githubActors := []*github.BypassActor{}
+
+	for _, actor := range sortedBypassActors(actors) {
+		githubActor := &github.BypassActor{BypassMode: github.Ptr(github.BypassMode(actor.Mode))}
+
+		switch {
+		case actor.App != "":
+			githubActor.ActorType = github.Ptr(github.BypassActorTypeIntegration)
+			githubActor.ActorID = github.Int64(actor.id)
+		case actor.Team != "":
+			githubActor.ActorType = github.Ptr(github.BypassActorTypeTeam)
+			githubActor.ActorID = github.Int64(actor.id)
+		default:
+			githubActor.ActorType = github.Ptr(github.BypassActorTypeOrganizationAdmin)
+			githubActor.ActorID = github.Int64(organizationAdminActorID)
+		}
+
+		githubActors = append(githubActors, githubActor)
+	}
+
+	return githubActors
+}
+
+// organizationAdminActorID is the id github expects for the organization admin bypass actor
+const organizationAdminActorID = 1
+
+// resolveBypassActors looks up the ids of the apps and teams bypassing the rulesets of the settings, then names the bypass
+// actors of the live rulesets with the slugs of the settings so both sides compare equal. The live apps and teams the
+// settings do not reference keep their id as name
+func (client *Client) resolveBypassActors(ctx context.Context, githubSettings, settings *Settings) (*Settings, error) {
+	if settings.Disable.Rulesets || len(settings.Rulesets) == 0 {
+		return settings, nil
+	}
+
+	resolved := *settings
+	resolved.Rulesets = make([]ruleset, 0, len(settings.Rulesets))
+	slugs := map[string]string{}
+
+	for _, rulesetSettings := range settings.Rulesets {
+		actors := make([]bypassActor, 0, len(rulesetSettings.BypassActors))
+
+		for _, actor := range rulesetSettings.BypassActors {
+			var err error
+
+			switch {
+			case actor.App != "":
+				actor.id, err = client.appID(ctx, actor.App)
+				slugs["app/"+strconv.FormatInt(actor.id, 10)] = actor.App
+			case actor.Team != "":
+				actor.id, err = client.teamID(ctx, settings.Repository.Owner, actor.Team)
+				slugs["team/"+strconv.FormatInt(actor.id, 10)] = actor.Team
+			}
+
+			if err != nil {
+				return nil, errors.Wrapf(err, "Error resolving the bypass actors of ruleset %s", rulesetSettings.Name)
+			}
+
+			actors = append(actors, actor)
+		}
+
+		rulesetSettings.BypassActors = emptyToNilActors(actors)
+		resolved.Rulesets = append(resolved.Rulesets, rulesetSettings)
+	}
+
+	for i, githubRuleset := range githubSettings.Rulesets {
+		actors := make([]bypassActor, 0, len(githubRuleset.BypassActors))
+
+		for _, actor := range githubRuleset.BypassActors {
+			if slug, ok := slugs["app/"+actor.App]; ok && actor.App != "" {
+				actor.App = slug
+			}
+
+			if slug, ok := slugs["team/"+actor.Team]; ok && actor.Team != "" {
+				actor.Team = slug
+			}
+
+			actors = append(actors, actor)
+		}
+
+		githubSettings.Rulesets[i].BypassActors = emptyToNilActors(actors)
+	}
+
+	return &resolved, nil
+}
@@ -0,0 +1,141 @@
+package github
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const bypassSettings = `
+repository: {owner: acme, name: api}
+disable: {repository: true}
+branches:
+  - name: main
+    protection:
+      requiredapprovingreviewcount:
+        requiredapprovingreviewcount: 1
+        bypasspullrequestallowances: {apps: [merge-bot], teams: [release]}
+      restrictions: {apps: [merge-bot], teams: [platform]}
+rulesets:
+  - name: release
+    branches: [~DEFAULT_BRANCH]
+    rules: {deletion: true}
+    bypassactors:
+      - app: merge-bot
+      - team: platform
+        mode: pull_request
+      - organizationadmin: true
+`
+
+func TestApplyBypassActorsThenPlanHasNoChanges(t *testing.T) {
+	server, client := newTestClient(t)
+	server.AddRepository("acme", "api")
+
+	result, err := client.Apply(context.Background(), settingsFromYAML(t, bypassSettings))
+
+	if err != nil {
+		t.Fatalf("Error applying settings: %v", err)
+	}
+
+	if len(result.Failed) != 0 {
+		t.Fatalf("Changes failed: %v", result.Failed)
+	}
+
+	repo := server.Repository("acme", "api")
+	actors := map[string]string{}
+
+	for _, ruleset := range repo.Rulesets() {
+		for _, actor := range ruleset.BypassActors {
+			actors[actor.Type] = actor.Mode
+
+			if actor.Type == "Integration" && actor.ID != serverAppID(t, client, "merge-bot") {
+				t.Errorf("App bypass actor has id %d, want the id of merge-bot", actor.ID)
+			}
+		}
+	}
+
+	if len(actors) != 3 || actors["Team"] != "pull_request" || actors["Integration"] != "always" {
+		t.Errorf("Bypass actors are %v, want the app, team and organization admin", actors)
+	}
+
+	if apps := repo.PullRequestBypassApps("main"); !slices.Equal(apps, []string{"merge-bot"}) {
+		t.Errorf("Branch main bypass allowances are %v, want merge-bot", apps)
+	}
+
+	plan := planOf(t, client, settingsFromYAML(t, bypassSettings))
+
+	if !plan.Empty() {
+		t.Errorf("Plan after apply has changes %v:\n%s", changeNames(plan), plan)
+	}
+}
+
+func TestApplyClearsRemovedBypassActors(t *testing.T) {
+	server, client := newTestClient(t)
+	server.AddRepository("acme", "api")
+
+	_, err := client.Apply(context.Background(), settingsFromYAML(t, bypassSettings))
+
+	if err != nil {
+		t.Fatalf("Error applying settings: %v", err)
+	}
+
+	withoutActors := strings.Split(bypassSettings, "    bypassactors:")[0]
+	plan := planOf(t, client, settingsFromYAML(t, withoutActors))
+
+	if strings.Join(changeNames(plan), ",") != "rulesets update release" {
+		t.Fatalf("Plan changes are %v, want [rulesets update release]", changeNames(plan))
+	}
+
+	_, err = client.Apply(context.Background(), settingsFromYAML(t, withoutActors))
+
+	if err != nil {
+		t.Fatalf("Error applying settings without bypass actors: %v", err)
+	}
+
+	for _, ruleset := range server.Repository("acme", "api").Rulesets() {
+		if len(ruleset.BypassActors) != 0 {
+			t.Errorf("Ruleset %s has bypass actors %v, want none", ruleset.Name, ruleset.BypassActors)
+		}
+
+		if !slices.Contains(ruleset.Rules, "deletion") {
+			t.Errorf("Ruleset %s lost its rules when its bypass actors were cleared", ruleset.Name)
+		}
+	}
+}
+
+func TestValidateBypassActors(t *testing.T) {
+	problems, err := Validate([]byte(`
+repository: {owner: acme, name: api}
+rulesets:
+  - name: release
+    branches: [main]
+    bypassactors:
+      - app: merge-bot
+        team: platform
+      - mode: always
+      - app: merge-bot
+        mode: sometimes
+`))
+
+	if err != nil {
+		t.Fatalf("Error validating settings: %v", err)
+	}
+
+	if len(problems) != 3 {
+		t.Errorf("Problems are %v, want two actors without exactly one kind and an invalid mode", problems)
+	}
+}
+
+// serverAppID looks up the id the fake server gives to an app
+func serverAppID(t *testing.T, client *Client, slug string) int64 {
+	t.Helper()
+
+	id, err := client.appID(context.Background(), slug)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return id
+}
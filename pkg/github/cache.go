@@ -0,0 +1,248 @@
+package github
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// cacheFilePermission is the permission of the apply cache file, it holds no secret
+const cacheFilePermission = 0644
+
+// ApplyCache remembers the repositories applied successfully so the next apply skips those whose settings and live state did not change
+// The live state is tracked with the etag of the repository, changes github does not reflect in it (ex: a label edited by hand) are only caught once the settings or the repository change
+type ApplyCache struct {
+	mutex   sync.Mutex
+	path    string
+	entries map[string]cacheEntry
+}
+
+// cacheEntry is the state of a repository after its last successful apply
+type cacheEntry struct {
+	// ConfigHash is the hash of the effective settings of the repository
+	ConfigHash string
+	// ETag is the etag of the repository once applied
+	ETag      string
+	AppliedAt time.Time
+}
+
+// LoadApplyCache reads the apply cache of a file, a missing file is an empty cache
+func LoadApplyCache(path string) (*ApplyCache, error) {
+	cache := &ApplyCache{path: path, entries: map[string]cacheEntry{}}
+	content, err := ioutil.ReadFile(path)
+
+	if os.IsNotExist(err) {
+		return cache, nil
+	}
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error while reading apply cache %s", path)
+	}
+
+	err = json.Unmarshal(content, &cache.entries)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error while unmarshal apply cache %s", path)
+	}
+
+	return cache, nil
+}
+
+// Save writes the apply cache to its file
+func (cache *ApplyCache) Save() error {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	content, err := json.MarshalIndent(cache.entries, "", "  ")
+
+	if err != nil {
+		return errors.Wrap(err, "Error while marshal apply cache")
+	}
+
+	err = ioutil.WriteFile(cache.path, content, cacheFilePermission)
+
+	if err != nil {
+		return errors.Wrapf(err, "Error while writing apply cache %s", cache.path)
+	}
+
+	return nil
+}
+
+// CachedApply is the last successful apply of a repository recorded in the apply cache
+type CachedApply struct {
+	AppliedAt time.Time
+	// ConfigChanged is set when the settings changed since, the next apply plans the repository again
+	ConfigChanged bool
+}
+
+// CachedApply returns the last successful apply of the settings of a repository from the apply cache of the client, false
+// without cache or when the repository was never applied successfully or failed since. It does not call github
+func (client *Client) CachedApply(settings *Settings) (CachedApply, bool) {
+	if client.cache == nil {
+		return CachedApply{}, false
+	}
+
+	entry, ok := client.cache.get(settings.Repository.Owner + "/" + settings.Repository.Name)
+
+	if !ok {
+		return CachedApply{}, false
+	}
+
+	return CachedApply{AppliedAt: entry.AppliedAt, ConfigChanged: entry.ConfigHash != client.configHash(settings)}, true
+}
+
+func (cache *ApplyCache) get(repository string) (cacheEntry, bool) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	entry, ok := cache.entries[repository]
+
+	return entry, ok
+}
+
+func (cache *ApplyCache) set(repository string, entry cacheEntry) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	cache.entries[repository] = entry
+}
+
+func (cache *ApplyCache) remove(repository string) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	delete(cache.entries, repository)
+}
+
+// configHash returns the hash of the effective settings of a repository with the options changing its plan
+// Settings overwriting secrets are never cached since the values of the secrets can't be compared, an empty hash is returned
+func (client *Client) configHash(settings *Settings) string {
+	for _, secretSettings := range settings.Secrets {
+		if secretSettings.Overwrite {
+			return ""
+		}
+	}
+
+	for _, secretSettings := range settings.DependabotSecrets {
+		if secretSettings.Overwrite {
+			return ""
+		}
+	}
+
+	for _, environmentSettings := range settings.Environments {
+		for _, secretSettings := range environmentSettings.Secrets {
+			if secretSettings.Overwrite {
+				return ""
+			}
+		}
+	}
+
+	// The content of the files read from a source is part of the settings
+	files, err := resolveFiles(settings.Files, settings.Repository)
+
+	if err != nil {
+		return ""
+	}
+
+	content, err := json.Marshal(struct {
+		Settings *Settings
+		Files    []file
+		Prune    bool
+	}{settings, files, client.prune})
+
+	if err != nil {
+		return ""
+	}
+
+	sum := sha256.Sum256(content)
+
+	return hex.EncodeToString(sum[:])
+}
+
+// lookupCache returns the hash of the settings of a repository and true when they and the repository itself did not change since its last successful apply
+// Without cache nothing is hashed, with force every repository is planned but their hash is still recorded once applied
+func (client *Client) lookupCache(ctx context.Context, settings *Settings) (string, bool) {
+	if client.cache == nil {
+		return "", false
+	}
+
+	hash := client.configHash(settings)
+
+	if client.force || hash == "" {
+		return hash, false
+	}
+
+	fullName := settings.Repository.Owner + "/" + settings.Repository.Name
+	entry, ok := client.cache.get(fullName)
+
+	if !ok || entry.ConfigHash != hash || entry.ETag == "" {
+		return hash, false
+	}
+
+	_, notModified, err := client.repositoryETag(ctx, settings.Repository.Owner, settings.Repository.Name, entry.ETag)
+
+	if err != nil {
+		log.Printf("[WARN] Ignoring apply cache of %s: %s\n", fullName, err)
+		return hash, false
+	}
+
+	return hash, notModified
+}
+
+// rememberApplied records a repository applied successfully, a repository left with changes is removed from the cache
+func (client *Client) rememberApplied(ctx context.Context, plan *Plan, result *Result, err error) {
+	if client.cache == nil {
+		return
+	}
+
+	fullName := plan.Owner + "/" + plan.Name
+
+	if err != nil || plan.configHash == "" || len(result.Skipped) != 0 || len(result.Failed) != 0 || len(result.Unverified) != 0 || len(result.Reported) != 0 {
+		client.cache.remove(fullName)
+		return
+	}
+
+	etag, _, err := client.repositoryETag(ctx, plan.Owner, plan.Name, "")
+
+	if err != nil || etag == "" {
+		client.cache.remove(fullName)
+		return
+	}
+
+	client.cache.set(fullName, cacheEntry{ConfigHash: plan.configHash, ETag: etag, AppliedAt: client.clock.Now().UTC()})
+}
+
+// repositoryETag gets the etag of a repository, github answers a conditional request matching the etag with not modified without counting it in the rate limit
+func (client *Client) repositoryETag(ctx context.Context, owner, name, etag string) (string, bool, error) {
+	request, err := client.github.NewRequest(http.MethodGet, fmt.Sprintf("repos/%s/%s", owner, name), nil)
+
+	if err != nil {
+		return "", false, errors.Wrap(err, "Error while creating repository request")
+	}
+
+	if etag != "" {
+		request.Header.Set("If-None-Match", etag)
+	}
+
+	response, err := client.github.Do(ctx, request, nil)
+
+	if response != nil && response.StatusCode == http.StatusNotModified {
+		return etag, true, nil
+	}
+
+	if err != nil {
+		return "", false, errors.Wrap(err, "Error while getting repository")
+	}
+
+	return response.Header.Get("ETag"), false, nil
+}
@@ -0,0 +1,40 @@
+package github
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestCachedApplyFlagsChangedSettings(t *testing.T) {
+	cache, err := LoadApplyCache(filepath.Join(t.TempDir(), "cache.json"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server, client := newTestClient(t, WithApplyCache(cache))
+	server.AddRepository("acme", "api")
+
+	settings := settingsFromYAML(t, "repository: {owner: acme, name: api}\ndisable: {repository: true}\nlabels: [{name: bug, color: d73a4a}]\n")
+
+	if _, ok := client.CachedApply(settings); ok {
+		t.Fatal("Expected no cached apply before the first apply")
+	}
+
+	if _, err := client.ApplyAll(context.Background(), []*Settings{settings}, 1); err != nil {
+		t.Fatalf("Error applying settings: %v", err)
+	}
+
+	cached, ok := client.CachedApply(settings)
+
+	if !ok || cached.AppliedAt.IsZero() || cached.ConfigChanged {
+		t.Fatalf("Cached apply is %+v (%t), want the apply of the current settings", cached, ok)
+	}
+
+	changed := settingsFromYAML(t, "repository: {owner: acme, name: api}\ndisable: {repository: true}\nlabels: [{name: bug, color: ff0000}]\n")
+
+	if cached, _ := client.CachedApply(changed); !cached.ConfigChanged {
+		t.Error("Expected the changed settings to be flagged")
+	}
+}
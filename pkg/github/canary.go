@@ -0,0 +1,70 @@
+package github
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+// Canary selects the repositories applied first during a staged rollout, the others are applied once the canary succeeded
+// and was confirmed or soaked
+type Canary struct {
+	// Count is the number of repositories with changes applied first, in the order of the settings
+	Count int
+	// Repositories are the full names (owner/name) of the repositories applied first, they take precedence over Count
+	Repositories []string
+}
+
+// Enabled returns true when the canary selects some repositories
+func (canary Canary) Enabled() bool {
+	return canary.Count > 0 || len(canary.Repositories) > 0
+}
+
+// Split separates the planned repositories applied first from the rest
+// Only the repositories with changes are counted, the repositories without changes or that failed planning are left in the rest
+func (canary Canary) Split(planned Results) (Results, Results, error) {
+	selected := map[string]bool{}
+
+	for _, name := range canary.Repositories {
+		if _, ok := planned.Get(name); !ok {
+			return nil, nil, errors.Errorf("Canary repository %s is not targeted by the settings", name)
+		}
+
+		selected[name] = true
+	}
+
+	first, rest := Results{}, Results{}
+
+	for _, result := range planned {
+		changed := result.Err == nil && result.Plan != nil && !result.Plan.Empty()
+
+		if len(selected) != 0 && selected[result.Repository] || len(selected) == 0 && changed && len(first) < canary.Count {
+			first = append(first, result)
+			continue
+		}
+
+		rest = append(rest, result)
+	}
+
+	return first, rest, nil
+}
+
+// Replan computes again the plans of the repositories (ex: the rest of a canary rollout after a soak period), since the live
+// settings may have changed while waiting, the repositories that failed planning or were skipped by the cache are returned as is
+func (client *Client) Replan(ctx context.Context, planned Results, concurrency int) (Results, error) {
+	names := make([]string, 0, len(planned))
+
+	for _, result := range planned {
+		names = append(names, result.Repository)
+	}
+
+	results := runAll(names, concurrency, func(i int) RepositoryResult {
+		if planned[i].Err != nil || planned[i].Plan == nil || planned[i].Plan.settings == nil || planned[i].Cached {
+			return planned[i]
+		}
+
+		return client.planResult(ctx, planned[i].Plan.settings)
+	})
+
+	return results, results.Err()
+}
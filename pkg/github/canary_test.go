@@ -0,0 +1,109 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+// plannedResults returns results named after the repositories, the ones listed as changed have a plan with a change
+func plannedResults(changed map[string]bool, names ...string) Results {
+	results := Results{}
+
+	for _, name := range names {
+		plan := &Plan{}
+
+		if changed[name] {
+			plan.Changes = []Change{newChange(ResourceLabels, "bug", ActionCreate, nil, label{Name: "bug"})}
+		}
+
+		results = append(results, RepositoryResult{Repository: name, Plan: plan})
+	}
+
+	return results
+}
+
+func resultNames(results Results) []string {
+	names := []string{}
+
+	for _, result := range results {
+		names = append(names, result.Repository)
+	}
+
+	return names
+}
+
+func TestCanarySplitCountsRepositoriesWithChanges(t *testing.T) {
+	planned := plannedResults(map[string]bool{"acme/b": true, "acme/c": true, "acme/d": true}, "acme/a", "acme/b", "acme/c", "acme/d")
+	planned = append(planned, RepositoryResult{Repository: "acme/e", Err: errors.New("boom")})
+
+	first, rest, err := Canary{Count: 2}.Split(planned)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if names := resultNames(first); !reflect.DeepEqual(names, []string{"acme/b", "acme/c"}) {
+		t.Errorf("Expected the first repositories with changes, got %v", names)
+	}
+
+	if names := resultNames(rest); !reflect.DeepEqual(names, []string{"acme/a", "acme/d", "acme/e"}) {
+		t.Errorf("Expected the other repositories in order, got %v", names)
+	}
+}
+
+func TestCanarySplitSelectsNamedRepositories(t *testing.T) {
+	planned := plannedResults(map[string]bool{"acme/a": true}, "acme/a", "acme/b", "acme/c")
+
+	first, rest, err := Canary{Count: 1, Repositories: []string{"acme/c"}}.Split(planned)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if names := resultNames(first); !reflect.DeepEqual(names, []string{"acme/c"}) {
+		t.Errorf("Expected the named repository, got %v", names)
+	}
+
+	if names := resultNames(rest); !reflect.DeepEqual(names, []string{"acme/a", "acme/b"}) {
+		t.Errorf("Expected the other repositories, got %v", names)
+	}
+
+	_, _, err = Canary{Repositories: []string{"acme/missing"}}.Split(planned)
+
+	if err == nil {
+		t.Error("Expected an error for a canary repository not targeted by the settings")
+	}
+}
+
+func TestReplanSeesTheChangesMadeWhileWaiting(t *testing.T) {
+	server, client := newTestClient(t)
+	repo := server.AddRepository("acme", "api")
+
+	settings := settingsFromYAML(t, `
+repository: {owner: acme, name: api}
+labels:
+  - {name: bug, color: d73a4a}
+`)
+
+	planned := Results{{Repository: "acme/api", Plan: planOf(t, client, settings)}}
+
+	if !slices.Contains(changeNames(planned[0].Plan), "labels create bug") {
+		t.Fatalf("Expected the label to be created, got %v", changeNames(planned[0].Plan))
+	}
+
+	// Someone creates the label during the soak period
+	repo.SetLabel("bug", "d73a4a", "")
+
+	replanned, err := client.Replan(context.Background(), planned, 1)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if slices.Contains(changeNames(replanned[0].Plan), "labels create bug") {
+		t.Errorf("Expected the label created while waiting not to be planned again, got %v", changeNames(replanned[0].Plan))
+	}
+}
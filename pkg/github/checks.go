@@ -0,0 +1,59 @@
+package github
+
+import (
+	"context"
+	"sort"
+
+	"github.com/google/go-github/v75/github"
+	"github.com/pkg/errors"
+)
+
+// DiscoverStatusChecks returns the status contexts and check run names reported on the last commits of a branch
+func (client *Client) DiscoverStatusChecks(ctx context.Context, owner, name, branch string, commits int) ([]string, error) {
+	ctx = withRetries(ctx)
+
+	githubCommits, _, err := client.github.Repositories.ListCommits(ctx, owner, name, &github.CommitsListOptions{
+		SHA:         branch,
+		ListOptions: github.ListOptions{PerPage: commits},
+	})
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error listing commits of branch %s", branch)
+	}
+
+	contexts := map[string]bool{}
+
+	for _, commit := range githubCommits {
+		statuses, _, err := client.github.Repositories.ListStatuses(ctx, owner, name, commit.GetSHA(), &github.ListOptions{PerPage: 100})
+
+		if err != nil {
+			return nil, errors.Wrapf(err, "Error listing statuses of commit %s", commit.GetSHA())
+		}
+
+		for _, status := range statuses {
+			contexts[status.GetContext()] = true
+		}
+
+		checkRuns, _, err := client.github.Checks.ListCheckRunsForRef(ctx, owner, name, commit.GetSHA(), &github.ListCheckRunsOptions{
+			ListOptions: github.ListOptions{PerPage: 100},
+		})
+
+		if err != nil {
+			return nil, errors.Wrapf(err, "Error listing check runs of commit %s", commit.GetSHA())
+		}
+
+		for _, checkRun := range checkRuns.CheckRuns {
+			contexts[checkRun.GetName()] = true
+		}
+	}
+
+	discovered := make([]string, 0, len(contexts))
+
+	for statusContext := range contexts {
+		discovered = append(discovered, statusContext)
+	}
+
+	sort.Strings(discovered)
+
+	return discovered, nil
+}
@@ -0,0 +1,22 @@
+package github
+
+import "time"
+
+// Clock tells the time and waits for durations, the client reads the time through it so the code depending on time (the
+// retry backoff, the schedules of serve, the maintenance windows) can be tested without waiting
+type Clock interface {
+	Now() time.Time
+	// After sends the time on the channel returned once the duration elapsed
+	After(duration time.Duration) <-chan time.Time
+}
+
+// systemClock is the clock of the system, the default clock of the client
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) After(duration time.Duration) <-chan time.Time {
+	return time.After(duration)
+}
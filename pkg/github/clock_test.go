@@ -0,0 +1,112 @@
+package github
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock only moves when advanced, the channels returned by After receive the time once it is advanced past their deadline
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []fakeTimer
+	// waiting receives a value every time After is called, so a test advances the clock once the code waits on it
+	waiting chan struct{}
+}
+
+type fakeTimer struct {
+	deadline time.Time
+	channel  chan time.Time
+}
+
+func newFakeClock(now time.Time) *fakeClock {
+	return &fakeClock{now: now, waiting: make(chan struct{}, 100)}
+}
+
+func (clock *fakeClock) Now() time.Time {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+
+	return clock.now
+}
+
+func (clock *fakeClock) After(duration time.Duration) <-chan time.Time {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+
+	channel := make(chan time.Time, 1)
+	clock.timers = append(clock.timers, fakeTimer{deadline: clock.now.Add(duration), channel: channel})
+	clock.waiting <- struct{}{}
+
+	return channel
+}
+
+// Advance moves the time forward and fires the timers whose deadline passed
+func (clock *fakeClock) Advance(duration time.Duration) {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+
+	clock.now = clock.now.Add(duration)
+	pending := []fakeTimer{}
+
+	for _, timer := range clock.timers {
+		if timer.deadline.After(clock.now) {
+			pending = append(pending, timer)
+			continue
+		}
+
+		timer.channel <- clock.now
+	}
+
+	clock.timers = pending
+}
+
+// wait blocks until the code calls After or fails the test
+func (clock *fakeClock) wait(tb testing.TB) {
+	tb.Helper()
+
+	select {
+	case <-clock.waiting:
+	case <-time.After(5 * time.Second):
+		tb.Fatal("Timed out waiting for the clock to be waited on")
+	}
+}
+
+func TestRateLimitWaitsUntilTheReset(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	response := &http.Response{StatusCode: http.StatusForbidden, Header: http.Header{}}
+	response.Header.Set("X-RateLimit-Remaining", "0")
+	response.Header.Set("X-RateLimit-Reset", strconv.FormatInt(now.Add(30*time.Second).Unix(), 10))
+
+	wait, limited := rateLimitWait(response, 0, now)
+
+	if !limited || wait != 31*time.Second {
+		t.Errorf("Expected to wait 31s for the rate limit reset, got %s (limited %t)", wait, limited)
+	}
+}
+
+func TestSleepWaitsOnTheClock(t *testing.T) {
+	clock := newFakeClock(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC))
+	done := make(chan error, 1)
+
+	go func() { done <- sleep(context.Background(), clock, time.Minute) }()
+
+	clock.wait(t)
+	clock.Advance(59 * time.Second)
+
+	select {
+	case <-done:
+		t.Fatal("Expected the sleep to last until the backoff elapsed")
+	default:
+	}
+
+	clock.Advance(time.Second)
+
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
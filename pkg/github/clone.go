@@ -0,0 +1,129 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// SectionProtection names the branches section when cloning, only protected branches are cloned
+const SectionProtection = "protection"
+
+// CloneSections are the resource kinds clone can copy between repositories
+// Secrets and files are left out, github never returns the values of secrets and files are not read from live repositories
+// nolint:gochecknoglobals
+var CloneSections = []string{
+	ResourceRepository,
+	ResourceLabels,
+	ResourceBranches,
+	ResourceWebhooks,
+	ResourceTopics,
+	ResourceCollaborators,
+	ResourceTeams,
+	ResourceVariables,
+	ResourceEnvironments,
+	ResourceRulesets,
+}
+
+// CloneSettings returns settings giving the target repository the live sections of the source repository, every section when none is selected
+// The other sections are disabled, the repository keeps its name, description, homepage, default branch and visibility,
+// the warnings list what could not be cloned (ex: webhook secrets github does not return)
+func (client *Client) CloneSettings(ctx context.Context, fromOwner, fromName, toOwner, toName string, sections []string) (*Settings, []string, error) {
+	selected, err := cloneSections(sections)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	settings, err := client.Export(ctx, fromOwner, fromName)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	githubRepo, _, err := client.github.Repositories.Get(ctx, toOwner, toName)
+
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "Error while getting repository from github")
+	}
+
+	warnings := []string{}
+
+	settings.Status = nil
+	settings.Secrets = nil
+	settings.DependabotSecrets = nil
+	settings.Files = nil
+	settings.Disable = Disabled{
+		Repository:    !selected[ResourceRepository],
+		Labels:        !selected[ResourceLabels],
+		Branches:      !selected[ResourceBranches],
+		Webhooks:      !selected[ResourceWebhooks],
+		Topics:        !selected[ResourceTopics],
+		Collaborators: !selected[ResourceCollaborators],
+		Teams:         !selected[ResourceTeams],
+		Secrets:       true,
+		Variables:     !selected[ResourceVariables],
+		Environments:  !selected[ResourceEnvironments],
+		Files:         true,
+		Rulesets:      !selected[ResourceRulesets],
+	}
+
+	settings.Repository.Name = githubRepo.GetName()
+	settings.Repository.Owner = githubRepo.GetOwner().GetLogin()
+	settings.Repository.Description = githubRepo.GetDescription()
+	settings.Repository.Homepage = githubRepo.GetHomepage()
+	settings.Repository.DefaultBranch = githubRepo.GetDefaultBranch()
+	settings.Repository.Private = githubRepo.GetPrivate()
+	settings.Repository.IsTemplate = githubRepo.GetIsTemplate()
+	settings.Repository.Archived = githubRepo.GetArchived()
+
+	if selected[ResourceWebhooks] {
+		for i, webhookSettings := range settings.Webhooks {
+			if webhookSettings.Secret == RedactedSecret {
+				settings.Webhooks[i].Secret = ""
+				warnings = append(warnings, fmt.Sprintf("webhook %s is cloned without its secret, github does not return it", webhookSettings.URL))
+			}
+		}
+	}
+
+	if selected[ResourceEnvironments] {
+		for i, environmentSettings := range settings.Environments {
+			for _, secretSettings := range environmentSettings.Secrets {
+				warnings = append(warnings, fmt.Sprintf("secret %s of environment %s is not cloned, github does not return its value", secretSettings.Name, environmentSettings.Name))
+			}
+
+			settings.Environments[i].Secrets = nil
+		}
+	}
+
+	return settings, warnings, nil
+}
+
+// cloneSections returns the resource kinds selected for clone, protection selects the branches
+func cloneSections(sections []string) (map[string]bool, error) {
+	selected := map[string]bool{}
+	known := map[string]bool{}
+
+	for _, section := range CloneSections {
+		known[section] = true
+		selected[section] = len(sections) == 0
+	}
+
+	for _, section := range sections {
+		section = strings.ToLower(section)
+
+		if section == SectionProtection {
+			section = ResourceBranches
+		}
+
+		if !known[section] {
+			return nil, errors.Errorf("Unknown section %q (allowed values: %s, %s)", section, strings.Join(CloneSections, ", "), SectionProtection)
+		}
+
+		selected[section] = true
+	}
+
+	return selected, nil
+}
@@ -0,0 +1,136 @@
+package github
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// ProtectionsToRulesets converts the classic protections of the protected branches of the settings to equivalent rulesets,
+// one per branch named after it, in the mode given (active when empty). The settings returned only hold the rulesets, the
+// warnings list the options rulesets can't express or express differently, they need a manual review
+func ProtectionsToRulesets(settings *Settings, mode string) (*Settings, []string, error) {
+	rulesetsMode := rulesetMode(mode)
+
+	if mode == "" {
+		rulesetsMode = rulesetModeActive
+	}
+
+	if rulesetsMode != rulesetModeActive && rulesetsMode != rulesetModeEvaluate && rulesetsMode != rulesetModeDisabled {
+		return nil, nil, errors.Errorf("Invalid ruleset mode %s (active, evaluate or disabled)", mode)
+	}
+
+	converted := &Settings{Repository: repository{Owner: settings.Repository.Owner, Name: settings.Repository.Name}, Rulesets: []ruleset{}}
+	warnings := []string{}
+
+	for _, branchSettings := range settings.Branches {
+		if !branchSettings.Protection.Enabled {
+			continue
+		}
+
+		rulesetSettings, branchWarnings := protectionToRuleset(branchSettings)
+		rulesetSettings.Mode = rulesetsMode
+		converted.Rulesets = append(converted.Rulesets, rulesetSettings)
+
+		for _, warning := range branchWarnings {
+			warnings = append(warnings, fmt.Sprintf("%s/%s branch %s: %s", settings.Repository.Owner, settings.Repository.Name, branchSettings.Name, warning))
+		}
+	}
+
+	return converted, warnings, nil
+}
+
+// protectionToRuleset converts the protection of a branch to a ruleset and warns about the options it can't carry over
+func protectionToRuleset(branchSettings branch) (ruleset, []string) {
+	protectionSettings := branchSettings.Protection
+	reviews := protectionSettings.RequiredApprovingReviewCount
+	warnings := []string{}
+
+	rulesetSettings := ruleset{
+		Name:     branchSettings.Name,
+		Branches: []string{branchSettings.Name},
+		Rules: rulesetRules{
+			Deletion:              !protectionSettings.AllowDeletions,
+			NonFastForward:        !protectionSettings.AllowForcePushes,
+			RequiredLinearHistory: protectionSettings.RequiredLinearHistory,
+			RequiredSignatures:    protectionSettings.RequiredSignatures,
+			RequiredStatusChecks:  protectionSettings.RequiredStatusChecks,
+			PullRequest: rulesetPullRequest{
+				Required:                       reviews.RequiredApprovingReviewCount > 0,
+				RequiredApprovingReviewCount:   reviews.RequiredApprovingReviewCount,
+				DismissStaleReviewsOnPush:      reviews.DismissStaleReviews,
+				RequireCodeOwnerReview:         reviews.RequireCodeOwnerReviews,
+				RequiredReviewThreadResolution: protectionSettings.RequiredConversationResolution,
+			},
+		},
+		annotations: annotations{Reason: branchSettings.Reason, Ticket: branchSettings.Ticket},
+	}
+
+	if protectionSettings.RequiredConversationResolution && !rulesetSettings.Rules.PullRequest.Required {
+		rulesetSettings.Rules.PullRequest.Required = true
+		warnings = append(warnings, "the conversation resolution of a ruleset is part of its pull request rule, the branch now requires pull requests")
+	}
+
+	// The actors keep the most permissive mode they are given
+	actors := map[string]bypassActor{}
+
+	addActors := func(allowed *restrictions, mode bypassMode) {
+		for _, slug := range allowed.Apps {
+			if actors["app/"+slug].Mode != bypassModeAlways {
+				actors["app/"+slug] = bypassActor{App: slug, Mode: mode}
+			}
+		}
+
+		for _, slug := range allowed.Teams {
+			if actors["team/"+slug].Mode != bypassModeAlways {
+				actors["team/"+slug] = bypassActor{Team: slug, Mode: mode}
+			}
+		}
+	}
+
+	if !protectionSettings.EnforceAdmins {
+		actors["organizationadmin"] = bypassActor{OrganizationAdmin: true, Mode: bypassModeAlways}
+		warnings = append(warnings, "the admins of the repository bypassed the protection, only the organization admins bypass the ruleset")
+	}
+
+	if allowed := reviews.BypassPullRequestAllowances; allowed != nil {
+		addActors(allowed, bypassModePullRequest)
+
+		if len(allowed.Users) != 0 {
+			warnings = append(warnings, fmt.Sprintf("users can't bypass a ruleset, %v no longer bypass the required reviews", allowed.Users))
+		}
+	}
+
+	if allowed := protectionSettings.Restrictions; allowed != nil {
+		rulesetSettings.Rules.Update = true
+		addActors(allowed, bypassModeAlways)
+		warnings = append(warnings, "the push restrictions became an update rule, the apps and teams allowed to push bypass every rule of the ruleset")
+
+		if len(allowed.Users) != 0 {
+			warnings = append(warnings, fmt.Sprintf("users can't bypass a ruleset, %v can no longer push", allowed.Users))
+		}
+	}
+
+	if reviews.DismissalRestrictions != nil {
+		warnings = append(warnings, "rulesets have no dismissal restrictions, everyone with write access can dismiss the reviews")
+	}
+
+	if protectionSettings.RequiredStatusChecks.Strict && len(protectionSettings.RequiredStatusChecks.Contexts) == 0 {
+		warnings = append(warnings, "the status checks of a ruleset are only enforced with contexts, the strict mode without contexts is dropped")
+	}
+
+	keys := make([]string, 0, len(actors))
+
+	for key := range actors {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		rulesetSettings.BypassActors = append(rulesetSettings.BypassActors, actors[key])
+	}
+
+	return rulesetSettings, warnings
+}
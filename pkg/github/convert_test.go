@@ -0,0 +1,105 @@
+package github
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestProtectionsToRulesets(t *testing.T) {
+	settings := settingsFromYAML(t, `
+repository: {owner: acme, name: api}
+branches:
+  - name: main
+    protection:
+      enabled: true
+      requiredapprovingreviewcount:
+        requiredapprovingreviewcount: 2
+        requirecodeownerreviews: true
+        bypasspullrequestallowances: {apps: [merge-bot], users: [octocat]}
+      requiredstatuschecks: {strict: true, contexts: [ci]}
+      restrictions: {apps: [merge-bot], teams: [release]}
+      requiredlinearhistory: true
+      enforceadmins: true
+`)
+
+	// A live branch without protection
+	settings.Branches = append(settings.Branches, branch{Name: "dev"})
+
+	converted, warnings, err := ProtectionsToRulesets(settings, "evaluate")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(converted.Rulesets) != 1 {
+		t.Fatalf("Expected a ruleset for the protected branch only, got %v", converted.Rulesets)
+	}
+
+	main := converted.Rulesets[0]
+
+	expected := rulesetRules{
+		Deletion:              true,
+		NonFastForward:        true,
+		Update:                true,
+		RequiredLinearHistory: true,
+		RequiredStatusChecks:  requiredStatusChecks{Strict: true, Contexts: []string{"ci"}},
+		PullRequest:           rulesetPullRequest{Required: true, RequiredApprovingReviewCount: 2, RequireCodeOwnerReview: true},
+	}
+
+	if main.Name != "main" || !reflect.DeepEqual(main.Branches, []string{"main"}) || main.Mode != rulesetModeEvaluate {
+		t.Errorf("Expected an evaluated ruleset targeting main, got %+v", main)
+	}
+
+	if !reflect.DeepEqual(main.Rules, expected) {
+		t.Errorf("Expected rules %+v, got %+v", expected, main.Rules)
+	}
+
+	// The app allowed to push bypasses every rule, not only the reviews
+	actors := []bypassActor{{App: "merge-bot", Mode: bypassModeAlways}, {Team: "release", Mode: bypassModeAlways}}
+
+	if !reflect.DeepEqual(main.BypassActors, actors) {
+		t.Errorf("Expected bypass actors %+v, got %+v", actors, main.BypassActors)
+	}
+
+	joined := strings.Join(warnings, "\n")
+
+	if !strings.Contains(joined, "[octocat] no longer bypass") || !strings.Contains(joined, "update rule") {
+		t.Errorf("Expected warnings for the users and the push restrictions, got %v", warnings)
+	}
+}
+
+func TestProtectionsToRulesetsLetsOrganizationAdminsBypass(t *testing.T) {
+	settings := settingsFromYAML(t, `
+repository: {owner: acme, name: api}
+branches:
+  - name: main
+    protection: {enabled: true, allowforcepushes: true, requiredconversationresolution: true}
+`)
+
+	converted, warnings, err := ProtectionsToRulesets(settings, "")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	main := converted.Rulesets[0]
+
+	if main.Mode != rulesetModeActive || main.Rules.NonFastForward || !main.Rules.PullRequest.Required {
+		t.Errorf("Expected an active ruleset allowing force pushes and requiring pull requests, got %+v", main)
+	}
+
+	if !reflect.DeepEqual(main.BypassActors, []bypassActor{{OrganizationAdmin: true, Mode: bypassModeAlways}}) {
+		t.Errorf("Expected the organization admins to bypass the ruleset, got %+v", main.BypassActors)
+	}
+
+	if len(warnings) != 2 {
+		t.Errorf("Expected warnings for the admins and the conversation resolution, got %v", warnings)
+	}
+
+	_, _, err = ProtectionsToRulesets(settings, "strict")
+
+	if err == nil {
+		t.Error("Expected an error for an unknown mode")
+	}
+}
@@ -0,0 +1,54 @@
+package github
+
+import (
+	"context"
+
+	"github.com/google/go-github/v75/github"
+	"github.com/pkg/errors"
+)
+
+// BranchRef is a branch of a repository (owner/repo:branch)
+type BranchRef struct {
+	Owner  string
+	Name   string
+	Branch string
+}
+
+func (ref BranchRef) String() string {
+	return ref.Owner + "/" + ref.Name + ":" + ref.Branch
+}
+
+// CopyProtection returns the settings protecting the target branch like the live source branch
+// Only the target branch is managed by the settings: the other sections are disabled and the other branches are not pruned,
+// they are planned and applied like any settings or exported with MarshalSections
+func (client *Client) CopyProtection(ctx context.Context, from, to BranchRef) (*Settings, error) {
+	branchProtection, err := client.getProtection(ctx, from.Owner, from.Name, from.Branch)
+
+	// Github answers the same for branches missing and branches without protection
+	if isNotFound(err) {
+		return nil, errors.Errorf("Branch %s is not protected or does not exist, there is no protection to copy", from)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &Settings{
+		Disable: Disabled{
+			Repository:    true,
+			Labels:        true,
+			Webhooks:      true,
+			Topics:        true,
+			Collaborators: true,
+			Teams:         true,
+			Secrets:       true,
+			Variables:     true,
+			Environments:  true,
+			Files:         true,
+			Rulesets:      true,
+		},
+		Prune:      Prune{Branches: github.Bool(false)},
+		Repository: repository{Owner: to.Owner, Name: to.Name},
+		Branches:   []branch{{Name: to.Branch, Protection: branchProtection}},
+	}, nil
+}
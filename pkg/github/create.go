@@ -0,0 +1,122 @@
+package github
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-github/v75/github"
+	"github.com/pkg/errors"
+)
+
+// planCreation plans the creation of a missing repository followed by the settings applied to an empty repository
+// The settings are planned again against the created repository before being applied
+func planCreation(settings *Settings) *Plan {
+	empty := &Settings{Repository: repository{Owner: settings.Repository.Owner, Name: settings.Repository.Name}}
+	plan := computePlan(empty, settings)
+	plan.settings = settings
+
+	changes := []Change{newChange(ResourceRepository, settings.Repository.Name, ActionCreate, repository{}, settings.Repository)}
+
+	for _, change := range plan.Changes {
+		if change.Resource != ResourceRepository {
+			changes = append(changes, change)
+		}
+	}
+
+	plan.Changes = changes
+	plan.setRationale(settings)
+	plan.setImpacts()
+
+	return plan
+}
+
+// isNotFound returns true when github answered with a 404
+func isNotFound(err error) bool {
+	errorResponse, ok := errors.Cause(err).(*github.ErrorResponse)
+
+	return ok && errorResponse.Response != nil && errorResponse.Response.StatusCode == http.StatusNotFound
+}
+
+// isForbidden returns true when github answered with a 403 (ex: a feature missing from the plan or a token lacking a permission)
+func isForbidden(err error) bool {
+	errorResponse, ok := errors.Cause(err).(*github.ErrorResponse)
+
+	return ok && errorResponse.Response != nil && errorResponse.Response.StatusCode == http.StatusForbidden
+}
+
+// isGone returns true when github answered with a 410 (ex: the issues of a repository with issues disabled)
+func isGone(err error) bool {
+	errorResponse, ok := errors.Cause(err).(*github.ErrorResponse)
+
+	return ok && errorResponse.Response != nil && errorResponse.Response.StatusCode == http.StatusGone
+}
+
+// isConflict returns true when github answered with a 409 (ex: the commits of an empty repository)
+func isConflict(err error) bool {
+	errorResponse, ok := errors.Cause(err).(*github.ErrorResponse)
+
+	return ok && errorResponse.Response != nil && errorResponse.Response.StatusCode == http.StatusConflict
+}
+
+func (client *Client) createRepository(ctx context.Context, report reporter, owner, name string, repo repository) error {
+	report.changed(ResourceRepository, "Creating repository %s/%s\n", owner, name)
+
+	if repo.Template != "" {
+		templateOwner, templateName, ok := strings.Cut(repo.Template, "/")
+
+		if !ok {
+			return errors.Errorf("Invalid template repository %s, expected owner/name", repo.Template)
+		}
+
+		_, _, err := client.github.Repositories.CreateFromTemplate(ctx, templateOwner, templateName, &github.TemplateRepoRequest{
+			Name:        github.String(name),
+			Owner:       github.String(owner),
+			Description: github.String(repo.Description),
+			Private:     github.Bool(repo.Private),
+		})
+
+		if err != nil {
+			return errors.Wrap(err, "Error creating repository from template\n")
+		}
+
+		return nil
+	}
+
+	githubOwner, _, err := client.github.Users.Get(ctx, owner)
+
+	if err != nil {
+		return errors.Wrap(err, "Error getting repository owner\n")
+	}
+
+	// Repositories of a user are created for the authenticated user
+	org := owner
+
+	if githubOwner.GetType() != ownerOrganization {
+		org = ""
+	}
+
+	created, _, err := client.github.Repositories.Create(ctx, org, &github.Repository{
+		Name:        github.String(name),
+		Description: github.String(repo.Description),
+		Homepage:    github.String(repo.Homepage),
+		Private:     github.Bool(repo.Private),
+		AutoInit:    github.Bool(repo.AutoInit),
+	})
+
+	if err != nil {
+		return errors.Wrap(err, "Error creating repository\n")
+	}
+
+	if !repo.AutoInit || repo.DefaultBranch == "" || created.GetDefaultBranch() == repo.DefaultBranch {
+		return nil
+	}
+
+	_, _, err = client.github.Repositories.RenameBranch(ctx, owner, name, created.GetDefaultBranch(), repo.DefaultBranch)
+
+	if err != nil {
+		return errors.Wrap(err, "Error renaming the initial branch\n")
+	}
+
+	return nil
+}
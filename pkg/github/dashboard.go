@@ -0,0 +1,113 @@
+package github
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+	"time"
+)
+
+// DashboardPath is the path of the dashboard served by Serve
+const DashboardPath = "/"
+
+// dashboardChanges is the number of recent drifts listed by the dashboard
+const dashboardChanges = 50
+
+// nolint:gochecknoglobals
+var dashboardTemplate = template.Must(template.New("dashboard").Funcs(template.FuncMap{
+	"time": func(t time.Time) string {
+		if t.IsZero() {
+			return "never"
+		}
+
+		return t.Format(time.RFC3339)
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta http-equiv="refresh" content="60">
+<title>github-settings</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border-bottom: 1px solid #ddd; padding: 0.3em 1em; text-align: left; vertical-align: top; }
+.synced { color: #1a7f37; }
+.drifted { color: #9a6700; }
+.failed { color: #cf222e; }
+pre { margin: 0; }
+</style>
+</head>
+<body>
+<h1>Repositories</h1>
+<table>
+<tr><th>Repository</th><th>Status</th><th>Last reconciled</th><th>Last applied</th></tr>
+{{- range .Statuses}}
+<tr>
+<td>{{.Repository}}</td>
+{{- if .Error}}
+<td class="failed">error: {{.Error}}</td>
+{{- else if .Changes}}
+<td class="drifted">{{len .Changes}} changes {{if .Applied}}applied{{else}}reported{{end}}</td>
+{{- else}}
+<td class="synced">in sync</td>
+{{- end}}
+<td>{{time .Time}}</td>
+<td>{{time .LastApplied}}</td>
+</tr>
+{{- end}}
+</table>
+<h1>Recent changes</h1>
+<table>
+<tr><th>Time</th><th>Repository</th><th>Changes</th></tr>
+{{- range .Drifts}}
+<tr><td>{{time .Time}}</td><td>{{.Repository}}</td><td><pre>{{.}}</pre></td></tr>
+{{- end}}
+</table>
+</body>
+</html>
+`))
+
+// dashboardHandler serves a read-only page with the status of every repository of the history and their recent drift
+func dashboardHandler(history *History) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != DashboardPath {
+			http.NotFound(w, r)
+			return
+		}
+
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		statuses, err := history.Statuses(r.Context())
+
+		if err != nil {
+			log.Printf("[WARN] Error rendering the dashboard, %s\n", err)
+			http.Error(w, "Error reading the history", http.StatusInternalServerError)
+
+			return
+		}
+
+		drifts, err := history.Query(r.Context(), HistoryQuery{Drift: true, Limit: dashboardChanges})
+
+		if err != nil {
+			log.Printf("[WARN] Error rendering the dashboard, %s\n", err)
+			http.Error(w, "Error reading the history", http.StatusInternalServerError)
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+		err = dashboardTemplate.Execute(w, struct {
+			Statuses []RepositoryStatus
+			Drifts   []HistoryEntry
+		}{statuses, drifts})
+
+		if err != nil {
+			log.Printf("[WARN] Error rendering the dashboard, %s\n", err)
+		}
+	}
+}
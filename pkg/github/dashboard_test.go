@@ -0,0 +1,70 @@
+package github
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDashboardShowsRepositoryStatuses(t *testing.T) {
+	server, client := newTestClient(t)
+	server.AddRepository("acme", "api")
+	server.AddRepository("acme", "web")
+
+	history, err := OpenHistory(filepath.Join(t.TempDir(), "history.db"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer history.Close()
+
+	for _, name := range []string{"api", "web"} {
+		config := writeSettings(t, "repository: {owner: acme, name: "+name+"}\ndisable: {repository: true}\nlabels: [{name: <bug>, color: d73a4a}]\n")
+		client.reconcile(context.Background(), ServeOptions{Config: config, Enforce: name == "api", History: history, Notifier: &recordingNotifier{}}, reconciliation{})
+	}
+
+	statuses, err := history.Statuses(context.Background())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(statuses) != 2 || statuses[0].LastApplied.IsZero() || !statuses[1].LastApplied.IsZero() {
+		t.Fatalf("Statuses are %+v, want acme/api applied and acme/web reported", statuses)
+	}
+
+	recorder := httptest.NewRecorder()
+	dashboardHandler(history)(recorder, httptest.NewRequest(http.MethodGet, DashboardPath, nil))
+	body := recorder.Body.String()
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Dashboard answered %d: %s", recorder.Code, body)
+	}
+
+	for _, want := range []string{"acme/api", "1 changes applied", "1 changes reported", "&#43; labels &lt;bug&gt;"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Dashboard does not contain %q:\n%s", want, body)
+		}
+	}
+
+	recorder = httptest.NewRecorder()
+	dashboardHandler(history)(recorder, httptest.NewRequest(http.MethodPost, DashboardPath, nil))
+
+	if recorder.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Dashboard answered %d to a post, want %d", recorder.Code, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestServeDashboardRequiresHistory(t *testing.T) {
+	_, client := newTestClient(t)
+
+	err := client.Serve(context.Background(), ServeOptions{Config: "settings.yml", Addr: "127.0.0.1:0", WebhookSecret: "secret", Dashboard: true})
+
+	if err == nil || !strings.Contains(err.Error(), "requires a history") {
+		t.Errorf("Serve of a dashboard without history failed with %v, want a refusal", err)
+	}
+}
@@ -0,0 +1,137 @@
+package github
+
+import (
+	"sort"
+	"strings"
+)
+
+// Values assigned by github to settings left out of a request
+const (
+	defaultLabelColor         = "ededed"
+	defaultWebhookContentType = contentTypeForm
+	defaultWebhookEvent       = "push"
+)
+
+// withServerDefaults fills the repository settings left unset with the values github keeps for them
+func (repo repository) withServerDefaults(githubRepo repository) repository {
+	if repo.DefaultBranch == "" {
+		repo.DefaultBranch = githubRepo.DefaultBranch
+	}
+
+	// An explicit visibility decides whether the repository is private, otherwise private decides its visibility
+	switch {
+	case repo.Visibility != "":
+		repo.Private = repo.Visibility != visibilityPublic
+	case repo.Private == githubRepo.Private:
+		repo.Visibility = githubRepo.Visibility
+	case repo.Private:
+		repo.Visibility = visibilityPrivate
+	default:
+		repo.Visibility = visibilityPublic
+	}
+
+	if repo.MergeCommitTitle == "" {
+		repo.MergeCommitTitle = githubRepo.MergeCommitTitle
+	}
+
+	if repo.MergeCommitMessage == "" {
+		repo.MergeCommitMessage = githubRepo.MergeCommitMessage
+	}
+
+	if repo.SquashMergeCommitTitle == "" {
+		repo.SquashMergeCommitTitle = githubRepo.SquashMergeCommitTitle
+	}
+
+	if repo.SquashMergeCommitMessage == "" {
+		repo.SquashMergeCommitMessage = githubRepo.SquashMergeCommitMessage
+	}
+
+	repo.Security = repo.Security.withServerDefaults(githubRepo.Security)
+
+	return repo
+}
+
+// withServerDefaults fills an unset color with the current color of the label or the color github assigns to new labels
+func (labelSetting label) withServerDefaults(githubLabel label) label {
+	if labelSetting.Color == "" {
+		labelSetting.Color = githubLabel.Color
+	}
+
+	if labelSetting.Color == "" {
+		labelSetting.Color = defaultLabelColor
+	}
+
+	return labelSetting
+}
+
+// withServerDefaults fills an unset content type and events with the current values of the webhook or github defaults
+func (webhookSettings webhook) withServerDefaults(githubWebhook webhook) webhook {
+	if webhookSettings.ContentType == "" {
+		webhookSettings.ContentType = githubWebhook.ContentType
+	}
+
+	if webhookSettings.ContentType == "" {
+		webhookSettings.ContentType = defaultWebhookContentType
+	}
+
+	if len(webhookSettings.Events) == 0 {
+		webhookSettings.Events = githubWebhook.Events
+	}
+
+	if len(webhookSettings.Events) == 0 {
+		webhookSettings.Events = []string{defaultWebhookEvent}
+	}
+
+	return webhookSettings
+}
+
+// withServerDefaults clears the review options github keeps but ignores when no approving review is required
+// and sorts the restrictions, it is applied to both the github and the configured protection so they compare equal
+func (branchSettings branch) withServerDefaults() branch {
+	reviews := &branchSettings.Protection.RequiredApprovingReviewCount
+
+	if reviews.RequiredApprovingReviewCount == 0 {
+		reviews.DismissStaleReviews = false
+		reviews.RequireCodeOwnerReviews = false
+		reviews.DismissalRestrictions = nil
+		reviews.BypassPullRequestAllowances = nil
+	}
+
+	reviews.DismissalRestrictions = reviews.DismissalRestrictions.normalize()
+	reviews.BypassPullRequestAllowances = reviews.BypassPullRequestAllowances.normalize()
+	branchSettings.Protection.Restrictions = branchSettings.Protection.Restrictions.normalize()
+	branchSettings.Protection.RequiredStatusChecks.Contexts = emptyToNil(branchSettings.Protection.RequiredStatusChecks.Contexts)
+
+	return branchSettings
+}
+
+// normalize returns a copy of the restrictions sorted the same way on both sides of a comparison
+// Logins are case insensitive and github returns them with their original case
+func (value *restrictions) normalize() *restrictions {
+	if value == nil {
+		return nil
+	}
+
+	users := []string{}
+
+	for _, user := range value.Users {
+		users = append(users, strings.ToLower(user))
+	}
+
+	return &restrictions{
+		Users: sortedOrNil(users),
+		Teams: sortedOrNil(value.Teams),
+		Apps:  sortedOrNil(value.Apps),
+	}
+}
+
+func sortedOrNil(values []string) []string {
+	if len(values) == 0 {
+		return nil
+	}
+
+	sorted := append([]string{}, values...)
+	sort.Strings(sorted)
+
+	return sorted
+}
@@ -0,0 +1,99 @@
+package github
+
+import (
+	"context"
+	"log"
+
+	"github.com/google/go-github/v75/github"
+	"github.com/pkg/errors"
+)
+
+// ResourceDependabotSecrets is the resource kind of the dependabot secrets, they are only available to the dependabot jobs
+const ResourceDependabotSecrets = "dependabotsecrets"
+
+func (client *Client) getDependabotSecrets(ctx context.Context, owner, name string) ([]secret, error) {
+	githubSecrets, err := listAll(func(opts github.ListOptions) ([]*github.Secret, *github.Response, error) {
+		page, response, err := client.github.Dependabot.ListRepoSecrets(ctx, owner, name, &opts)
+
+		if err != nil {
+			return nil, response, err
+		}
+
+		return page.Secrets, response, nil
+	})
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Error while listing dependabot secrets")
+	}
+
+	secrets := make([]secret, 0, len(githubSecrets))
+
+	for _, githubSecret := range githubSecrets {
+		secrets = append(secrets, secret{Name: githubSecret.Name, updatedAt: githubSecret.UpdatedAt.Time})
+	}
+
+	return secrets, nil
+}
+
+// planDependabotSecrets plans the dependabot secrets like the actions secrets: the values can't be read so the drift is the
+// missing and unexpected names and the secrets last written before their updatedafter
+func planDependabotSecrets(disabled bool, githubSecrets, secretsSettings []secret) []Change {
+	if disabled {
+		log.Print("[INFO] Skipping disabled repository dependabot secrets\n")
+		return nil
+	}
+
+	changes := planSecrets(false, githubSecrets, secretsSettings)
+
+	for i := range changes {
+		changes[i].Resource = ResourceDependabotSecrets
+	}
+
+	return changes
+}
+
+func (client *Client) updateDependabotSecret(ctx context.Context, report reporter, owner, name string, action Action, githubSecret, secretSettings secret) error {
+	if action == ActionDelete {
+		report.changed(ResourceDependabotSecrets, "Deleting dependabot secret %s\n", githubSecret.Name)
+
+		_, err := client.github.Dependabot.DeleteRepoSecret(ctx, owner, name, githubSecret.Name)
+
+		if err != nil {
+			return errors.Wrap(err, "Error deleting a dependabot secret\n")
+		}
+
+		return nil
+	}
+
+	value, err := client.secretValue(secretSettings)
+
+	if err != nil {
+		return err
+	}
+
+	report.changed(ResourceDependabotSecrets, "Writing dependabot secret %s\n", secretSettings.Name)
+
+	publicKey, _, err := client.github.Dependabot.GetRepoPublicKey(ctx, owner, name)
+
+	if err != nil {
+		return errors.Wrap(err, "Error getting the repository dependabot public key\n")
+	}
+
+	encryptedValue, err := encryptSecret(publicKey.GetKey(), value)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = client.github.Dependabot.CreateOrUpdateRepoSecret(ctx, owner, name, &github.DependabotEncryptedSecret{
+		Name:           secretSettings.Name,
+		KeyID:          publicKey.GetKeyID(),
+		EncryptedValue: encryptedValue,
+	})
+
+	if err != nil {
+		return errors.Wrap(err, "Error writing a dependabot secret\n")
+	}
+
+	return nil
+}
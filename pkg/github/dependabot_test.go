@@ -0,0 +1,89 @@
+package github
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestApplyWritesTheDependabotSecretsUpdatedBeforeTheirRotation(t *testing.T) {
+	server, client := newTestClient(t, WithSecretValues(map[string]string{"NPM_TOKEN": "rotated", "REGISTRY_TOKEN": "new"}))
+	repo := server.AddRepository("acme", "api")
+	repo.DependabotSecrets["NPM_TOKEN"] = "old"
+	repo.DependabotSecrets["GO_TOKEN"] = "old"
+	repo.Secrets["NPM_TOKEN"] = "actions"
+
+	content := `
+repository: {owner: acme, name: api}
+disable: {repository: true}
+dependabotsecrets:
+  - name: npm_token
+    updatedafter: 2026-03-01
+  - name: go_token
+    updatedafter: 2019-06-01
+  - name: registry_token
+`
+	plan := planOf(t, client, settingsFromYAML(t, content))
+	want := "dependabotsecrets update NPM_TOKEN,dependabotsecrets create REGISTRY_TOKEN"
+
+	if strings.Join(changeNames(plan), ",") != want {
+		t.Fatalf("Plan changes are %v, want %s", changeNames(plan), want)
+	}
+
+	if field := plan.Changes[0].Fields; len(field) != 1 || field[0].Before != "2020-01-01T00:00:00Z" || field[0].After != "after 2026-03-01" {
+		t.Errorf("Stale dependabot secret fields are %+v", field)
+	}
+
+	if _, err := client.Apply(context.Background(), settingsFromYAML(t, content)); err != nil {
+		t.Fatalf("Error applying settings: %v", err)
+	}
+
+	if repo.DependabotSecrets["NPM_TOKEN"] != "rotated" || repo.DependabotSecrets["GO_TOKEN"] != "old" || repo.DependabotSecrets["REGISTRY_TOKEN"] != "new" {
+		t.Errorf("Dependabot secret values are %v, want NPM_TOKEN rotated and REGISTRY_TOKEN created", repo.DependabotSecrets)
+	}
+
+	if repo.Secrets["NPM_TOKEN"] != "actions" {
+		t.Errorf("Actions secret NPM_TOKEN is %q, the dependabot secrets must not write it", repo.Secrets["NPM_TOKEN"])
+	}
+
+	if names := changeNames(planOf(t, client, settingsFromYAML(t, content))); len(names) != 0 {
+		t.Errorf("Expected no changes once applied, got %v", names)
+	}
+}
+
+func TestPlanWarnsAboutUnexpectedDependabotSecretsKept(t *testing.T) {
+	server, client := newTestClient(t)
+	repo := server.AddRepository("acme", "api")
+	repo.DependabotSecrets["LEGACY_TOKEN"] = "old"
+
+	settings := "repository: {owner: acme, name: api}\ndisable: {repository: true}\ndependabotsecrets: []\n"
+
+	if names := changeNames(planOf(t, client, settingsFromYAML(t, settings))); strings.Join(names, ",") != "dependabotsecrets delete LEGACY_TOKEN" {
+		t.Errorf("Plan changes are %v, want the unexpected dependabot secret deleted", names)
+	}
+
+	plan := planOf(t, client, settingsFromYAML(t, settings+"prune: {dependabotsecrets: false}\n"))
+
+	if !plan.Empty() {
+		t.Errorf("Plan without pruning has changes %v", changeNames(plan))
+	}
+
+	if len(plan.Warnings) != 1 || !strings.Contains(plan.Warnings[0], "secret LEGACY_TOKEN is not in the settings, it is kept since dependabotsecrets are not pruned") {
+		t.Errorf("Plan warnings are %v, want the unexpected dependabot secret", plan.Warnings)
+	}
+}
+
+func TestPlanLeavesTheDependabotSecretsUnmanagedWithoutSection(t *testing.T) {
+	server, client := newTestClient(t)
+	server.AddRepository("acme", "api").DependabotSecrets["LEGACY_TOKEN"] = "old"
+
+	if plan := planOf(t, client, settingsFromYAML(t, "repository: {owner: acme, name: api}\ndisable: {repository: true}\n")); !plan.Empty() {
+		t.Errorf("Plan without dependabot secrets section has changes %v", changeNames(plan))
+	}
+
+	for _, request := range server.Requests() {
+		if strings.Contains(request, "/dependabot/") {
+			t.Errorf("Unmanaged dependabot secrets fetched with %s", request)
+		}
+	}
+}
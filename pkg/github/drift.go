@@ -0,0 +1,80 @@
+package github
+
+import (
+	"sort"
+)
+
+// DriftEntry counts the repositories whose live settings drift the same way from their config
+// Updates are grouped by resource kind, field and values whatever the resource name (ex: the protection of main and master),
+// creations and deletions by resource kind and name
+type DriftEntry struct {
+	Resource string
+	Action   Action
+	// Setting is the updated field or the name of the resource missing or not declared
+	Setting string
+	// Live and Desired are the formatted values of the field, empty for creations and deletions
+	Live    string
+	Desired string
+	// Repositories are the full names of the repositories with this drift
+	Repositories []string
+}
+
+// DriftHeatmap aggregates the drift of many plans into how often each setting drifts, the most frequent first
+// The repositories that failed planning are ignored, the report only changes are counted like the others
+func DriftHeatmap(results []RepositoryResult) []DriftEntry {
+	entries := map[string]*DriftEntry{}
+
+	add := func(entry DriftEntry, repository string) {
+		key := entry.Resource + "/" + string(entry.Action) + "/" + entry.Setting + "/" + entry.Live + "/" + entry.Desired
+		existing, ok := entries[key]
+
+		if !ok {
+			existing = &entry
+			entries[key] = existing
+		}
+
+		existing.Repositories = appendDistinct(existing.Repositories, repository)
+	}
+
+	for _, result := range results {
+		if result.Err != nil || result.Plan == nil {
+			continue
+		}
+
+		for _, change := range result.Plan.Changes {
+			if change.Action != ActionUpdate || len(change.Fields) == 0 {
+				add(DriftEntry{Resource: change.Resource, Action: change.Action, Setting: change.Name}, result.Repository)
+				continue
+			}
+
+			for _, field := range change.Fields {
+				add(DriftEntry{Resource: change.Resource, Action: change.Action, Setting: field.Field, Live: formatValue(field.Before), Desired: formatValue(field.After)}, result.Repository)
+			}
+		}
+	}
+
+	heatmap := make([]DriftEntry, 0, len(entries))
+
+	for _, entry := range entries {
+		sort.Strings(entry.Repositories)
+		heatmap = append(heatmap, *entry)
+	}
+
+	sort.Slice(heatmap, func(i, j int) bool {
+		if len(heatmap[i].Repositories) != len(heatmap[j].Repositories) {
+			return len(heatmap[i].Repositories) > len(heatmap[j].Repositories)
+		}
+
+		if heatmap[i].Resource != heatmap[j].Resource {
+			return heatmap[i].Resource < heatmap[j].Resource
+		}
+
+		if heatmap[i].Setting != heatmap[j].Setting {
+			return heatmap[i].Setting < heatmap[j].Setting
+		}
+
+		return heatmap[i].Live < heatmap[j].Live
+	})
+
+	return heatmap
+}
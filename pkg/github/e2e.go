@@ -0,0 +1,158 @@
+package github
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// Names of the resources created by the end to end test
+const (
+	e2eName    = "github-settings-e2e"
+	e2eWebhook = "https://example.com/github-settings-e2e"
+	e2eSecret  = "GITHUB_SETTINGS_E2E"
+)
+
+// Steps of the end to end test
+const (
+	StepCreate  = "create"
+	StepUpdate  = "update"
+	StepDelete  = "delete"
+	StepCleanup = "cleanup"
+)
+
+// EndToEndStep is the outcome of a step of the end to end test
+type EndToEndStep struct {
+	Name   string
+	Result *Result
+	// Remaining is the plan computed again once the step is applied, it is empty when the step converged
+	Remaining *Plan
+	Err       error
+}
+
+// EndToEnd exercises the create, update and delete lifecycle of every resource kind on a sandbox repository
+// Each step is applied then planned again to check it converged. The last step restores the settings the
+// sandbox had before the test and removes the branch it created, it runs even when a previous step failed
+// Collaborators and teams are left out since they would invite real accounts
+func (client *Client) EndToEnd(ctx context.Context, owner, name string) ([]EndToEndStep, error) {
+	original, err := client.GetSettingsFromGithub(ctx, owner, name)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Error getting the settings of the sandbox repository")
+	}
+
+	original.Disable.Collaborators = true
+	original.Disable.Teams = true
+	original.Status = nil
+
+	sandbox := *client
+	sandbox.prune = true
+	sandbox.secretValues = map[string]string{e2eSecret: StepCreate}
+
+	steps := []EndToEndStep{sandbox.endToEndStep(ctx, StepCreate, e2eSettings(original, StepCreate))}
+
+	if steps[0].Err == nil {
+		sandbox.secretValues = map[string]string{e2eSecret: StepUpdate}
+		steps = append(steps, sandbox.endToEndStep(ctx, StepUpdate, e2eSettings(original, StepUpdate)))
+	}
+
+	steps = append(steps, sandbox.endToEndStep(ctx, StepDelete, original))
+
+	cleanup := EndToEndStep{Name: StepCleanup}
+	_, err = client.github.Git.DeleteRef(ctx, owner, name, "heads/"+e2eName)
+
+	if err != nil && !isNotFound(err) {
+		cleanup.Err = errors.Wrapf(err, "Error deleting branch %s", e2eName)
+	}
+
+	return append(steps, cleanup), nil
+}
+
+func (client *Client) endToEndStep(ctx context.Context, name string, settings *Settings) EndToEndStep {
+	step := EndToEndStep{Name: name}
+	step.Result, step.Err = client.Apply(ctx, settings)
+
+	if step.Err != nil {
+		return step
+	}
+
+	step.Remaining, step.Err = client.Plan(ctx, settings)
+
+	if step.Err != nil {
+		return step
+	}
+
+	remaining := 0
+
+	for _, change := range step.Remaining.Changes {
+		// Overwritten secrets are rewritten on every apply since their value can't be compared
+		if change.Resource != ResourceSecrets || change.Action != ActionUpdate {
+			remaining++
+		}
+	}
+
+	if remaining != 0 {
+		step.Err = errors.Errorf("Error %d changes remain after the %s step", remaining, name)
+	}
+
+	return step
+}
+
+// e2eSettings adds the resources of the end to end test to the original settings of the sandbox, the update
+// step changes a field of each of them
+func e2eSettings(original *Settings, step string) *Settings {
+	settings := *original
+	updated := step == StepUpdate
+
+	settings.Repository.Description = fmt.Sprintf("%s %s", e2eName, step)
+
+	e2eLabel := label{Name: e2eName, Description: step, Color: "0e8a16"}
+
+	if updated {
+		e2eLabel.Color = "d73a4a"
+	}
+
+	settings.Labels = append(append([]label{}, original.Labels...), e2eLabel)
+
+	e2eBranch := branch{Name: e2eName, Protection: protection{Enabled: true}}
+
+	if updated {
+		e2eBranch.Protection.EnforceAdmins = true
+		e2eBranch.Protection.RequiredApprovingReviewCount.RequiredApprovingReviewCount = 1
+		e2eBranch.Protection.RequiredStatusChecks = requiredStatusChecks{Strict: true, Contexts: []string{e2eName}}
+	}
+
+	settings.Branches = append(append([]branch{}, original.Branches...), e2eBranch)
+
+	e2eWebhookSettings := webhook{URL: e2eWebhook, ContentType: contentTypeJSON, Events: []string{"push"}}
+
+	if updated {
+		e2eWebhookSettings.Events = []string{"pull_request", "push"}
+	}
+
+	settings.Webhooks = append(append([]webhook{}, original.Webhooks...), e2eWebhookSettings)
+	settings.Topics = append(append([]string{}, original.Topics...), e2eName)
+	settings.Secrets = append(append([]secret{}, original.Secrets...), secret{Name: e2eSecret, Overwrite: updated})
+	settings.Variables = append(append([]variable{}, original.Variables...), variable{Name: e2eSecret, Value: step})
+
+	e2eEnvironment := environment{Name: e2eName, Variables: []variable{{Name: e2eSecret, Value: step}}}
+
+	if updated {
+		e2eEnvironment.WaitTimer = 1
+		e2eEnvironment.DeploymentBranchPolicy.ProtectedBranches = true
+	}
+
+	settings.Environments = append(append([]environment{}, original.Environments...), e2eEnvironment)
+
+	e2eRuleset := ruleset{Name: e2eName, Branches: []string{e2eName + "/**"}, Rules: rulesetRules{Deletion: true}}
+
+	if updated {
+		e2eRuleset.Rules.NonFastForward = true
+		e2eRuleset.Rules.PullRequest = rulesetPullRequest{Required: true, RequiredApprovingReviewCount: 1}
+	}
+
+	settings.Rulesets = append(append([]ruleset{}, original.Rulesets...), e2eRuleset)
+
+	return &settings
+}
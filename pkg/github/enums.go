@@ -0,0 +1,374 @@
+package github
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// contentType is the payload format of a webhook
+type contentType string
+
+const (
+	contentTypeJSON contentType = "json"
+	contentTypeForm contentType = "form"
+)
+
+// UnmarshalYAML rejects unknown webhook content types when parsing the settings
+func (value *contentType) UnmarshalYAML(node *yaml.Node) error {
+	parsed, err := unmarshalEnum(node, "webhook content type", string(contentTypeJSON), string(contentTypeForm))
+
+	if err != nil {
+		return err
+	}
+
+	*value = contentType(parsed)
+
+	return nil
+}
+
+// matchBy is the key used to match a configured webhook with an existing github webhook
+type matchBy string
+
+const (
+	matchByURL  matchBy = "url"
+	matchByID   matchBy = "id"
+	matchByName matchBy = "name"
+)
+
+// UnmarshalYAML rejects unknown webhook matching keys when parsing the settings
+func (value *matchBy) UnmarshalYAML(node *yaml.Node) error {
+	parsed, err := unmarshalEnum(node, "webhook match key", string(matchByURL), string(matchByID), string(matchByName))
+
+	if err != nil {
+		return err
+	}
+
+	*value = matchBy(parsed)
+
+	return nil
+}
+
+// annotations are embedded in the settings of every resource
+type annotations struct {
+	// Enforcement set to report only plans the changes of the resource, apply leaves it as it is
+	Enforcement enforcement `yaml:",omitempty"`
+	// Reason and Ticket annotate the changes of the resource in the plan, the apply log and the notifications
+	Reason string `yaml:",omitempty"`
+	Ticket string `yaml:",omitempty"`
+}
+
+// enforcement selects whether the changes of a resource are applied or only reported
+type enforcement string
+
+const (
+	enforcementEnforce enforcement = "enforce"
+	enforcementReport  enforcement = "report"
+)
+
+// UnmarshalYAML rejects unknown enforcement modes when parsing the settings
+func (value *enforcement) UnmarshalYAML(node *yaml.Node) error {
+	parsed, err := unmarshalEnum(node, "enforcement", string(enforcementEnforce), string(enforcementReport))
+
+	if err != nil {
+		return err
+	}
+
+	*value = enforcement(parsed)
+
+	return nil
+}
+
+// rulesetMode selects whether the rules of a ruleset are enforced by github
+type rulesetMode string
+
+const (
+	rulesetModeActive   rulesetMode = "active"
+	rulesetModeEvaluate rulesetMode = "evaluate"
+	rulesetModeDisabled rulesetMode = "disabled"
+)
+
+// UnmarshalYAML rejects unknown ruleset modes when parsing the settings
+func (value *rulesetMode) UnmarshalYAML(node *yaml.Node) error {
+	parsed, err := unmarshalEnum(node, "ruleset mode", string(rulesetModeActive), string(rulesetModeEvaluate), string(rulesetModeDisabled))
+
+	if err != nil {
+		return err
+	}
+
+	*value = rulesetMode(parsed)
+
+	return nil
+}
+
+// bypassMode selects when a bypass actor may bypass the rules of a ruleset
+type bypassMode string
+
+const (
+	bypassModeAlways      bypassMode = "always"
+	bypassModePullRequest bypassMode = "pull_request"
+)
+
+// UnmarshalYAML rejects unknown bypass modes when parsing the settings
+func (value *bypassMode) UnmarshalYAML(node *yaml.Node) error {
+	parsed, err := unmarshalEnum(node, "bypass mode", string(bypassModeAlways), string(bypassModePullRequest))
+
+	if err != nil {
+		return err
+	}
+
+	*value = bypassMode(parsed)
+
+	return nil
+}
+
+// visibility is who can see a repository, internal repositories are visible to the members of the enterprise
+type visibility string
+
+const (
+	visibilityPublic   visibility = "public"
+	visibilityPrivate  visibility = "private"
+	visibilityInternal visibility = "internal"
+)
+
+// UnmarshalYAML rejects unknown repository visibilities when parsing the settings
+func (value *visibility) UnmarshalYAML(node *yaml.Node) error {
+	parsed, err := unmarshalEnum(node, "visibility", string(visibilityPublic), string(visibilityPrivate), string(visibilityInternal))
+
+	if err != nil {
+		return err
+	}
+
+	*value = visibility(parsed)
+
+	return nil
+}
+
+// mergeCommitTitle is the default title of merge commits
+type mergeCommitTitle string
+
+const (
+	mergeCommitTitlePRTitle      mergeCommitTitle = "PR_TITLE"
+	mergeCommitTitleMergeMessage mergeCommitTitle = "MERGE_MESSAGE"
+)
+
+// UnmarshalYAML rejects unknown merge commit titles when parsing the settings
+func (value *mergeCommitTitle) UnmarshalYAML(node *yaml.Node) error {
+	parsed, err := unmarshalEnum(node, "merge commit title", string(mergeCommitTitlePRTitle), string(mergeCommitTitleMergeMessage))
+
+	if err != nil {
+		return err
+	}
+
+	*value = mergeCommitTitle(parsed)
+
+	return nil
+}
+
+// mergeCommitMessage is the default message of merge commits
+type mergeCommitMessage string
+
+const (
+	mergeCommitMessagePRBody  mergeCommitMessage = "PR_BODY"
+	mergeCommitMessagePRTitle mergeCommitMessage = "PR_TITLE"
+	mergeCommitMessageBlank   mergeCommitMessage = "BLANK"
+)
+
+// UnmarshalYAML rejects unknown merge commit messages when parsing the settings
+func (value *mergeCommitMessage) UnmarshalYAML(node *yaml.Node) error {
+	parsed, err := unmarshalEnum(node, "merge commit message", string(mergeCommitMessagePRBody), string(mergeCommitMessagePRTitle), string(mergeCommitMessageBlank))
+
+	if err != nil {
+		return err
+	}
+
+	*value = mergeCommitMessage(parsed)
+
+	return nil
+}
+
+// squashMergeCommitTitle is the default title of squash merge commits
+type squashMergeCommitTitle string
+
+const (
+	squashMergeCommitTitlePRTitle         squashMergeCommitTitle = "PR_TITLE"
+	squashMergeCommitTitleCommitOrPRTitle squashMergeCommitTitle = "COMMIT_OR_PR_TITLE"
+)
+
+// UnmarshalYAML rejects unknown squash merge commit titles when parsing the settings
+func (value *squashMergeCommitTitle) UnmarshalYAML(node *yaml.Node) error {
+	parsed, err := unmarshalEnum(node, "squash merge commit title", string(squashMergeCommitTitlePRTitle), string(squashMergeCommitTitleCommitOrPRTitle))
+
+	if err != nil {
+		return err
+	}
+
+	*value = squashMergeCommitTitle(parsed)
+
+	return nil
+}
+
+// squashMergeCommitMessage is the default message of squash merge commits
+type squashMergeCommitMessage string
+
+const (
+	squashMergeCommitMessagePRBody         squashMergeCommitMessage = "PR_BODY"
+	squashMergeCommitMessageCommitMessages squashMergeCommitMessage = "COMMIT_MESSAGES"
+	squashMergeCommitMessageBlank          squashMergeCommitMessage = "BLANK"
+)
+
+// UnmarshalYAML rejects unknown squash merge commit messages when parsing the settings
+func (value *squashMergeCommitMessage) UnmarshalYAML(node *yaml.Node) error {
+	parsed, err := unmarshalEnum(node, "squash merge commit message", string(squashMergeCommitMessagePRBody), string(squashMergeCommitMessageCommitMessages), string(squashMergeCommitMessageBlank))
+
+	if err != nil {
+		return err
+	}
+
+	*value = squashMergeCommitMessage(parsed)
+
+	return nil
+}
+
+// unmarshalEnum parses a string and validates it against the allowed values, an empty value is left to github defaults
+func unmarshalEnum(node *yaml.Node, kind string, allowed ...string) (string, error) {
+	var value string
+	err := node.Decode(&value)
+
+	if err != nil {
+		return "", errors.Wrapf(err, "Error while unmarshal %s", kind)
+	}
+
+	if value == "" {
+		return value, nil
+	}
+
+	for _, allowedValue := range allowed {
+		if value == allowedValue {
+			return value, nil
+		}
+	}
+
+	return "", errors.Errorf("Invalid %s %q, did you mean %q? (allowed values: %s)", kind, value, closest(value, allowed), strings.Join(allowed, ", "))
+}
+
+// closest returns the allowed value with the smallest edit distance to value
+func closest(value string, allowed []string) string {
+	best := ""
+	bestDistance := -1
+
+	for _, allowedValue := range allowed {
+		distance := levenshtein(strings.ToLower(value), allowedValue)
+
+		if bestDistance == -1 || distance < bestDistance {
+			best = allowedValue
+			bestDistance = distance
+		}
+	}
+
+	return best
+}
+
+func levenshtein(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+
+		previous, current = current, previous
+	}
+
+	return previous[len(b)]
+}
+
+// permission is the access level granted to a collaborator or a team, a built-in level or the name of a custom repository role of the org
+type permission string
+
+const (
+	permissionPull     permission = "pull"
+	permissionTriage   permission = "triage"
+	permissionPush     permission = "push"
+	permissionMaintain permission = "maintain"
+	permissionAdmin    permission = "admin"
+)
+
+// permissionAliases are the role names github returns and accepts for the built-in permissions
+// nolint:gochecknoglobals
+var permissionAliases = map[string]permission{
+	"read":  permissionPull,
+	"write": permissionPush,
+}
+
+// UnmarshalYAML normalizes the built-in permissions and rejects the values one typo away from them,
+// the other values are custom repository roles checked against the roles of the org when planning
+func (value *permission) UnmarshalYAML(node *yaml.Node) error {
+	var parsed string
+	err := node.Decode(&parsed)
+
+	if err != nil {
+		return errors.Wrap(err, "Error while unmarshal permission")
+	}
+
+	lowered := strings.ToLower(parsed)
+
+	if alias, ok := permissionAliases[lowered]; ok {
+		*value = alias
+		return nil
+	}
+
+	if builtinPermission(permission(lowered)) {
+		*value = permission(lowered)
+		return nil
+	}
+
+	builtins := []string{string(permissionPull), string(permissionTriage), string(permissionPush), string(permissionMaintain), string(permissionAdmin)}
+
+	if suggestion := closest(lowered, builtins); levenshtein(lowered, suggestion) <= maxPermissionTypo {
+		return errors.Errorf("Invalid permission %q, did you mean %q? (built-in permissions: %s, or the name of a custom repository role)", parsed, suggestion, strings.Join(builtins, ", "))
+	}
+
+	*value = permission(parsed)
+
+	return nil
+}
+
+// maxPermissionTypo is the edit distance under which a permission is taken for a misspelled built-in permission rather than a custom role
+const maxPermissionTypo = 1
+
+// builtinPermission returns true for the permissions every repository has, the other permissions are custom roles of the org
+func builtinPermission(value permission) bool {
+	switch value {
+	case "", permissionPull, permissionTriage, permissionPush, permissionMaintain, permissionAdmin:
+		return true
+	default:
+		return false
+	}
+}
+
+// toPermission converts the role names returned by github (read, write) to permissions
+func toPermission(role string) permission {
+	switch role {
+	case "read":
+		return permissionPull
+	case "write":
+		return permissionPush
+	default:
+		return permission(role)
+	}
+}
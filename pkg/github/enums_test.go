@@ -0,0 +1,100 @@
+package github
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEnumsRejectInvalidValues(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{"webhooks: [{url: https://x, contenttype: jsn}]", `Invalid webhook content type "jsn", did you mean "json"?`},
+		{"repository: {visibility: internl}", `Invalid visibility "internl", did you mean "internal"?`},
+		{"repository: {mergecommittitle: PR_TITEL}", `Invalid merge commit title "PR_TITEL", did you mean "PR_TITLE"?`},
+		{"repository: {squashmergecommitmessage: blank}", `Invalid squash merge commit message "blank", did you mean "BLANK"?`},
+		{"collaborators: [{username: alice, permission: admn}]", `Invalid permission "admn", did you mean "admin"?`},
+		{"teams: [{slug: platform, permission: mantain}]", `Invalid permission "mantain", did you mean "maintain"?`},
+	}
+
+	for _, test := range tests {
+		_, err := GetSettingsFromBytes([]byte(test.content))
+
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("Parsing %q failed with %v, want %q", test.content, err, test.want)
+		}
+	}
+}
+
+func TestPermissionNormalizesBuiltinPermissions(t *testing.T) {
+	settings, err := GetSettingsFromBytes([]byte(`
+collaborators:
+  - {username: alice, permission: write}
+  - {username: bob, permission: Read}
+  - {username: carol, permission: MAINTAIN}
+  - {username: dave, permission: security-auditor}
+`))
+
+	if err != nil {
+		t.Fatalf("Error parsing settings: %v", err)
+	}
+
+	want := []permission{permissionPush, permissionPull, permissionMaintain, "security-auditor"}
+
+	for i, collaboratorSettings := range settings.Collaborators {
+		if collaboratorSettings.Permission != want[i] {
+			t.Errorf("Permission of %s is %q, want %q", collaboratorSettings.Username, collaboratorSettings.Permission, want[i])
+		}
+	}
+}
+
+func TestRepositoryVisibilityFollowsPrivate(t *testing.T) {
+	githubRepo := repository{Private: true, Visibility: visibilityInternal}
+
+	tests := []struct {
+		repo repository
+		want repository
+	}{
+		{repository{Private: true}, repository{Private: true, Visibility: visibilityInternal}},
+		{repository{Private: false}, repository{Private: false, Visibility: visibilityPublic}},
+		{repository{Visibility: visibilityPrivate}, repository{Private: true, Visibility: visibilityPrivate}},
+		{repository{Private: true, Visibility: visibilityPublic}, repository{Private: false, Visibility: visibilityPublic}},
+	}
+
+	for _, test := range tests {
+		got := test.repo.withServerDefaults(githubRepo)
+
+		if got.Private != test.want.Private || got.Visibility != test.want.Visibility {
+			t.Errorf("Repository %+v has private %t and visibility %q, want %t and %q", test.repo, got.Private, got.Visibility, test.want.Private, test.want.Visibility)
+		}
+	}
+}
+
+func TestAnnotationsAreInlinedInTheResources(t *testing.T) {
+	content := "labels: [{name: bug, enforcement: report, reason: triage, ticket: OPS-42}]"
+	problems, err := Validate([]byte(content))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(problems) != 0 {
+		t.Errorf("Expected the annotations to be valid, got %v", problems)
+	}
+
+	settings := settingsFromYAML(t, content)
+
+	if bug := settings.Labels[0]; bug.Enforcement != enforcementReport || bug.Reason != "triage" || bug.Ticket != "OPS-42" {
+		t.Errorf("Expected the annotations of bug, got %+v", bug)
+	}
+
+	properties := schemaOf(reflect.TypeOf(label{}))["properties"].(map[string]interface{})
+
+	for _, name := range []string{"enforcement", "reason", "ticket"} {
+		if _, ok := properties[name]; !ok {
+			t.Errorf("Expected %s in the schema of the labels", name)
+		}
+	}
+}
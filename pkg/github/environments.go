@@ -0,0 +1,516 @@
+package github
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"github.com/google/go-github/v75/github"
+	"github.com/michaelmass/github-settings/pkg/diff"
+	"github.com/pkg/errors"
+)
+
+// Resource kinds of the deployment environments, environment secrets and variables are named environment/NAME
+const (
+	ResourceEnvironments         = "environments"
+	ResourceEnvironmentSecrets   = "environmentsecrets"
+	ResourceEnvironmentVariables = "environmentvariables"
+)
+
+// Protection rule types of an environment returned by github
+const (
+	protectionRuleWaitTimer = "wait_timer"
+	protectionRuleReviewers = "required_reviewers"
+)
+
+// deploymentPolicyBranch is the type of the deployment policies matching branch names, the others match tags
+const deploymentPolicyBranch = "branch"
+
+// environment is a deployment environment, its secrets and variables are only available to the jobs deploying to it
+type environment struct {
+	Name string
+	// WaitTimer is the number of minutes a deployment waits before it starts
+	WaitTimer int
+	// Reviewers approve the deployments, deployments start without approval when empty
+	Reviewers reviewers
+	// DeploymentBranchPolicy limits the branches allowed to deploy, every branch can deploy by default
+	DeploymentBranchPolicy deploymentBranchPolicy
+	// Secrets and Variables of the environment are planned as their own resources
+	Secrets     []secret   `yaml:",omitempty" diff:"-"`
+	Variables   []variable `yaml:",omitempty" diff:"-"`
+	annotations `yaml:",inline" diff:"-"`
+}
+
+type reviewers struct {
+	Users []string `yaml:",omitempty"`
+	Teams []string `yaml:",omitempty"`
+}
+
+type deploymentBranchPolicy struct {
+	// ProtectedBranches only accepts deployments from protected branches
+	ProtectedBranches bool
+	// Branches only accepts deployments from the branches matching one of the name patterns (ex: release/*)
+	Branches []string `yaml:",omitempty"`
+}
+
+// environmentSecret is a secret of an environment, the environment name is needed to write it
+type environmentSecret struct {
+	environment string
+	secret      secret
+}
+
+// environmentVariable is a variable of an environment, the environment name is needed to write it
+type environmentVariable struct {
+	environment string
+	variable    variable
+}
+
+// withServerDefaults sorts the reviewers and lowercases the users so both sides of a comparison match
+func (environmentSettings environment) withServerDefaults() environment {
+	users := []string{}
+
+	for _, user := range environmentSettings.Reviewers.Users {
+		users = append(users, strings.ToLower(user))
+	}
+
+	environmentSettings.Reviewers = reviewers{
+		Users: sortedOrNil(users),
+		Teams: sortedOrNil(environmentSettings.Reviewers.Teams),
+	}
+	environmentSettings.DeploymentBranchPolicy.Branches = sortedOrNil(environmentSettings.DeploymentBranchPolicy.Branches)
+
+	return environmentSettings
+}
+
+func (client *Client) getEnvironments(ctx context.Context, owner, name string, repoID int64) ([]environment, error) {
+	githubEnvironments, err := listAll(func(opts github.ListOptions) ([]*github.Environment, *github.Response, error) {
+		page, response, err := client.github.Repositories.ListEnvironments(ctx, owner, name, &github.EnvironmentListOptions{ListOptions: opts})
+
+		if err != nil {
+			return nil, response, err
+		}
+
+		return page.Environments, response, nil
+	})
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Error while listing environments")
+	}
+
+	environments := make([]environment, 0, len(githubEnvironments))
+
+	for _, githubEnvironment := range githubEnvironments {
+		environmentSettings := environment{
+			Name: githubEnvironment.GetName(),
+			DeploymentBranchPolicy: deploymentBranchPolicy{
+				ProtectedBranches: githubEnvironment.GetDeploymentBranchPolicy().GetProtectedBranches(),
+			},
+		}
+
+		if githubEnvironment.GetDeploymentBranchPolicy().GetCustomBranchPolicies() {
+			environmentSettings.DeploymentBranchPolicy.Branches, err = client.getDeploymentBranches(ctx, owner, name, environmentSettings.Name)
+
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		for _, rule := range githubEnvironment.ProtectionRules {
+			switch rule.GetType() {
+			case protectionRuleWaitTimer:
+				environmentSettings.WaitTimer = rule.GetWaitTimer()
+			case protectionRuleReviewers:
+				for _, reviewer := range rule.Reviewers {
+					switch typed := reviewer.Reviewer.(type) {
+					case *github.User:
+						environmentSettings.Reviewers.Users = append(environmentSettings.Reviewers.Users, typed.GetLogin())
+					case *github.Team:
+						environmentSettings.Reviewers.Teams = append(environmentSettings.Reviewers.Teams, typed.GetSlug())
+					}
+				}
+			}
+		}
+
+		environmentSettings.Secrets, err = client.getEnvironmentSecrets(ctx, repoID, environmentSettings.Name)
+
+		if err != nil {
+			return nil, err
+		}
+
+		environmentSettings.Variables, err = client.getEnvironmentVariables(ctx, owner, name, environmentSettings.Name)
+
+		if err != nil {
+			return nil, err
+		}
+
+		environments = append(environments, environmentSettings)
+	}
+
+	return environments, nil
+}
+
+// getDeploymentBranches returns the name patterns of the branches allowed to deploy to an environment
+func (client *Client) getDeploymentBranches(ctx context.Context, owner, name, environmentName string) ([]string, error) {
+	policies, err := client.listDeploymentBranchPolicies(ctx, owner, name, environmentName)
+
+	if err != nil {
+		return nil, err
+	}
+
+	branches := []string{}
+
+	for _, policy := range policies {
+		branches = append(branches, policy.GetName())
+	}
+
+	return branches, nil
+}
+
+// listDeploymentBranchPolicies returns the branch policies of an environment, the tag policies are left alone
+func (client *Client) listDeploymentBranchPolicies(ctx context.Context, owner, name, environmentName string) ([]*github.DeploymentBranchPolicy, error) {
+	response, _, err := client.github.Repositories.ListDeploymentBranchPolicies(ctx, owner, name, environmentName)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error while listing deployment branch policies of environment %s", environmentName)
+	}
+
+	policies := []*github.DeploymentBranchPolicy{}
+
+	for _, policy := range response.BranchPolicies {
+		if policy.GetType() == "" || policy.GetType() == deploymentPolicyBranch {
+			policies = append(policies, policy)
+		}
+	}
+
+	return policies, nil
+}
+
+func (client *Client) getEnvironmentSecrets(ctx context.Context, repoID int64, environmentName string) ([]secret, error) {
+	githubSecrets, err := listAll(func(opts github.ListOptions) ([]*github.Secret, *github.Response, error) {
+		page, response, err := client.github.Actions.ListEnvSecrets(ctx, int(repoID), environmentName, &opts)
+
+		if err != nil {
+			return nil, response, err
+		}
+
+		return page.Secrets, response, nil
+	})
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error while listing secrets of environment %s", environmentName)
+	}
+
+	secrets := make([]secret, 0, len(githubSecrets))
+
+	for _, githubSecret := range githubSecrets {
+		secrets = append(secrets, secret{Name: githubSecret.Name, updatedAt: githubSecret.UpdatedAt.Time})
+	}
+
+	return secrets, nil
+}
+
+func (client *Client) getEnvironmentVariables(ctx context.Context, owner, name, environmentName string) ([]variable, error) {
+	githubVariables, err := listAll(func(opts github.ListOptions) ([]*github.ActionsVariable, *github.Response, error) {
+		page, response, err := client.github.Actions.ListEnvVariables(ctx, owner, name, environmentName, &opts)
+
+		if err != nil {
+			return nil, response, err
+		}
+
+		return page.Variables, response, nil
+	})
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error while listing variables of environment %s", environmentName)
+	}
+
+	variables := make([]variable, 0, len(githubVariables))
+
+	for _, githubVariable := range githubVariables {
+		variables = append(variables, variable{Name: githubVariable.Name, Value: githubVariable.Value})
+	}
+
+	return variables, nil
+}
+
+func planEnvironments(disabled bool, githubEnvironments, environmentsSettings []environment) []Change {
+	if disabled {
+		log.Print("[INFO] Skipping disabled repository environments\n")
+		return nil
+	}
+
+	// Without an environments section the environments are left alone, an empty list removes them all
+	if environmentsSettings == nil {
+		log.Print("[INFO] Skipping unmanaged repository environments\n")
+		return nil
+	}
+
+	changes := []Change{}
+	environmentsToUpdate := []Change{}
+	// Secrets and variables are planned once their environment exists
+	contentChanges := []Change{}
+	deleteEnvironmentsMap := map[string]environment{}
+
+	for _, githubEnvironment := range githubEnvironments {
+		deleteEnvironmentsMap[githubEnvironment.Name] = githubEnvironment
+	}
+
+	for _, environmentSettings := range environmentsSettings {
+		environmentSettings = environmentSettings.withServerDefaults()
+		githubEnvironment, ok := deleteEnvironmentsMap[environmentSettings.Name]
+
+		if !ok {
+			changes = append(changes, newChange(ResourceEnvironments, environmentSettings.Name, ActionCreate, environment{}, environmentSettings))
+		} else {
+			delete(deleteEnvironmentsMap, environmentSettings.Name)
+			githubEnvironment = githubEnvironment.withServerDefaults()
+
+			if !diff.Equal(githubEnvironment, environmentSettings) {
+				environmentsToUpdate = append(environmentsToUpdate, newChange(ResourceEnvironments, environmentSettings.Name, ActionUpdate, githubEnvironment, environmentSettings))
+			}
+		}
+
+		// The secrets and variables of an environment are only managed when declared, like the repository ones
+		for _, change := range planSecrets(false, githubEnvironment.Secrets, environmentSettings.Secrets) {
+			change.Resource = ResourceEnvironmentSecrets
+			change.Name = environmentSettings.Name + "/" + change.Name
+			change.current = environmentSecret{environment: environmentSettings.Name, secret: change.current.(secret)}
+			change.desired = environmentSecret{environment: environmentSettings.Name, secret: change.desired.(secret)}
+			contentChanges = append(contentChanges, change)
+		}
+
+		for _, change := range planVariables(false, githubEnvironment.Variables, environmentSettings.Variables) {
+			change.Resource = ResourceEnvironmentVariables
+			change.Name = environmentSettings.Name + "/" + change.Name
+			change.current = environmentVariable{environment: environmentSettings.Name, variable: change.current.(variable)}
+			change.desired = environmentVariable{environment: environmentSettings.Name, variable: change.desired.(variable)}
+			contentChanges = append(contentChanges, change)
+		}
+	}
+
+	// Deleting an environment deletes its secrets and variables
+	for _, environmentToDeleteName := range sortedKeys(deleteEnvironmentsMap) {
+		environmentToDelete := deleteEnvironmentsMap[environmentToDeleteName]
+		changes = append(changes, newChange(ResourceEnvironments, environmentToDeleteName, ActionDelete, environmentToDelete, environment{}))
+	}
+
+	changes = append(changes, environmentsToUpdate...)
+
+	return append(changes, contentChanges...)
+}
+
+func (client *Client) updateEnvironment(ctx context.Context, report reporter, owner, name string, action Action, githubEnvironment, environmentSettings environment) error {
+	if action == ActionDelete {
+		report.changed(ResourceEnvironments, "Deleting environment %s\n", githubEnvironment.Name)
+
+		_, err := client.github.Repositories.DeleteEnvironment(ctx, owner, name, githubEnvironment.Name)
+
+		if err != nil {
+			return errors.Wrap(err, "Error deleting an environment\n")
+		}
+
+		return nil
+	}
+
+	environmentReviewers, err := client.environmentReviewers(ctx, owner, environmentSettings.Reviewers)
+
+	if err != nil {
+		return err
+	}
+
+	if action == ActionCreate {
+		report.changed(ResourceEnvironments, "Creating environment %s\n", environmentSettings.Name)
+	} else {
+		report.changed(ResourceEnvironments, "Updating environment %s\n", environmentSettings.Name)
+	}
+
+	var branchPolicy *github.BranchPolicy
+
+	switch {
+	case len(environmentSettings.DeploymentBranchPolicy.Branches) != 0:
+		branchPolicy = &github.BranchPolicy{
+			ProtectedBranches:    github.Bool(false),
+			CustomBranchPolicies: github.Bool(true),
+		}
+	case environmentSettings.DeploymentBranchPolicy.ProtectedBranches:
+		branchPolicy = &github.BranchPolicy{
+			ProtectedBranches:    github.Bool(true),
+			CustomBranchPolicies: github.Bool(false),
+		}
+	}
+
+	_, _, err = client.github.Repositories.CreateUpdateEnvironment(ctx, owner, name, environmentSettings.Name, &github.CreateUpdateEnvironment{
+		WaitTimer:              github.Int(environmentSettings.WaitTimer),
+		Reviewers:              environmentReviewers,
+		DeploymentBranchPolicy: branchPolicy,
+	})
+
+	if err != nil {
+		return errors.Wrap(err, "Error writing an environment\n")
+	}
+
+	if len(environmentSettings.DeploymentBranchPolicy.Branches) == 0 {
+		return nil
+	}
+
+	return client.updateDeploymentBranches(ctx, owner, name, environmentSettings.Name, environmentSettings.DeploymentBranchPolicy.Branches)
+}
+
+// updateDeploymentBranches creates the missing branch policies of an environment and deletes the others
+func (client *Client) updateDeploymentBranches(ctx context.Context, owner, name, environmentName string, branches []string) error {
+	policies, err := client.listDeploymentBranchPolicies(ctx, owner, name, environmentName)
+
+	if err != nil {
+		return errors.Wrap(err, "Error writing an environment\n")
+	}
+
+	deletePoliciesMap := map[string]*github.DeploymentBranchPolicy{}
+
+	for _, policy := range policies {
+		deletePoliciesMap[policy.GetName()] = policy
+	}
+
+	for _, branch := range branches {
+		if _, ok := deletePoliciesMap[branch]; ok {
+			delete(deletePoliciesMap, branch)
+			continue
+		}
+
+		_, _, err := client.github.Repositories.CreateDeploymentBranchPolicy(ctx, owner, name, environmentName, &github.DeploymentBranchPolicyRequest{
+			Name: github.String(branch),
+			Type: github.String(deploymentPolicyBranch),
+		})
+
+		if err != nil {
+			return errors.Wrapf(err, "Error creating deployment branch policy %s\n", branch)
+		}
+	}
+
+	for _, branch := range sortedKeys(deletePoliciesMap) {
+		_, err := client.github.Repositories.DeleteDeploymentBranchPolicy(ctx, owner, name, environmentName, deletePoliciesMap[branch].GetID())
+
+		if err != nil {
+			return errors.Wrapf(err, "Error deleting deployment branch policy %s\n", branch)
+		}
+	}
+
+	return nil
+}
+
+// environmentReviewers resolves the ids of the users and teams reviewing the deployments of an environment
+func (client *Client) environmentReviewers(ctx context.Context, owner string, reviewersSettings reviewers) ([]*github.EnvReviewers, error) {
+	environmentReviewers := []*github.EnvReviewers{}
+
+	for _, login := range reviewersSettings.Users {
+		id, err := client.userID(ctx, login)
+
+		if err != nil {
+			return nil, errors.Wrapf(err, "Error getting reviewer %s\n", login)
+		}
+
+		environmentReviewers = append(environmentReviewers, &github.EnvReviewers{Type: github.String("User"), ID: github.Int64(id)})
+	}
+
+	for _, slug := range reviewersSettings.Teams {
+		id, err := client.teamID(ctx, owner, slug)
+
+		if err != nil {
+			return nil, errors.Wrapf(err, "Error getting reviewer team %s\n", slug)
+		}
+
+		environmentReviewers = append(environmentReviewers, &github.EnvReviewers{Type: github.String("Team"), ID: github.Int64(id)})
+	}
+
+	return environmentReviewers, nil
+}
+
+func (client *Client) updateEnvironmentSecret(ctx context.Context, report reporter, owner, name string, action Action, githubSecret, secretSettings environmentSecret) error {
+	githubRepo, _, err := client.github.Repositories.Get(ctx, owner, name)
+
+	if err != nil {
+		return errors.Wrap(err, "Error while getting repository from github\n")
+	}
+
+	repoID := int(githubRepo.GetID())
+
+	if action == ActionDelete {
+		report.changed(ResourceEnvironmentSecrets, "Deleting secret %s of environment %s\n", githubSecret.secret.Name, githubSecret.environment)
+
+		_, err := client.github.Actions.DeleteEnvSecret(ctx, repoID, githubSecret.environment, githubSecret.secret.Name)
+
+		if err != nil {
+			return errors.Wrap(err, "Error deleting an environment secret\n")
+		}
+
+		return nil
+	}
+
+	value, err := client.secretValue(secretSettings.secret)
+
+	if err != nil {
+		return err
+	}
+
+	report.changed(ResourceEnvironmentSecrets, "Writing secret %s of environment %s\n", secretSettings.secret.Name, secretSettings.environment)
+
+	publicKey, _, err := client.github.Actions.GetEnvPublicKey(ctx, repoID, secretSettings.environment)
+
+	if err != nil {
+		return errors.Wrap(err, "Error getting the environment public key\n")
+	}
+
+	encryptedValue, err := encryptSecret(publicKey.GetKey(), value)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = client.github.Actions.CreateOrUpdateEnvSecret(ctx, repoID, secretSettings.environment, &github.EncryptedSecret{
+		Name:           secretSettings.secret.Name,
+		KeyID:          publicKey.GetKeyID(),
+		EncryptedValue: encryptedValue,
+	})
+
+	if err != nil {
+		return errors.Wrap(err, "Error writing an environment secret\n")
+	}
+
+	return nil
+}
+
+func (client *Client) updateEnvironmentVariable(ctx context.Context, report reporter, owner, name string, action Action, githubVariable, variableSettings environmentVariable) error {
+	switch action {
+	case ActionDelete:
+		report.changed(ResourceEnvironmentVariables, "Deleting variable %s of environment %s\n", githubVariable.variable.Name, githubVariable.environment)
+
+		_, err := client.github.Actions.DeleteEnvVariable(ctx, owner, name, githubVariable.environment, githubVariable.variable.Name)
+
+		if err != nil {
+			return errors.Wrap(err, "Error deleting an environment variable\n")
+		}
+	case ActionCreate:
+		report.changed(ResourceEnvironmentVariables, "Creating variable %s of environment %s\n", variableSettings.variable.Name, variableSettings.environment)
+
+		_, err := client.github.Actions.CreateEnvVariable(ctx, owner, name, variableSettings.environment, &github.ActionsVariable{
+			Name:  variableSettings.variable.Name,
+			Value: variableSettings.variable.Value,
+		})
+
+		if err != nil {
+			return errors.Wrap(err, "Error creating an environment variable\n")
+		}
+	case ActionUpdate:
+		report.changed(ResourceEnvironmentVariables, "Updating variable %s of environment %s\n", variableSettings.variable.Name, variableSettings.environment)
+
+		_, err := client.github.Actions.UpdateEnvVariable(ctx, owner, name, variableSettings.environment, &github.ActionsVariable{
+			Name:  variableSettings.variable.Name,
+			Value: variableSettings.variable.Value,
+		})
+
+		if err != nil {
+			return errors.Wrap(err, "Error updating an environment variable\n")
+		}
+	}
+
+	return nil
+}
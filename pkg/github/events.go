@@ -0,0 +1,108 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// EventType is the kind of progress event emitted while applying settings
+type EventType string
+
+// Progress events emitted by ApplyStream
+const (
+	EventStarted         EventType = "started"
+	EventResourceChanged EventType = "resource-changed"
+	EventCompleted       EventType = "completed"
+	EventError           EventType = "error"
+)
+
+// Event describes the progress of an apply
+type Event struct {
+	Type EventType
+	// Repository is the full name (owner/name) of the repository being applied
+	Repository string
+	// Resource is the kind of resource changed (repository, labels, branches, webhooks, topics)
+	Resource string
+	Message  string
+	// Reason and Ticket reference why the change of a resource changed event is made
+	Reason string
+	Ticket string
+	Err    error
+}
+
+// reporter logs changes and forwards them as events when streaming
+type reporter func(Event)
+
+func (report reporter) emit(event Event) {
+	if report != nil {
+		report(event)
+	}
+}
+
+// forChange returns a reporter adding the reason and the ticket of a change to its events
+func (report reporter) forChange(change Change) reporter {
+	if report == nil {
+		return nil
+	}
+
+	return func(event Event) {
+		event.Reason, event.Ticket = change.Reason, change.Ticket
+		report(event)
+	}
+}
+
+// changed logs a resource change and emits the corresponding event
+func (report reporter) changed(resource, format string, args ...interface{}) {
+	log.Printf("[INFO] "+format, args...)
+
+	report.emit(Event{
+		Type:     EventResourceChanged,
+		Resource: resource,
+		Message:  strings.TrimSpace(fmt.Sprintf(format, args...)),
+	})
+}
+
+// ApplyStream applies the settings in the background and emits progress events on the returned channel
+// The channel is closed once a completed or error event has been sent or the context is cancelled
+func (client *Client) ApplyStream(ctx context.Context, settings *Settings) (<-chan Event, error) {
+	if settings.Repository.Owner == "" || settings.Repository.Name == "" {
+		return nil, errors.New("Repository owner and name are required")
+	}
+
+	fullName := settings.Repository.Owner + "/" + settings.Repository.Name
+	events := make(chan Event)
+
+	send := func(event Event) {
+		event.Repository = fullName
+
+		select {
+		case events <- event:
+		case <-ctx.Done():
+		}
+	}
+
+	go func() {
+		defer close(events)
+
+		send(Event{Type: EventStarted})
+
+		result, err := client.apply(ctx, settings, send)
+
+		if err == nil {
+			err = skippedError(result.Skipped)
+		}
+
+		if err != nil {
+			send(Event{Type: EventError, Message: err.Error(), Err: err})
+			return
+		}
+
+		send(Event{Type: EventCompleted})
+	}()
+
+	return events, nil
+}
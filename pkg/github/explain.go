@@ -0,0 +1,299 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/michaelmass/github-settings/pkg/config"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// Layers of a multi repository settings file a value can come from
+const (
+	LayerOrg          = "org"
+	LayerDefaults     = "defaults"
+	LayerRepositories = "repositories"
+)
+
+// Explanation is the effective value of a setting of a repository and where it comes from
+type Explanation struct {
+	// Path is the yaml path of the setting, the resources are named by their key (ex: labels.bug.color)
+	Path  string
+	Value interface{}
+	// Source is the settings file, path or url, that set the value, it is empty when no file sets it and the default applies
+	Source string
+	// Layer is the section of a multi repository file that set the value, empty for a single repository file
+	Layer string
+}
+
+// ValueString formats the value, the lists and maps are formatted as json
+func (explanation Explanation) ValueString() string {
+	switch value := explanation.Value.(type) {
+	case nil:
+		return "null"
+	case string, bool, int, float64:
+		return fmt.Sprint(value)
+	default:
+		content, err := json.Marshal(value)
+
+		if err != nil {
+			return fmt.Sprint(value)
+		}
+
+		return string(content)
+	}
+}
+
+// SourceString describes where the value comes from (ex: base.yml (defaults)), default when no file sets it
+func (explanation Explanation) SourceString() string {
+	if explanation.Source == "" {
+		return "default"
+	}
+
+	if explanation.Layer == "" {
+		return explanation.Source
+	}
+
+	return fmt.Sprintf("%s (%s)", explanation.Source, explanation.Layer)
+}
+
+// origin is the file and layer that set a value, it replaces the values of a provenance document
+type origin struct {
+	Source string
+	Layer  string
+}
+
+// Explain returns the effective settings of a repository targeted by a settings file with the file, and the section of a
+// multi repository file, that set each value once the extended and included files and the defaults are merged
+// The values set by no file are the defaults of the settings, the live repository variables are substituted when planning
+func (client *Client) Explain(ctx context.Context, file, owner, name string) ([]Explanation, error) {
+	opts, err := client.loaderOptions()
+
+	if err != nil {
+		return nil, err
+	}
+
+	content, provenance, err := config.Trace(file, opts...)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Error while loading settings file")
+	}
+
+	settings, err := repositorySettingsFromBytes(content, owner, name)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Error decoding settings content")
+	}
+
+	if settings == nil {
+		return nil, errors.Errorf("Settings file %s does not target %s/%s", file, owner, name)
+	}
+
+	repositoryProvenance, err := resolveProvenance(content, provenance, owner, name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	document := &yaml.Node{}
+	effective, err := yaml.Marshal(settings)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Error while marshal settings")
+	}
+
+	err = yaml.Unmarshal(effective, document)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Error while unmarshal settings")
+	}
+
+	explanations := []Explanation{}
+
+	err = explainNode(document.Content[0], nil, func(path []string, node *yaml.Node) error {
+		explanation := Explanation{Path: strings.Join(path, ".")}
+		err := node.Decode(&explanation.Value)
+
+		if err != nil {
+			return errors.Wrapf(err, "Error decoding %s", explanation.Path)
+		}
+
+		if found, ok := lookupOrigin(repositoryProvenance, path); ok {
+			explanation.Source, explanation.Layer = found.Source, found.Layer
+		}
+
+		explanations = append(explanations, explanation)
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return explanations, nil
+}
+
+// resolveProvenance merges the provenance of the defaults and the overrides of a repository like resolveRepository merges
+// their values, the provenance of a single repository file is returned as is
+func resolveProvenance(content []byte, provenance map[string]interface{}, owner, name string) (interface{}, error) {
+	multi, err := parseMultiSettings(content)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if multi == nil {
+		return withLayer(provenance, ""), nil
+	}
+
+	org := withLayer(provenance["org"], LayerOrg)
+
+	// An org declaring settings holds its name in a mapping
+	if orgSettings, ok := org.(map[string]interface{}); ok {
+		org = orgSettings["name"]
+	}
+
+	overrides := map[string]interface{}{}
+
+	// The repositories of an organization are listed, their name comes from the org
+	if len(multi.Repositories) == 0 {
+		overrides["repository"] = map[string]interface{}{"name": org}
+	}
+
+	repositories, _ := provenance["repositories"].([]interface{})
+
+	for i, repositoryOverrides := range multi.Repositories {
+		settings, err := resolveRepository(multi, repositoryOverrides)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if sameRepository(settings, owner, name) && i < len(repositories) {
+			overrides, _ = withLayer(repositories[i], LayerRepositories).(map[string]interface{})
+			break
+		}
+	}
+
+	defaults, _ := withLayer(provenance["defaults"], LayerDefaults).(map[string]interface{})
+	merged := mergeMaps(mergeMaps(map[string]interface{}{}, defaults), overrides)
+
+	if multi.Org.Name != "" {
+		merged = mergeMaps(map[string]interface{}{
+			"repository": map[string]interface{}{"owner": org},
+		}, merged)
+	}
+
+	return merged, nil
+}
+
+// withLayer replaces the sources of a provenance document by their origin in a layer, the keys of the resources are kept
+func withLayer(provenance interface{}, layer string) interface{} {
+	switch typed := provenance.(type) {
+	case config.Source:
+		return origin{Source: string(typed), Layer: layer}
+	case map[string]interface{}:
+		layered := make(map[string]interface{}, len(typed))
+
+		for key, value := range typed {
+			layered[key] = withLayer(value, layer)
+		}
+
+		return layered
+	case []interface{}:
+		layered := make([]interface{}, 0, len(typed))
+
+		for _, value := range typed {
+			layered = append(layered, withLayer(value, layer))
+		}
+
+		return layered
+	default:
+		return provenance
+	}
+}
+
+// explainNode calls explain for every value of the effective settings, the resources merged by their key are named by it
+// and their key is left out, the other lists are explained as a whole
+func explainNode(node *yaml.Node, path []string, explain func(path []string, node *yaml.Node) error) error {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			err := explainNode(node.Content[i+1], append(append([]string{}, path...), node.Content[i].Value), explain)
+
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	case yaml.SequenceNode:
+		itemKey, keyed := "", false
+
+		if len(path) != 0 {
+			itemKey, keyed = config.ListKey(path[len(path)-1])
+		}
+
+		if !keyed {
+			return explain(path, node)
+		}
+
+		for _, item := range node.Content {
+			key := mappingValue(item, itemKey)
+
+			if item.Kind != yaml.MappingNode || key == item {
+				return explain(path, node)
+			}
+
+			for i := 0; i+1 < len(item.Content); i += 2 {
+				if item.Content[i].Value == itemKey {
+					continue
+				}
+
+				err := explainNode(item.Content[i+1], append(append([]string{}, path...), key.Value, item.Content[i].Value), explain)
+
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	default:
+		return explain(path, node)
+	}
+}
+
+// lookupOrigin returns the origin of the value at a path of the effective settings, the origin of the closest parent replaced
+// as a whole when the value itself has none
+func lookupOrigin(provenance interface{}, path []string) (origin, bool) {
+	current := provenance
+
+	for i, segment := range path {
+		switch typed := current.(type) {
+		case origin:
+			return typed, true
+		case map[string]interface{}:
+			current = typed[segment]
+		case []interface{}:
+			itemKey, _ := config.ListKey(path[i-1])
+			current = nil
+
+			for _, item := range typed {
+				if itemMap, ok := item.(map[string]interface{}); ok && fmt.Sprint(itemMap[itemKey]) == segment {
+					current = itemMap
+				}
+			}
+		default:
+			return origin{}, false
+		}
+	}
+
+	found, ok := current.(origin)
+
+	return found, ok
+}
@@ -0,0 +1,93 @@
+package github
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExplainNamesTheSourceOfEachValue(t *testing.T) {
+	_, client := newTestClient(t)
+	path := writeSettings(t, `
+extends: base.yml
+org: acme
+defaults:
+  branches:
+    - name: main
+      protection:
+        requiredapprovingreviewcount: {requiredapprovingreviewcount: 1}
+repositories:
+  - repository: {name: api}
+    labels:
+      - {name: feature, color: a2eeef}
+  - repository: {name: web}
+`)
+
+	base := `
+repository: {description: Managed by the platform team}
+labels:
+  - {name: bug, color: d73a4a}
+branches:
+  - name: main
+    protection:
+      enforceadmins: true
+      requiredapprovingreviewcount: {requiredapprovingreviewcount: 2}
+`
+
+	if err := os.WriteFile(filepath.Join(filepath.Dir(path), "base.yml"), []byte(base), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	explanations, err := client.Explain(context.Background(), path, "acme", "web")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sources := map[string]string{}
+
+	for _, explanation := range explanations {
+		sources[explanation.Path+"="+explanation.ValueString()] = explanation.SourceString()
+	}
+
+	baseSource := filepath.Join(filepath.Dir(path), "base.yml")
+
+	expected := map[string]string{
+		"repository.owner=acme":                               path + " (org)",
+		"repository.name=web":                                 path + " (repositories)",
+		"repository.description=Managed by the platform team": baseSource + " (defaults)",
+		"repository.private=false":                            "default",
+		"labels.bug.color=d73a4a":                             baseSource + " (defaults)",
+		"branches.main.protection.enforceadmins=true":         baseSource + " (defaults)",
+		"branches.main.protection.requiredapprovingreviewcount.requiredapprovingreviewcount=1": path + " (defaults)",
+	}
+
+	for value, source := range expected {
+		if sources[value] != source {
+			t.Errorf("Expected %s from %s, got %q", value, source, sources[value])
+		}
+	}
+
+	explanations, err = client.Explain(context.Background(), path, "acme", "api")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, explanation := range explanations {
+		if explanation.Path == "labels.bug.color" {
+			t.Errorf("Expected the labels of the repository to replace the default labels, got %+v", explanation)
+		}
+
+		if explanation.Path == "labels.feature.color" && explanation.SourceString() != path+" (repositories)" {
+			t.Errorf("Expected the label of the repository from its overrides, got %s", explanation.SourceString())
+		}
+	}
+
+	_, err = client.Explain(context.Background(), path, "acme", "missing")
+
+	if err == nil {
+		t.Error("Expected an error for a repository the settings do not target")
+	}
+}
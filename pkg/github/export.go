@@ -0,0 +1,293 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v75/github"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// RedactedSecret replaces secrets that cannot be exported
+const RedactedSecret = "<redacted>"
+
+// ExportSections are the resource kinds export can be filtered by, each is a top level section of the settings
+// nolint:gochecknoglobals
+var ExportSections = []string{
+	ResourceRepository,
+	ResourceLabels,
+	ResourceBranches,
+	ResourceWebhooks,
+	ResourceTopics,
+	ResourceCollaborators,
+	ResourceTeams,
+	ResourceSecrets,
+	ResourceVariables,
+	ResourceDependabotSecrets,
+	ResourceEnvironments,
+	ResourceRulesets,
+	ResourceFiles,
+}
+
+// status is the read-only information of a repository, it can't be applied
+type status struct {
+	Visibility string
+	HasPages   bool
+	Fork       bool
+	License    string `yaml:",omitempty"`
+	Language   string `yaml:",omitempty"`
+	CreatedAt  string `yaml:",omitempty"`
+	PushedAt   string `yaml:",omitempty"`
+	Stars      int
+	Forks      int
+	Watchers   int
+	OpenIssues int
+	// Size is the size of the repository in kilobytes
+	Size int
+	// Properties are the values of the custom properties of the repository
+	Properties map[string]string `yaml:",omitempty"`
+	// ManagedBy is the other tool declared as managing the repository by a custom property or a managed-by topic
+	ManagedBy string `yaml:",omitempty"`
+	// OwnerType is Organization or User, the repositories of a personal account have no teams and a single collaborator access
+	OwnerType string `yaml:",omitempty"`
+}
+
+func newStatus(githubRepo *github.Repository) *status {
+	return &status{
+		Visibility: githubRepo.GetVisibility(),
+		HasPages:   githubRepo.GetHasPages(),
+		Fork:       githubRepo.GetFork(),
+		License:    githubRepo.GetLicense().GetSPDXID(),
+		Language:   githubRepo.GetLanguage(),
+		CreatedAt:  formatTimestamp(githubRepo.CreatedAt),
+		PushedAt:   formatTimestamp(githubRepo.PushedAt),
+		Stars:      githubRepo.GetStargazersCount(),
+		Forks:      githubRepo.GetForksCount(),
+		Watchers:   githubRepo.GetSubscribersCount(),
+		OpenIssues: githubRepo.GetOpenIssuesCount(),
+		Size:       githubRepo.GetSize(),
+		Properties: customProperties(githubRepo),
+		ManagedBy:  managedBy(githubRepo),
+		OwnerType:  githubRepo.GetOwner().GetType(),
+	}
+}
+
+func formatTimestamp(timestamp *github.Timestamp) string {
+	if timestamp == nil {
+		return ""
+	}
+
+	return timestamp.UTC().Format(time.RFC3339)
+}
+
+// Export returns the live settings of a repository in a form accepted by Apply
+// Webhook secrets cannot be read back from github and are replaced by RedactedSecret
+// Actions secrets are exported by name only, their values are read from the environment on apply
+// Read-only information (visibility, license, counts) is exported in the status section which apply ignores
+func (client *Client) Export(ctx context.Context, owner, name string) (*Settings, error) {
+	settings, err := client.GetSettingsFromGithub(ctx, owner, name)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Error getting settings from github")
+	}
+
+	// Every branch of the settings file is protected, unprotected branches are left out
+	branches := []branch{}
+
+	for _, branch := range settings.Branches {
+		if branch.Protection.Enabled {
+			branches = append(branches, branch)
+		}
+	}
+
+	settings.Branches = branches
+	canonicalize(settings)
+
+	for i := range settings.Webhooks {
+		settings.Webhooks[i].ID = 0
+
+		// github masks configured secrets
+		if settings.Webhooks[i].Secret != "" {
+			settings.Webhooks[i].Secret = RedactedSecret
+		}
+	}
+
+	return settings, nil
+}
+
+// canonicalize sorts the lists of the settings so the same live settings always export to the same file
+// The resources are sorted by their key and the lists of names by value, the yaml keys follow the order of the fields
+// The branch and ruleset patterns keep their order, it is the order they were declared in
+func canonicalize(settings *Settings) {
+	sort.SliceStable(settings.Labels, func(i, j int) bool { return settings.Labels[i].Name < settings.Labels[j].Name })
+	sort.SliceStable(settings.Branches, func(i, j int) bool { return settings.Branches[i].Name < settings.Branches[j].Name })
+	sort.SliceStable(settings.Webhooks, func(i, j int) bool {
+		if settings.Webhooks[i].URL != settings.Webhooks[j].URL {
+			return settings.Webhooks[i].URL < settings.Webhooks[j].URL
+		}
+
+		return settings.Webhooks[i].ID < settings.Webhooks[j].ID
+	})
+	sort.SliceStable(settings.Collaborators, func(i, j int) bool {
+		return strings.ToLower(settings.Collaborators[i].Username) < strings.ToLower(settings.Collaborators[j].Username)
+	})
+	sort.SliceStable(settings.Teams, func(i, j int) bool { return settings.Teams[i].Slug < settings.Teams[j].Slug })
+	sort.SliceStable(settings.Secrets, func(i, j int) bool { return settings.Secrets[i].Name < settings.Secrets[j].Name })
+	sort.SliceStable(settings.Variables, func(i, j int) bool { return settings.Variables[i].Name < settings.Variables[j].Name })
+	sort.SliceStable(settings.DependabotSecrets, func(i, j int) bool { return settings.DependabotSecrets[i].Name < settings.DependabotSecrets[j].Name })
+	sort.SliceStable(settings.Environments, func(i, j int) bool { return settings.Environments[i].Name < settings.Environments[j].Name })
+	sort.SliceStable(settings.Rulesets, func(i, j int) bool { return settings.Rulesets[i].Name < settings.Rulesets[j].Name })
+	sort.SliceStable(settings.Files, func(i, j int) bool { return settings.Files[i].Path < settings.Files[j].Path })
+	sort.Strings(settings.Topics)
+
+	for _, branchSettings := range settings.Branches {
+		sort.Strings(branchSettings.Protection.RequiredStatusChecks.Contexts)
+		sortRestrictions(branchSettings.Protection.Restrictions)
+		sortRestrictions(branchSettings.Protection.RequiredApprovingReviewCount.DismissalRestrictions)
+		sortRestrictions(branchSettings.Protection.RequiredApprovingReviewCount.BypassPullRequestAllowances)
+	}
+
+	for _, webhookSettings := range settings.Webhooks {
+		sort.Strings(webhookSettings.Events)
+	}
+
+	for _, environmentSettings := range settings.Environments {
+		sort.Strings(environmentSettings.Reviewers.Users)
+		sort.Strings(environmentSettings.Reviewers.Teams)
+		sort.Strings(environmentSettings.DeploymentBranchPolicy.Branches)
+		sort.SliceStable(environmentSettings.Secrets, func(i, j int) bool { return environmentSettings.Secrets[i].Name < environmentSettings.Secrets[j].Name })
+		sort.SliceStable(environmentSettings.Variables, func(i, j int) bool {
+			return environmentSettings.Variables[i].Name < environmentSettings.Variables[j].Name
+		})
+	}
+
+	for i, rulesetSettings := range settings.Rulesets {
+		sort.Strings(rulesetSettings.Rules.RequiredStatusChecks.Contexts)
+		settings.Rulesets[i].BypassActors = sortedBypassActors(rulesetSettings.BypassActors)
+	}
+}
+
+func sortRestrictions(restrictions *restrictions) {
+	if restrictions == nil {
+		return
+	}
+
+	sort.Strings(restrictions.Users)
+	sort.Strings(restrictions.Teams)
+	sort.Strings(restrictions.Apps)
+}
+
+// MarshalSettings encodes settings to yaml indented with two spaces
+func MarshalSettings(settings *Settings) ([]byte, error) {
+	buffer := &bytes.Buffer{}
+	encoder := yaml.NewEncoder(buffer)
+	encoder.SetIndent(2)
+
+	err := encoder.Encode(settings)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Error while marshal settings")
+	}
+
+	err = encoder.Close()
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Error while marshal settings")
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// MarshalSections encodes the sections of the settings of the resource kinds listed in only (every kind when empty)
+// and not listed in exclude, the status section is kept with the repository section
+func MarshalSections(settings *Settings, only, exclude []string) ([]byte, error) {
+	if len(only) == 0 && len(exclude) == 0 {
+		return MarshalSettings(settings)
+	}
+
+	kept, err := selectSections(only, exclude)
+
+	if err != nil {
+		return nil, err
+	}
+
+	document := &yaml.Node{}
+	content, err := yaml.Marshal(settings)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Error while marshal settings")
+	}
+
+	err = yaml.Unmarshal(content, document)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Error while unmarshal settings")
+	}
+
+	mapping := document.Content[0]
+	filtered := []*yaml.Node{}
+
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if kept[mapping.Content[i].Value] {
+			filtered = append(filtered, mapping.Content[i], mapping.Content[i+1])
+		}
+	}
+
+	mapping.Content = filtered
+	buffer := &bytes.Buffer{}
+	encoder := yaml.NewEncoder(buffer)
+	encoder.SetIndent(2)
+
+	err = encoder.Encode(document)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Error while marshal settings")
+	}
+
+	err = encoder.Close()
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Error while marshal settings")
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// selectSections returns the yaml keys of the sections kept by the only and exclude resource kinds
+func selectSections(only, exclude []string) (map[string]bool, error) {
+	known := map[string]bool{}
+
+	for _, section := range ExportSections {
+		known[section] = true
+	}
+
+	for _, section := range append(append([]string{}, only...), exclude...) {
+		if !known[strings.ToLower(section)] {
+			return nil, errors.Errorf("Unknown section %q (allowed values: %s)", section, strings.Join(ExportSections, ", "))
+		}
+	}
+
+	kept := map[string]bool{}
+
+	for _, section := range ExportSections {
+		kept[section] = len(only) == 0
+	}
+
+	for _, section := range only {
+		kept[strings.ToLower(section)] = true
+	}
+
+	for _, section := range exclude {
+		kept[strings.ToLower(section)] = false
+	}
+
+	// The annotations are persisted as topics and the status describes the repository
+	kept["annotations"] = kept[ResourceTopics]
+	kept["status"] = kept[ResourceRepository]
+
+	return kept, nil
+}
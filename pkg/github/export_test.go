@@ -0,0 +1,78 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestCanonicalizeSortsLists(t *testing.T) {
+	settings := &Settings{
+		Labels:        []label{{Name: "wontfix"}, {Name: "bug"}},
+		Webhooks:      []webhook{{URL: "https://b", Events: []string{"push", "issues"}}, {URL: "https://a"}},
+		Topics:        []string{"web", "api"},
+		Collaborators: []collaborator{{Username: "bob"}, {Username: "Alice"}},
+		Environments:  []environment{{Name: "staging"}, {Name: "production", Reviewers: reviewers{Users: []string{"carol", "alice"}}}},
+		Branches:      []branch{{Name: "main", Protection: protection{RequiredStatusChecks: requiredStatusChecks{Contexts: []string{"lint", "ci"}}}}},
+	}
+
+	canonicalize(settings)
+
+	got := strings.Join([]string{
+		settings.Labels[0].Name,
+		settings.Webhooks[0].URL,
+		strings.Join(settings.Webhooks[1].Events, ","),
+		strings.Join(settings.Topics, ","),
+		settings.Collaborators[0].Username,
+		settings.Environments[0].Name,
+		strings.Join(settings.Environments[0].Reviewers.Users, ","),
+		strings.Join(settings.Branches[0].Protection.RequiredStatusChecks.Contexts, ","),
+	}, " ")
+
+	if want := "bug https://a issues,push api,web Alice production alice,carol ci,lint"; got != want {
+		t.Errorf("Canonical settings are %q, want %q", got, want)
+	}
+}
+
+func TestExportIsDeterministic(t *testing.T) {
+	server, client := newTestClient(t)
+	repo := server.AddRepository("acme", "api")
+	repo.SetAttributes(map[string]interface{}{"topics": []string{"web", "api", "go"}})
+	repo.Collaborators["bob"] = "write"
+	repo.Collaborators["alice"] = "admin"
+	repo.AddHook("https://c", "release")
+	repo.AddHook("https://b", "push", "issues")
+
+	exports := [][]byte{}
+
+	for i := 0; i < 5; i++ {
+		settings, err := client.Export(context.Background(), "acme", "api")
+
+		if err != nil {
+			t.Fatalf("Error exporting settings: %v", err)
+		}
+
+		content, err := MarshalSettings(settings)
+
+		if err != nil {
+			t.Fatalf("Error marshal settings: %v", err)
+		}
+
+		exports = append(exports, content)
+	}
+
+	for _, content := range exports[1:] {
+		if !bytes.Equal(content, exports[0]) {
+			t.Fatalf("Exports differ:\n%s\n%s", exports[0], content)
+		}
+	}
+
+	if !strings.Contains(string(exports[0]), "topics:\n  - api\n  - go\n  - web\n") {
+		t.Errorf("Topics are not sorted:\n%s", exports[0])
+	}
+
+	if strings.Index(string(exports[0]), "https://b") > strings.Index(string(exports[0]), "https://c") {
+		t.Errorf("Webhooks are not sorted by url:\n%s", exports[0])
+	}
+}
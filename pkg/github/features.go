@@ -0,0 +1,149 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/michaelmass/github-settings/pkg/config"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// Features of github a github enterprise server only supports from a version
+const (
+	FeatureRulesets         = "rulesets"
+	FeatureCustomProperties = "custom properties"
+)
+
+// featureVersions are the first github enterprise server versions supporting the features
+// nolint:gochecknoglobals
+var featureVersions = map[string]string{
+	FeatureRulesets:         "3.11",
+	FeatureCustomProperties: "3.12",
+}
+
+// serverVersion keeps the version of the github enterprise server for the lifetime of the client
+type serverVersion struct {
+	mutex   sync.Mutex
+	loaded  bool
+	version string
+}
+
+// enterpriseVersion returns the version of the github enterprise server read from /meta once, empty for github.com
+func (client *Client) enterpriseVersion(ctx context.Context) (string, error) {
+	client.server.mutex.Lock()
+	defer client.server.mutex.Unlock()
+
+	if client.server.loaded {
+		return client.server.version, nil
+	}
+
+	if client.github.BaseURL.String() == DefaultBaseURL {
+		client.server.loaded = true
+		return "", nil
+	}
+
+	request, err := client.github.NewRequest(http.MethodGet, "meta", nil)
+
+	if err != nil {
+		return "", errors.Wrap(err, "Error while getting the server version")
+	}
+
+	meta := struct {
+		InstalledVersion string `json:"installed_version"`
+	}{}
+
+	response, err := client.github.Do(ctx, request, &meta)
+
+	if err != nil {
+		return "", errors.Wrap(err, "Error while getting the server version")
+	}
+
+	client.server.version = meta.InstalledVersion
+
+	if client.server.version == "" {
+		client.server.version = response.Header.Get("X-GitHub-Enterprise-Version")
+	}
+
+	client.server.loaded = true
+
+	return client.server.version, nil
+}
+
+// supports returns true when the server supports a feature, github.com supports them all
+func (client *Client) supports(ctx context.Context, feature string) (bool, error) {
+	version, err := client.enterpriseVersion(ctx)
+
+	if err != nil {
+		return false, err
+	}
+
+	return version == "" || !versionBefore(version, featureVersions[feature]), nil
+}
+
+// checkFeatures fails the settings using features the github enterprise server does not support, before they are applied
+func (client *Client) checkFeatures(ctx context.Context, settings *Settings) error {
+	used := []string{}
+
+	if settings.manages(ResourceRulesets) {
+		used = append(used, FeatureRulesets)
+	}
+
+	if usesCustomProperties(settings) {
+		used = append(used, FeatureCustomProperties)
+	}
+
+	unsupported := []string{}
+
+	for _, feature := range used {
+		supported, err := client.supports(ctx, feature)
+
+		if err != nil {
+			return err
+		}
+
+		if !supported {
+			unsupported = append(unsupported, fmt.Sprintf("%s (requires %s)", feature, featureVersions[feature]))
+		}
+	}
+
+	if len(unsupported) == 0 {
+		return nil
+	}
+
+	version, _ := client.enterpriseVersion(ctx)
+
+	return errors.Errorf("The settings of %s/%s use %s, unsupported on your server (github enterprise server %s)",
+		settings.Repository.Owner, settings.Repository.Name, strings.Join(unsupported, ", "), version)
+}
+
+// usesCustomProperties returns true when the settings reference the custom properties of the repository
+func usesCustomProperties(settings *Settings) bool {
+	content, err := yaml.Marshal(settings)
+
+	return err == nil && strings.Contains(string(content), "${"+config.RepoPropertyPrefix)
+}
+
+// versionBefore compares the major and minor numbers of two versions (ex: 3.10.2 is before 3.11)
+func versionBefore(version, other string) bool {
+	parse := func(value string) (int, int) {
+		parts := strings.SplitN(value, ".", 3)
+		major, _ := strconv.Atoi(parts[0])
+		minor := 0
+
+		if len(parts) > 1 {
+			minor, _ = strconv.Atoi(parts[1])
+		}
+
+		return major, minor
+	}
+
+	major, minor := parse(version)
+	otherMajor, otherMinor := parse(other)
+
+	return major < otherMajor || major == otherMajor && minor < otherMinor
+}
@@ -0,0 +1,79 @@
+package github
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+const featuresSettings = `
+repository: {owner: acme, name: api, description: '${REPO_PROPERTY_COST_CENTER}'}
+rulesets:
+  - name: main
+    branches: [~DEFAULT_BRANCH]
+    rules: {deletion: true}
+`
+
+func TestPlanFailsOnFeaturesUnsupportedByTheServer(t *testing.T) {
+	server, client := newTestClient(t)
+	server.AddRepository("acme", "api")
+	server.SetEnterpriseVersion("3.10.4")
+
+	_, err := client.Plan(context.Background(), settingsFromYAML(t, featuresSettings))
+
+	if err == nil {
+		t.Fatal("Expected the plan to fail on an old server")
+	}
+
+	for _, want := range []string{"rulesets (requires 3.11)", "custom properties (requires 3.12)", "unsupported on your server (github enterprise server 3.10.4)"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected the error to contain %q, got %v", want, err)
+		}
+	}
+
+	for _, request := range server.Requests() {
+		if strings.Contains(request, "/rulesets") {
+			t.Errorf("Expected no ruleset request on an old server, got %s", request)
+		}
+	}
+
+	settings, err := client.GetSettingsFromGithub(context.Background(), "acme", "api")
+
+	if err != nil {
+		t.Fatalf("Expected the live settings without rulesets, got %v", err)
+	}
+
+	if len(settings.Rulesets) != 0 {
+		t.Errorf("Expected no rulesets, got %v", settings.Rulesets)
+	}
+}
+
+func TestPlanUsesFeaturesSupportedByTheServer(t *testing.T) {
+	server, client := newTestClient(t)
+	server.AddRepository("acme", "api")
+	server.SetEnterpriseVersion("3.12.0")
+
+	plan := planOf(t, client, settingsFromYAML(t, featuresSettings))
+
+	if !strings.Contains(strings.Join(changeNames(plan), ","), "rulesets create main") {
+		t.Errorf("Expected the ruleset to be created, got %v", changeNames(plan))
+	}
+}
+
+func TestVersionBefore(t *testing.T) {
+	cases := []struct {
+		version, other string
+		before         bool
+	}{
+		{"3.10.4", "3.11", true},
+		{"3.11.0", "3.11", false},
+		{"3.9", "3.11", true},
+		{"4.0", "3.12", false},
+	}
+
+	for _, c := range cases {
+		if before := versionBefore(c.version, c.other); before != c.before {
+			t.Errorf("Expected %s before %s to be %v", c.version, c.other, c.before)
+		}
+	}
+}
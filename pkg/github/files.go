@@ -0,0 +1,312 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/google/go-github/v75/github"
+	"github.com/pkg/errors"
+	"gopkg.in/src-d/go-billy.v4/memfs"
+	"gopkg.in/src-d/go-git.v4"
+	gitconfig "gopkg.in/src-d/go-git.v4/config"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"gopkg.in/src-d/go-git.v4/storage/memory"
+)
+
+// ResourceFiles is the resource kind of the files committed to the repository
+const ResourceFiles = "files"
+
+// Author of the commits of managed files
+const (
+	fileCommitAuthor = "github-settings"
+	fileCommitEmail  = "github-settings@users.noreply.github.com"
+)
+
+// filesBranchPrefix prefixes the branches of the pull requests proposing managed files
+const filesBranchPrefix = "github-settings/"
+
+// nolint:gochecknoglobals
+var invalidBranchChars = regexp.MustCompile("[^a-zA-Z0-9_-]+")
+
+// file is a file committed to the repository when its content drifts, files missing from the settings are left alone
+type file struct {
+	Path string
+	// Source is the local path of the file content, Content is used when empty
+	Source  string `yaml:",omitempty"`
+	Content string `yaml:",omitempty"`
+	// Template renders the content as a go template with the repository settings (ex: {{ .Owner }}/{{ .Name }})
+	Template bool `yaml:",omitempty"`
+	// Message of the commit, defaults to "Update <path>"
+	Message string `yaml:",omitempty"`
+	// Branch the file is committed to, defaults to the default branch
+	Branch string `yaml:",omitempty"`
+	// PullRequest opens a pull request against the branch instead of pushing to it
+	PullRequest bool `yaml:",omitempty"`
+	annotations `yaml:",inline" diff:"-"`
+}
+
+// fileState is the compared state of a file, its content is summarized to keep plans readable
+type fileState struct {
+	Branch   string
+	Size     int
+	Checksum string
+}
+
+func (fileSettings file) state() fileState {
+	sum := sha256.Sum256([]byte(fileSettings.Content))
+
+	return fileState{
+		Branch:   fileSettings.Branch,
+		Size:     len(fileSettings.Content),
+		Checksum: hex.EncodeToString(sum[:])[:12],
+	}
+}
+
+// resolveFiles reads the sources and renders the templates of the files
+func resolveFiles(files []file, repo repository) ([]file, error) {
+	resolved := make([]file, 0, len(files))
+
+	for _, fileSettings := range files {
+		if fileSettings.Source != "" {
+			content, err := ioutil.ReadFile(fileSettings.Source)
+
+			if err != nil {
+				return nil, errors.Wrapf(err, "Error while reading source of file %s", fileSettings.Path)
+			}
+
+			fileSettings.Content = string(content)
+		}
+
+		if fileSettings.Template {
+			parsed, err := template.New(fileSettings.Path).Parse(fileSettings.Content)
+
+			if err != nil {
+				return nil, errors.Wrapf(err, "Error while parsing template of file %s", fileSettings.Path)
+			}
+
+			buffer := &bytes.Buffer{}
+			err = parsed.Execute(buffer, repo)
+
+			if err != nil {
+				return nil, errors.Wrapf(err, "Error while rendering template of file %s", fileSettings.Path)
+			}
+
+			fileSettings.Content = buffer.String()
+		}
+
+		// The resolved content is final, resolving it again leaves it as it is
+		fileSettings.Source = ""
+		fileSettings.Template = false
+
+		if fileSettings.Message == "" {
+			fileSettings.Message = "Update " + fileSettings.Path
+		}
+
+		resolved = append(resolved, fileSettings)
+	}
+
+	return resolved, nil
+}
+
+// getFiles reads the live content of the files of the settings, the files missing from their branch are left out
+func (client *Client) getFiles(ctx context.Context, owner, name, defaultBranch string, files []file) ([]file, error) {
+	githubFiles := []file{}
+
+	for _, fileSettings := range files {
+		branch := fileSettings.Branch
+
+		if branch == "" {
+			branch = defaultBranch
+		}
+
+		content, _, response, err := client.github.Repositories.GetContents(ctx, owner, name, fileSettings.Path, &github.RepositoryContentGetOptions{Ref: branch})
+
+		if response != nil && response.StatusCode == http.StatusNotFound {
+			continue
+		}
+
+		if err != nil {
+			return nil, errors.Wrapf(err, "Error while getting file %s", fileSettings.Path)
+		}
+
+		if content == nil {
+			return nil, errors.Errorf("Error %s is a directory", fileSettings.Path)
+		}
+
+		decoded, err := content.GetContent()
+
+		if err != nil {
+			return nil, errors.Wrapf(err, "Error while decoding file %s", fileSettings.Path)
+		}
+
+		githubFiles = append(githubFiles, file{Path: fileSettings.Path, Content: decoded, Branch: fileSettings.Branch})
+	}
+
+	return githubFiles, nil
+}
+
+func planFiles(disabled bool, githubFiles, filesSettings []file) []Change {
+	if disabled {
+		log.Print("[INFO] Skipping disabled repository files\n")
+		return nil
+	}
+
+	changes := []Change{}
+	githubFilesMap := map[string]file{}
+
+	for _, githubFile := range githubFiles {
+		githubFilesMap[githubFile.Branch+":"+githubFile.Path] = githubFile
+	}
+
+	for _, fileSettings := range filesSettings {
+		githubFile, ok := githubFilesMap[fileSettings.Branch+":"+fileSettings.Path]
+
+		if ok && githubFile.Content == fileSettings.Content {
+			continue
+		}
+
+		action := ActionUpdate
+		current := githubFile.state()
+
+		if !ok {
+			action = ActionCreate
+			current = fileState{}
+		}
+
+		// The fields compare the summarized state while apply needs the content
+		change := newChange(ResourceFiles, fileSettings.Path, action, current, fileSettings.state())
+		change.ReportOnly = reportOnly(fileSettings)
+		change.Reason, change.Ticket = rationale(fileSettings)
+		change.current, change.desired = githubFile, fileSettings
+		changes = append(changes, change)
+	}
+
+	return changes
+}
+
+// updateFile commits a file over git, to its branch or to the branch of a pull request
+func (client *Client) updateFile(ctx context.Context, report reporter, owner, name string, fileSettings file) error {
+	token, err := client.currentToken()
+
+	if err != nil {
+		return err
+	}
+
+	options := &git.CloneOptions{URL: fmtGithubURL(client.host, owner, name, token)}
+
+	// Without a branch the clone checks out the default branch, go-git resolves a single branch clone to master
+	if fileSettings.Branch != "" {
+		options.ReferenceName = plumbing.NewBranchReferenceName(fileSettings.Branch)
+		options.SingleBranch = true
+	}
+
+	repo, err := git.CloneContext(ctx, memory.NewStorage(), memfs.New(), options)
+
+	if err != nil {
+		return errors.Wrap(err, "Error initializing git repository\n")
+	}
+
+	headRef, err := repo.Head()
+
+	if err != nil {
+		return errors.Wrap(err, "Error getting repository head\n")
+	}
+
+	worktree, err := repo.Worktree()
+
+	if err != nil {
+		return errors.Wrap(err, "Error getting repository worktree\n")
+	}
+
+	target := headRef.Name()
+
+	if fileSettings.PullRequest {
+		target = plumbing.NewBranchReferenceName(filesBranchPrefix + strings.Trim(invalidBranchChars.ReplaceAllString(fileSettings.Path, "-"), "-"))
+
+		err = worktree.Checkout(&git.CheckoutOptions{Branch: target, Create: true})
+
+		if err != nil {
+			return errors.Wrapf(err, "Error creating branch %s\n", target.Short())
+		}
+	}
+
+	written, err := worktree.Filesystem.Create(fileSettings.Path)
+
+	if err != nil {
+		return errors.Wrapf(err, "Error writing file %s\n", fileSettings.Path)
+	}
+
+	_, err = written.Write([]byte(fileSettings.Content))
+
+	if closeErr := written.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return errors.Wrapf(err, "Error writing file %s\n", fileSettings.Path)
+	}
+
+	_, err = worktree.Add(fileSettings.Path)
+
+	if err != nil {
+		return errors.Wrapf(err, "Error adding file %s\n", fileSettings.Path)
+	}
+
+	_, err = worktree.Commit(fileSettings.Message, &git.CommitOptions{
+		Author: &object.Signature{Name: fileCommitAuthor, Email: fileCommitEmail, When: client.clock.Now()},
+	})
+
+	if err != nil {
+		return errors.Wrapf(err, "Error committing file %s\n", fileSettings.Path)
+	}
+
+	refSpec := gitconfig.RefSpec(target.String() + ":" + target.String())
+
+	// The branch of a pull request is rewritten on every apply until it is merged
+	if fileSettings.PullRequest {
+		refSpec = "+" + refSpec
+	}
+
+	if fileSettings.PullRequest {
+		report.changed(ResourceFiles, "Proposing file %s in a pull request to %s\n", fileSettings.Path, headRef.Name().Short())
+	} else {
+		report.changed(ResourceFiles, "Committing file %s to %s\n", fileSettings.Path, target.Short())
+	}
+
+	err = repo.PushContext(ctx, &git.PushOptions{RefSpecs: []gitconfig.RefSpec{refSpec}})
+
+	if err != nil {
+		return errors.Wrapf(err, "Error pushing file %s\n", fileSettings.Path)
+	}
+
+	if !fileSettings.PullRequest {
+		return nil
+	}
+
+	_, response, err := client.github.PullRequests.Create(ctx, owner, name, &github.NewPullRequest{
+		Title: github.String(fileSettings.Message),
+		Head:  github.String(target.Short()),
+		Base:  github.String(headRef.Name().Short()),
+		Body:  github.String("Managed by github-settings, the file drifted from the settings."),
+	})
+
+	// The pull request of a previous apply was updated by the push
+	if response != nil && response.StatusCode == http.StatusUnprocessableEntity {
+		return nil
+	}
+
+	if err != nil {
+		return errors.Wrapf(err, "Error opening pull request for file %s\n", fileSettings.Path)
+	}
+
+	return nil
+}
@@ -3,41 +3,153 @@ package github
 import (
 	"context"
 	"fmt"
-	"io/ioutil"
-
-	"github.com/google/go-github/v28/github"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v75/github"
+	"github.com/michaelmass/github-settings/pkg/config"
 	"github.com/pkg/errors"
 	"golang.org/x/oauth2"
 	"gopkg.in/src-d/go-billy.v4/memfs"
 	"gopkg.in/src-d/go-git.v4"
 	"gopkg.in/src-d/go-git.v4/plumbing"
 	"gopkg.in/src-d/go-git.v4/storage/memory"
-	"gopkg.in/yaml.v2"
+	"gopkg.in/yaml.v3"
 )
 
+const maxTopicLength = 50
+
+// nolint:gochecknoglobals
+var invalidTopicChars = regexp.MustCompile("[^a-z0-9]+")
+
 // Client used to call the github api
 type Client struct {
-	github *github.Client
-	token  string
+	github *githubAPI
+	tokens oauth2.TokenSource
+	// auth authenticates the requests and reloads the credentials, it is nil for a client created from an http or go-github client
+	auth *reloadableAuth
+	// host serves the git repositories (github.com or the github enterprise server)
+	host            string
+	resourceTimeout time.Duration
+	breaker         *breaker
+	secretValues    map[string]string
+	// createRepositories creates every missing repository, not only those with create set
+	createRepositories bool
+	// prune deletes the live resources missing from the settings unless their section overrides it
+	prune bool
+	// force applies the settings to repositories managed by another tool
+	force bool
+	// verify plans the repositories again after apply to check github reflects the applied changes
+	verify bool
+	// state records the managed resources, only they are pruned when set
+	state *StateStore
+	// archiveInactive archives the inactive repositories whose inactivity policy archives, they are only flagged otherwise
+	archiveInactive bool
+	// webhookFailures is the number of failed deliveries in a row reporting a webhook as unhealthy, 0 does not check them
+	webhookFailures int
+	// roles are the custom repository roles of the orgs, listed once per org
+	roles *roleCache
+	// ids are the ids of the apps, teams and users referenced by slug or login, looked up once
+	ids *idCache
+	// server is the version of the github enterprise server, read once
+	server *serverVersion
+	// cache skips the repositories unchanged since their last successful apply
+	cache *ApplyCache
+	// snapshot records the live settings of the repositories planned
+	snapshot *Snapshot
+	// against replaces the live settings of github when planning
+	against *Snapshot
+	// remoteEnv are the environment variables the remote extended files can reference
+	remoteEnv []string
+	clock     Clock
 }
 
 // Settings contains the settings to be apply to a github repository
 type Settings struct {
-	Disable    Disabled
+	Disable Disabled `yaml:",omitempty"`
+	// Prune overrides per section whether the resources missing from the settings are deleted
+	Prune Prune `yaml:",omitempty"`
+	// Inactivity flags, or archives, the repository once inactive for a number of days
+	Inactivity inactivity `yaml:",omitempty"`
 	Repository repository
 	Labels     []label
 	Branches   []branch
 	Webhooks   []webhook
 	Topics     []string
+	// Collaborators and Teams granted access to the repository, others are removed
+	Collaborators []collaborator
+	Teams         []team
+	// Secrets and Variables of github actions, secret values are never written in the settings
+	Secrets   []secret
+	Variables []variable
+	// DependabotSecrets are only available to dependabot, like the actions secrets their values are never written in the settings
+	DependabotSecrets []secret `yaml:",omitempty"`
+	// Environments are the deployment environments with their protection rules, secrets and variables
+	Environments []environment `yaml:",omitempty"`
+	// Rulesets protect the branches matching their patterns, including the branches created later
+	Rulesets []ruleset `yaml:",omitempty"`
+	// Files are committed to the repository when their content drifts, other files are left alone
+	Files []file `yaml:",omitempty"`
+	// Status holds read-only information filled by export, it is ignored by plan and apply
+	Status *status `yaml:",omitempty"`
+	// Anchors holds yaml blocks shared through anchors and merge keys (<<: *name), it is ignored otherwise
+	Anchors map[string]interface{} `yaml:",omitempty"`
+	// Annotations are persisted as repository topics so the repository shows it is under declarative management
+	Annotations map[string]string `yaml:",omitempty"`
+	// Reason and Ticket annotate the changes whose resource has none, including the deletions and the topics
+	Reason string `yaml:",omitempty"`
+	Ticket string `yaml:",omitempty"`
 }
 
 // Disabled specify if a functionnality sould be disabled
 type Disabled struct {
-	Repository bool
-	Labels     bool
-	Branches   bool
-	Webhooks   bool
-	Topics     bool
+	Repository    bool
+	Labels        bool
+	Branches      bool
+	Webhooks      bool
+	Topics        bool
+	Collaborators bool
+	Teams         bool
+	Secrets       bool
+	Variables     bool
+	// DependabotSecrets disables the dependabot secrets
+	DependabotSecrets bool
+	Environments      bool
+	Files             bool
+	Rulesets          bool
+}
+
+// manages returns true when the settings plan a kind of resource, disabled sections are never planned
+// The collaborators, teams, secrets, variables, dependabot secrets, environments and rulesets are only managed when their section is declared: a missing section leaves them alone while an empty list removes them all
+func (settings *Settings) manages(resource string) bool {
+	switch resource {
+	case ResourceLabels:
+		return !settings.Disable.Labels
+	case ResourceBranches:
+		return !settings.Disable.Branches
+	case ResourceWebhooks:
+		return !settings.Disable.Webhooks
+	case ResourceCollaborators:
+		return !settings.Disable.Collaborators && settings.Collaborators != nil
+	case ResourceTeams:
+		return !settings.Disable.Teams && settings.Teams != nil
+	case ResourceSecrets:
+		return !settings.Disable.Secrets && settings.Secrets != nil
+	case ResourceVariables:
+		return !settings.Disable.Variables && settings.Variables != nil
+	case ResourceDependabotSecrets:
+		return !settings.Disable.DependabotSecrets && settings.DependabotSecrets != nil
+	case ResourceEnvironments:
+		return !settings.Disable.Environments && settings.Environments != nil
+	case ResourceRulesets:
+		return !settings.Disable.Rulesets && settings.Rulesets != nil
+	default:
+		return true
+	}
 }
 
 type repository struct {
@@ -49,7 +161,6 @@ type repository struct {
 	Private          bool
 	HasIssues        bool
 	HasProjects      bool
-	HasPages         bool
 	HasWiki          bool
 	HasDownloads     bool
 	IsTemplate       bool
@@ -57,17 +168,35 @@ type repository struct {
 	AllowSquashMerge bool
 	AllowMergeCommit bool
 	AllowRebaseMerge bool
+	// Visibility is public, private or internal, it follows private when unset
+	Visibility visibility `yaml:",omitempty"`
+	// The default title and message of merge and squash commits, github keeps the current ones when unset
+	MergeCommitTitle         mergeCommitTitle         `yaml:",omitempty"`
+	MergeCommitMessage       mergeCommitMessage       `yaml:",omitempty"`
+	SquashMergeCommitTitle   squashMergeCommitTitle   `yaml:",omitempty"`
+	SquashMergeCommitMessage squashMergeCommitMessage `yaml:",omitempty"`
+	// Security enables the github advanced security features
+	Security security `yaml:",omitempty"`
+	// Create creates the repository when it does not exist
+	Create bool `yaml:",omitempty" diff:"-"`
+	// Template is the owner/name of the template repository a created repository is generated from
+	Template string `yaml:",omitempty" diff:"-"`
+	// AutoInit creates the repository with an initial commit on its default branch
+	AutoInit    bool `yaml:",omitempty" diff:"-"`
+	annotations `yaml:",inline" diff:"-"`
 }
 
 type label struct {
 	Name        string
 	Description string
 	Color       string
+	annotations `yaml:",inline" diff:"-"`
 }
 
 type branch struct {
-	Name       string
-	Protection protection
+	Name        string
+	Protection  protection
+	annotations `yaml:",inline" diff:"-"`
 }
 
 type protection struct {
@@ -75,12 +204,30 @@ type protection struct {
 	EnforceAdmins                bool
 	RequiredApprovingReviewCount requiredApprovingReviewCount
 	RequiredStatusChecks         requiredStatusChecks
+	// Restrictions limits who can push to the branch, everyone with write access can push when unset
+	Restrictions                   *restrictions `yaml:",omitempty"`
+	RequiredSignatures             bool
+	RequiredLinearHistory          bool
+	AllowForcePushes               bool
+	AllowDeletions                 bool
+	RequiredConversationResolution bool
 }
 
 type requiredApprovingReviewCount struct {
 	RequiredApprovingReviewCount int
 	DismissStaleReviews          bool
 	RequireCodeOwnerReviews      bool
+	// DismissalRestrictions limits who can dismiss reviews, everyone with write access can when unset
+	DismissalRestrictions *restrictions `yaml:",omitempty"`
+	// BypassPullRequestAllowances are the users, teams and apps (ex: a merge bot) allowed to push without the required reviews
+	BypassPullRequestAllowances *restrictions `yaml:",omitempty"`
+}
+
+// restrictions lists the users, teams and apps allowed to perform an action on a protected branch
+type restrictions struct {
+	Users []string `yaml:",omitempty"`
+	Teams []string `yaml:",omitempty"`
+	Apps  []string `yaml:",omitempty"`
 }
 
 type requiredStatusChecks struct {
@@ -89,33 +236,162 @@ type requiredStatusChecks struct {
 }
 
 type webhook struct {
-	ID          int64
+	ID int64 `yaml:",omitempty"`
+	// Name identifies the webhook in the state store when it is matched by name, github webhooks have no name
+	Name        string `yaml:",omitempty" diff:"-"`
 	URL         string
-	ContentType string
-	Secret      string
+	ContentType contentType
+	Secret      string `diff:"sensitive"`
 	Events      []string
+	// MatchBy selects how the webhook is matched with github (url by default, id or name to allow editing the url in place)
+	MatchBy     matchBy `yaml:",omitempty" diff:"-"`
+	annotations `yaml:",inline" diff:"-"`
+}
+
+// New creates a new client calling github.com with a token unless other options are given
+func New(opts ...Option) (*Client, error) {
+	o := newOptions(opts)
+
+	auth, err := newReloadableAuth(o.tokenSource)
+
+	if err != nil {
+		return nil, err
+	}
+
+	tc := &http.Client{Transport: newTransport(auth, o, o.basePath())}
+
+	githubClient, err := newGithubClient(tc, o.baseURL, o.uploadURL)
+
+	if err != nil {
+		return nil, err
+	}
+
+	githubClient.UserAgent = o.fullUserAgent()
+
+	client := newClient(githubClient, auth, o)
+	client.auth = auth
+
+	return client, nil
 }
 
-// New creates a new client
-func New(token string) *Client {
-	ts := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: token},
-	)
+// NewFromHTTPClient creates a new client reusing the auth and transport of an existing http client, calling the base and
+// upload urls of WithBaseURL and WithUploadURL as they are (github.com by default). The token is only used to push new branches over git
+// Requests are sent through a copy of the http client with the headers of the options, counting them in the stats and retrying them when rate limited
+func NewFromHTTPClient(httpClient *http.Client, token string, opts ...Option) (*Client, error) {
+	o := newOptions(opts)
+	baseURL, err := url.Parse(strings.TrimSuffix(o.baseURL, "/") + "/")
 
-	tc := oauth2.NewClient(context.Background(), ts)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Invalid github url %s", o.baseURL)
+	}
 
+	uploadURL := baseURL
+
+	if o.uploadURL != "" {
+		uploadURL, err = url.Parse(strings.TrimSuffix(o.uploadURL, "/") + "/")
+
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid github upload url %s", o.uploadURL)
+		}
+	}
+
+	tc := *httpClient
+	tc.Transport = newTransport(httpClient.Transport, o, strings.TrimSuffix(baseURL.Path, "/"))
+
+	githubClient := github.NewClient(&tc)
+	githubClient.BaseURL = baseURL
+	githubClient.UploadURL = uploadURL
+	githubClient.UserAgent = o.fullUserAgent()
+
+	var tokens oauth2.TokenSource
+
+	if token != "" {
+		tokens = oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
+	}
+
+	return newClient(githubClient, tokens, o), nil
+}
+
+// NewFromGithubClient creates a new client reusing the auth, transport and urls of an existing go-github client
+// The token is only used to push new branches over git
+// Requests are sent through a copy of the go-github client with the headers of the options, counting them in the stats and retrying them when rate limited
+// The go-github client ties callers to the go-github major version pinned in go.mod, they break when it is upgraded. NewFromHTTPClient does not
+func NewFromGithubClient(githubClient *github.Client, token string, opts ...Option) *Client {
+	o := newOptions(opts)
+
+	httpClient := githubClient.Client()
+	httpClient.Transport = newTransport(httpClient.Transport, o, strings.TrimSuffix(githubClient.BaseURL.Path, "/"))
+
+	copied := github.NewClient(httpClient)
+	copied.BaseURL = githubClient.BaseURL
+	copied.UploadURL = githubClient.UploadURL
+	copied.UserAgent = o.fullUserAgent()
+
+	var tokens oauth2.TokenSource
+
+	if token != "" {
+		tokens = oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
+	}
+
+	return newClient(copied, tokens, o)
+}
+
+func newClient(githubClient *github.Client, tokens oauth2.TokenSource, o *options) *Client {
 	return &Client{
-		github: github.NewClient(tc),
-		token:  token,
+		github:             newGithubAPI(githubClient),
+		tokens:             tokens,
+		host:               webHost(githubClient.BaseURL),
+		resourceTimeout:    o.resourceTimeout,
+		breaker:            newBreaker(o.breakerThreshold),
+		secretValues:       o.secretValues,
+		createRepositories: o.createRepositories,
+		prune:              o.prune,
+		force:              o.force,
+		verify:             o.verify,
+		webhookFailures:    o.webhookFailures,
+		archiveInactive:    o.archiveInactive,
+		state:              o.state,
+		roles:              newRoleCache(),
+		ids:                newIDCache(),
+		server:             &serverVersion{},
+		cache:              o.applyCache,
+		snapshot:           o.snapshot,
+		against:            o.against,
+		remoteEnv:          o.remoteEnv,
+		clock:              o.clock,
 	}
 }
 
+// Raw returns the underlying go-github client to perform custom calls
+// Its type ties callers to the go-github major version pinned in go.mod, they break when it is upgraded. Do does not
+func (client *Client) Raw() *github.Client {
+	return client.github.client
+}
+
+// Do performs a custom call of the github rest api with the auth, retries and stats of the client
+// The path is relative to the base url (ex: repos/acme/api/pages), the body is sent and the answer decoded as json when they are not nil
+func (client *Client) Do(ctx context.Context, method, path string, body, result interface{}) (*http.Response, error) {
+	request, err := client.github.NewRequest(method, path, body)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Error while creating request")
+	}
+
+	response, err := client.github.Do(ctx, request, result)
+
+	if response == nil {
+		return nil, err
+	}
+
+	return response.Response, err
+}
+
 // GetSettingsFromFile parse a yaml file containing settings
 func GetSettingsFromFile(file string) (*Settings, error) {
-	content, err := ioutil.ReadFile(file)
+	content, err := config.Load(file)
 
 	if err != nil {
-		return nil, errors.Wrap(err, "Error while reading settings file")
+		return nil, errors.Wrap(err, "Error while loading settings file")
 	}
 
 	settings, err := GetSettingsFromBytes(content)
@@ -136,67 +412,170 @@ func GetSettingsFromBytes(content []byte) (*Settings, error) {
 		return nil, errors.Wrap(err, "Error while unmarshal settings")
 	}
 
-	for i, branch := range settings.Branches {
+	for i := range settings.Branches {
 		settings.Branches[i].Protection.Enabled = true
-		if branch.Protection.RequiredApprovingReviewCount.RequiredApprovingReviewCount == 0 {
-			settings.Branches[i].Protection.RequiredApprovingReviewCount.DismissStaleReviews = false
-			settings.Branches[i].Protection.RequiredApprovingReviewCount.RequireCodeOwnerReviews = false
-		}
 	}
 
+	settings.Topics = emptyToNil(settings.Topics)
+
 	return &settings, nil
 }
 
-// Apply the specified settings to a repository
-func (client *Client) Apply(settings *Settings) error {
-	githubSettings, err := client.GetSettingsFromGithub(settings.Repository.Owner, settings.Repository.Name)
+// Apply the specified settings to a repository and fails when changes were skipped
+func (client *Client) Apply(ctx context.Context, settings *Settings) (*Result, error) {
+	result, err := client.apply(ctx, settings, nil)
 
-	if err != nil {
-		return errors.Wrap(err, "Error getting settings from github")
+	if err == nil {
+		err = skippedError(result.Skipped)
 	}
 
-	err = client.updateRepoSettings(settings.Disable.Repository, settings.Repository.Owner, settings.Repository.Name, githubSettings.Repository, settings.Repository)
+	return result, err
+}
+
+func (client *Client) apply(ctx context.Context, settings *Settings, report reporter) (*Result, error) {
+	plan, err := client.Plan(ctx, settings)
 
 	if err != nil {
-		return errors.Wrap(err, "Error updating repository settings")
+		return nil, err
 	}
 
-	err = client.updateLabels(settings.Disable.Labels, settings.Repository.Owner, settings.Repository.Name, githubSettings.Labels, settings.Labels)
+	return client.executePlan(ctx, plan, report)
+}
+
+// GetSettingsFromGithub returns the settings current applied on a github repository
+func (client *Client) GetSettingsFromGithub(ctx context.Context, owner string, name string) (*Settings, error) {
+	return client.getSettings(ctx, owner, name, func(string) bool { return true })
+}
+
+// getSettings returns the live settings of a repository with the sections of the resource kinds fetched, the others are left empty
+// so planning settings needs no more permissions than the sections they manage (ex: listing collaborators requires admin access)
+func (client *Client) getSettings(ctx context.Context, owner, name string, fetch func(resource string) bool) (*Settings, error) {
+	ctx = withRetries(ctx)
+
+	githubRepo, _, err := client.github.Repositories.Get(ctx, owner, name)
 
 	if err != nil {
-		return errors.Wrap(err, "Error updating repository labels")
+		return nil, errors.Wrap(err, "Error while getting repository from github")
+	}
+
+	settings := &Settings{
+		Topics: emptyToNil(githubRepo.Topics),
+		Repository: repository{
+			Name:             githubRepo.GetName(),
+			Owner:            githubRepo.Owner.GetLogin(),
+			Description:      githubRepo.GetDescription(),
+			Homepage:         githubRepo.GetHomepage(),
+			DefaultBranch:    githubRepo.GetDefaultBranch(),
+			Private:          githubRepo.GetPrivate(),
+			HasIssues:        githubRepo.GetHasIssues(),
+			HasProjects:      githubRepo.GetHasProjects(),
+			HasWiki:          githubRepo.GetHasWiki(),
+			HasDownloads:     githubRepo.GetHasDownloads(),
+			IsTemplate:       githubRepo.GetIsTemplate(),
+			AllowSquashMerge: githubRepo.GetAllowSquashMerge(),
+			AllowMergeCommit: githubRepo.GetAllowMergeCommit(),
+			AllowRebaseMerge: githubRepo.GetAllowRebaseMerge(),
+			Archived:         githubRepo.GetArchived(),
+			Visibility:       visibility(githubRepo.GetVisibility()),
+
+			MergeCommitTitle:         mergeCommitTitle(githubRepo.GetMergeCommitTitle()),
+			MergeCommitMessage:       mergeCommitMessage(githubRepo.GetMergeCommitMessage()),
+			SquashMergeCommitTitle:   squashMergeCommitTitle(githubRepo.GetSquashMergeCommitTitle()),
+			SquashMergeCommitMessage: squashMergeCommitMessage(githubRepo.GetSquashMergeCommitMessage()),
+			Security:                 newSecurity(githubRepo.GetSecurityAndAnalysis()),
+		},
+		Status: newStatus(githubRepo),
 	}
 
-	err = client.updateBranchSettings(settings.Disable.Branches, settings.Repository.Owner, settings.Repository.Name, githubSettings.Branches, settings.Branches)
+	if fetch(ResourceLabels) {
+		settings.Labels, err = client.getLabels(ctx, owner, name)
 
-	if err != nil {
-		return errors.Wrap(err, "Error updating repository branches protection")
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	err = client.updateWebhooks(settings.Disable.Webhooks, settings.Repository.Owner, settings.Repository.Name, githubSettings.Webhooks, settings.Webhooks)
+	// The rulesets are compared with the live branches they match
+	if fetch(ResourceBranches) || fetch(ResourceRulesets) {
+		settings.Branches, err = client.getBranches(ctx, owner, name)
 
-	if err != nil {
-		return errors.Wrap(err, "Error updating repository webhooks")
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	err = client.updateTopicsSettings(settings.Disable.Topics, settings.Repository.Owner, settings.Repository.Name, githubSettings.Topics, settings.Topics)
+	if fetch(ResourceWebhooks) {
+		settings.Webhooks, err = client.getWebhooks(ctx, owner, name)
 
-	if err != nil {
-		return errors.Wrap(err, "Error updating repository topics")
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	return nil
-}
+	if fetch(ResourceCollaborators) {
+		settings.Collaborators, err = client.getCollaborators(ctx, owner, name)
 
-// GetSettingsFromGithub returns the settings current applied on a github repository
-func (client *Client) GetSettingsFromGithub(owner string, name string) (*Settings, error) {
-	githubRepo, _, err := client.github.Repositories.Get(context.Background(), owner, name)
+		if err != nil {
+			return nil, err
+		}
+	}
 
-	if err != nil {
-		return nil, errors.Wrap(err, "Error while getting repository from github")
+	// The teams endpoints only exist for organizations
+	if fetch(ResourceTeams) && githubRepo.GetOwner().GetType() != ownerUser {
+		settings.Teams, err = client.getTeams(ctx, owner, name)
+
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	githubLabels, _, err := client.github.Issues.ListLabels(context.Background(), owner, name, &github.ListOptions{})
+	if fetch(ResourceSecrets) {
+		settings.Secrets, err = client.getSecrets(ctx, owner, name)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if fetch(ResourceVariables) {
+		settings.Variables, err = client.getVariables(ctx, owner, name)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if fetch(ResourceDependabotSecrets) {
+		settings.DependabotSecrets, err = client.getDependabotSecrets(ctx, owner, name)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if fetch(ResourceEnvironments) {
+		settings.Environments, err = client.getEnvironments(ctx, owner, name, githubRepo.GetID())
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if fetch(ResourceRulesets) {
+		settings.Rulesets, err = client.getRulesets(ctx, owner, name)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return settings, nil
+}
+
+func (client *Client) getLabels(ctx context.Context, owner, name string) ([]label, error) {
+	githubLabels, err := listAll(func(opts github.ListOptions) ([]*github.Label, *github.Response, error) {
+		return client.github.Issues.ListLabels(ctx, owner, name, &opts)
+	})
 
 	if err != nil {
 		return nil, errors.Wrap(err, "Error while getting labels from github")
@@ -212,50 +591,42 @@ func (client *Client) GetSettingsFromGithub(owner string, name string) (*Setting
 		})
 	}
 
+	return labelSettings, nil
+}
+
+func (client *Client) getBranches(ctx context.Context, owner, name string) ([]branch, error) {
 	branchesSettings := []branch{}
 
-	githubBranches, _, err := client.github.Repositories.ListBranches(context.Background(), owner, name, &github.ListOptions{})
+	githubBranches, err := listAll(func(opts github.ListOptions) ([]*github.Branch, *github.Response, error) {
+		return client.github.Repositories.ListBranches(ctx, owner, name, &github.BranchListOptions{ListOptions: opts})
+	})
 
 	if err != nil {
 		return nil, errors.Wrap(err, "Error while listing branches")
 	}
 
 	for _, githubBranch := range githubBranches {
-		if githubBranch.GetProtected() {
-			githubProtection, _, err := client.github.Repositories.GetBranchProtection(context.Background(), owner, name, githubBranch.GetName())
-
-			if err != nil {
-				return nil, errors.Wrap(err, "Error while getting branch protection")
-			}
-
-			var requiredReview requiredApprovingReviewCount
-
-			if githubProtection.RequiredPullRequestReviews != nil {
-				requiredReview = requiredApprovingReviewCount{
-					RequiredApprovingReviewCount: githubProtection.RequiredPullRequestReviews.RequiredApprovingReviewCount,
-					RequireCodeOwnerReviews:      githubProtection.RequiredPullRequestReviews.RequireCodeOwnerReviews,
-					DismissStaleReviews:          githubProtection.RequiredPullRequestReviews.DismissStaleReviews,
-				}
-			}
-
-			branchesSettings = append(branchesSettings, branch{
-				Name: githubBranch.GetName(),
-				Protection: protection{
-					Enabled:                      true,
-					EnforceAdmins:                githubProtection.GetEnforceAdmins().Enabled,
-					RequiredApprovingReviewCount: requiredReview,
-					RequiredStatusChecks: requiredStatusChecks{
-						Strict:   githubProtection.RequiredStatusChecks.Strict,
-						Contexts: githubProtection.RequiredStatusChecks.Contexts,
-					},
-				},
-			})
-		} else {
+		if !githubBranch.GetProtected() {
 			branchesSettings = append(branchesSettings, branch{Name: githubBranch.GetName()})
+			continue
+		}
+
+		branchProtection, err := client.getProtection(ctx, owner, name, githubBranch.GetName())
+
+		if err != nil {
+			return nil, err
 		}
+
+		branchesSettings = append(branchesSettings, branch{Name: githubBranch.GetName(), Protection: branchProtection})
 	}
 
-	hooks, _, err := client.github.Repositories.ListHooks(context.Background(), owner, name, &github.ListOptions{})
+	return branchesSettings, nil
+}
+
+func (client *Client) getWebhooks(ctx context.Context, owner, name string) ([]webhook, error) {
+	hooks, err := listAll(func(opts github.ListOptions) ([]*github.Hook, *github.Response, error) {
+		return client.github.Repositories.ListHooks(ctx, owner, name, &opts)
+	})
 
 	if err != nil {
 		return nil, errors.Wrap(err, "Error getting webhooks")
@@ -266,40 +637,72 @@ func (client *Client) GetSettingsFromGithub(owner string, name string) (*Setting
 	for _, hook := range hooks {
 		webhooksSettings = append(webhooksSettings, webhook{
 			ID:          hook.GetID(),
-			URL:         hook.Config["url"].(string),
-			ContentType: hook.Config["content_type"].(string),
-			Secret:      hook.Config["secret"].(string),
+			URL:         hook.GetConfig().GetURL(),
+			ContentType: contentType(hook.GetConfig().GetContentType()),
+			Secret:      hook.GetConfig().GetSecret(),
 			Events:      hook.Events,
 		})
 	}
 
-	return &Settings{
-		Topics: githubRepo.Topics,
-		Repository: repository{
-			Name:             githubRepo.GetName(),
-			Owner:            githubRepo.Owner.GetLogin(),
-			Description:      githubRepo.GetDescription(),
-			Homepage:         githubRepo.GetHomepage(),
-			DefaultBranch:    githubRepo.GetDefaultBranch(),
-			Private:          githubRepo.GetPrivate(),
-			HasIssues:        githubRepo.GetHasIssues(),
-			HasProjects:      githubRepo.GetHasProjects(),
-			HasWiki:          githubRepo.GetHasWiki(),
-			HasDownloads:     githubRepo.GetHasDownloads(),
-			IsTemplate:       githubRepo.GetIsTemplate(),
-			AllowSquashMerge: githubRepo.GetAllowSquashMerge(),
-			AllowMergeCommit: githubRepo.GetAllowMergeCommit(),
-			AllowRebaseMerge: githubRepo.GetAllowRebaseMerge(),
-			Archived:         githubRepo.GetArchived(),
-		},
-		Labels:   labelSettings,
-		Branches: branchesSettings,
-		Webhooks: webhooksSettings,
+	return webhooksSettings, nil
+}
+
+// getProtection reads the protection of a protected branch
+func (client *Client) getProtection(ctx context.Context, owner, name, branchName string) (protection, error) {
+	githubProtection, _, err := client.github.Repositories.GetBranchProtection(ctx, owner, name, branchName)
+
+	if err != nil {
+		return protection{}, errors.Wrap(err, "Error while getting branch protection")
+	}
+
+	var requiredReview requiredApprovingReviewCount
+	var requiredChecks requiredStatusChecks
+
+	if githubProtection.RequiredPullRequestReviews != nil {
+		requiredReview = requiredApprovingReviewCount{
+			RequiredApprovingReviewCount: githubProtection.RequiredPullRequestReviews.RequiredApprovingReviewCount,
+			RequireCodeOwnerReviews:      githubProtection.RequiredPullRequestReviews.RequireCodeOwnerReviews,
+			DismissStaleReviews:          githubProtection.RequiredPullRequestReviews.DismissStaleReviews,
+		}
+
+		if dismissal := githubProtection.RequiredPullRequestReviews.DismissalRestrictions; dismissal != nil {
+			requiredReview.DismissalRestrictions = newRestrictions(dismissal.Users, dismissal.Teams, dismissal.Apps)
+		}
+
+		if bypass := githubProtection.RequiredPullRequestReviews.BypassPullRequestAllowances; bypass != nil {
+			requiredReview.BypassPullRequestAllowances = newRestrictions(bypass.Users, bypass.Teams, bypass.Apps)
+		}
+	}
+
+	if githubProtection.RequiredStatusChecks != nil {
+		requiredChecks = requiredStatusChecks{
+			Strict:   githubProtection.RequiredStatusChecks.Strict,
+			Contexts: emptyToNil(githubProtection.RequiredStatusChecks.GetContexts()),
+		}
+	}
+
+	var pushRestrictions *restrictions
+
+	if githubProtection.Restrictions != nil {
+		pushRestrictions = newRestrictions(githubProtection.Restrictions.Users, githubProtection.Restrictions.Teams, githubProtection.Restrictions.Apps)
+	}
+
+	return protection{
+		Enabled:                        true,
+		EnforceAdmins:                  githubProtection.GetEnforceAdmins().Enabled,
+		RequiredApprovingReviewCount:   requiredReview,
+		RequiredStatusChecks:           requiredChecks,
+		Restrictions:                   pushRestrictions,
+		RequiredSignatures:             githubProtection.GetRequiredSignatures().GetEnabled(),
+		RequiredLinearHistory:          githubProtection.RequireLinearHistory != nil && githubProtection.RequireLinearHistory.Enabled,
+		AllowForcePushes:               githubProtection.AllowForcePushes != nil && githubProtection.AllowForcePushes.Enabled,
+		AllowDeletions:                 githubProtection.AllowDeletions != nil && githubProtection.AllowDeletions.Enabled,
+		RequiredConversationResolution: githubProtection.RequiredConversationResolution != nil && githubProtection.RequiredConversationResolution.Enabled,
 	}, nil
 }
 
-func (client *Client) createBranch(branches []string, url string) error {
-	repo, err := git.Clone(memory.NewStorage(), memfs.New(), &git.CloneOptions{
+func (client *Client) createBranch(ctx context.Context, branches []string, url string) error {
+	repo, err := git.CloneContext(ctx, memory.NewStorage(), memfs.New(), &git.CloneOptions{
 		URL: url,
 	})
 
@@ -320,7 +723,7 @@ func (client *Client) createBranch(branches []string, url string) error {
 			return errors.Wrapf(err, "Error setting reference storer for branch %s", branch)
 		}
 
-		err = repo.Push(&git.PushOptions{})
+		err = repo.PushContext(ctx, &git.PushOptions{})
 
 		if err != nil {
 			return errors.Wrapf(err, "Error pushing reference for branch %s", branch)
@@ -330,6 +733,77 @@ func (client *Client) createBranch(branches []string, url string) error {
 	return nil
 }
 
-func fmtGithubURL(owner, name, token string) string {
-	return fmt.Sprintf("https://%s@github.com/%s/%s.git", token, owner, name)
+func newRestrictions(users []*github.User, teams []*github.Team, apps []*github.App) *restrictions {
+	result := &restrictions{}
+
+	for _, user := range users {
+		result.Users = append(result.Users, user.GetLogin())
+	}
+
+	for _, team := range teams {
+		result.Teams = append(result.Teams, team.GetSlug())
+	}
+
+	for _, app := range apps {
+		result.Apps = append(result.Apps, app.GetSlug())
+	}
+
+	return result
+}
+
+// emptyToNil normalizes empty lists returned by github to match unset lists in the settings file
+func emptyToNil(values []string) []string {
+	if len(values) == 0 {
+		return nil
+	}
+
+	return values
+}
+
+// optionalString returns nil for an empty string so github keeps the current value of the field
+func optionalString(value string) *string {
+	if value == "" {
+		return nil
+	}
+
+	return github.String(value)
+}
+
+// desiredTopics returns the topics of the settings with the topics of their annotations, sorted and without duplicates
+// The settings are left untouched, the topics are copied before being extended
+func desiredTopics(settings *Settings) []string {
+	topics := []string{}
+
+	for _, topic := range append(append([]string{}, settings.Topics...), annotationTopics(settings.Annotations)...) {
+		topics = appendDistinct(topics, topic)
+	}
+
+	sort.Strings(topics)
+
+	return emptyToNil(topics)
+}
+
+// annotationTopics converts annotations to topics (ex: owner: platform-team becomes owner-platform-team)
+func annotationTopics(annotations map[string]string) []string {
+	topics := make([]string, 0, len(annotations))
+
+	for key, value := range annotations {
+		topic := invalidTopicChars.ReplaceAllString(strings.ToLower(key+"-"+value), "-")
+		topic = strings.Trim(topic, "-")
+
+		if len(topic) > maxTopicLength {
+			topic = strings.TrimRight(topic[:maxTopicLength], "-")
+		}
+
+		topics = append(topics, topic)
+	}
+
+	sort.Strings(topics)
+
+	return topics
+}
+
+// fmtGithubURL returns the git url of a repository, the token is sent as the password like app installation tokens require
+func fmtGithubURL(host, owner, name, token string) string {
+	return fmt.Sprintf("https://x-access-token:%s@%s/%s/%s.git", token, host, owner, name)
 }
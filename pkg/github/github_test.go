@@ -0,0 +1,109 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/michaelmass/github-settings/pkg/githubtest/server"
+)
+
+func FuzzGetSettingsFromBytes(f *testing.F) {
+	f.Add([]byte("repository: {owner: acme, name: api}\n"))
+	f.Add([]byte(convergingSettings))
+	f.Add([]byte("anchors:\n  bug: &bug {name: bug}\nrepository: {owner: acme, name: api}\nlabels: [*bug]\n"))
+	f.Add([]byte("repository: {owner: acme, name: api}\nbranches: [{name: main, protection: {requiredapprovingreviewcount: 2}}]\n"))
+	f.Add([]byte("repository: {owner: acme, name: api}\ncollaborators: [{username: alice, permission: write}]\n"))
+	f.Add([]byte("repository: {owner: acme, name: api}\nrulesets: [{name: release, enforcement: evaluate}]\n"))
+
+	f.Fuzz(func(t *testing.T, content []byte) {
+		settings, err := GetSettingsFromBytes(content)
+
+		if err != nil {
+			return
+		}
+
+		// The settings read must survive being written back like export does
+		marshaled, err := MarshalSettings(settings)
+
+		if err != nil {
+			t.Fatalf("Error marshal parsed settings: %v", err)
+		}
+
+		_, err = GetSettingsFromBytes(marshaled)
+
+		if err != nil {
+			t.Fatalf("Error parsing marshaled settings %q: %v", marshaled, err)
+		}
+	})
+}
+
+// randomRepository fills a fake repository with random labels, webhooks, topics, collaborators, teams and actions variables
+func randomRepository(random *rand.Rand, repo *server.Repository) {
+	for i := random.Intn(20); i > 0; i-- {
+		name := fmt.Sprintf("label-%d", random.Intn(1000))
+		repo.SetLabel(name, fmt.Sprintf("%06x", random.Intn(0xffffff)), fmt.Sprintf("Label %d", i))
+	}
+
+	for i := random.Intn(3); i > 0; i-- {
+		repo.AddHook(fmt.Sprintf("https://hooks.acme.dev/%d", i), "push")
+	}
+
+	topics := []string{}
+
+	for i := random.Intn(5); i > 0; i-- {
+		topics = appendDistinct(topics, fmt.Sprintf("topic-%d", random.Intn(10)))
+	}
+
+	roles := []string{"read", "triage", "write", "maintain", "admin"}
+
+	for i := random.Intn(5); i > 0; i-- {
+		repo.Collaborators[fmt.Sprintf("user-%d", random.Intn(50))] = roles[random.Intn(len(roles))]
+	}
+
+	permissions := []string{"pull", "triage", "push", "maintain", "admin"}
+
+	for i := random.Intn(3); i > 0; i-- {
+		repo.Teams[fmt.Sprintf("team-%d", random.Intn(10))] = permissions[random.Intn(len(permissions))]
+	}
+
+	for i := random.Intn(5); i > 0; i-- {
+		repo.Variables[fmt.Sprintf("VARIABLE_%d", random.Intn(50))] = fmt.Sprintf("value-%d", random.Intn(1000))
+	}
+
+	repo.SetAttributes(map[string]interface{}{
+		"topics":             topics,
+		"description":        fmt.Sprintf("Repository %d", random.Intn(1000)),
+		"has_wiki":           random.Intn(2) == 0,
+		"allow_rebase_merge": random.Intn(2) == 0,
+	})
+}
+
+// TestExportThenPlanHasNoChanges checks that the exported settings of random repositories describe them exactly
+func TestExportThenPlanHasNoChanges(t *testing.T) {
+	for seed := int64(0); seed < 50; seed++ {
+		t.Run(fmt.Sprintf("seed %d", seed), func(t *testing.T) {
+			server, client := newTestClient(t)
+			randomRepository(rand.New(rand.NewSource(seed)), server.AddRepository("acme", "api"))
+
+			exported, err := client.Export(context.Background(), "acme", "api")
+
+			if err != nil {
+				t.Fatalf("Error exporting settings: %v", err)
+			}
+
+			content, err := MarshalSettings(exported)
+
+			if err != nil {
+				t.Fatalf("Error marshal exported settings: %v", err)
+			}
+
+			plan := planOf(t, client, settingsFromYAML(t, string(content)))
+
+			if !plan.Empty() {
+				t.Errorf("Plan of the exported settings has changes:\n%s\nexported:\n%s", plan, content)
+			}
+		})
+	}
+}
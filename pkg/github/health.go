@@ -0,0 +1,78 @@
+package github
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/go-github/v75/github"
+	"github.com/pkg/errors"
+)
+
+// webhookHealthField is the field of the changes reporting an unhealthy webhook
+const webhookHealthField = "health"
+
+// unhealthyWebhooks checks the recent deliveries of the webhooks of the settings that exist on github and reports the
+// webhooks whose last deliveries all failed, a configured webhook that can't deliver is as good as a missing one
+// The changes are only reported, apply can't fix the endpoint receiving the deliveries
+func (client *Client) unhealthyWebhooks(ctx context.Context, githubSettings, settings *Settings) ([]Change, error) {
+	if client.webhookFailures <= 0 || settings.Disable.Webhooks {
+		return nil, nil
+	}
+
+	githubWebhooks := map[int64]webhook{}
+
+	for _, githubWebhook := range githubSettings.Webhooks {
+		githubWebhooks[githubWebhook.ID] = githubWebhook
+	}
+
+	changes := []Change{}
+
+	for _, webhookSettings := range settings.Webhooks {
+		githubWebhook, ok := findWebhook(githubWebhooks, webhookSettings)
+
+		if !ok {
+			continue
+		}
+
+		deliveries, _, err := client.github.Repositories.ListHookDeliveries(ctx, settings.Repository.Owner, settings.Repository.Name, githubWebhook.ID, &github.ListCursorOptions{PerPage: client.webhookFailures})
+
+		if err != nil {
+			return nil, errors.Wrapf(err, "Error getting the deliveries of webhook %s", webhookSettings.URL)
+		}
+
+		if !failing(deliveries, client.webhookFailures) {
+			continue
+		}
+
+		changes = append(changes, Change{
+			Resource: ResourceWebhooks,
+			Name:     webhookSettings.URL,
+			Action:   ActionUpdate,
+			Fields: []FieldChange{{
+				Field:  webhookHealthField,
+				Before: fmt.Sprintf("unhealthy, the last %d deliveries failed (%s)", len(deliveries), deliveries[0].GetStatus()),
+				After:  "healthy",
+			}},
+			ReportOnly: true,
+			Reason:     webhookSettings.Reason,
+			Ticket:     webhookSettings.Ticket,
+		})
+	}
+
+	return changes, nil
+}
+
+// failing returns true when there are at least as many deliveries as the failures and none of them succeeded
+func failing(deliveries []*github.HookDelivery, failures int) bool {
+	if len(deliveries) < failures {
+		return false
+	}
+
+	for _, delivery := range deliveries {
+		if status := delivery.GetStatusCode(); status >= 200 && status < 300 {
+			return false
+		}
+	}
+
+	return true
+}
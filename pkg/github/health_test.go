@@ -0,0 +1,60 @@
+package github
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+const webhookSettings = `
+repository: {owner: acme, name: api}
+disable: {repository: true}
+webhooks:
+  - {url: "https://ci.example.com/hook", contenttype: json, events: [push]}
+`
+
+func TestPlanReportsUnhealthyWebhooks(t *testing.T) {
+	failed, succeeded := 500, 200
+
+	for _, test := range []struct {
+		name       string
+		failures   int
+		deliveries []int
+		unhealthy  bool
+	}{
+		{"every recent delivery failed", 3, []int{failed, failed, failed, succeeded}, true},
+		{"a recent delivery succeeded", 3, []int{failed, succeeded, failed}, false},
+		{"too few deliveries", 3, []int{failed, failed}, false},
+		{"health not checked", 0, []int{failed, failed, failed}, false},
+	} {
+		server, client := newTestClient(t, WithWebhookHealth(test.failures))
+		repo := server.AddRepository("acme", "api")
+		repo.SetHookDeliveries(repo.AddHook("https://ci.example.com/hook", "push"), test.deliveries...)
+
+		plan := planOf(t, client, settingsFromYAML(t, webhookSettings))
+
+		if unhealthy := len(plan.Changes) == 1 && plan.Changes[0].ReportOnly && plan.Changes[0].Fields[0].Field == webhookHealthField; unhealthy != test.unhealthy {
+			t.Errorf("%s: planned %v, want the webhook reported as unhealthy: %t", test.name, plan.Changes, test.unhealthy)
+		}
+
+		if test.unhealthy && !strings.Contains(plan.String(), "the last 3 deliveries failed (Invalid HTTP Response: 500)") {
+			t.Errorf("%s: the plan does not describe the failures:\n%s", test.name, plan)
+		}
+	}
+}
+
+func TestApplyOnlyReportsUnhealthyWebhooks(t *testing.T) {
+	server, client := newTestClient(t, WithWebhookHealth(1))
+	repo := server.AddRepository("acme", "api")
+	repo.SetHookDeliveries(repo.AddHook("https://ci.example.com/hook", "push"), 404)
+
+	result, err := client.Apply(context.Background(), settingsFromYAML(t, webhookSettings))
+
+	if err != nil {
+		t.Fatalf("Error applying settings: %v", err)
+	}
+
+	if len(result.Applied) != 0 || len(result.Reported) != 1 {
+		t.Errorf("Applied %v and reported %v, want the unhealthy webhook reported only", result.Applied, result.Reported)
+	}
+}
@@ -0,0 +1,381 @@
+package github
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	// The sqlite driver is written in go, the binary keeps building without cgo
+	_ "modernc.org/sqlite"
+)
+
+// DefaultHistoryLimit is the number of entries returned by a history query without limit
+const DefaultHistoryLimit = 20
+
+// historySchema creates the table of the entries, the changes are stored as json
+const historySchema = `
+CREATE TABLE IF NOT EXISTS history (
+	id         INTEGER PRIMARY KEY,
+	repository TEXT NOT NULL,
+	time       INTEGER NOT NULL,
+	drift      INTEGER NOT NULL,
+	changes    TEXT NOT NULL,
+	applied    TEXT,
+	failed     TEXT NOT NULL,
+	error      TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS history_repository_time ON history (repository, time);
+`
+
+// History persists the outcome of every repository reconciled by serve or applied, so the drift of a repository can be
+// queried without an external system
+type History struct {
+	db *sql.DB
+}
+
+// HistoryEntry is the outcome of a repository at a point in time
+type HistoryEntry struct {
+	Repository string
+	Time       time.Time
+	// Changes are the drift planned, the repository had none of it when empty
+	Changes []Change
+	// Applied are the changes made on github, it is nil when the drift was only reported
+	Applied []Change
+	// Failed are the changes github rejected
+	Failed []Change `json:",omitempty"`
+	// Error is the error planning or applying the repository
+	Error string `json:",omitempty"`
+}
+
+// HistoryQuery selects the entries returned by Query
+type HistoryQuery struct {
+	// Repository is the full name (owner/name) of a repository, every repository when empty
+	Repository string
+	// Since only returns the entries recorded after it
+	Since time.Time
+	// Drift only returns the entries with changes or an error
+	Drift bool
+	// Limit is the maximum number of entries, DefaultHistoryLimit when 0
+	Limit int
+}
+
+// OpenHistory opens the history of a sqlite database file and creates it when missing
+func OpenHistory(path string) (*History, error) {
+	// The history command reads the database while serve writes to it
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error opening history %s", path)
+	}
+
+	db.SetMaxOpenConns(1)
+
+	_, err = db.Exec(historySchema)
+
+	if err != nil {
+		db.Close()
+		return nil, errors.Wrapf(err, "Error creating history %s", path)
+	}
+
+	return &History{db: db}, nil
+}
+
+// Close closes the database of the history
+func (history *History) Close() error {
+	return history.db.Close()
+}
+
+// Record adds the results of a run to the history, the results of the repositories applied record their applied changes
+func (history *History) Record(ctx context.Context, results Results, now time.Time) error {
+	tx, err := history.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return errors.Wrap(err, "Error recording history")
+	}
+
+	defer tx.Rollback() // nolint:errcheck
+
+	for _, result := range results {
+		entry := newHistoryEntry(result, now)
+		changes, err := json.Marshal(emptyToNilChanges(entry.Changes))
+
+		if err != nil {
+			return errors.Wrapf(err, "Error recording history of %s", entry.Repository)
+		}
+
+		var appliedChanges []byte
+
+		if entry.Applied != nil {
+			appliedChanges, err = json.Marshal(entry.Applied)
+
+			if err != nil {
+				return errors.Wrapf(err, "Error recording history of %s", entry.Repository)
+			}
+		}
+
+		failed, err := json.Marshal(emptyToNilChanges(entry.Failed))
+
+		if err != nil {
+			return errors.Wrapf(err, "Error recording history of %s", entry.Repository)
+		}
+
+		_, err = tx.ExecContext(ctx, "INSERT INTO history (repository, time, drift, changes, applied, failed, error) VALUES (?, ?, ?, ?, ?, ?, ?)",
+			entry.Repository, entry.Time.UnixNano(), len(entry.Changes), string(changes), nullString(appliedChanges), string(failed), entry.Error)
+
+		if err != nil {
+			return errors.Wrapf(err, "Error recording history of %s", entry.Repository)
+		}
+	}
+
+	err = tx.Commit()
+
+	if err != nil {
+		return errors.Wrap(err, "Error recording history")
+	}
+
+	return nil
+}
+
+// newHistoryEntry converts the result of a repository, the changes of a repository skipped by the apply cache are unknown
+func newHistoryEntry(result RepositoryResult, now time.Time) HistoryEntry {
+	entry := HistoryEntry{Repository: result.Repository, Time: now.UTC()}
+
+	if result.Plan != nil {
+		entry.Changes = result.Plan.Changes
+	}
+
+	if result.Err != nil {
+		entry.Error = result.Err.Error()
+	}
+
+	if result.Result != nil {
+		entry.Applied = append([]Change{}, result.Result.Applied...)
+		entry.Failed = result.Result.Failed
+	}
+
+	return entry
+}
+
+// Query returns the entries of the history matching the query, the most recent first
+func (history *History) Query(ctx context.Context, query HistoryQuery) ([]HistoryEntry, error) {
+	conditions := []string{"time >= ?"}
+	args := []interface{}{query.Since.UnixNano()}
+
+	if query.Since.IsZero() {
+		args[0] = int64(0)
+	}
+
+	if query.Repository != "" {
+		conditions = append(conditions, "repository = ?")
+		args = append(args, query.Repository)
+	}
+
+	if query.Drift {
+		conditions = append(conditions, "(drift > 0 OR error != '')")
+	}
+
+	if query.Limit == 0 {
+		query.Limit = DefaultHistoryLimit
+	}
+
+	args = append(args, query.Limit)
+
+	rows, err := history.db.QueryContext(ctx, "SELECT repository, time, changes, applied, failed, error FROM history WHERE "+
+		strings.Join(conditions, " AND ")+" ORDER BY time DESC, id DESC LIMIT ?", args...)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Error querying history")
+	}
+
+	defer rows.Close()
+
+	entries := []HistoryEntry{}
+
+	for rows.Next() {
+		entry, err := scanHistoryEntry(rows)
+
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "Error querying history")
+	}
+
+	return entries, nil
+}
+
+func scanHistoryEntry(rows *sql.Rows) (HistoryEntry, error) {
+	entry := HistoryEntry{}
+	var nanoseconds int64
+	var changes, failed string
+	var applied sql.NullString
+
+	err := rows.Scan(&entry.Repository, &nanoseconds, &changes, &applied, &failed, &entry.Error)
+
+	if err != nil {
+		return entry, errors.Wrap(err, "Error reading history")
+	}
+
+	entry.Time = time.Unix(0, nanoseconds).UTC()
+
+	for _, column := range []struct {
+		value   string
+		changes *[]Change
+	}{{changes, &entry.Changes}, {applied.String, &entry.Applied}, {failed, &entry.Failed}} {
+		if column.value == "" {
+			continue
+		}
+
+		err = json.Unmarshal([]byte(column.value), column.changes)
+
+		if err != nil {
+			return entry, errors.Wrapf(err, "Error reading history of %s", entry.Repository)
+		}
+	}
+
+	if applied.Valid && entry.Applied == nil {
+		entry.Applied = []Change{}
+	}
+
+	return entry, nil
+}
+
+// String describes the entry with its changes like a plan
+func (entry HistoryEntry) String() string {
+	builder := &strings.Builder{}
+	fmt.Fprintf(builder, "%s %s: ", entry.Time.Format(time.RFC3339), entry.Repository)
+
+	switch {
+	case entry.Error != "":
+		fmt.Fprintf(builder, "error, %s\n", entry.Error)
+	case len(entry.Changes) == 0:
+		fmt.Fprintf(builder, "no drift\n")
+	case entry.Applied == nil:
+		fmt.Fprintf(builder, "%d changes reported\n", len(entry.Changes))
+	default:
+		fmt.Fprintf(builder, "%d changes, %d applied, %d failed\n", len(entry.Changes), len(entry.Applied), len(entry.Failed))
+	}
+
+	for _, change := range entry.Changes {
+		fmt.Fprintf(builder, "  %s\n", strings.TrimSpace(fmt.Sprintf("%s %s %s", actionSymbols[change.Action], change.Resource, change.Name)))
+
+		for _, line := range change.FieldLines() {
+			fmt.Fprintf(builder, "      %s\n", line)
+		}
+	}
+
+	return builder.String()
+}
+
+func emptyToNilChanges(changes []Change) []Change {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	return changes
+}
+
+// nullString stores an empty value as null
+func nullString(value []byte) sql.NullString {
+	return sql.NullString{String: string(value), Valid: value != nil}
+}
+
+// RepositoryStatus is the latest entry of a repository in the history
+type RepositoryStatus struct {
+	HistoryEntry
+	// LastApplied is the last time changes were applied to the repository, zero when never
+	LastApplied time.Time
+}
+
+// Statuses returns the latest entry of every repository of the history, sorted by repository
+func (history *History) Statuses(ctx context.Context) ([]RepositoryStatus, error) {
+	rows, err := history.db.QueryContext(ctx, `SELECT repository, time, changes, applied, failed, error FROM history
+		WHERE id IN (SELECT MAX(id) FROM history GROUP BY repository) ORDER BY repository`)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Error querying history")
+	}
+
+	defer rows.Close()
+
+	statuses := []RepositoryStatus{}
+
+	for rows.Next() {
+		entry, err := scanHistoryEntry(rows)
+
+		if err != nil {
+			return nil, err
+		}
+
+		statuses = append(statuses, RepositoryStatus{HistoryEntry: entry})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "Error querying history")
+	}
+
+	applied, err := history.lastApplied(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range statuses {
+		statuses[i].LastApplied = applied[statuses[i].Repository]
+	}
+
+	return statuses, nil
+}
+
+// State summarizes the latest entry of the repository: failed, in sync, or drifted with its changes applied or only reported
+func (status RepositoryStatus) State() string {
+	switch {
+	case status.Error != "":
+		return "failed"
+	case len(status.Changes) == 0:
+		return "in sync"
+	case status.Applied != nil:
+		return fmt.Sprintf("applied %d changes", len(status.Applied))
+	default:
+		return fmt.Sprintf("drifted, %d changes", len(status.Changes))
+	}
+}
+
+// lastApplied returns the last time changes were applied to each repository
+func (history *History) lastApplied(ctx context.Context) (map[string]time.Time, error) {
+	rows, err := history.db.QueryContext(ctx, "SELECT repository, MAX(time) FROM history WHERE applied IS NOT NULL AND applied != '[]' GROUP BY repository")
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Error querying history")
+	}
+
+	defer rows.Close()
+
+	applied := map[string]time.Time{}
+
+	for rows.Next() {
+		var repository string
+		var nanoseconds int64
+
+		if err := rows.Scan(&repository, &nanoseconds); err != nil {
+			return nil, errors.Wrap(err, "Error reading history")
+		}
+
+		applied[repository] = time.Unix(0, nanoseconds).UTC()
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "Error querying history")
+	}
+
+	return applied, nil
+}
@@ -0,0 +1,84 @@
+package github
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHistoryRecordsReconciliations(t *testing.T) {
+	server, client := newTestClient(t)
+	server.AddRepository("acme", "api")
+
+	history, err := OpenHistory(filepath.Join(t.TempDir(), "history.db"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer history.Close()
+
+	config := writeSettings(t, "repository: {owner: acme, name: api}\ndisable: {repository: true}\nlabels: [{name: bug, color: d73a4a}]\n")
+	options := ServeOptions{Config: config, Enforce: true, History: history, Notifier: &recordingNotifier{}}
+
+	client.reconcile(context.Background(), options, reconciliation{})
+	client.reconcile(context.Background(), options, reconciliation{})
+
+	entries, err := history.Query(context.Background(), HistoryQuery{Repository: "acme/api"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 2 {
+		t.Fatalf("History of acme/api has %d entries, want 2", len(entries))
+	}
+
+	if len(entries[0].Changes) != 0 || entries[0].Error != "" {
+		t.Errorf("Latest entry is %+v, want no drift", entries[0])
+	}
+
+	if len(entries[1].Changes) != 1 || len(entries[1].Applied) != 1 || entries[1].Changes[0].Name != "bug" {
+		t.Errorf("First entry is %+v, want the label bug applied", entries[1])
+	}
+
+	if !strings.Contains(entries[1].String(), "+ labels bug") {
+		t.Errorf("First entry is described as %q", entries[1])
+	}
+
+	drift, err := history.Query(context.Background(), HistoryQuery{Drift: true})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(drift) != 1 || drift[0].Repository != "acme/api" {
+		t.Errorf("Drift history is %+v, want the first reconciliation of acme/api", drift)
+	}
+
+	recent, err := history.Query(context.Background(), HistoryQuery{Since: time.Now().Add(time.Hour)})
+
+	if err != nil || len(recent) != 0 {
+		t.Errorf("History since an hour from now is %+v (%v), want none", recent, err)
+	}
+}
+
+func TestRepositoryStatusState(t *testing.T) {
+	changes := []Change{newChange(ResourceLabels, "bug", ActionCreate, nil, label{Name: "bug"})}
+
+	for _, test := range []struct {
+		status RepositoryStatus
+		state  string
+	}{
+		{RepositoryStatus{}, "in sync"},
+		{RepositoryStatus{HistoryEntry: HistoryEntry{Changes: changes}}, "drifted, 1 changes"},
+		{RepositoryStatus{HistoryEntry: HistoryEntry{Changes: changes, Applied: changes}}, "applied 1 changes"},
+		{RepositoryStatus{HistoryEntry: HistoryEntry{Changes: changes, Error: "boom"}}, "failed"},
+	} {
+		if state := test.status.State(); state != test.state {
+			t.Errorf("State of %+v is %q, want %q", test.status.HistoryEntry, state, test.state)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package github
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// idCache keeps the ids of the apps, teams and users looked up by slug or login for the lifetime of the client, they never change
+type idCache struct {
+	mutex sync.Mutex
+	ids   map[string]int64
+}
+
+func newIDCache() *idCache {
+	return &idCache{ids: map[string]int64{}}
+}
+
+// lookup returns the cached id of a key or calls get once to find it
+func (cache *idCache) lookup(key string, get func() (int64, error)) (int64, error) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	if id, ok := cache.ids[key]; ok {
+		return id, nil
+	}
+
+	id, err := get()
+
+	if err != nil {
+		return 0, err
+	}
+
+	cache.ids[key] = id
+
+	return id, nil
+}
+
+// appID returns the id of a github app from its slug, a numeric slug is already an id
+func (client *Client) appID(ctx context.Context, slug string) (int64, error) {
+	if id, err := strconv.ParseInt(slug, 10, 64); err == nil {
+		return id, nil
+	}
+
+	return client.ids.lookup("app/"+slug, func() (int64, error) {
+		app, _, err := client.github.Apps.Get(ctx, slug)
+
+		if isNotFound(err) {
+			return 0, errors.Errorf("Unknown app %s", slug)
+		}
+
+		if err != nil {
+			return 0, errors.Wrapf(err, "Error getting app %s", slug)
+		}
+
+		return app.GetID(), nil
+	})
+}
+
+// teamID returns the id of a team of an organization from its slug, a numeric slug is already an id
+func (client *Client) teamID(ctx context.Context, org, slug string) (int64, error) {
+	if id, err := strconv.ParseInt(slug, 10, 64); err == nil {
+		return id, nil
+	}
+
+	return client.ids.lookup("team/"+org+"/"+slug, func() (int64, error) {
+		githubTeam, _, err := client.github.Teams.GetTeamBySlug(ctx, org, slug)
+
+		if isNotFound(err) {
+			return 0, errors.Errorf("Unknown team %s in %s", slug, org)
+		}
+
+		if err != nil {
+			return 0, errors.Wrapf(err, "Error getting team %s", slug)
+		}
+
+		return githubTeam.GetID(), nil
+	})
+}
+
+// userID returns the id of a user from its login
+func (client *Client) userID(ctx context.Context, login string) (int64, error) {
+	return client.ids.lookup("user/"+strings.ToLower(login), func() (int64, error) {
+		user, _, err := client.github.Users.Get(ctx, login)
+
+		if isNotFound(err) {
+			return 0, errors.Errorf("Unknown user %s", login)
+		}
+
+		if err != nil {
+			return 0, errors.Wrapf(err, "Error getting user %s", login)
+		}
+
+		return user.GetID(), nil
+	})
+}
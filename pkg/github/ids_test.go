@@ -0,0 +1,56 @@
+package github
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+const reviewersSettings = `
+repository: {owner: acme, name: api}
+disable: {repository: true}
+environments:
+  - name: staging
+    reviewers: {users: [alice], teams: [platform]}
+  - name: production
+    reviewers: {users: [alice], teams: [platform]}
+`
+
+func TestEnvironmentReviewersAreLookedUpOnce(t *testing.T) {
+	server, client := newTestClient(t)
+	server.AddRepository("acme", "api")
+
+	result, err := client.Apply(context.Background(), settingsFromYAML(t, reviewersSettings))
+
+	if err != nil {
+		t.Fatalf("Error applying settings: %v", err)
+	}
+
+	if len(result.Failed) != 0 {
+		t.Fatalf("Changes failed: %v", result.Failed)
+	}
+
+	lookups := map[string]int{}
+
+	for _, request := range server.Requests() {
+		if request == "GET /users/alice" || request == "GET /orgs/acme/teams/platform" {
+			lookups[request]++
+		}
+	}
+
+	if lookups["GET /users/alice"] != 1 || lookups["GET /orgs/acme/teams/platform"] != 1 {
+		t.Errorf("Expected the reviewers of both environments to be looked up once, got %v", lookups)
+	}
+}
+
+func TestUnknownReviewerNamesTheLogin(t *testing.T) {
+	server, client := newTestClient(t)
+	server.AddRepository("acme", "api")
+	server.RemoveAccount("alice")
+
+	_, err := client.Apply(context.Background(), settingsFromYAML(t, reviewersSettings))
+
+	if err == nil || !strings.Contains(err.Error(), "Unknown user alice") {
+		t.Errorf("Expected the error to name the unknown user, got %v", err)
+	}
+}
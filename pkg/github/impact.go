@@ -0,0 +1,213 @@
+package github
+
+import (
+	"sort"
+	"strings"
+)
+
+// Impact classifies the consequence of a change a reviewer should not miss
+type Impact string
+
+// Impacts of the changes, from the most to the least severe
+const (
+	// ImpactExposesPublicly makes a repository public
+	ImpactExposesPublicly Impact = "exposes repo publicly"
+	// ImpactIrreversible deletes what the settings can't restore (the label of the issues, a webhook secret, a secret value, the history of an environment)
+	ImpactIrreversible Impact = "irreversible"
+	// ImpactRevokesAccess removes a collaborator or a team from a repository
+	ImpactRevokesAccess Impact = "revokes access"
+	// ImpactBlocksMerges adds a requirement the open pull requests may not meet yet
+	ImpactBlocksMerges Impact = "blocks merges"
+)
+
+// nolint:gochecknoglobals
+var impactSeverities = map[Impact]int{
+	ImpactExposesPublicly: 4,
+	ImpactIrreversible:    3,
+	ImpactRevokesAccess:   2,
+	ImpactBlocksMerges:    1,
+}
+
+// irreversibleDeletions are the resources whose deletion loses what the settings can't restore
+// nolint:gochecknoglobals
+var irreversibleDeletions = map[string]bool{
+	ResourceLabels:             true,
+	ResourceWebhooks:           true,
+	ResourceSecrets:            true,
+	ResourceDependabotSecrets:  true,
+	ResourceEnvironments:       true,
+	ResourceEnvironmentSecrets: true,
+}
+
+// Severity is the severity of the most severe impact of the change, 0 without impact
+func (change Change) Severity() int {
+	severity := 0
+
+	for _, impact := range change.Impacts {
+		if impactSeverities[impact] > severity {
+			severity = impactSeverities[impact]
+		}
+	}
+
+	return severity
+}
+
+// ImpactsString lists the impacts of the change separated by commas
+func (change Change) ImpactsString() string {
+	names := make([]string, 0, len(change.Impacts))
+
+	for _, impact := range change.Impacts {
+		names = append(names, string(impact))
+	}
+
+	return strings.Join(names, ", ")
+}
+
+// setImpacts classifies the impacts of every change of the plan
+func (plan *Plan) setImpacts() {
+	for i := range plan.Changes {
+		plan.Changes[i].Impacts = impacts(plan.Changes[i])
+	}
+}
+
+// impacts classifies a change from the resource it changes and its values before and after
+func impacts(change Change) []Impact {
+	found := []Impact{}
+
+	if change.Resource == ResourceRepository && exposesPublicly(change.current, change.desired) {
+		found = append(found, ImpactExposesPublicly)
+	}
+
+	if change.Action == ActionDelete && irreversibleDeletions[change.Resource] {
+		found = append(found, ImpactIrreversible)
+	}
+
+	if change.Action == ActionDelete && (change.Resource == ResourceCollaborators || change.Resource == ResourceTeams) {
+		found = append(found, ImpactRevokesAccess)
+	}
+
+	if blocksMerges(change) {
+		found = append(found, ImpactBlocksMerges)
+	}
+
+	if len(found) == 0 {
+		return nil
+	}
+
+	return found
+}
+
+// exposesPublicly returns true when a private or internal repository, or a repository created, becomes public
+func exposesPublicly(current, desired interface{}) bool {
+	currentRepo, _ := current.(repository)
+	desiredRepo, ok := desired.(repository)
+
+	if !ok {
+		return false
+	}
+
+	public := func(repo repository) bool {
+		return repo.Visibility == visibilityPublic || repo.Visibility == "" && !repo.Private
+	}
+
+	return public(desiredRepo) && (currentRepo.Name == "" || !public(currentRepo))
+}
+
+// blocksMerges returns true when a branch protection or an active ruleset adds a requirement to merge
+func blocksMerges(change Change) bool {
+	if change.Action == ActionDelete {
+		return false
+	}
+
+	switch desired := change.desired.(type) {
+	case branch:
+		current, _ := change.current.(branch)
+
+		return protectionRequires(current.Protection, desired.Protection)
+	case ruleset:
+		current, _ := change.current.(ruleset)
+
+		if desired.Mode != rulesetModeActive && desired.Mode != "" {
+			return false
+		}
+
+		if current.Mode != rulesetModeActive && current.Mode != "" || change.Action == ActionCreate {
+			current = ruleset{}
+		}
+
+		return rulesRequire(current.Rules, desired.Rules)
+	default:
+		return false
+	}
+}
+
+// protectionRequires returns true when the desired branch protection requires more than the current one
+func protectionRequires(current, desired protection) bool {
+	if !desired.Enabled {
+		return false
+	}
+
+	if !current.Enabled {
+		return true
+	}
+
+	currentReviews, desiredReviews := current.RequiredApprovingReviewCount, desired.RequiredApprovingReviewCount
+
+	return desiredReviews.RequiredApprovingReviewCount > currentReviews.RequiredApprovingReviewCount ||
+		desiredReviews.RequireCodeOwnerReviews && !currentReviews.RequireCodeOwnerReviews ||
+		requiresChecks(current.RequiredStatusChecks, desired.RequiredStatusChecks) ||
+		desired.EnforceAdmins && !current.EnforceAdmins ||
+		desired.RequiredSignatures && !current.RequiredSignatures ||
+		desired.RequiredLinearHistory && !current.RequiredLinearHistory ||
+		desired.RequiredConversationResolution && !current.RequiredConversationResolution ||
+		desired.Restrictions != nil && current.Restrictions == nil
+}
+
+// rulesRequire returns true when the desired rules of a ruleset require more than the current ones
+func rulesRequire(current, desired rulesetRules) bool {
+	currentPull, desiredPull := current.PullRequest, desired.PullRequest
+
+	return desired.Update && !current.Update ||
+		desired.RequiredSignatures && !current.RequiredSignatures ||
+		desired.RequiredLinearHistory && !current.RequiredLinearHistory ||
+		desiredPull.Required && !currentPull.Required ||
+		desiredPull.RequiredApprovingReviewCount > currentPull.RequiredApprovingReviewCount ||
+		desiredPull.RequireCodeOwnerReview && !currentPull.RequireCodeOwnerReview ||
+		desiredPull.RequireLastPushApproval && !currentPull.RequireLastPushApproval ||
+		desiredPull.RequiredReviewThreadResolution && !currentPull.RequiredReviewThreadResolution ||
+		requiresChecks(current.RequiredStatusChecks, desired.RequiredStatusChecks)
+}
+
+// requiresChecks returns true when a status check is newly required or the branches must be up to date
+func requiresChecks(current, desired requiredStatusChecks) bool {
+	if len(desired.Contexts) == 0 {
+		return false
+	}
+
+	if desired.Strict && !current.Strict {
+		return true
+	}
+
+	required := map[string]bool{}
+
+	for _, context := range current.Contexts {
+		required[context] = true
+	}
+
+	for _, context := range desired.Contexts {
+		if !required[context] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// BySeverity returns the changes of the plan with the most severe first, the changes of the same severity keep their order
+// The changes are applied in the order of the plan, only the reviews are sorted
+func (plan *Plan) BySeverity() []Change {
+	changes := append([]Change{}, plan.Changes...)
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].Severity() > changes[j].Severity() })
+
+	return changes
+}
@@ -0,0 +1,73 @@
+package github
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPlanAnnotatesImpactsAndSortsBySeverity(t *testing.T) {
+	server, client := newTestClient(t)
+	repo := server.AddRepository("acme", "api")
+	repo.SetAttributes(map[string]interface{}{"private": true, "visibility": "private"})
+	repo.SetLabel("wontfix", "ffffff", "")
+	repo.Collaborators["alice"] = "write"
+
+	plan := planOf(t, client, settingsFromYAML(t, `
+repository: {owner: acme, name: api, defaultbranch: main, visibility: public}
+labels: [{name: bug, color: d73a4a}]
+collaborators: []
+branches:
+  - name: main
+    protection:
+      requiredstatuschecks: {contexts: [ci]}
+`))
+
+	impacts := map[string]string{}
+
+	for _, change := range plan.Changes {
+		impacts[change.Resource+" "+change.Name] = change.ImpactsString()
+	}
+
+	want := map[string]string{
+		"repository api":      string(ImpactExposesPublicly),
+		"labels wontfix":      string(ImpactIrreversible),
+		"labels bug":          "",
+		"collaborators alice": string(ImpactRevokesAccess),
+		"branches main":       string(ImpactBlocksMerges),
+	}
+
+	for name, impact := range want {
+		if impacts[name] != impact {
+			t.Errorf("Impacts of %s are %q, want %q", name, impacts[name], impact)
+		}
+	}
+
+	sorted := []string{}
+
+	for _, change := range plan.BySeverity() {
+		sorted = append(sorted, change.Resource)
+	}
+
+	if strings.Join(sorted, ",") != "repository,labels,collaborators,branches,labels" {
+		t.Errorf("Changes by severity are %v, want the public repository first and the label created last", sorted)
+	}
+
+	if !strings.Contains(plan.String(), "  - labels wontfix [irreversible]\n") {
+		t.Errorf("Plan does not annotate the deleted label:\n%s", plan)
+	}
+}
+
+func TestRelaxedProtectionDoesNotBlockMerges(t *testing.T) {
+	current := protection{Enabled: true, RequiredStatusChecks: requiredStatusChecks{Strict: true, Contexts: []string{"ci", "lint"}}}
+	desired := protection{Enabled: true, RequiredStatusChecks: requiredStatusChecks{Contexts: []string{"ci"}}}
+
+	if protectionRequires(current, desired) {
+		t.Error("Removing a required status check is classified as blocking merges")
+	}
+
+	desired.RequiredStatusChecks.Contexts = []string{"ci", "test"}
+
+	if !protectionRequires(current, desired) {
+		t.Error("Requiring a new status check is not classified as blocking merges")
+	}
+}
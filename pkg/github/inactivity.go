@@ -0,0 +1,95 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/go-github/v75/github"
+	"github.com/pkg/errors"
+)
+
+// inactivity flags the repositories without push nor issue or pull request activity for a number of days
+type inactivity struct {
+	// Days without activity after which a repository is inactive, the policy is disabled when 0
+	Days int `yaml:",omitempty"`
+	// Archive archives the inactive repositories when apply confirms it with WithArchiveInactive, they are only flagged otherwise
+	Archive bool `yaml:",omitempty"`
+}
+
+// lastActivity returns the most recent of the last push, the creation and the last update of an issue or pull request
+func (client *Client) lastActivity(ctx context.Context, githubSettings *Settings) (time.Time, error) {
+	last := time.Time{}
+	timestamps := []string{}
+
+	if githubSettings.Status != nil {
+		timestamps = append(timestamps, githubSettings.Status.PushedAt, githubSettings.Status.CreatedAt)
+	}
+
+	for _, timestamp := range timestamps {
+		if parsed, err := time.Parse(time.RFC3339, timestamp); err == nil && parsed.After(last) {
+			last = parsed
+		}
+	}
+
+	issues, _, err := client.github.Issues.ListByRepo(ctx, githubSettings.Repository.Owner, githubSettings.Repository.Name, &github.IssueListByRepoOptions{
+		State:       "all",
+		Sort:        "updated",
+		Direction:   "desc",
+		ListOptions: github.ListOptions{PerPage: 1},
+	})
+
+	// Repositories with issues disabled have none
+	if err != nil && !isNotFound(err) && !isGone(err) {
+		return time.Time{}, errors.Wrap(err, "Error while getting the last issue from github")
+	}
+
+	if len(issues) != 0 && issues[0].GetUpdatedAt().After(last) {
+		last = issues[0].GetUpdatedAt().Time
+	}
+
+	return last, nil
+}
+
+// planInactivity flags the repository when it has been inactive for the days of the policy of the settings
+// The archival is only reported unless the policy archives and the client confirms it, then it replaces the other changes
+// since an archived repository is read-only
+func (client *Client) planInactivity(ctx context.Context, githubSettings, settings *Settings, plan *Plan) error {
+	policy := settings.Inactivity
+
+	if policy.Days <= 0 || settings.Disable.Repository || githubSettings.Repository.Archived {
+		return nil
+	}
+
+	last, err := client.lastActivity(ctx, githubSettings)
+
+	if err != nil {
+		return err
+	}
+
+	inactive := client.clock.Now().Sub(last)
+
+	if inactive < time.Duration(policy.Days)*24*time.Hour {
+		return nil
+	}
+
+	archived := githubSettings.Repository
+	archived.Archived = true
+
+	change := newChange(ResourceRepository, settings.Repository.Name, ActionUpdate, githubSettings.Repository, archived)
+	change.Reason = fmt.Sprintf("inactive for %d days, last activity on %s", int(inactive.Hours()/24), last.UTC().Format("2006-01-02"))
+	change.Ticket = settings.Ticket
+	change.ReportOnly = !policy.Archive || !client.archiveInactive
+
+	if change.ReportOnly {
+		plan.Warnings = append(plan.Warnings, fmt.Sprintf("Repository %s, set inactivity.archive and confirm with archive inactive to archive it", change.Reason))
+		plan.Changes = append(plan.Changes, change)
+
+		return nil
+	}
+
+	plan.Warnings = append(plan.Warnings, fmt.Sprintf("Repository %s, it is archived and its other changes are skipped", change.Reason))
+	plan.Changes = []Change{change}
+
+	return nil
+}
@@ -0,0 +1,79 @@
+package github
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+)
+
+const inactivitySettings = `
+inactivity:
+  days: 180
+  archive: true
+repository:
+  owner: acme
+  name: api
+  description: api
+`
+
+func TestPlanReportsTheInactiveRepositoriesWithoutArchiving(t *testing.T) {
+	server, client := newTestClient(t)
+	repo := server.AddRepository("acme", "api")
+	repo.SetAttributes(map[string]interface{}{"pushed_at": time.Now().Add(-365 * 24 * time.Hour)})
+	repo.AddIssue(1, time.Now().Add(-200*24*time.Hour))
+
+	plan := planOf(t, client, settingsFromYAML(t, inactivitySettings))
+
+	if names := changeNames(plan); !slices.Equal(names, []string{"repository update api", "repository update api"}) {
+		t.Fatalf("Expected the description and the archival changes, got %v", names)
+	}
+
+	archival := plan.Changes[1]
+
+	if !archival.ReportOnly || archival.Reason != "inactive for 200 days, last activity on "+time.Now().Add(-200*24*time.Hour).UTC().Format("2006-01-02") {
+		t.Errorf("Expected a report only archival with the last activity, got %+v", archival)
+	}
+
+	result, err := client.Apply(context.Background(), settingsFromYAML(t, inactivitySettings))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if server.Repository("acme", "api").Attribute("archived") == true || len(result.Reported) != 1 {
+		t.Errorf("Expected the archival reported only, got %+v", result)
+	}
+}
+
+func TestApplyArchivesTheInactiveRepositoriesWhenConfirmed(t *testing.T) {
+	server, client := newTestClient(t, WithArchiveInactive(true))
+	server.AddRepository("acme", "api").SetAttributes(map[string]interface{}{"pushed_at": time.Now().Add(-365 * 24 * time.Hour)})
+
+	plan := planOf(t, client, settingsFromYAML(t, inactivitySettings))
+
+	if len(plan.Changes) != 1 || plan.Changes[0].ReportOnly {
+		t.Fatalf("Expected only the archival, got %+v", plan.Changes)
+	}
+
+	if _, err := client.Apply(context.Background(), settingsFromYAML(t, inactivitySettings)); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := server.Repository("acme", "api")
+
+	if repo.Attribute("archived") != true || repo.Attribute("description") == "api" {
+		t.Errorf("Expected the repository archived without its other changes, got archived %v and description %v", repo.Attribute("archived"), repo.Attribute("description"))
+	}
+}
+
+func TestPlanIgnoresTheActiveRepositories(t *testing.T) {
+	server, client := newTestClient(t, WithArchiveInactive(true))
+	repo := server.AddRepository("acme", "api")
+	repo.SetAttributes(map[string]interface{}{"pushed_at": time.Now().Add(-365 * 24 * time.Hour)})
+	repo.AddIssue(1, time.Now().Add(-24*time.Hour))
+
+	if names := changeNames(planOf(t, client, settingsFromYAML(t, inactivitySettings))); !slices.Equal(names, []string{"repository update api"}) {
+		t.Errorf("Expected only the description change, got %v", names)
+	}
+}
@@ -0,0 +1,284 @@
+package github
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/michaelmass/github-settings/pkg/githubtest/server"
+)
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+
+	// The plans and applies log every resource, only keep them when the tests are verbose
+	if !testing.Verbose() {
+		log.SetOutput(ioutil.Discard)
+	}
+
+	os.Exit(m.Run())
+}
+
+// newTestClient starts a fake github server and returns a client sending its requests to it
+func newTestClient(tb testing.TB, opts ...Option) (*server.Server, *Client) {
+	tb.Helper()
+
+	fake := server.NewServer()
+	tb.Cleanup(fake.Close)
+
+	return fake, newServerClient(tb, fake, opts...)
+}
+
+// newServerClient returns a client sending its requests to a fake github server
+func newServerClient(tb testing.TB, server *server.Server, opts ...Option) *Client {
+	tb.Helper()
+
+	client, err := NewFromHTTPClient(server.Client(), "", append([]Option{WithBaseURL(server.BaseURL())}, opts...)...)
+
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	return client
+}
+
+// settingsFromYAML parses settings or fails the test
+func settingsFromYAML(tb testing.TB, content string) *Settings {
+	tb.Helper()
+
+	settings, err := GetSettingsFromBytes([]byte(content))
+
+	if err != nil {
+		tb.Fatalf("Error parsing settings: %v", err)
+	}
+
+	return settings
+}
+
+// planOf plans settings or fails the test
+func planOf(tb testing.TB, client *Client, settings *Settings) *Plan {
+	tb.Helper()
+
+	plan, err := client.Plan(context.Background(), settings)
+
+	if err != nil {
+		tb.Fatalf("Error planning %s/%s: %v", settings.Repository.Owner, settings.Repository.Name, err)
+	}
+
+	return plan
+}
+
+// changeNames returns the resource, action and name of every change of a plan
+func changeNames(plan *Plan) []string {
+	names := []string{}
+
+	for _, change := range plan.Changes {
+		names = append(names, change.Resource+" "+string(change.Action)+" "+change.Name)
+	}
+
+	return names
+}
+
+func TestApplyCreatesResources(t *testing.T) {
+	server, client := newTestClient(t)
+	server.AddRepository("acme", "api")
+
+	settings := settingsFromYAML(t, `
+repository: {owner: acme, name: api, description: The api}
+labels:
+  - name: bug
+    color: d73a4a
+webhooks:
+  - url: https://hooks.acme.dev/github
+    contenttype: json
+    events: [push]
+topics: [go, api]
+collaborators:
+  - username: alice
+    permission: push
+teams:
+  - slug: platform
+    permission: maintain
+variables:
+  - name: region
+    value: eu
+`)
+
+	result, err := client.Apply(context.Background(), settings)
+
+	if err != nil {
+		t.Fatalf("Error applying settings: %v", err)
+	}
+
+	if len(result.Failed) != 0 {
+		t.Fatalf("Changes failed: %v", result.Failed)
+	}
+
+	repo := server.Repository("acme", "api")
+
+	if repo.Attribute("description") != "The api" {
+		t.Errorf("Description is %q, want %q", repo.Attribute("description"), "The api")
+	}
+
+	if bug, _ := repo.Label("bug"); bug.Color != "d73a4a" {
+		t.Errorf("Label bug has color %q, want d73a4a", bug.Color)
+	}
+
+	if len(repo.Hooks()) != 1 {
+		t.Errorf("Repository has %d webhooks, want 1", len(repo.Hooks()))
+	}
+
+	if strings.Join(repo.Topics(), ",") != "api,go" {
+		t.Errorf("Topics are %v, want [api go]", repo.Topics())
+	}
+
+	if repo.Collaborators["alice"] != "write" {
+		t.Errorf("Collaborator alice has role %q, want write", repo.Collaborators["alice"])
+	}
+
+	if repo.Teams["platform"] != "maintain" {
+		t.Errorf("Team platform has permission %q, want maintain", repo.Teams["platform"])
+	}
+
+	if repo.Variables["REGION"] != "eu" {
+		t.Errorf("Variable REGION is %q, want eu", repo.Variables["REGION"])
+	}
+}
+
+func TestPlanLeavesMissingSectionsUnmanaged(t *testing.T) {
+	server, client := newTestClient(t)
+	repo := server.AddRepository("acme", "api")
+	repo.Collaborators["alice"] = "write"
+	repo.Teams["platform"] = "push"
+	repo.Secrets["TOKEN"] = "secret"
+	repo.Variables["REGION"] = "eu"
+	repo.AddEnvironment("production")
+	repo.AddRuleset("release")
+
+	plan := planOf(t, client, settingsFromYAML(t, "repository: {owner: acme, name: api}\ndisable: {repository: true}\n"))
+
+	if !plan.Empty() {
+		t.Errorf("Plan without sections has changes %v", changeNames(plan))
+	}
+
+	for _, request := range server.Requests() {
+		for _, unmanaged := range []string{"/collaborators", "/teams", "/actions/secrets", "/actions/variables", "/environments", "/rulesets"} {
+			if strings.HasSuffix(request, unmanaged) {
+				t.Errorf("Unmanaged section fetched with %s", request)
+			}
+		}
+	}
+}
+
+func TestPlanPrunesEmptySections(t *testing.T) {
+	server, client := newTestClient(t)
+	repo := server.AddRepository("acme", "api")
+	repo.Collaborators["alice"] = "write"
+	repo.Teams["platform"] = "push"
+	repo.Secrets["TOKEN"] = "secret"
+	repo.Variables["REGION"] = "eu"
+	repo.AddEnvironment("production")
+
+	plan := planOf(t, client, settingsFromYAML(t, `
+repository: {owner: acme, name: api}
+disable: {repository: true}
+collaborators: []
+teams: []
+secrets: []
+variables: []
+environments: []
+`))
+
+	want := []string{
+		"collaborators delete alice",
+		"teams delete platform",
+		"secrets delete TOKEN",
+		"variables delete REGION",
+		"environments delete production",
+	}
+
+	if strings.Join(changeNames(plan), "\n") != strings.Join(want, "\n") {
+		t.Errorf("Plan changes are %v, want %v", changeNames(plan), want)
+	}
+}
+
+func TestPlanSkipsDisabledSections(t *testing.T) {
+	server, client := newTestClient(t)
+	repo := server.AddRepository("acme", "api")
+	repo.SetLabel("bug", "d73a4a", "")
+
+	plan := planOf(t, client, settingsFromYAML(t, `
+repository: {owner: acme, name: api}
+disable: {repository: true, labels: true}
+labels: []
+`))
+
+	if !plan.Empty() {
+		t.Errorf("Plan of disabled labels has changes %v", changeNames(plan))
+	}
+
+	for _, request := range server.Requests() {
+		if strings.HasSuffix(request, "/labels") {
+			t.Errorf("Disabled labels fetched with %s", request)
+		}
+	}
+}
+
+func TestPlanListsEveryPage(t *testing.T) {
+	server, client := newTestClient(t)
+	repo := server.AddRepository("acme", "api")
+	labels := []string{}
+
+	for i := 0; i < 250; i++ {
+		name := fmt.Sprintf("label-%03d", i)
+		repo.SetLabel(name, "ededed", "")
+		labels = append(labels, fmt.Sprintf("  - {name: %s, color: ededed}", name))
+	}
+
+	plan := planOf(t, client, settingsFromYAML(t, "repository: {owner: acme, name: api}\ndisable: {repository: true}\nlabels:\n"+strings.Join(labels, "\n")+"\n"))
+
+	if !plan.Empty() {
+		t.Errorf("Plan of the live labels has changes %v", changeNames(plan))
+	}
+}
+
+func TestPlanRetriesRateLimits(t *testing.T) {
+	server, client := newTestClient(t)
+	server.AddRepository("acme", "api")
+	server.RateLimit(2)
+
+	plan := planOf(t, client, settingsFromYAML(t, "repository: {owner: acme, name: api}\ndisable: {repository: true}\nlabels: [{name: bug, color: d73a4a}]\n"))
+
+	if strings.Join(changeNames(plan), ",") != "labels create bug" {
+		t.Errorf("Plan changes are %v, want [labels create bug]", changeNames(plan))
+	}
+}
+
+func TestPlanAdaptsPersonalAccounts(t *testing.T) {
+	server, client := newTestClient(t)
+	server.AddPersonalAccount("alice")
+	server.AddRepository("alice", "dotfiles")
+
+	plan := planOf(t, client, settingsFromYAML(t, `
+repository: {owner: alice, name: dotfiles}
+disable: {repository: true}
+collaborators:
+  - username: bob
+    permission: admin
+teams:
+  - slug: platform
+`))
+
+	if strings.Join(changeNames(plan), ",") != "collaborators create bob" {
+		t.Errorf("Plan changes are %v, want [collaborators create bob]", changeNames(plan))
+	}
+
+	if len(plan.Warnings) != 2 {
+		t.Errorf("Plan warnings are %v, want the ignored teams and admin permission", plan.Warnings)
+	}
+}
@@ -0,0 +1,162 @@
+package github
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Thresholds of the random looking words reported as secrets, shorter or more regular words are identifiers, hashes or paths
+const (
+	minSecretLength  = 24
+	minSecretEntropy = 4.0
+)
+
+// ignoreSecretComment is the line comment marking a value reported as a secret as expected
+const ignoreSecretComment = "settings:ignore-secret"
+
+// secretPattern is the format of a kind of credential with a known shape
+type secretPattern struct {
+	kind    string
+	pattern *regexp.Regexp
+}
+
+// nolint:gochecknoglobals
+var secretPatterns = []secretPattern{
+	{"github token", regexp.MustCompile(`\b(ghp|gho|ghu|ghs|ghr)_[A-Za-z0-9]{36}\b`)},
+	{"github fine-grained token", regexp.MustCompile(`\bgithub_pat_[A-Za-z0-9_]{22,}\b`)},
+	{"slack token", regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}`)},
+	{"slack webhook url", regexp.MustCompile(`hooks\.slack\.com/services/T[A-Za-z0-9_]+/B[A-Za-z0-9_]+/[A-Za-z0-9_]+`)},
+	{"aws access key", regexp.MustCompile(`\b(AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"gitlab token", regexp.MustCompile(`\bglpat-[A-Za-z0-9_-]{20,}`)},
+	{"stripe key", regexp.MustCompile(`\b[rs]k_live_[A-Za-z0-9]{16,}`)},
+	{"private key", regexp.MustCompile(`-----BEGIN ([A-Z]+ )?PRIVATE KEY-----`)},
+	{"password in a url", regexp.MustCompile(`[a-z][a-z0-9+.-]*://[^/\s:@]+:[^/\s@$]+@`)},
+}
+
+// nolint:gochecknoglobals
+var secretWord = regexp.MustCompile(`[A-Za-z0-9+/=_-]+`)
+
+// secretKeys are the fields whose literal value is a secret whatever it looks like (ex: the secret of a webhook)
+// nolint:gochecknoglobals
+var secretKeys = map[string]bool{
+	"secret":   true,
+	"password": true,
+	"token":    true,
+}
+
+// lintSecrets reports the literal values of a settings file that look like secrets, the settings are meant to be committed
+// so secret values are referenced with variables (${NAME}) set from the environment instead
+func lintSecrets(node *yaml.Node, path string) []Problem {
+	// Anchors are linted where they are defined, the aliases referencing them would report them twice
+	switch node.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		problems := []Problem{}
+
+		for i, item := range node.Content {
+			itemPath := path
+
+			if node.Kind == yaml.SequenceNode {
+				itemPath = fmt.Sprintf("%s[%d]", path, i)
+			}
+
+			problems = append(problems, lintSecrets(item, itemPath)...)
+		}
+
+		return problems
+	case yaml.MappingNode:
+		problems := []Problem{}
+
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			valuePath := joinPath(path, key.Value)
+
+			if problem, ok := secretValue(value, valuePath, secretKeys[strings.ToLower(key.Value)]); ok {
+				problems = append(problems, problem)
+				continue
+			}
+
+			problems = append(problems, lintSecrets(value, valuePath)...)
+		}
+
+		return problems
+	case yaml.ScalarNode:
+		if problem, ok := secretValue(node, path, false); ok {
+			return []Problem{problem}
+		}
+	}
+
+	return nil
+}
+
+// secretValue returns a problem when a scalar looks like a secret, any literal of a secret field is one
+func secretValue(node *yaml.Node, path string, secretField bool) (Problem, bool) {
+	if node.Kind != yaml.ScalarNode || node.Tag == "!!null" || node.Value == "" || strings.Contains(node.LineComment, ignoreSecretComment) {
+		return Problem{}, false
+	}
+
+	kind := secretKind(node.Value)
+
+	if kind == "" && secretField && !strings.Contains(node.Value, "${") {
+		kind = "secret"
+	}
+
+	if kind == "" {
+		return Problem{}, false
+	}
+
+	return newProblem(node, path, fmt.Sprintf("value looks like a %s, reference it from the environment with a variable (ex: ${NAME}) instead of committing it (mark the line with # %s when it is not a secret)", kind, ignoreSecretComment)), true
+}
+
+// secretKind returns the kind of credential a value contains or an empty string, the variables it references are left out
+func secretKind(value string) string {
+	for _, secret := range secretPatterns {
+		if secret.pattern.MatchString(value) {
+			return secret.kind
+		}
+	}
+
+	for _, word := range secretWord.FindAllString(value, -1) {
+		if len(word) >= minSecretLength && mixedCharacters(word) && entropy(word) >= minSecretEntropy {
+			return "random token"
+		}
+	}
+
+	return ""
+}
+
+// mixedCharacters returns true when a word mixes lowercase and uppercase letters with digits like generated tokens
+func mixedCharacters(word string) bool {
+	var lower, upper, digit bool
+
+	for _, character := range word {
+		lower = lower || unicode.IsLower(character)
+		upper = upper || unicode.IsUpper(character)
+		digit = digit || unicode.IsDigit(character)
+	}
+
+	return lower && upper && digit
+}
+
+// entropy returns the shannon entropy of a word in bits per character
+func entropy(word string) float64 {
+	counts := map[rune]int{}
+
+	for _, character := range word {
+		counts[character]++
+	}
+
+	result := 0.0
+	length := float64(len(word))
+
+	for _, count := range counts {
+		frequency := float64(count) / length
+		result -= frequency * math.Log2(frequency)
+	}
+
+	return result
+}
@@ -0,0 +1,54 @@
+package github
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/go-github/v75/github"
+	"github.com/pkg/errors"
+)
+
+// ManagedByTopicPrefix prefixes the topic naming the tool managing a repository (ex: managed-by-terraform)
+const ManagedByTopicPrefix = "managed-by-"
+
+// managedByProperties are the custom properties naming the tool managing a repository
+// nolint:gochecknoglobals
+var managedByProperties = []string{"managed-by", "managed_by"}
+
+// managedBy returns the other tool declared as managing the repository by a custom property or a topic
+// Markers naming github-settings, like the topic of a managed-by annotation, are ignored
+func managedBy(githubRepo *github.Repository) string {
+	tools := []string{}
+
+	for _, property := range managedByProperties {
+		if value, ok := githubRepo.CustomProperties[property]; ok && value != nil {
+			tools = append(tools, fmt.Sprintf("%v", value))
+		}
+	}
+
+	for _, topic := range githubRepo.Topics {
+		if strings.HasPrefix(topic, ManagedByTopicPrefix) {
+			tools = append(tools, strings.TrimPrefix(topic, ManagedByTopicPrefix))
+		}
+	}
+
+	sort.Strings(tools)
+
+	for _, tool := range tools {
+		if tool != "" && !strings.EqualFold(tool, DefaultUserAgent) {
+			return tool
+		}
+	}
+
+	return ""
+}
+
+// checkManagedBy refuses to change a repository managed by another tool unless the client is forced
+func (client *Client) checkManagedBy(plan *Plan) error {
+	if plan.ManagedBy == "" || plan.Empty() || client.force {
+		return nil
+	}
+
+	return errors.Errorf("Repository %s/%s is managed by %s, applying would conflict with it (use force to apply anyway)", plan.Owner, plan.Name, plan.ManagedBy)
+}
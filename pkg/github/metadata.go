@@ -0,0 +1,216 @@
+package github
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/google/go-github/v75/github"
+	"github.com/michaelmass/github-settings/pkg/config"
+	"github.com/pkg/errors"
+)
+
+// repositoryMetadata returns the metadata variables of the live repository, a repository planned for creation is described
+// by its settings. The custom properties the repository has no value for are empty
+func repositoryMetadata(githubSettings, settings *Settings) map[string]string {
+	if githubSettings == nil {
+		visibility := settings.Repository.Visibility
+
+		if visibility == "" && settings.Repository.Private {
+			visibility = visibilityPrivate
+		} else if visibility == "" {
+			visibility = visibilityPublic
+		}
+
+		return map[string]string{
+			config.RepoLanguageVariable:   "",
+			config.RepoVisibilityVariable: string(visibility),
+			config.RepoTopicsVariable:     strings.Join(settings.Topics, ","),
+		}
+	}
+
+	metadata := map[string]string{
+		config.RepoTopicsVariable: strings.Join(githubSettings.Topics, ","),
+	}
+
+	if githubSettings.Status != nil {
+		metadata[config.RepoLanguageVariable] = githubSettings.Status.Language
+		metadata[config.RepoVisibilityVariable] = githubSettings.Status.Visibility
+
+		for property, value := range githubSettings.Status.Properties {
+			metadata[config.PropertyVariable(property)] = value
+		}
+	}
+
+	return metadata
+}
+
+// customProperties returns the values of the custom properties of a repository, the values of multi select properties are
+// separated by commas
+func customProperties(githubRepo *github.Repository) map[string]string {
+	if len(githubRepo.CustomProperties) == 0 {
+		return nil
+	}
+
+	properties := map[string]string{}
+
+	for property, value := range githubRepo.CustomProperties {
+		switch typed := value.(type) {
+		case nil:
+			continue
+		case []interface{}:
+			values := make([]string, 0, len(typed))
+
+			for _, item := range typed {
+				values = append(values, fmt.Sprintf("%v", item))
+			}
+
+			sort.Strings(values)
+			properties[property] = strings.Join(values, ",")
+		default:
+			properties[property] = fmt.Sprintf("%v", typed)
+		}
+	}
+
+	return properties
+}
+
+// substituteMetadata returns a copy of the settings with the metadata variables (ex: ${REPO_LANGUAGE}) replaced by the values
+// of the repository, the settings are returned as is when they reference none. The other variables were substituted when
+// the settings were loaded
+func substituteMetadata(githubSettings, settings *Settings) (*Settings, error) {
+	metadata := repositoryMetadata(githubSettings, settings)
+	referenced := false
+
+	lookup := func(name string) (string, bool) {
+		if !config.IsMetadataVariable(name) {
+			return "${" + name + "}", true
+		}
+
+		referenced = true
+		value, ok := metadata[name]
+
+		return value, ok || strings.HasPrefix(name, config.RepoPropertyPrefix)
+	}
+
+	substituted, err := substituteStrings(reflect.ValueOf(settings).Elem(), func(value string) (string, error) {
+		if !strings.Contains(value, "${") {
+			return value, nil
+		}
+
+		document, err := config.Substitute(value, lookup)
+
+		if err != nil {
+			return "", err
+		}
+
+		return document.(string), nil
+	})
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error substituting the metadata variables of %s/%s", settings.Repository.Owner, settings.Repository.Name)
+	}
+
+	if !referenced {
+		return settings, nil
+	}
+
+	copied := substituted.Interface().(Settings)
+
+	return &copied, nil
+}
+
+// substituteStrings returns a copy of a value with every string it holds substituted, the nil slices, maps and pointers stay
+// nil so the unmanaged sections stay unmanaged. The unexported fields are copied as is
+func substituteStrings(value reflect.Value, substitute func(string) (string, error)) (reflect.Value, error) {
+	switch value.Kind() {
+	case reflect.String:
+		substituted, err := substitute(value.String())
+
+		if err != nil {
+			return reflect.Value{}, err
+		}
+
+		copied := reflect.New(value.Type()).Elem()
+		copied.SetString(substituted)
+
+		return copied, nil
+	case reflect.Ptr, reflect.Interface:
+		if value.IsNil() {
+			return value, nil
+		}
+
+		elem, err := substituteStrings(value.Elem(), substitute)
+
+		if err != nil {
+			return reflect.Value{}, err
+		}
+
+		if value.Kind() == reflect.Interface {
+			copied := reflect.New(value.Type()).Elem()
+			copied.Set(elem)
+
+			return copied, nil
+		}
+
+		copied := reflect.New(value.Type().Elem())
+		copied.Elem().Set(elem)
+
+		return copied, nil
+	case reflect.Slice:
+		if value.IsNil() {
+			return value, nil
+		}
+
+		copied := reflect.MakeSlice(value.Type(), value.Len(), value.Len())
+
+		for i := 0; i < value.Len(); i++ {
+			elem, err := substituteStrings(value.Index(i), substitute)
+
+			if err != nil {
+				return reflect.Value{}, err
+			}
+
+			copied.Index(i).Set(elem)
+		}
+
+		return copied, nil
+	case reflect.Map:
+		if value.IsNil() {
+			return value, nil
+		}
+
+		copied := reflect.MakeMapWithSize(value.Type(), value.Len())
+		entries := value.MapRange()
+
+		for entries.Next() {
+			elem, err := substituteStrings(entries.Value(), substitute)
+
+			if err != nil {
+				return reflect.Value{}, err
+			}
+
+			copied.SetMapIndex(entries.Key(), elem)
+		}
+
+		return copied, nil
+	case reflect.Struct:
+		copied := reflect.New(value.Type()).Elem()
+		copied.Set(value)
+
+		for _, field := range settingsFields(value.Type()) {
+			substituted, err := substituteStrings(value.FieldByIndex(field.Index), substitute)
+
+			if err != nil {
+				return reflect.Value{}, err
+			}
+
+			copied.FieldByIndex(field.Index).Set(substituted)
+		}
+
+		return copied, nil
+	default:
+		return value, nil
+	}
+}
@@ -0,0 +1,55 @@
+package github
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestPlanSubstitutesRepositoryMetadata(t *testing.T) {
+	server, client := newTestClient(t)
+	repo := server.AddRepository("acme", "api")
+	repo.SetAttributes(map[string]interface{}{"language": "Go", "custom_properties": map[string]interface{}{"cost-center": "42", "stacks": []string{"web", "api"}}})
+	repo.Collaborators["alice"] = "write"
+
+	config := writeSettings(t, `
+repository: {owner: acme, name: api, description: "${REPO_VISIBILITY} ${REPO_PROPERTY_COST_CENTER} ${REPO_PROPERTY_STACKS}${REPO_PROPERTY_MISSING}"}
+labels:
+  - {name: "lang-${REPO_LANGUAGE}", color: d73a4a}
+`)
+
+	allSettings, err := client.GetAllSettingsFromFile(context.Background(), config)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plan := planOf(t, client, allSettings[0])
+	names := changeNames(plan)
+
+	if !slices.Contains(names, "labels create lang-Go") {
+		t.Errorf("Expected the label of the language, got %v", names)
+	}
+
+	if slices.Contains(names, "collaborators delete alice") {
+		t.Errorf("Expected the collaborators to stay unmanaged, got %v", names)
+	}
+
+	description := ""
+
+	for _, change := range plan.Changes {
+		for _, field := range change.Fields {
+			if field.Field == "description" {
+				description, _ = field.After.(string)
+			}
+		}
+	}
+
+	if description != "public 42 api,web" {
+		t.Errorf("Description is planned as %q, want the visibility and the custom properties", description)
+	}
+
+	if allSettings[0].Labels[0].Name != "lang-${REPO_LANGUAGE}" {
+		t.Errorf("Planning modified the settings, the label is %s", allSettings[0].Labels[0].Name)
+	}
+}
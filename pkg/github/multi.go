@@ -0,0 +1,676 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v75/github"
+	"github.com/michaelmass/github-settings/pkg/config"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultConcurrency is the number of repositories applied in parallel
+const DefaultConcurrency = 4
+
+// MultiSettings targets many repositories with shared defaults and per repository overrides
+// Every repository of the organization is targeted when no repository is listed
+type MultiSettings struct {
+	Org          OrgSettings
+	Defaults     map[string]interface{}
+	Repositories []map[string]interface{}
+	// Anchors holds yaml blocks shared through anchors and merge keys (<<: *name), it is ignored otherwise
+	Anchors map[string]interface{} `yaml:",omitempty"`
+}
+
+// RepositoryResult is the outcome of planning or applying the settings of a single repository
+type RepositoryResult struct {
+	Repository string
+	Plan       *Plan
+	// Result lists the changes applied, skipped and failed, it is nil when the repository was only planned
+	Result *Result
+	Err    error
+	// Duration is the time spent planning or applying the repository
+	Duration time.Duration
+	// Cached is set when the repository was skipped since neither its settings nor the repository changed since its last successful apply
+	Cached bool `json:",omitempty"`
+
+	// index is the position of the repository in a stream
+	index int
+}
+
+// Results are the outcomes of the repositories of a multi repository run, in the order of their settings
+type Results []RepositoryResult
+
+// Get returns the result of a repository by its full name (owner/name)
+func (results Results) Get(repository string) (RepositoryResult, bool) {
+	for _, result := range results {
+		if result.Repository == repository {
+			return result, true
+		}
+	}
+
+	return RepositoryResult{}, false
+}
+
+// Err returns a MultiError holding the error of every repository that failed, nil when none failed
+func (results Results) Err() error {
+	failed := MultiError{}
+
+	for _, result := range results {
+		if result.Err != nil {
+			failed[result.Repository] = result.Err
+		}
+	}
+
+	if len(failed) == 0 {
+		return nil
+	}
+
+	return failed
+}
+
+// MultiError holds the errors of the repositories that failed in a multi repository run, keyed by their full name (owner/name)
+// The other repositories completed, their results are returned alongside it
+type MultiError map[string]error
+
+func (multiError MultiError) Error() string {
+	messages := make([]string, 0, len(multiError))
+
+	for _, repository := range sortedKeys(multiError) {
+		messages = append(messages, repository+": "+multiError[repository].Error())
+	}
+
+	return fmt.Sprintf("Error in %d repositories, %s", len(multiError), strings.Join(messages, ", "))
+}
+
+// Unwrap returns the errors of the repositories so errors.Is and errors.As match any of them
+func (multiError MultiError) Unwrap() []error {
+	errs := make([]error, 0, len(multiError))
+
+	for _, repository := range sortedKeys(multiError) {
+		errs = append(errs, multiError[repository])
+	}
+
+	return errs
+}
+
+// GetAllSettingsFromFile parses a settings file targeting one or many repositories
+// The files it extends are fetched with the client token when they are hosted on github
+func (client *Client) GetAllSettingsFromFile(ctx context.Context, file string) ([]*Settings, error) {
+	content, err := client.loadSettingsFile(file)
+
+	if err != nil {
+		return nil, err
+	}
+
+	settings, err := client.GetAllSettingsFromBytes(ctx, content)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Error decoding settings content")
+	}
+
+	return settings, nil
+}
+
+// GetAllSettingsFromBytes parses settings targeting one or many repositories
+func (client *Client) GetAllSettingsFromBytes(ctx context.Context, content []byte) ([]*Settings, error) {
+	return collectSettings(client.streamAllSettingsFromBytes(ctx, content))
+}
+
+// StreamAllSettingsFromFile parses a settings file like GetAllSettingsFromFile but sends the settings of each repository as soon
+// as they are resolved, the repositories of an organization are resolved page by page while the listing continues
+// The settings channel is closed once every repository was sent, the error channel then receives the error that stopped the stream
+func (client *Client) StreamAllSettingsFromFile(ctx context.Context, file string) (<-chan *Settings, <-chan error) {
+	content, err := client.loadSettingsFile(file)
+
+	if err != nil {
+		return failedStream(err)
+	}
+
+	return client.streamAllSettingsFromBytes(ctx, content)
+}
+
+// GetRepositorySettingsFromFile returns the settings of a single repository targeted by a settings file, the repositories of an
+// organization are not listed so a repository created a moment ago is resolved too. It returns nil when the file does not target the repository
+func (client *Client) GetRepositorySettingsFromFile(ctx context.Context, file, owner, name string) (*Settings, error) {
+	content, err := client.loadSettingsFile(file)
+
+	if err != nil {
+		return nil, err
+	}
+
+	settings, err := repositorySettingsFromBytes(content, owner, name)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Error decoding settings content")
+	}
+
+	return settings, nil
+}
+
+// loadSettingsFile loads a settings file with the files it extends, fetched with the client token when they are hosted on github
+func (client *Client) loadSettingsFile(file string) ([]byte, error) {
+	opts, err := client.loaderOptions()
+
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := config.Load(file, opts...)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Error while loading settings file")
+	}
+
+	return content, nil
+}
+
+// loaderOptions returns the options of the settings files loader, the files hosted on github are fetched with the client token
+func (client *Client) loaderOptions() ([]config.Option, error) {
+	token, err := client.currentToken()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return []config.Option{config.WithToken(token), config.WithGithubHosts(client.host), config.WithRemoteEnv(client.remoteEnv...)}, nil
+}
+
+// repositorySettingsFromBytes resolves the settings of a repository from settings targeting one or many repositories
+func repositorySettingsFromBytes(content []byte, owner, name string) (*Settings, error) {
+	multi, err := parseMultiSettings(content)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if multi == nil {
+		settings, err := GetSettingsFromBytes(content)
+
+		if err != nil || !sameRepository(settings, owner, name) {
+			return nil, err
+		}
+
+		return settings, nil
+	}
+
+	if len(multi.Repositories) == 0 {
+		if !strings.EqualFold(multi.Org.Name, owner) {
+			return nil, nil
+		}
+
+		return resolveRepository(multi, map[string]interface{}{
+			"repository": map[string]interface{}{"name": name},
+		})
+	}
+
+	for _, overrides := range multi.Repositories {
+		settings, err := resolveRepository(multi, overrides)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if sameRepository(settings, owner, name) {
+			return settings, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// sameRepository returns true when the settings target the repository, github names are case insensitive
+func sameRepository(settings *Settings, owner, name string) bool {
+	return strings.EqualFold(settings.Repository.Owner, owner) && strings.EqualFold(settings.Repository.Name, name)
+}
+
+// parseMultiSettings parses settings targeting many repositories, it returns nil when they target a single repository
+func parseMultiSettings(content []byte) (*MultiSettings, error) {
+	var keys map[string]interface{}
+	err := yaml.Unmarshal(content, &keys)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Error while unmarshal settings")
+	}
+
+	_, hasOrg := keys["org"]
+	_, hasDefaults := keys["defaults"]
+	_, hasRepositories := keys["repositories"]
+
+	if !hasOrg && !hasDefaults && !hasRepositories {
+		return nil, nil
+	}
+
+	var multi MultiSettings
+	err = yaml.Unmarshal(content, &multi)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Error while unmarshal multi repository settings")
+	}
+
+	return &multi, nil
+}
+
+func (client *Client) streamAllSettingsFromBytes(ctx context.Context, content []byte) (<-chan *Settings, <-chan error) {
+	multi, err := parseMultiSettings(content)
+
+	if err != nil {
+		return failedStream(err)
+	}
+
+	if multi == nil {
+		settings, err := GetSettingsFromBytes(content)
+
+		if err != nil {
+			return failedStream(err)
+		}
+
+		return settingsStream(ctx, func(send func(*Settings) error) error {
+			return send(settings)
+		})
+	}
+
+	return client.StreamSettings(ctx, multi)
+}
+
+// ResolveSettings merges the defaults with each repository overrides
+func (client *Client) ResolveSettings(ctx context.Context, multi *MultiSettings) ([]*Settings, error) {
+	return collectSettings(client.StreamSettings(ctx, multi))
+}
+
+// StreamSettings merges the defaults with each repository overrides and sends the settings of each repository as soon as they
+// are resolved, see StreamAllSettingsFromFile
+func (client *Client) StreamSettings(ctx context.Context, multi *MultiSettings) (<-chan *Settings, <-chan error) {
+	return settingsStream(ctx, func(send func(*Settings) error) error {
+		if len(multi.Repositories) != 0 {
+			for _, overrides := range multi.Repositories {
+				settings, err := resolveRepository(multi, overrides)
+
+				if err != nil {
+					return err
+				}
+
+				err = send(settings)
+
+				if err != nil {
+					return err
+				}
+			}
+
+			return nil
+		}
+
+		if multi.Org.Name == "" {
+			return errors.New("An org is required when no repositories are listed")
+		}
+
+		// Errors of the handler are returned as is, only the listing errors are wrapped
+		var resolveErr error
+
+		err := client.eachOwnerRepository(ctx, multi.Org.Name, func(name string) error {
+			var settings *Settings
+			settings, resolveErr = resolveRepository(multi, map[string]interface{}{
+				"repository": map[string]interface{}{"name": name},
+			})
+
+			if resolveErr == nil {
+				resolveErr = send(settings)
+			}
+
+			return resolveErr
+		})
+
+		if resolveErr != nil {
+			return resolveErr
+		}
+
+		if err != nil {
+			return errors.Wrapf(err, "Error listing repositories of %s", multi.Org.Name)
+		}
+
+		return nil
+	})
+}
+
+// resolveRepository merges the defaults with the overrides of a repository and substitutes the repository variables
+func resolveRepository(multi *MultiSettings, overrides map[string]interface{}) (*Settings, error) {
+	merged := mergeMaps(mergeMaps(map[string]interface{}{}, multi.Defaults), overrides)
+
+	if multi.Org.Name != "" {
+		merged = mergeMaps(map[string]interface{}{
+			"repository": map[string]interface{}{"owner": multi.Org.Name},
+		}, merged)
+	}
+
+	repository, _ := merged["repository"].(map[string]interface{})
+	owner, _ := repository["owner"].(string)
+	name, _ := repository["name"].(string)
+
+	// Defaults are templates, the repository variables are substituted for each repository
+	substituted, err := config.Substitute(merged, config.RepositoryLookup(owner, name))
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error substituting variables of %s/%s", owner, name)
+	}
+
+	content, err := yaml.Marshal(substituted)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Error while marshal repository settings")
+	}
+
+	settings, err := GetSettingsFromBytes(content)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if settings.Repository.Owner == "" || settings.Repository.Name == "" {
+		return nil, errors.New("Every repository requires an owner and a name")
+	}
+
+	return settings, nil
+}
+
+// settingsStream runs the producer in the background, the settings it sends are received on the returned channel
+func settingsStream(ctx context.Context, produce func(send func(*Settings) error) error) (<-chan *Settings, <-chan error) {
+	settingsChannel := make(chan *Settings)
+	errorChannel := make(chan error, 1)
+
+	go func() {
+		defer close(errorChannel)
+
+		err := produce(func(settings *Settings) error {
+			select {
+			case settingsChannel <- settings:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+
+		close(settingsChannel)
+
+		if err != nil {
+			errorChannel <- err
+		}
+	}()
+
+	return settingsChannel, errorChannel
+}
+
+func failedStream(err error) (<-chan *Settings, <-chan error) {
+	return settingsStream(context.Background(), func(send func(*Settings) error) error {
+		return err
+	})
+}
+
+func collectSettings(settingsChannel <-chan *Settings, errorChannel <-chan error) ([]*Settings, error) {
+	allSettings := []*Settings{}
+
+	for settings := range settingsChannel {
+		allSettings = append(allSettings, settings)
+	}
+
+	err := <-errorChannel
+
+	if err != nil {
+		return nil, err
+	}
+
+	return allSettings, nil
+}
+
+// PlanAll computes the plan of many repositories concurrently, a failing repository does not stop the others
+// The results of every repository are returned with a MultiError of the repositories that failed
+func (client *Client) PlanAll(ctx context.Context, allSettings []*Settings, concurrency int) (Results, error) {
+	results := runAll(settingsNames(allSettings), concurrency, func(i int) RepositoryResult {
+		return client.planResult(ctx, allSettings[i])
+	})
+
+	return results, results.Err()
+}
+
+// ApplyAll applies the settings of many repositories concurrently, a failing repository does not stop the others
+// The results of every repository are returned with a MultiError of the repositories that failed
+func (client *Client) ApplyAll(ctx context.Context, allSettings []*Settings, concurrency int) (Results, error) {
+	results := runAll(settingsNames(allSettings), concurrency, func(i int) RepositoryResult {
+		return client.applyResult(ctx, allSettings[i])
+	})
+
+	return results, results.Err()
+}
+
+// PlanStream plans the settings received concurrently and sends each result as soon as it is planned
+// The results channel is closed once the settings channel is closed and every repository was planned, it must be drained
+func (client *Client) PlanStream(ctx context.Context, settings <-chan *Settings, concurrency int) <-chan RepositoryResult {
+	return runStream(settings, concurrency, func(repositorySettings *Settings) RepositoryResult {
+		return client.planResult(ctx, repositorySettings)
+	})
+}
+
+// ApplyAllStream applies the settings received concurrently and sends each result as soon as it is applied, see PlanStream
+func (client *Client) ApplyAllStream(ctx context.Context, settings <-chan *Settings, concurrency int) <-chan RepositoryResult {
+	return runStream(settings, concurrency, func(repositorySettings *Settings) RepositoryResult {
+		return client.applyResult(ctx, repositorySettings)
+	})
+}
+
+func (client *Client) planResult(ctx context.Context, settings *Settings) RepositoryResult {
+	hash, cached := client.lookupCache(ctx, settings)
+
+	if cached {
+		return cachedResult(settings)
+	}
+
+	plan, err := client.Plan(ctx, settings)
+
+	if plan != nil {
+		plan.configHash = hash
+	}
+
+	return RepositoryResult{Plan: plan, Err: err}
+}
+
+func (client *Client) applyResult(ctx context.Context, settings *Settings) RepositoryResult {
+	hash, cached := client.lookupCache(ctx, settings)
+
+	if cached {
+		return cachedResult(settings)
+	}
+
+	plan, err := client.Plan(ctx, settings)
+
+	if err != nil {
+		return RepositoryResult{Err: err}
+	}
+
+	plan.configHash = hash
+
+	result, err := client.executePlan(ctx, plan, nil)
+
+	return RepositoryResult{Plan: plan, Result: result, Err: err}
+}
+
+// cachedResult is the result of a repository skipped by the apply cache, its plan has no changes
+func cachedResult(settings *Settings) RepositoryResult {
+	return RepositoryResult{
+		Plan:   &Plan{Owner: settings.Repository.Owner, Name: settings.Repository.Name},
+		Cached: true,
+	}
+}
+
+// ApplyPlans applies the plans returned by PlanAll concurrently, the repositories that failed planning are returned as is
+// It allows reviewing the plans (ex: confirming deletions) before anything is changed, see ApplyAll for the error returned
+func (client *Client) ApplyPlans(ctx context.Context, planned []RepositoryResult, concurrency int) (Results, error) {
+	names := make([]string, 0, len(planned))
+
+	for _, result := range planned {
+		names = append(names, result.Repository)
+	}
+
+	results := runAll(names, concurrency, func(i int) RepositoryResult {
+		if planned[i].Err != nil || planned[i].Plan == nil || planned[i].Cached {
+			return planned[i]
+		}
+
+		result, err := client.executePlan(ctx, planned[i].Plan, nil)
+
+		return RepositoryResult{Plan: planned[i].Plan, Result: result, Err: err}
+	})
+
+	return results, results.Err()
+}
+
+// runAll runs a function for each repository concurrently and names the results after the repositories
+func runAll(repositories []string, concurrency int, run func(int) RepositoryResult) Results {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	results := make(Results, len(repositories))
+	semaphore := make(chan struct{}, concurrency)
+	wait := sync.WaitGroup{}
+
+	for i, fullName := range repositories {
+		wait.Add(1)
+		semaphore <- struct{}{}
+
+		go func(i int, fullName string) {
+			defer wait.Done()
+			defer func() { <-semaphore }()
+
+			log.Printf("[INFO] Processing repository %s\n", fullName)
+
+			started := time.Now()
+			results[i] = run(i)
+			results[i].Repository = fullName
+			results[i].Duration = time.Since(started)
+		}(i, fullName)
+	}
+
+	wait.Wait()
+
+	return results
+}
+
+// runStream runs a function for each settings received concurrently and sends the results as soon as they complete
+func runStream(settings <-chan *Settings, concurrency int, run func(*Settings) RepositoryResult) <-chan RepositoryResult {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	results := make(chan RepositoryResult, concurrency)
+
+	go func() {
+		semaphore := make(chan struct{}, concurrency)
+		wait := sync.WaitGroup{}
+		index := 0
+
+		for repositorySettings := range settings {
+			wait.Add(1)
+			semaphore <- struct{}{}
+
+			go func(index int, repositorySettings *Settings) {
+				defer wait.Done()
+				defer func() { <-semaphore }()
+
+				fullName := repositorySettings.Repository.Owner + "/" + repositorySettings.Repository.Name
+				log.Printf("[INFO] Processing repository %s\n", fullName)
+
+				started := time.Now()
+				result := run(repositorySettings)
+				result.Repository = fullName
+				result.Duration = time.Since(started)
+				result.index = index
+
+				results <- result
+			}(index, repositorySettings)
+
+			index++
+		}
+
+		wait.Wait()
+		close(results)
+	}()
+
+	return results
+}
+
+// CollectResults drains the results of a stream and returns them in the order their settings were received
+// The handler, when set, is called with each result as soon as it is received (ex: to print it)
+// The repositories that failed are returned by the Err method of the results
+func CollectResults(results <-chan RepositoryResult, handle func(RepositoryResult)) Results {
+	collected := Results{}
+
+	for result := range results {
+		if handle != nil {
+			handle(result)
+		}
+
+		collected = append(collected, result)
+	}
+
+	sort.SliceStable(collected, func(i, j int) bool { return collected[i].index < collected[j].index })
+
+	return collected
+}
+
+func settingsNames(allSettings []*Settings) []string {
+	names := make([]string, 0, len(allSettings))
+
+	for _, settings := range allSettings {
+		names = append(names, settings.Repository.Owner+"/"+settings.Repository.Name)
+	}
+
+	return names
+}
+
+// eachOrgRepository calls the handler with the name of every non archived repository of an organization, page by page
+func (client *Client) eachOrgRepository(ctx context.Context, org string, handle func(name string) error) error {
+	return eachRepository(func(opts github.ListOptions) ([]*github.Repository, *github.Response, error) {
+		return client.github.Repositories.ListByOrg(ctx, org, &github.RepositoryListByOrgOptions{Sort: "full_name", Direction: "asc", ListOptions: opts})
+	}, handle)
+}
+
+// eachRepository calls the handler with the name of every non archived repository listed, page by page
+func eachRepository(list func(opts github.ListOptions) ([]*github.Repository, *github.Response, error), handle func(name string) error) error {
+	return eachPage(list, func(repos []*github.Repository) error {
+		for _, repo := range repos {
+			if repo.GetArchived() {
+				log.Printf("[INFO] Skipping archived repository %s\n", repo.GetFullName())
+				continue
+			}
+
+			err := handle(repo.GetName())
+
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// mergeMaps merges the override into the base recursively, lists and values of the override replace the base ones
+func mergeMaps(base, override map[string]interface{}) map[string]interface{} {
+	for key, value := range override {
+		baseMap, baseIsMap := base[key].(map[string]interface{})
+		overrideMap, overrideIsMap := value.(map[string]interface{})
+
+		if baseIsMap && overrideIsMap {
+			base[key] = mergeMaps(mergeMaps(map[string]interface{}{}, baseMap), overrideMap)
+		} else {
+			base[key] = value
+		}
+	}
+
+	return base
+}
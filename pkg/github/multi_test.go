@@ -0,0 +1,59 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestApplyAllIsolatesFailingRepositories(t *testing.T) {
+	server, client := newTestClient(t)
+	server.AddRepository("acme", "api")
+	server.AddRepository("acme", "web")
+
+	allSettings := []*Settings{}
+
+	for _, name := range []string{"api", "missing", "web"} {
+		allSettings = append(allSettings, settingsFromYAML(t, "repository: {owner: acme, name: "+name+"}\ndisable: {repository: true}\nlabels: [{name: bug, color: d73a4a}]\n"))
+	}
+
+	results, err := client.ApplyAll(context.Background(), allSettings, 2)
+
+	multiError := MultiError{}
+
+	if !errors.As(err, &multiError) || len(multiError) != 1 || multiError["acme/missing"] == nil {
+		t.Fatalf("Apply failed with %v, want a MultiError of acme/missing", err)
+	}
+
+	if !strings.HasPrefix(err.Error(), "Error in 1 repositories, acme/missing: ") {
+		t.Errorf("MultiError message is %q", err)
+	}
+
+	for _, name := range []string{"acme/api", "acme/web"} {
+		result, found := results.Get(name)
+
+		if !found || result.Err != nil || len(result.Result.Applied) != 1 {
+			t.Errorf("Result of %s is %+v, want the label applied", name, result)
+		}
+
+		if _, ok := server.Repository("acme", strings.TrimPrefix(name, "acme/")).Label("bug"); !ok {
+			t.Errorf("Label bug is not created on %s", name)
+		}
+	}
+}
+
+func TestResultsErrIsNilWithoutFailures(t *testing.T) {
+	server, client := newTestClient(t)
+	server.AddRepository("acme", "api")
+
+	results, err := client.PlanAll(context.Background(), []*Settings{settingsFromYAML(t, "repository: {owner: acme, name: api}\n")}, 1)
+
+	if err != nil || results.Err() != nil {
+		t.Errorf("Plan failed with %v, want no error", err)
+	}
+
+	if _, found := results.Get("acme/other"); found {
+		t.Error("Result found for a repository that was not planned")
+	}
+}
@@ -0,0 +1,159 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Notifiers reporting the drift found by Serve
+const (
+	NotifierLog     = "log"
+	NotifierWebhook = "webhook"
+	NotifierSlack   = "slack"
+)
+
+// notifyTimeout is the maximum time spent sending a notification
+const notifyTimeout = 30 * time.Second
+
+// Notifier is told about every repository whose settings drifted, or failed to be planned or applied, during Serve
+type Notifier interface {
+	Notify(ctx context.Context, result RepositoryResult) error
+}
+
+// NewNotifier returns the notifier of a kind (log, webhook or slack), the url is the endpoint of the webhook and slack notifiers
+func NewNotifier(kind, url string) (Notifier, error) {
+	switch kind {
+	case "", NotifierLog:
+		return logNotifier{}, nil
+	case NotifierWebhook, NotifierSlack:
+		if url == "" {
+			return nil, errors.Errorf("The %s notifier requires an url", kind)
+		}
+
+		return &httpNotifier{url: url, slack: kind == NotifierSlack, client: &http.Client{Timeout: notifyTimeout}}, nil
+	default:
+		return nil, errors.Errorf("Unknown notifier %q (allowed values: %s, %s, %s)", kind, NotifierLog, NotifierWebhook, NotifierSlack)
+	}
+}
+
+// logNotifier writes the drift to the log
+type logNotifier struct{}
+
+func (logNotifier) Notify(ctx context.Context, result RepositoryResult) error {
+	log.Printf("[WARN] %s", driftMessage(result))
+
+	return nil
+}
+
+// httpNotifier posts the drift as json, a slack incoming webhook receives it as a text message
+type httpNotifier struct {
+	url    string
+	slack  bool
+	client *http.Client
+}
+
+// driftNotification is the payload posted by the webhook notifier
+type driftNotification struct {
+	Repository string
+	// Changes are the planned changes, Applied those made on github when the drift is enforced
+	Changes []Change `json:",omitempty"`
+	Applied []Change `json:",omitempty"`
+	Failed  []Change `json:",omitempty"`
+	// Unverified are the applied changes github did not reflect when verified
+	Unverified []Change `json:",omitempty"`
+	Error      string   `json:",omitempty"`
+}
+
+func (notifier *httpNotifier) Notify(ctx context.Context, result RepositoryResult) error {
+	var payload interface{} = map[string]string{"text": driftMessage(result)}
+
+	if !notifier.slack {
+		notification := driftNotification{Repository: result.Repository}
+
+		if result.Plan != nil {
+			notification.Changes = result.Plan.Changes
+		}
+
+		if result.Result != nil {
+			notification.Applied = result.Result.Applied
+			notification.Failed = result.Result.Failed
+			notification.Unverified = result.Result.Unverified
+		}
+
+		if result.Err != nil {
+			notification.Error = result.Err.Error()
+		}
+
+		payload = notification
+	}
+
+	body, err := json.Marshal(payload)
+
+	if err != nil {
+		return errors.Wrap(err, "Error while marshal notification")
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, notifier.url, bytes.NewReader(body))
+
+	if err != nil {
+		return errors.Wrap(err, "Error creating notification request")
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := notifier.client.Do(request)
+
+	if err != nil {
+		return errors.Wrap(err, "Error sending notification")
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode >= http.StatusMultipleChoices {
+		return errors.Errorf("Error sending notification, the notifier answered %s", response.Status)
+	}
+
+	return nil
+}
+
+// driftMessage describes the drift of a repository and what was done about it
+func driftMessage(result RepositoryResult) string {
+	builder := &strings.Builder{}
+
+	switch {
+	case result.Result != nil:
+		fmt.Fprintf(builder, "Drift of %s enforced, %d changes applied", result.Repository, len(result.Result.Applied))
+
+		if len(result.Result.Failed)+len(result.Result.Skipped) != 0 {
+			fmt.Fprintf(builder, ", %d failed and %d skipped", len(result.Result.Failed), len(result.Result.Skipped))
+		}
+
+		if len(result.Result.Unverified) != 0 {
+			fmt.Fprintf(builder, ", %d not verified", len(result.Result.Unverified))
+		}
+	case result.Plan != nil:
+		fmt.Fprintf(builder, "Drift of %s detected", result.Repository)
+	default:
+		fmt.Fprintf(builder, "Error planning %s", result.Repository)
+	}
+
+	if result.Err != nil {
+		fmt.Fprintf(builder, ": %s", result.Err)
+	}
+
+	builder.WriteString("\n")
+
+	if result.Plan != nil {
+		builder.WriteString(result.Plan.String())
+	}
+
+	return builder.String()
+}
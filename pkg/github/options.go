@@ -0,0 +1,439 @@
+package github
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// DefaultAPIVersion is the github rest api version sent with every request
+const DefaultAPIVersion = "2022-11-28"
+
+// DefaultUserAgent identifies requests made by github-settings
+const DefaultUserAgent = "github-settings"
+
+// Option configures the client
+type Option func(*options)
+
+type options struct {
+	token              string
+	tokenFile          string
+	baseURL            string
+	uploadURL          string
+	appID              int64
+	installationID     int64
+	appPrivateKey      []byte
+	authProvider       AuthProvider
+	apiVersion         string
+	previews           []string
+	endpointHeaders    []EndpointHeaders
+	userAgent          string
+	userAgentSuffix    string
+	resourceTimeout    time.Duration
+	breakerThreshold   int
+	maxRetries         int
+	secretValues       map[string]string
+	createRepositories bool
+	prune              bool
+	force              bool
+	verify             bool
+	webhookFailures    int
+	archiveInactive    bool
+	state              *StateStore
+	applyCache         *ApplyCache
+	snapshot           *Snapshot
+	against            *Snapshot
+	remoteEnv          []string
+	clock              Clock
+}
+
+// WithRemoteEnv allows the remote files extended by the settings files to reference environment variables,
+// they can only reference the variables describing the repository otherwise
+func WithRemoteEnv(names ...string) Option {
+	return func(opts *options) {
+		opts.remoteEnv = append(opts.remoteEnv, names...)
+	}
+}
+
+// WithClock replaces the clock of the system read by the client (ex: to test the retries and the schedules of serve without waiting)
+func WithClock(clock Clock) Option {
+	return func(opts *options) {
+		opts.clock = clock
+	}
+}
+
+// WithToken authenticates the requests with a personal access token
+func WithToken(token string) Option {
+	return func(opts *options) {
+		opts.token = token
+	}
+}
+
+// WithSnapshot records the live settings of the repositories planned in a snapshot
+func WithSnapshot(snapshot *Snapshot) Option {
+	return func(opts *options) {
+		opts.snapshot = snapshot
+	}
+}
+
+// WithAgainst plans the settings against the live settings of a snapshot instead of github, only the plans are meaningful
+// The custom roles and the bypass actors are compared as they are written since their names can't be looked up
+func WithAgainst(snapshot *Snapshot) Option {
+	return func(opts *options) {
+		opts.against = snapshot
+	}
+}
+
+// WithTokenFile authenticates the requests with the token held by a file, it is read again when the credentials are reloaded
+func WithTokenFile(path string) Option {
+	return func(opts *options) {
+		opts.tokenFile = path
+	}
+}
+
+// WithBaseURL calls a github enterprise server rest api (ex: https://github.example.com/api/v3/) instead of github.com
+func WithBaseURL(baseURL string) Option {
+	return func(opts *options) {
+		opts.baseURL = baseURL
+	}
+}
+
+// WithUploadURL sets the upload url of a github enterprise server, it defaults to the base url
+func WithUploadURL(uploadURL string) Option {
+	return func(opts *options) {
+		opts.uploadURL = uploadURL
+	}
+}
+
+// WithAppAuth authenticates as a github app installation instead of a token
+// Installation tokens are created from the pem encoded private key of the app and refreshed before they expire
+func WithAppAuth(appID, installationID int64, privateKey []byte) Option {
+	return func(opts *options) {
+		opts.appID = appID
+		opts.installationID = installationID
+		opts.appPrivateKey = privateKey
+	}
+}
+
+// WithAuthProvider authenticates with the tokens of a provider (ex: a credential broker minting short lived tokens) instead of a token or an app
+func WithAuthProvider(provider AuthProvider) Option {
+	return func(opts *options) {
+		opts.authProvider = provider
+	}
+}
+
+// WithAPIVersion pins the github rest api version sent in the X-GitHub-Api-Version header
+func WithAPIVersion(version string) Option {
+	return func(opts *options) {
+		opts.apiVersion = version
+	}
+}
+
+// WithPreviews adds preview media types to the Accept header of every request
+func WithPreviews(previews ...string) Option {
+	return func(opts *options) {
+		opts.previews = append(opts.previews, previews...)
+	}
+}
+
+// WithEndpointHeaders overrides the api version or adds preview media types for the endpoints matching a path pattern
+// The pattern is a rest api path without the base url where * matches one path segment and ** any number of segments (ex: /repos/*/*/rulesets/**),
+// an empty api version keeps the version of the other requests
+func WithEndpointHeaders(pattern, apiVersion string, previews ...string) Option {
+	return func(opts *options) {
+		opts.endpointHeaders = append(opts.endpointHeaders, EndpointHeaders{Pattern: pattern, APIVersion: apiVersion, Previews: previews})
+	}
+}
+
+// WithUserAgent replaces the User-Agent sent with every request (ex: github-settings/1.2.0)
+func WithUserAgent(agent string) Option {
+	return func(opts *options) {
+		opts.userAgent = agent
+	}
+}
+
+// WithUserAgentSuffix appends caller identification to the User-Agent (ex: a pipeline id)
+func WithUserAgentSuffix(suffix string) Option {
+	return func(opts *options) {
+		opts.userAgentSuffix = suffix
+	}
+}
+
+// WithResourceTimeout bounds the time spent applying a single resource change
+func WithResourceTimeout(timeout time.Duration) Option {
+	return func(opts *options) {
+		opts.resourceTimeout = timeout
+	}
+}
+
+// WithCircuitBreaker skips a resource kind after the given number of consecutive server failures, 0 disables it
+func WithCircuitBreaker(threshold int) Option {
+	return func(opts *options) {
+		opts.breakerThreshold = threshold
+	}
+}
+
+// WithMaxRetries sets the number of times a request rejected by a github rate limit is retried, 0 disables retries
+func WithMaxRetries(retries int) Option {
+	return func(opts *options) {
+		opts.maxRetries = retries
+	}
+}
+
+// WithSecretValues provides the values of the actions secrets by name, they take precedence over environment variables
+func WithSecretValues(values map[string]string) Option {
+	return func(opts *options) {
+		for secretName, value := range values {
+			opts.secretValues[strings.ToUpper(secretName)] = value
+		}
+	}
+}
+
+// WithCreateRepositories creates the missing repositories as if their settings had repository.create set
+func WithCreateRepositories(create bool) Option {
+	return func(opts *options) {
+		opts.createRepositories = create
+	}
+}
+
+// WithPrune deletes the live resources missing from the settings, it is enabled by default
+// The prune section of the settings overrides it per resource kind
+func WithPrune(prune bool) Option {
+	return func(opts *options) {
+		opts.prune = prune
+	}
+}
+
+// WithForce applies the settings to repositories declared as managed by another tool
+func WithForce(force bool) Option {
+	return func(opts *options) {
+		opts.force = force
+	}
+}
+
+// WithVerify fetches the settings of each repository again once its changes are applied and records the changes github does not reflect
+func WithVerify(verify bool) Option {
+	return func(opts *options) {
+		opts.verify = verify
+	}
+}
+
+// WithWebhookHealth reports the webhooks of the settings whose last deliveries all failed as drift, 0 does not check
+// the deliveries
+func WithWebhookHealth(failures int) Option {
+	return func(opts *options) {
+		opts.webhookFailures = failures
+	}
+}
+
+// WithArchiveInactive confirms the archival of the repositories inactive for the days of their inactivity policy when the
+// policy archives them, the inactive repositories are only reported otherwise
+func WithArchiveInactive(archive bool) Option {
+	return func(opts *options) {
+		opts.archiveInactive = archive
+	}
+}
+
+// WithStateStore records the resources applied from the settings in the state store and only prunes the resources it records,
+// the live resources the tool did not create nor adopt are left alone
+func WithStateStore(store *StateStore) Option {
+	return func(opts *options) {
+		opts.state = store
+	}
+}
+
+// WithApplyCache skips the repositories whose settings and live state did not change since their last successful apply and records the repositories applied
+// Force plans every repository anyway, the cache is saved by the caller
+func WithApplyCache(cache *ApplyCache) Option {
+	return func(opts *options) {
+		opts.applyCache = cache
+	}
+}
+
+func (opts *options) fullUserAgent() string {
+	if opts.userAgentSuffix == "" {
+		return opts.userAgent
+	}
+
+	return opts.userAgent + " " + opts.userAgentSuffix
+}
+
+// basePath returns the path of the base url without its trailing slash
+func (opts *options) basePath() string {
+	baseURL, err := url.Parse(opts.baseURL)
+
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSuffix(baseURL.Path, "/")
+}
+
+// tokenSource returns the source of the tokens authenticating the client, nil when it is anonymous
+func (opts *options) tokenSource() (oauth2.TokenSource, error) {
+	provider, err := opts.provider()
+
+	if err != nil || provider == nil {
+		return nil, err
+	}
+
+	return oauth2.ReuseTokenSource(nil, providerTokenSource{provider: provider}), nil
+}
+
+// provider returns the provider authenticating the requests, an explicit provider comes before the app, the token file and the token
+func (opts *options) provider() (AuthProvider, error) {
+	if opts.authProvider != nil {
+		return opts.authProvider, nil
+	}
+
+	if opts.appID != 0 {
+		provider, err := NewAppProvider(opts.appID, opts.installationID, opts.appPrivateKey, opts.baseURL, opts.uploadURL)
+
+		if err != nil {
+			return nil, err
+		}
+
+		// The jwt is issued at the time of the client and the installation tokens are requested with its headers and retries
+		provider.(*appProvider).clock = opts.clock
+		provider.(*appProvider).options = opts
+
+		return provider, nil
+	}
+
+	if opts.tokenFile != "" {
+		return NewTokenFileProvider(opts.tokenFile), nil
+	}
+
+	if opts.token == "" {
+		return nil, nil
+	}
+
+	return StaticTokenProvider(opts.token), nil
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{
+		baseURL:          DefaultBaseURL,
+		apiVersion:       DefaultAPIVersion,
+		userAgent:        DefaultUserAgent,
+		endpointHeaders:  append([]EndpointHeaders{}, DefaultEndpointHeaders...),
+		breakerThreshold: DefaultBreakerThreshold,
+		maxRetries:       DefaultMaxRetries,
+		secretValues:     map[string]string{},
+		prune:            true,
+		clock:            systemClock{},
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// EndpointHeaders are the api version and preview media types sent to the endpoints whose path matches a pattern
+type EndpointHeaders struct {
+	Pattern    string
+	APIVersion string
+	Previews   []string
+}
+
+// DefaultEndpointHeaders are the previews the endpoints used by github-settings required before github promoted them,
+// they are sent explicitly so the endpoints keep answering the same way if github changes their defaults
+// nolint:gochecknoglobals
+var DefaultEndpointHeaders = []EndpointHeaders{
+	{Pattern: "/repos/*/*/topics", Previews: []string{"application/vnd.github.mercy-preview+json"}},
+	{Pattern: "/repos/*/*/branches/**/protection/required_signatures", Previews: []string{"application/vnd.github.zzzax-preview+json"}},
+}
+
+// matches returns true when the path of a request matches the pattern of the endpoint
+func (endpoint EndpointHeaders) matches(requestPath string) bool {
+	return matchSegments(strings.Split(strings.Trim(endpoint.Pattern, "/"), "/"), strings.Split(strings.Trim(requestPath, "/"), "/"))
+}
+
+// matchSegments matches path segments with pattern segments where * matches one segment and ** any number of segments
+func matchSegments(pattern, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+
+	if pattern[0] == "**" {
+		for i := 0; i <= len(segments); i++ {
+			if matchSegments(pattern[1:], segments[i:]) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	if len(segments) == 0 || (pattern[0] != "*" && pattern[0] != segments[0]) {
+		return false
+	}
+
+	return matchSegments(pattern[1:], segments[1:])
+}
+
+// newTransport sends the requests of a client through base with the headers of the options, retrying them when rate limited
+// and counting them in the stats. basePath is the path of the base url the endpoint patterns are relative to
+func newTransport(base http.RoundTripper, opts *options, basePath string) http.RoundTripper {
+	return &headerTransport{
+		base: &retryTransport{
+			base:       &countingTransport{base: base},
+			maxRetries: opts.maxRetries,
+			clock:      opts.clock,
+		},
+		apiVersion: opts.apiVersion,
+		previews:   opts.previews,
+		basePath:   basePath,
+		endpoints:  opts.endpointHeaders,
+	}
+}
+
+// headerTransport sets the api version and accept headers explicitly on each request
+type headerTransport struct {
+	base       http.RoundTripper
+	apiVersion string
+	previews   []string
+	// basePath is the path of the base url (ex: /api/v3 for a github enterprise server), it is not part of the endpoint patterns
+	basePath  string
+	endpoints []EndpointHeaders
+}
+
+func (transport *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	apiVersion, previews := transport.apiVersion, transport.previews
+	endpointPath := strings.TrimPrefix(req.URL.Path, transport.basePath)
+
+	// The endpoints are matched in order, a later match overrides the api version and adds its previews
+	for _, endpoint := range transport.endpoints {
+		if !endpoint.matches(endpointPath) {
+			continue
+		}
+
+		if endpoint.APIVersion != "" {
+			apiVersion = endpoint.APIVersion
+		}
+
+		previews = append(append([]string{}, previews...), endpoint.Previews...)
+	}
+
+	if apiVersion != "" {
+		req.Header.Set("X-GitHub-Api-Version", apiVersion)
+	}
+
+	if len(previews) != 0 {
+		accept := previews
+
+		if current := req.Header.Get("Accept"); current != "" {
+			accept = append([]string{current}, accept...)
+		}
+
+		req.Header.Set("Accept", strings.Join(accept, ", "))
+	}
+
+	return transport.base.RoundTrip(req)
+}
@@ -0,0 +1,191 @@
+package github
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEndpointHeadersMatches(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"/repos/*/*/topics", "/repos/acme/api/topics", true},
+		{"/repos/*/*/topics", "/repos/acme/api/labels", false},
+		{"/repos/*/*/topics", "/repos/acme/api/topics/extra", false},
+		{"/repos/*/*/branches/**/protection/required_signatures", "/repos/acme/api/branches/release/1.0/protection/required_signatures", true},
+		{"/repos/*/*/branches/**/protection/required_signatures", "/repos/acme/api/branches/main/protection", false},
+		{"/repos/*/*/rulesets/**", "/repos/acme/api/rulesets", true},
+		{"/repos/*/*/rulesets/**", "/repos/acme/api/rulesets/12", true},
+	}
+
+	for _, test := range tests {
+		if got := (EndpointHeaders{Pattern: test.pattern}).matches(test.path); got != test.want {
+			t.Errorf("Pattern %s matching %s is %t, want %t", test.pattern, test.path, got, test.want)
+		}
+	}
+}
+
+func TestHeaderTransportSetsEndpointHeaders(t *testing.T) {
+	headers := map[string]http.Header{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers[r.URL.Path] = r.Header.Clone()
+	}))
+	defer server.Close()
+
+	transport := &headerTransport{
+		base:       http.DefaultTransport,
+		apiVersion: DefaultAPIVersion,
+		basePath:   "/api/v3",
+		endpoints:  append(append([]EndpointHeaders{}, DefaultEndpointHeaders...), EndpointHeaders{Pattern: "/repos/*/*/rulesets", APIVersion: "2026-03-10", Previews: []string{"application/vnd.github.rulesets-preview+json"}}),
+	}
+
+	for _, path := range []string{"/api/v3/repos/acme/api/labels", "/api/v3/repos/acme/api/rulesets", "/api/v3/repos/acme/api/topics"} {
+		request, _ := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		request.Header.Set("Accept", "application/vnd.github.v3+json")
+		response, err := transport.RoundTrip(request)
+
+		if err != nil {
+			t.Fatalf("Error requesting %s: %v", path, err)
+		}
+
+		response.Body.Close()
+	}
+
+	want := map[string][2]string{
+		"/api/v3/repos/acme/api/labels":   {DefaultAPIVersion, "application/vnd.github.v3+json"},
+		"/api/v3/repos/acme/api/rulesets": {"2026-03-10", "application/vnd.github.v3+json, application/vnd.github.rulesets-preview+json"},
+		"/api/v3/repos/acme/api/topics":   {DefaultAPIVersion, "application/vnd.github.v3+json, application/vnd.github.mercy-preview+json"},
+	}
+
+	for path, expected := range want {
+		if version := headers[path].Get("X-GitHub-Api-Version"); version != expected[0] {
+			t.Errorf("Api version of %s is %q, want %q", path, version, expected[0])
+		}
+
+		if accept := headers[path].Get("Accept"); accept != expected[1] {
+			t.Errorf("Accept of %s is %q, want %q", path, accept, expected[1])
+		}
+	}
+}
+
+func TestReloadCredentialsReadsTheTokenFileAgain(t *testing.T) {
+	tokens := make(chan string, 4)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokens <- r.Header.Get("Authorization")
+		fmt.Fprint(w, `{"login": "octocat"}`)
+	}))
+	defer server.Close()
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	writeToken := func(token string) {
+		if err := os.WriteFile(tokenFile, []byte(token+"\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeToken("first")
+
+	client, err := New(WithTokenFile(tokenFile), WithBaseURL(server.URL+"/"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	get := func() string {
+		_, _, err := client.github.Users.Get(context.Background(), "")
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return <-tokens
+	}
+
+	if token := get(); token != "Bearer first" {
+		t.Errorf("Authorization is %q, want the first token", token)
+	}
+
+	writeToken("second")
+
+	if token := get(); token != "Bearer first" {
+		t.Errorf("Authorization before the reload is %q, want the first token", token)
+	}
+
+	if err := client.ReloadCredentials(); err != nil {
+		t.Fatal(err)
+	}
+
+	if token := get(); token != "Bearer second" {
+		t.Errorf("Authorization after the reload is %q, want the second token", token)
+	}
+
+	writeToken("")
+
+	if err := client.ReloadCredentials(); err == nil {
+		t.Error("Reloading an empty token file succeeded, want an error")
+	}
+
+	if token := get(); token != "Bearer second" {
+		t.Errorf("Authorization after a failed reload is %q, want the previous token", token)
+	}
+}
+
+func TestAppInstallationTokenIsRequestedWithTheHeadersOfTheClient(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	privateKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	headers := http.Header{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header.Clone()
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"token": "installation-token"}`))
+	}))
+	defer server.Close()
+
+	provider, err := newOptions([]Option{
+		WithBaseURL(server.URL),
+		WithAppAuth(1, 2, privateKey),
+		WithAPIVersion("2026-03-10"),
+		WithUserAgentSuffix("pipeline-42"),
+	}).provider()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := provider.Token(context.Background())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if token.AccessToken != "installation-token" {
+		t.Errorf("Expected the installation token, got %q", token.AccessToken)
+	}
+
+	if version := headers.Get("X-GitHub-Api-Version"); version != "2026-03-10" {
+		t.Errorf("Expected the api version 2026-03-10, got %q", version)
+	}
+
+	if agent := headers.Get("User-Agent"); agent != DefaultUserAgent+" pipeline-42" {
+		t.Errorf("Expected the user agent %q, got %q", DefaultUserAgent+" pipeline-42", agent)
+	}
+}
@@ -0,0 +1,129 @@
+package github
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// ResourceSecurityManagers are the teams holding the security manager role of an organization
+const ResourceSecurityManagers = "securitymanagers"
+
+// OrgSettings are the settings of the organization of a multi repository settings file
+// org is either the name of the organization or a mapping with its name and its settings
+type OrgSettings struct {
+	Name     string
+	Security orgSecurity `yaml:",omitempty"`
+}
+
+// orgSecurity are the security settings of an organization
+type orgSecurity struct {
+	// ManagerTeams are the slugs of the teams granted the security manager role, they are not managed when missing
+	// and every security manager team is removed when empty
+	ManagerTeams []string
+}
+
+// securityManager is a team holding the security manager role of an organization
+type securityManager struct {
+	Team string
+}
+
+// UnmarshalYAML accepts the name of the organization alone or a mapping with its name and its settings
+func (org *OrgSettings) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		return node.Decode(&org.Name)
+	}
+
+	type plain OrgSettings
+
+	return node.Decode((*plain)(org))
+}
+
+// GetOrgSettingsFromFile returns the settings of the organization declared by the org of a multi repository settings file
+func (client *Client) GetOrgSettingsFromFile(file string) (*OrgSettings, error) {
+	content, err := client.loadSettingsFile(file)
+
+	if err != nil {
+		return nil, err
+	}
+
+	multi, err := parseMultiSettings(content)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if multi == nil || multi.Org.Name == "" {
+		return nil, errors.Errorf("The settings file %s declares no org", file)
+	}
+
+	return &multi.Org, nil
+}
+
+// PlanOrg computes the changes required to apply the settings of an organization, the plan has the organization as owner and no name
+func (client *Client) PlanOrg(ctx context.Context, org *OrgSettings) (*Plan, error) {
+	plan := &Plan{Owner: org.Name, Changes: []Change{}}
+
+	if org.Security.ManagerTeams == nil {
+		return plan, nil
+	}
+
+	teams, _, err := client.github.Organizations.ListSecurityManagerTeams(withRetries(ctx), org.Name)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error while listing the security manager teams of %s", org.Name)
+	}
+
+	live := map[string]string{}
+
+	for _, team := range teams {
+		live[strings.ToLower(team.GetSlug())] = team.GetSlug()
+	}
+
+	desired := map[string]bool{}
+
+	for _, slug := range org.Security.ManagerTeams {
+		desired[strings.ToLower(slug)] = true
+
+		if _, ok := live[strings.ToLower(slug)]; !ok {
+			plan.Changes = append(plan.Changes, newChange(ResourceSecurityManagers, slug, ActionCreate, securityManager{}, securityManager{Team: slug}))
+		}
+	}
+
+	for _, key := range sortedKeys(live) {
+		if !desired[key] {
+			plan.Changes = append(plan.Changes, newChange(ResourceSecurityManagers, live[key], ActionDelete, securityManager{Team: live[key]}, securityManager{}))
+		}
+	}
+
+	sort.SliceStable(plan.Changes, func(i, j int) bool { return plan.Changes[i].Name < plan.Changes[j].Name })
+
+	return plan, nil
+}
+
+func (client *Client) updateSecurityManager(ctx context.Context, report reporter, org string, action Action, current, desired securityManager) error {
+	if action == ActionDelete {
+		report.changed(ResourceSecurityManagers, "Removing security manager team %s\n", current.Team)
+
+		_, err := client.github.Organizations.RemoveSecurityManagerTeam(ctx, org, current.Team)
+
+		if err != nil {
+			return errors.Wrap(err, "Error removing a security manager team")
+		}
+
+		return nil
+	}
+
+	report.changed(ResourceSecurityManagers, "Adding security manager team %s\n", desired.Team)
+
+	_, err := client.github.Organizations.AddSecurityManagerTeam(ctx, org, desired.Team)
+
+	if err != nil {
+		return errors.Wrap(err, "Error adding a security manager team")
+	}
+
+	return nil
+}
@@ -0,0 +1,99 @@
+package github
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+const orgSettings = `
+org:
+  name: acme
+  security:
+    managerteams: [security, Platform]
+defaults:
+  repository:
+    description: managed
+`
+
+func TestApplyOrgSetsTheSecurityManagerTeams(t *testing.T) {
+	server, client := newTestClient(t)
+	server.SetSecurityManagers("acme", "platform", "legacy")
+
+	org, err := client.GetOrgSettingsFromFile(writeSettings(t, orgSettings))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err := client.PlanOrg(context.Background(), org)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if names := changeNames(plan); !slices.Equal(names, []string{"securitymanagers delete legacy", "securitymanagers create security"}) {
+		t.Fatalf("Expected the legacy team removed and the security team added, got %v", names)
+	}
+
+	if _, err := client.ApplyPlan(context.Background(), plan); err != nil {
+		t.Fatal(err)
+	}
+
+	if managers := server.SecurityManagers("acme"); !reflect.DeepEqual(managers, []string{"platform", "security"}) {
+		t.Errorf("Expected the platform and security teams, got %v", managers)
+	}
+
+	plan, err = client.PlanOrg(context.Background(), org)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !plan.Empty() || plan.String() != "acme: no changes\n" {
+		t.Errorf("Expected no changes once applied, got %s", plan)
+	}
+}
+
+func TestPlanOrgLeavesTheSecurityManagersAloneWhenMissing(t *testing.T) {
+	server, client := newTestClient(t)
+	server.SetSecurityManagers("acme", "platform")
+
+	org, err := client.GetOrgSettingsFromFile(writeSettings(t, "org: acme\n"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err := client.PlanOrg(context.Background(), org)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if org.Name != "acme" || !plan.Empty() {
+		t.Errorf("Expected the security managers of acme unmanaged, got %v", changeNames(plan))
+	}
+}
+
+func TestOrgSettingsKeepTargetingTheRepositoriesOfTheOrg(t *testing.T) {
+	server, client := newTestClient(t)
+	server.AddRepository("acme", "api")
+
+	allSettings, err := client.GetAllSettingsFromFile(context.Background(), writeSettings(t, orgSettings))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(allSettings) != 1 || allSettings[0].Repository.Owner != "acme" || allSettings[0].Repository.Description != "managed" {
+		t.Errorf("Expected the api repository of acme, got %v", allSettings)
+	}
+
+	problems, err := Validate([]byte(orgSettings))
+
+	if err != nil || len(problems) != 0 {
+		t.Errorf("Expected the org settings to be valid, got %v %v", problems, err)
+	}
+}
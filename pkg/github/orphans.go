@@ -0,0 +1,174 @@
+package github
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Orphans lists the live resources absent from the settings, keyed by resource kind
+type Orphans map[string][]string
+
+// Orphans returns the live resources that are not declared in the settings without deleting anything
+// Disabled sections are included since their resources are not declared either
+func (client *Client) Orphans(ctx context.Context, settings *Settings) (Orphans, error) {
+	githubSettings, err := client.GetSettingsFromGithub(ctx, settings.Repository.Owner, settings.Repository.Name)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Error getting settings from github")
+	}
+
+	return findOrphans(githubSettings, settings), nil
+}
+
+func findOrphans(githubSettings, settings *Settings) Orphans {
+	orphans := Orphans{}
+
+	labels := map[string]bool{}
+
+	for _, label := range settings.Labels {
+		labels[label.Name] = true
+	}
+
+	for _, label := range githubSettings.Labels {
+		if !labels[label.Name] {
+			orphans.add(ResourceLabels, label.Name)
+		}
+	}
+
+	branches := map[string]bool{}
+
+	for _, branch := range settings.Branches {
+		branches[branch.Name] = true
+	}
+
+	for _, branch := range githubSettings.Branches {
+		if branch.Protection.Enabled && !branches[branch.Name] {
+			orphans.add(ResourceBranches, branch.Name)
+		}
+	}
+
+	webhooks := map[int64]webhook{}
+
+	for _, githubWebhook := range githubSettings.Webhooks {
+		webhooks[githubWebhook.ID] = githubWebhook
+	}
+
+	for _, webhookSettings := range settings.Webhooks {
+		if githubWebhook, ok := findWebhook(webhooks, webhookSettings); ok {
+			delete(webhooks, githubWebhook.ID)
+		}
+	}
+
+	for _, githubWebhook := range webhooks {
+		orphans.add(ResourceWebhooks, githubWebhook.URL)
+	}
+
+	topics := map[string]bool{}
+
+	for _, topic := range desiredTopics(settings) {
+		topics[topic] = true
+	}
+
+	for _, topic := range githubSettings.Topics {
+		if !topics[topic] {
+			orphans.add(ResourceTopics, topic)
+		}
+	}
+
+	collaborators := map[string]bool{}
+
+	for _, collaborator := range settings.Collaborators {
+		collaborators[strings.ToLower(collaborator.Username)] = true
+	}
+
+	for _, collaborator := range githubSettings.Collaborators {
+		if !collaborators[strings.ToLower(collaborator.Username)] {
+			orphans.add(ResourceCollaborators, collaborator.Username)
+		}
+	}
+
+	teams := map[string]bool{}
+
+	for _, team := range settings.Teams {
+		teams[team.Slug] = true
+	}
+
+	for _, team := range githubSettings.Teams {
+		if !teams[team.Slug] {
+			orphans.add(ResourceTeams, team.Slug)
+		}
+	}
+
+	secrets := map[string]bool{}
+
+	for _, secret := range settings.Secrets {
+		secrets[strings.ToUpper(secret.Name)] = true
+	}
+
+	for _, secret := range githubSettings.Secrets {
+		if !secrets[secret.Name] {
+			orphans.add(ResourceSecrets, secret.Name)
+		}
+	}
+
+	variables := map[string]bool{}
+
+	for _, variable := range settings.Variables {
+		variables[strings.ToUpper(variable.Name)] = true
+	}
+
+	for _, variable := range githubSettings.Variables {
+		if !variables[variable.Name] {
+			orphans.add(ResourceVariables, variable.Name)
+		}
+	}
+
+	dependabotSecrets := map[string]bool{}
+
+	for _, secret := range settings.DependabotSecrets {
+		dependabotSecrets[strings.ToUpper(secret.Name)] = true
+	}
+
+	for _, secret := range githubSettings.DependabotSecrets {
+		if !dependabotSecrets[secret.Name] {
+			orphans.add(ResourceDependabotSecrets, secret.Name)
+		}
+	}
+
+	environments := map[string]bool{}
+
+	for _, environment := range settings.Environments {
+		environments[environment.Name] = true
+	}
+
+	for _, environment := range githubSettings.Environments {
+		if !environments[environment.Name] {
+			orphans.add(ResourceEnvironments, environment.Name)
+		}
+	}
+
+	rulesets := map[string]bool{}
+
+	for _, rulesetSettings := range settings.Rulesets {
+		rulesets[rulesetSettings.Name] = true
+	}
+
+	for _, githubRuleset := range githubSettings.Rulesets {
+		if !rulesets[githubRuleset.Name] {
+			orphans.add(ResourceRulesets, githubRuleset.Name)
+		}
+	}
+
+	for kind := range orphans {
+		sort.Strings(orphans[kind])
+	}
+
+	return orphans
+}
+
+func (orphans Orphans) add(kind, name string) {
+	orphans[kind] = append(orphans[kind], name)
+}
@@ -0,0 +1,112 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/google/go-github/v75/github"
+	"github.com/pkg/errors"
+)
+
+// Types of the accounts owning repositories returned by github
+const (
+	ownerOrganization = "Organization"
+	ownerUser         = "User"
+)
+
+// userOwned returns true when the live repository belongs to a personal account rather than an organization
+func userOwned(githubSettings *Settings) bool {
+	return githubSettings.Status != nil && githubSettings.Status.OwnerType == ownerUser
+}
+
+// forUserAccount adapts the settings of a repository owned by a personal account and warns about what cannot be applied to it
+// A personal account has no teams, grants write access to every collaborator and cannot restrict pushes or review dismissals
+func forUserAccount(settings *Settings) (*Settings, []string) {
+	adapted := *settings
+	owner := settings.Repository.Owner
+	warnings := []string{}
+
+	if !settings.Disable.Teams && len(settings.Teams) != 0 {
+		warnings = append(warnings, fmt.Sprintf("teams are ignored, %s is a personal account", owner))
+	}
+
+	adapted.Disable.Teams = true
+	adapted.Teams = nil
+
+	// A missing collaborators section stays unmanaged
+	if settings.Collaborators != nil {
+		adapted.Collaborators = make([]collaborator, 0, len(settings.Collaborators))
+	}
+
+	for _, collaboratorSettings := range settings.Collaborators {
+		if collaboratorSettings.Permission != "" && collaboratorSettings.Permission != permissionPush {
+			warnings = append(warnings, fmt.Sprintf("collaborator %s gets write access instead of %s, the only access of the repositories of the personal account %s", collaboratorSettings.Username, collaboratorSettings.Permission, owner))
+			collaboratorSettings.Permission = permissionPush
+		}
+
+		adapted.Collaborators = append(adapted.Collaborators, collaboratorSettings)
+	}
+
+	adapted.Branches = make([]branch, 0, len(settings.Branches))
+
+	for _, branchSettings := range settings.Branches {
+		reviews := &branchSettings.Protection.RequiredApprovingReviewCount
+
+		if branchSettings.Protection.Restrictions != nil || reviews.DismissalRestrictions != nil || reviews.BypassPullRequestAllowances != nil {
+			warnings = append(warnings, fmt.Sprintf("push, dismissal and bypass restrictions of branch %s are ignored, %s is a personal account", branchSettings.Name, owner))
+			branchSettings.Protection.Restrictions = nil
+			reviews.DismissalRestrictions = nil
+			reviews.BypassPullRequestAllowances = nil
+		}
+
+		adapted.Branches = append(adapted.Branches, branchSettings)
+	}
+
+	// A missing environments section stays unmanaged
+	if settings.Environments != nil {
+		adapted.Environments = make([]environment, 0, len(settings.Environments))
+	}
+
+	for _, environmentSettings := range settings.Environments {
+		if len(environmentSettings.Reviewers.Teams) != 0 {
+			warnings = append(warnings, fmt.Sprintf("team reviewers of environment %s are ignored, %s is a personal account", environmentSettings.Name, owner))
+			environmentSettings.Reviewers.Teams = nil
+		}
+
+		adapted.Environments = append(adapted.Environments, environmentSettings)
+	}
+
+	return &adapted, warnings
+}
+
+// eachOwnerRepository calls the handler with the name of every non archived repository of an organization or a personal account
+// The private repositories of a personal account are only listed when the client is authenticated as this account
+func (client *Client) eachOwnerRepository(ctx context.Context, owner string, handle func(name string) error) error {
+	ctx = withRetries(ctx)
+
+	account, _, err := client.github.Users.Get(ctx, owner)
+
+	if err != nil {
+		return errors.Wrapf(err, "Error getting account %s", owner)
+	}
+
+	if account.GetType() != ownerUser {
+		return client.eachOrgRepository(ctx, owner, handle)
+	}
+
+	authenticated, _, err := client.github.Users.Get(ctx, "")
+
+	if err == nil && strings.EqualFold(authenticated.GetLogin(), owner) {
+		return eachRepository(func(opts github.ListOptions) ([]*github.Repository, *github.Response, error) {
+			return client.github.Repositories.ListByAuthenticatedUser(ctx, &github.RepositoryListByAuthenticatedUserOptions{Affiliation: "owner", Sort: "full_name", Direction: "asc", ListOptions: opts})
+		}, handle)
+	}
+
+	log.Printf("[WARN] Listing the public repositories of %s only, the client is not authenticated as this personal account\n", owner)
+
+	return eachRepository(func(opts github.ListOptions) ([]*github.Repository, *github.Response, error) {
+		return client.github.Repositories.ListByUser(ctx, owner, &github.RepositoryListByUserOptions{Type: "owner", Sort: "full_name", Direction: "asc", ListOptions: opts})
+	}, handle)
+}
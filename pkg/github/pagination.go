@@ -0,0 +1,50 @@
+package github
+
+import (
+	"github.com/google/go-github/v75/github"
+)
+
+// pageSize is the number of items requested per page, the maximum accepted by github
+const pageSize = 100
+
+// listAll calls a paginated list endpoint from its first to its last page
+func listAll[T any](list func(opts github.ListOptions) ([]T, *github.Response, error)) ([]T, error) {
+	items := []T{}
+
+	err := eachPage(list, func(page []T) error {
+		items = append(items, page...)
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// eachPage calls a paginated list endpoint from its first to its last page and hands every page over as soon as it is received
+// The listing stops at the first error of the endpoint or of the handler
+func eachPage[T any](list func(opts github.ListOptions) ([]T, *github.Response, error), handle func(page []T) error) error {
+	opts := github.ListOptions{PerPage: pageSize}
+
+	for {
+		page, response, err := list(opts)
+
+		if err != nil {
+			return err
+		}
+
+		err = handle(page)
+
+		if err != nil {
+			return err
+		}
+
+		if response == nil || response.NextPage == 0 {
+			return nil
+		}
+
+		opts.Page = response.NextPage
+	}
+}
@@ -0,0 +1,104 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-github/v75/github"
+	"github.com/michaelmass/github-settings/pkg/githubtest/server"
+)
+
+// syntheticOrg adds an organization of repositories with labels to a fake server and returns settings declaring their labels
+func syntheticOrg(server *server.Server, org string, repositories, labels int) []*Settings {
+	allSettings := make([]*Settings, 0, repositories)
+
+	for i := 0; i < repositories; i++ {
+		repoName := fmt.Sprintf("repo-%04d", i)
+		repo := server.AddRepository(org, repoName)
+		settings := &Settings{Repository: repository{Owner: org, Name: repoName}, Disable: Disabled{Repository: true}}
+
+		for j := 0; j < labels; j++ {
+			name := fmt.Sprintf("label-%03d", j)
+			repo.SetLabel(name, "ededed", "")
+			settings.Labels = append(settings.Labels, label{Name: name, Color: "ededed"})
+		}
+
+		allSettings = append(allSettings, settings)
+	}
+
+	return allSettings
+}
+
+func TestListAllReadsEveryPage(t *testing.T) {
+	server, client := newTestClient(t)
+	syntheticOrg(server, "acme", 250, 0)
+
+	repos, err := listAll(func(opts github.ListOptions) ([]*github.Repository, *github.Response, error) {
+		return client.github.Repositories.ListByOrg(context.Background(), "acme", &github.RepositoryListByOrgOptions{ListOptions: opts})
+	})
+
+	if err != nil {
+		t.Fatalf("Error listing repositories: %v", err)
+	}
+
+	if len(repos) != 250 {
+		t.Errorf("Listed %d repositories, want 250", len(repos))
+	}
+}
+
+func TestEachPageStopsOnError(t *testing.T) {
+	pages := 0
+
+	err := eachPage(func(opts github.ListOptions) ([]int, *github.Response, error) {
+		return []int{opts.Page}, &github.Response{NextPage: opts.Page + 1}, nil
+	}, func(page []int) error {
+		pages++
+
+		if pages == 3 {
+			return fmt.Errorf("stop")
+		}
+
+		return nil
+	})
+
+	if err == nil || pages != 3 {
+		t.Errorf("Handled %d pages with error %v, want 3 pages and the handler error", pages, err)
+	}
+}
+
+// BenchmarkListAll lists the repositories of an organization of 1000 repositories, 10 pages of 100
+func BenchmarkListAll(b *testing.B) {
+	server, client := newTestClient(b)
+	syntheticOrg(server, "acme", 1000, 0)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_, err := listAll(func(opts github.ListOptions) ([]*github.Repository, *github.Response, error) {
+			return client.github.Repositories.ListByOrg(context.Background(), "acme", &github.RepositoryListByOrgOptions{ListOptions: opts})
+		})
+
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkEachPage handles the labels of a repository page by page
+func BenchmarkEachPage(b *testing.B) {
+	server, client := newTestClient(b)
+	syntheticOrg(server, "acme", 1, 1000)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		err := eachPage(func(opts github.ListOptions) ([]*github.Label, *github.Response, error) {
+			return client.github.Issues.ListLabels(context.Background(), "acme", "repo-0000", &opts)
+		}, func(page []*github.Label) error {
+			return nil
+		})
+
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
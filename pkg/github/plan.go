@@ -0,0 +1,659 @@
+package github
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/michaelmass/github-settings/pkg/diff"
+	"github.com/pkg/errors"
+)
+
+// Resource kinds managed by the settings
+const (
+	ResourceRepository = "repository"
+	ResourceLabels     = "labels"
+	ResourceBranches   = "branches"
+	ResourceWebhooks   = "webhooks"
+	ResourceTopics     = "topics"
+)
+
+const sensitiveValue = "(sensitive)"
+
+// Action is the kind of change planned on a resource
+type Action string
+
+// Actions planned on resources
+const (
+	ActionCreate Action = "create"
+	ActionUpdate Action = "update"
+	ActionDelete Action = "delete"
+)
+
+// Plan is the set of changes required to apply settings to a repository
+type Plan struct {
+	Owner   string
+	Name    string
+	Changes []Change
+	// ManagedBy is the other tool declared as managing the repository, the plan is only applied when forced
+	ManagedBy string
+	// Warnings point out risks the changes do not cover (ex: branches matching a ruleset not protected yet)
+	Warnings []string `json:",omitempty"`
+
+	// settings the plan was computed from, a plan creating the repository is computed again once it exists
+	settings *Settings
+	// configHash identifies the settings in the apply cache once the plan is applied successfully
+	configHash string
+}
+
+// Change is a planned change on a single resource
+type Change struct {
+	Resource string
+	// Name identifies the resource within its kind (label name, branch name, webhook url)
+	Name   string
+	Action Action
+	Fields []FieldChange
+	// ReportOnly changes are planned to report the drift of a resource whose enforcement is report, apply skips them
+	ReportOnly bool `json:",omitempty"`
+	// Impacts are the consequences of the change a reviewer should not miss (ex: irreversible)
+	Impacts []Impact `json:",omitempty"`
+	// Reason and Ticket reference why the change is made, they are copied from the resource or from the settings
+	Reason string `json:",omitempty"`
+	Ticket string `json:",omitempty"`
+
+	current interface{}
+	desired interface{}
+}
+
+// FieldChange is the value of a field before and after a change
+type FieldChange struct {
+	Field  string
+	Before interface{}
+	After  interface{}
+}
+
+// Plan computes the changes required to apply the settings without calling any mutating github api
+func (client *Client) Plan(ctx context.Context, settings *Settings) (*Plan, error) {
+	stats.Add(StatPlans, 1)
+
+	plan, err := client.plan(ctx, settings)
+
+	if err != nil {
+		stats.Add(StatPlanFailures, 1)
+		return nil, err
+	}
+
+	stats.Add(StatDrift, int64(len(plan.Changes)))
+
+	return plan, nil
+}
+
+// plan computes the changes of the settings without counting them in the stats
+func (client *Client) plan(ctx context.Context, settings *Settings) (*Plan, error) {
+	files, err := resolveFiles(settings.Files, settings.Repository)
+
+	if err != nil {
+		return nil, err
+	}
+
+	resolved := *settings
+	resolved.Files = files
+	settings = &resolved
+
+	if client.against != nil {
+		return client.planAgainst(settings)
+	}
+
+	err = client.checkFeatures(ctx, settings)
+
+	if err != nil {
+		return nil, err
+	}
+
+	githubSettings, err := client.getSettings(ctx, settings.Repository.Owner, settings.Repository.Name, client.fetches(settings))
+
+	if isNotFound(err) && (settings.Repository.Create || client.createRepositories) {
+		settings, err = substituteMetadata(nil, settings)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return planCreation(settings), nil
+	}
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Error getting settings from github")
+	}
+
+	settings, err = substituteMetadata(githubSettings, settings)
+
+	if err != nil {
+		return nil, err
+	}
+
+	settings, err = client.resolveWebhookNames(githubSettings, settings)
+
+	if err != nil {
+		return nil, err
+	}
+
+	settings, err = client.resolveBypassActors(ctx, githubSettings, settings)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Personal accounts have no custom roles, their collaborators are granted push
+	if !userOwned(githubSettings) {
+		settings, err = client.resolveRoles(ctx, settings)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !settings.Disable.Files && len(settings.Files) > 0 {
+		githubSettings.Files, err = client.getFiles(ctx, settings.Repository.Owner, settings.Repository.Name, githubSettings.Repository.DefaultBranch, settings.Files)
+
+		if err != nil {
+			return nil, errors.Wrap(err, "Error getting files from github")
+		}
+	}
+
+	if client.snapshot != nil {
+		err = client.snapshot.record(githubSettings)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	plan := client.newPlan(githubSettings, settings)
+	unhealthy, err := client.unhealthyWebhooks(ctx, githubSettings, settings)
+
+	if err != nil {
+		return nil, err
+	}
+
+	plan.Changes = append(plan.Changes, unhealthy...)
+	err = client.planInactivity(ctx, githubSettings, settings, plan)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return plan, nil
+}
+
+// planAgainst computes the changes of the settings against the live settings of a snapshot
+func (client *Client) planAgainst(settings *Settings) (*Plan, error) {
+	githubSettings, err := client.against.settings(settings.Repository.Owner, settings.Repository.Name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	settings, err = substituteMetadata(githubSettings, settings)
+
+	if err != nil {
+		return nil, err
+	}
+
+	settings, err = client.resolveWebhookNames(githubSettings, settings)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return client.newPlan(githubSettings, settings), nil
+}
+
+// fetches returns the kinds of resources read from github, every kind is read to record a snapshot since it may be planned
+// against settings managing more kinds
+func (client *Client) fetches(settings *Settings) func(resource string) bool {
+	if client.snapshot != nil {
+		return func(resource string) bool { return true }
+	}
+
+	return settings.manages
+}
+
+// newPlan computes the plan of the live settings with the warnings and the pruning of the client
+func (client *Client) newPlan(githubSettings, settings *Settings) *Plan {
+	plan := computePlan(githubSettings, settings)
+	plan.Warnings = append(plan.Warnings, unexpectedSecrets(plan.Changes, settings.Prune, client.prune)...)
+	plan.Changes = pruneChanges(plan.Changes, settings.Prune, client.prune)
+	plan.settings = settings
+	plan.ManagedBy = githubSettings.Status.ManagedBy
+	client.keepUnmanaged(plan)
+
+	return plan
+}
+
+// Empty returns true when the plan has no changes
+func (plan *Plan) Empty() bool {
+	return len(plan.Changes) == 0
+}
+
+func computePlan(githubSettings, settings *Settings) *Plan {
+	plan := &Plan{
+		Owner: settings.Repository.Owner,
+		Name:  settings.Repository.Name,
+	}
+
+	ownerWarnings := []string{}
+
+	if userOwned(githubSettings) {
+		settings, ownerWarnings = forUserAccount(settings)
+	}
+
+	plan.Changes = append(plan.Changes, planRepository(settings.Disable.Repository, githubSettings.Repository, settings.Repository)...)
+	plan.Changes = append(plan.Changes, planLabels(settings.Disable.Labels, githubSettings.Labels, settings.Labels)...)
+	plan.Changes = append(plan.Changes, planBranches(settings.Disable.Branches, githubSettings.Branches, settings.Branches)...)
+	plan.Changes = append(plan.Changes, planWebhooks(settings.Disable.Webhooks, githubSettings.Webhooks, settings.Webhooks)...)
+	plan.Changes = append(plan.Changes, planCollaborators(settings.Disable.Collaborators, githubSettings.Collaborators, settings.Collaborators)...)
+	plan.Changes = append(plan.Changes, planTeams(settings.Disable.Teams, githubSettings.Teams, settings.Teams)...)
+	plan.Changes = append(plan.Changes, planSecrets(settings.Disable.Secrets, githubSettings.Secrets, settings.Secrets)...)
+	plan.Changes = append(plan.Changes, planVariables(settings.Disable.Variables, githubSettings.Variables, settings.Variables)...)
+	plan.Changes = append(plan.Changes, planDependabotSecrets(settings.Disable.DependabotSecrets, githubSettings.DependabotSecrets, settings.DependabotSecrets)...)
+	plan.Changes = append(plan.Changes, planEnvironments(settings.Disable.Environments, githubSettings.Environments, settings.Environments)...)
+	plan.Changes = append(plan.Changes, planRulesets(settings.Disable.Rulesets, githubSettings.Rulesets, settings.Rulesets)...)
+	plan.Changes = append(plan.Changes, planFiles(settings.Disable.Files, githubSettings.Files, settings.Files)...)
+	plan.Changes = append(plan.Changes, planTopics(settings.Disable.Topics, githubSettings.Topics, desiredTopics(settings))...)
+	plan.Warnings = append(ownerWarnings, uncoveredBranches(settings.Disable.Rulesets, githubSettings, settings)...)
+	plan.Warnings = append(plan.Warnings, overlappingProtections(settings.Disable.Rulesets, githubSettings, settings)...)
+
+	if enablesAdvancedSecurity(plan) {
+		plan.Warnings = append(plan.Warnings, "Enabling advanced security consumes a seat for each committer of the last 90 days without one, report seats counts them")
+	}
+
+	plan.setRationale(settings)
+	plan.setImpacts()
+
+	return plan
+}
+
+func planRepository(disabled bool, githubRepo, repo repository) []Change {
+	if disabled {
+		log.Print("[INFO] Skipping disabled repository settings\n")
+		return nil
+	}
+
+	repo = repo.withServerDefaults(githubRepo)
+
+	if diff.Equal(githubRepo, repo) {
+		return nil
+	}
+
+	return []Change{newChange(ResourceRepository, repo.Name, ActionUpdate, githubRepo, repo)}
+}
+
+func planLabels(disabled bool, githubLabels, labelsSettings []label) []Change {
+	if disabled {
+		log.Print("[INFO] Skipping disabled repository labels\n")
+		return nil
+	}
+
+	labelsToCreate := []Change{}
+	labelsToUpdate := []Change{}
+	deleteLabelMap := map[string]label{}
+
+	for _, githubLabel := range githubLabels {
+		deleteLabelMap[githubLabel.Name] = githubLabel
+	}
+
+	for _, labelSetting := range labelsSettings {
+		githubLabel, ok := deleteLabelMap[labelSetting.Name]
+		labelSetting = labelSetting.withServerDefaults(githubLabel)
+
+		if !ok {
+			labelsToCreate = append(labelsToCreate, newChange(ResourceLabels, labelSetting.Name, ActionCreate, label{}, labelSetting))
+		} else {
+			delete(deleteLabelMap, labelSetting.Name)
+
+			if !diff.Equal(githubLabel, labelSetting) {
+				labelsToUpdate = append(labelsToUpdate, newChange(ResourceLabels, labelSetting.Name, ActionUpdate, githubLabel, labelSetting))
+			}
+		}
+	}
+
+	changes := []Change{}
+
+	for _, labelName := range sortedKeys(deleteLabelMap) {
+		labelToDelete := deleteLabelMap[labelName]
+		changes = append(changes, newChange(ResourceLabels, labelName, ActionDelete, labelToDelete, label{}))
+	}
+
+	changes = append(changes, labelsToCreate...)
+
+	return append(changes, labelsToUpdate...)
+}
+
+func planBranches(disabled bool, githubBranches []branch, branchesSettings []branch) []Change {
+	if disabled {
+		log.Print("[INFO] Skipping disabled repository branches\n")
+		return nil
+	}
+
+	branchesToCreate := []Change{}
+	branchesToUpdate := []Change{}
+	deleteBranchesMap := map[string]branch{}
+
+	// Add all github branch that exist except the branch without protection
+	for _, githubBranch := range githubBranches {
+		deleteBranchesMap[githubBranch.Name] = githubBranch
+	}
+
+	for _, branchSettings := range branchesSettings {
+		githubBranch, ok := deleteBranchesMap[branchSettings.Name]
+		branchSettings = branchSettings.withServerDefaults()
+
+		if !ok {
+			branchesToCreate = append(branchesToCreate, newChange(ResourceBranches, branchSettings.Name, ActionCreate, branch{}, branchSettings))
+		} else {
+			delete(deleteBranchesMap, branchSettings.Name)
+			githubBranch = githubBranch.withServerDefaults()
+
+			if !diff.Equal(githubBranch, branchSettings) {
+				branchesToUpdate = append(branchesToUpdate, newChange(ResourceBranches, branchSettings.Name, ActionUpdate, githubBranch, branchSettings))
+			}
+		}
+	}
+
+	changes := branchesToCreate
+
+	for _, branchToDeleteName := range sortedKeys(deleteBranchesMap) {
+		branchToDelete := deleteBranchesMap[branchToDeleteName]
+		if !branchToDelete.Protection.Enabled {
+			continue
+		}
+
+		changes = append(changes, newChange(ResourceBranches, branchToDeleteName, ActionDelete, branchToDelete, branch{Name: branchToDeleteName}))
+	}
+
+	return append(changes, branchesToUpdate...)
+}
+
+func planWebhooks(disabled bool, githubWebhooks []webhook, webhooksSettings []webhook) []Change {
+	if disabled {
+		log.Print("[INFO] Skipping disabled repository webhooks\n")
+		return nil
+	}
+
+	changes := []Change{}
+	webhooksToUpdate := []Change{}
+	deleteWebhooksMap := map[int64]webhook{}
+
+	for _, githubWebhook := range githubWebhooks {
+		deleteWebhooksMap[githubWebhook.ID] = githubWebhook
+	}
+
+	for _, webhookSettings := range webhooksSettings {
+		githubWebhook, ok := findWebhook(deleteWebhooksMap, webhookSettings)
+		webhookSettings = webhookSettings.withServerDefaults(githubWebhook)
+
+		if !ok {
+			changes = append(changes, newChange(ResourceWebhooks, webhookSettings.URL, ActionCreate, webhook{}, webhookSettings))
+		} else {
+			delete(deleteWebhooksMap, githubWebhook.ID)
+
+			webhookSettings.ID = githubWebhook.ID
+			githubWebhook.Secret = webhookSettings.Secret
+
+			if !diff.Equal(githubWebhook, webhookSettings) {
+				webhooksToUpdate = append(webhooksToUpdate, newChange(ResourceWebhooks, webhookSettings.URL, ActionUpdate, githubWebhook, webhookSettings))
+			}
+		}
+	}
+
+	for _, webhookToDeleteID := range sortedKeys(deleteWebhooksMap) {
+		webhookToDelete := deleteWebhooksMap[webhookToDeleteID]
+		changes = append(changes, newChange(ResourceWebhooks, webhookToDelete.URL, ActionDelete, webhookToDelete, webhook{}))
+	}
+
+	return append(changes, webhooksToUpdate...)
+}
+
+// planTopics compares the live topics with the desired topics, sorted and without duplicates
+func planTopics(disabled bool, githubTopics, topics []string) []Change {
+	if disabled {
+		log.Print("[INFO] Skipping disabled repository topics\n")
+		return nil
+	}
+
+	// The live topics are sorted on a copy to leave the live settings untouched
+	githubTopics = emptyToNil(append([]string{}, githubTopics...))
+	sort.Strings(githubTopics)
+
+	if diff.Equal(githubTopics, topics) {
+		return nil
+	}
+
+	return []Change{{
+		Resource: ResourceTopics,
+		Action:   ActionUpdate,
+		Fields:   []FieldChange{{Field: "topics", Before: githubTopics, After: topics}},
+		current:  githubTopics,
+		desired:  topics,
+	}}
+}
+
+// findWebhook returns the github webhook matching the webhook settings by url or by id, the webhooks matched by name
+// are matched by the id resolved from the state store (see resolveWebhookNames)
+// Among live webhooks sharing the url the oldest one (lowest id) is matched, the others are planned for deletion
+func findWebhook(githubWebhooks map[int64]webhook, webhookSettings webhook) (webhook, bool) {
+	if webhookSettings.MatchBy == matchByID || webhookSettings.MatchBy == matchByName {
+		githubWebhook, ok := githubWebhooks[webhookSettings.ID]
+		return githubWebhook, ok
+	}
+
+	for _, id := range sortedKeys(githubWebhooks) {
+		if githubWebhook := githubWebhooks[id]; githubWebhook.URL == webhookSettings.URL {
+			return githubWebhook, true
+		}
+	}
+
+	return webhook{}, false
+}
+
+func newChange(resource, name string, action Action, current, desired interface{}) Change {
+	reason, ticket := rationale(desired)
+
+	return Change{
+		Resource:   resource,
+		Name:       name,
+		Action:     action,
+		Fields:     fieldChanges(diff.Compare(current, desired)),
+		ReportOnly: reportOnly(desired),
+		Reason:     reason,
+		Ticket:     ticket,
+		current:    current,
+		desired:    desired,
+	}
+}
+
+// reportOnly returns true when the desired resource is only reported, a resource without enforcement is enforced
+func reportOnly(desired interface{}) bool {
+	value := reflect.ValueOf(desired)
+
+	if value.Kind() != reflect.Struct {
+		return false
+	}
+
+	field := value.FieldByName("Enforcement")
+
+	return field.IsValid() && enforcement(field.String()) == enforcementReport
+}
+
+// rationale returns the reason and the ticket of the desired resource, deleted resources have none
+func rationale(desired interface{}) (string, string) {
+	value := reflect.ValueOf(desired)
+
+	if value.Kind() != reflect.Struct {
+		return "", ""
+	}
+
+	reason, ticket := value.FieldByName("Reason"), value.FieldByName("Ticket")
+
+	if !reason.IsValid() || !ticket.IsValid() {
+		return "", ""
+	}
+
+	return reason.String(), ticket.String()
+}
+
+// setRationale gives the reason and the ticket of the settings to the changes whose resource has none (ex: deletions and topics)
+func (plan *Plan) setRationale(settings *Settings) {
+	for i, change := range plan.Changes {
+		if change.Reason == "" && change.Ticket == "" {
+			plan.Changes[i].Reason, plan.Changes[i].Ticket = settings.Reason, settings.Ticket
+		}
+	}
+}
+
+// Rationale describes why the change is made with its ticket and reason (ex: OPS-42: rotate the deploy key), it is empty without reason nor ticket
+func (change Change) Rationale() string {
+	switch {
+	case change.Ticket != "" && change.Reason != "":
+		return change.Ticket + ": " + change.Reason
+	case change.Ticket != "":
+		return change.Ticket
+	default:
+		return change.Reason
+	}
+}
+
+// fieldChanges converts the differences to field changes, the values of sensitive fields are masked
+func fieldChanges(differences []diff.Difference) []FieldChange {
+	fields := make([]FieldChange, 0, len(differences))
+
+	for _, difference := range differences {
+		fieldChange := FieldChange{Field: difference.Path, Before: difference.Old, After: difference.New}
+
+		if difference.Sensitive {
+			fieldChange.Before, fieldChange.After = sensitiveValue, sensitiveValue
+		}
+
+		fields = append(fields, fieldChange)
+	}
+
+	return fields
+}
+
+// target is the full name (owner/name) of the repository of the plan, or the organization of a plan without name
+func (plan *Plan) target() string {
+	if plan.Name == "" {
+		return plan.Owner
+	}
+
+	return plan.Owner + "/" + plan.Name
+}
+
+// String renders the plan as a readable changelog
+func (plan *Plan) String() string {
+	builder := &strings.Builder{}
+	target := plan.target()
+
+	if plan.Empty() {
+		fmt.Fprintf(builder, "%s: no changes\n", target)
+		return builder.String()
+	}
+
+	fmt.Fprintf(builder, "%s: %d changes\n", target, len(plan.Changes))
+
+	if plan.ManagedBy != "" {
+		fmt.Fprintf(builder, "  ! managed by %s, apply requires force\n", plan.ManagedBy)
+	}
+
+	for _, warning := range plan.Warnings {
+		fmt.Fprintf(builder, "  ! %s\n", warning)
+	}
+
+	for _, change := range plan.BySeverity() {
+		header := strings.TrimSpace(fmt.Sprintf("%s %s %s", actionSymbols[change.Action], change.Resource, change.Name))
+
+		if change.ReportOnly {
+			header += " (report only)"
+		}
+
+		if len(change.Impacts) != 0 {
+			header += " [" + change.ImpactsString() + "]"
+		}
+
+		fmt.Fprintf(builder, "  %s\n", header)
+
+		if rationale := change.Rationale(); rationale != "" {
+			fmt.Fprintf(builder, "      # %s\n", rationale)
+		}
+
+		for _, line := range change.FieldLines() {
+			fmt.Fprintf(builder, "      %s\n", line)
+		}
+	}
+
+	return builder.String()
+}
+
+// FieldLines describes each field of the change with the value created or deleted, or the values before and after an update
+func (change Change) FieldLines() []string {
+	lines := make([]string, 0, len(change.Fields))
+
+	for _, field := range change.Fields {
+		switch change.Action {
+		case ActionCreate:
+			lines = append(lines, fmt.Sprintf("%s: %s", field.Field, formatValue(field.After)))
+		case ActionDelete:
+			lines = append(lines, fmt.Sprintf("%s: %s", field.Field, formatValue(field.Before)))
+		default:
+			lines = append(lines, fmt.Sprintf("%s: %s -> %s", field.Field, formatValue(field.Before), formatValue(field.After)))
+		}
+	}
+
+	return lines
+}
+
+// nolint:gochecknoglobals
+var actionSymbols = map[Action]string{
+	ActionCreate: "+",
+	ActionUpdate: "~",
+	ActionDelete: "-",
+}
+
+func formatValue(value interface{}) string {
+	switch typed := value.(type) {
+	case string:
+		if typed == sensitiveValue {
+			return typed
+		}
+
+		return fmt.Sprintf("%q", typed)
+	case contentType, matchBy, permission, rulesetMode:
+		return fmt.Sprintf("%q", typed)
+	case *restrictions:
+		if typed == nil {
+			return "unrestricted"
+		}
+
+		return fmt.Sprintf("%+v", *typed)
+	default:
+		return fmt.Sprintf("%v", typed)
+	}
+}
+
+// sortedKeys returns the keys of a map in order, ranging over the map would plan its deletions in a random order
+func sortedKeys[K cmp.Ordered, T any](values map[K]T) []K {
+	keys := make([]K, 0, len(values))
+
+	for key := range values {
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+	return keys
+}
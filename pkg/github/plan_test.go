@@ -0,0 +1,123 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// syntheticSettings returns live settings and settings of a repository with labels, protected branches and webhooks,
+// every fourth resource of the settings differs from the live one
+func syntheticSettings(labels int) (*Settings, *Settings) {
+	githubSettings := &Settings{Repository: repository{Owner: "acme", Name: "api", DefaultBranch: "main"}}
+	settings := &Settings{Repository: githubSettings.Repository}
+
+	for i := 0; i < labels; i++ {
+		githubLabel := label{Name: fmt.Sprintf("label-%03d", i), Color: "ededed"}
+		labelSettings := githubLabel
+
+		if i%4 == 0 {
+			labelSettings.Color = "d73a4a"
+		}
+
+		githubSettings.Labels = append(githubSettings.Labels, githubLabel)
+		settings.Labels = append(settings.Labels, labelSettings)
+	}
+
+	for i := 0; i < labels/10; i++ {
+		githubBranch := branch{Name: fmt.Sprintf("release/%d", i), Protection: protection{Enabled: true, EnforceAdmins: true}}
+		branchSettings := githubBranch
+
+		if i%4 == 0 {
+			branchSettings.Protection.RequiredLinearHistory = true
+		}
+
+		githubSettings.Branches = append(githubSettings.Branches, githubBranch)
+		settings.Branches = append(settings.Branches, branchSettings)
+
+		webhookSettings := webhook{URL: fmt.Sprintf("https://hooks.acme.dev/%d", i), ContentType: "json", Events: []string{"push"}}
+		githubWebhook := webhookSettings
+		githubWebhook.ID = int64(i + 1)
+		githubSettings.Webhooks = append(githubSettings.Webhooks, githubWebhook)
+		settings.Webhooks = append(settings.Webhooks, webhookSettings)
+	}
+
+	return githubSettings, settings
+}
+
+func TestComputePlanOfSyntheticSettings(t *testing.T) {
+	githubSettings, settings := syntheticSettings(100)
+	plan := computePlan(githubSettings, settings)
+
+	if len(plan.Changes) != 25+3 {
+		t.Errorf("Plan has %d changes, want 28:\n%s", len(plan.Changes), plan)
+	}
+}
+
+// BenchmarkComputePlan plans a repository of 100 labels, 10 protected branches and 10 webhooks
+func BenchmarkComputePlan(b *testing.B) {
+	githubSettings, settings := syntheticSettings(100)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		computePlan(githubSettings, settings)
+	}
+}
+
+// BenchmarkPlanAll plans an organization of 1000 repositories of 100 labels each against the fake server
+func BenchmarkPlanAll(b *testing.B) {
+	server, client := newTestClient(b)
+	allSettings := syntheticOrg(server, "acme", 1000, 100)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_, err := client.PlanAll(context.Background(), allSettings, 8)
+
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestFindWebhookMatchesTheOldestDuplicate(t *testing.T) {
+	githubWebhooks := map[int64]webhook{}
+
+	for id := int64(1); id <= 20; id++ {
+		githubWebhooks[id] = webhook{ID: id, URL: "https://hooks.acme.dev/github"}
+	}
+
+	for i := 0; i < 10; i++ {
+		githubWebhook, ok := findWebhook(githubWebhooks, webhook{URL: "https://hooks.acme.dev/github"})
+
+		if !ok || githubWebhook.ID != 1 {
+			t.Fatalf("Matched webhook %d, want 1", githubWebhook.ID)
+		}
+	}
+}
+
+func TestDesiredTopicsLeavesSettingsUntouched(t *testing.T) {
+	topics := make([]string, 2, 10)
+	copy(topics, []string{"web", "api"})
+	settings := &Settings{Topics: topics, Annotations: map[string]string{"owner": "platform", "team": "web"}}
+
+	desired := desiredTopics(settings)
+	// The annotations topics must not be appended in the spare capacity of the settings topics
+	settings.Topics = append(settings.Topics, "extra")
+
+	if strings.Join(desired, ",") != "api,owner-platform,team-web,web" {
+		t.Errorf("Desired topics are %v, want [api owner-platform team-web web]", desired)
+	}
+
+	if strings.Join(topics, ",") != "web,api" {
+		t.Errorf("Settings topics were changed to %v", topics)
+	}
+}
+
+func TestDesiredTopicsRemovesDuplicates(t *testing.T) {
+	settings := &Settings{Topics: []string{"owner-platform", "api"}, Annotations: map[string]string{"owner": "Platform"}}
+
+	if desired := desiredTopics(settings); strings.Join(desired, ",") != "api,owner-platform" {
+		t.Errorf("Desired topics are %v, want [api owner-platform]", desired)
+	}
+}
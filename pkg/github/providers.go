@@ -0,0 +1,221 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+)
+
+// providerTimeout is the maximum time spent getting a token from an oidc exchange or a command
+const providerTimeout = 30 * time.Second
+
+// brokerToken is the token returned by an oidc exchange or a command, the same fields as a github installation token
+type brokerToken struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (token brokerToken) oauth2() (*oauth2.Token, error) {
+	if token.Token == "" {
+		return nil, errors.New("The token is empty")
+	}
+
+	return &oauth2.Token{AccessToken: token.Token, Expiry: token.ExpiresAt}, nil
+}
+
+// oidcProvider exchanges an oidc id token of the workload for a github token
+type oidcProvider struct {
+	exchangeURL string
+	audience    string
+	tokenFile   string
+	client      *http.Client
+}
+
+// NewOIDCProvider exchanges an oidc id token for a github token at an exchange url (ex: a token broker trusting the oidc issuer)
+// The id token is read from a file (ex: a projected kubernetes service account token) or requested from github actions when the file is empty,
+// it is posted as a bearer token and the exchange answers a json object with the token and its expiry ({"token": "...", "expires_at": "2006-01-02T15:04:05Z"})
+func NewOIDCProvider(exchangeURL, audience, tokenFile string) AuthProvider {
+	return &oidcProvider{exchangeURL: exchangeURL, audience: audience, tokenFile: tokenFile, client: &http.Client{Timeout: providerTimeout}}
+}
+
+func (provider *oidcProvider) Token(ctx context.Context) (*oauth2.Token, error) {
+	idToken, err := provider.idToken(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, provider.exchangeURL, nil)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Error creating oidc exchange request")
+	}
+
+	request.Header.Set("Authorization", "Bearer "+idToken)
+	request.Header.Set("Accept", "application/json")
+
+	token := brokerToken{}
+	err = provider.do(request, &token)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error exchanging the oidc token at %s", provider.exchangeURL)
+	}
+
+	oauthToken, err := token.oauth2()
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error exchanging the oidc token at %s", provider.exchangeURL)
+	}
+
+	return oauthToken, nil
+}
+
+// idToken reads the oidc id token of the workload, github actions issues it to jobs with the id-token: write permission
+func (provider *oidcProvider) idToken(ctx context.Context) (string, error) {
+	if provider.tokenFile != "" {
+		content, err := ioutil.ReadFile(provider.tokenFile)
+
+		if err != nil {
+			return "", errors.Wrapf(err, "Error reading oidc token %s", provider.tokenFile)
+		}
+
+		return strings.TrimSpace(string(content)), nil
+	}
+
+	requestURL, requestToken := os.Getenv("ACTIONS_ID_TOKEN_REQUEST_URL"), os.Getenv("ACTIONS_ID_TOKEN_REQUEST_TOKEN")
+
+	if requestURL == "" || requestToken == "" {
+		return "", errors.New("No oidc token available, set an oidc token file or run in github actions with the id-token: write permission")
+	}
+
+	if provider.audience != "" {
+		requestURL += "&audience=" + url.QueryEscape(provider.audience)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, nil)
+
+	if err != nil {
+		return "", errors.Wrap(err, "Error creating oidc token request")
+	}
+
+	request.Header.Set("Authorization", "Bearer "+requestToken)
+
+	token := struct {
+		Value string `json:"value"`
+	}{}
+
+	err = provider.do(request, &token)
+
+	if err != nil {
+		return "", errors.Wrap(err, "Error requesting the oidc token of github actions")
+	}
+
+	return token.Value, nil
+}
+
+// do sends a request and decodes its json answer
+func (provider *oidcProvider) do(request *http.Request, value interface{}) error {
+	response, err := provider.client.Do(request)
+
+	if err != nil {
+		return err
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode >= http.StatusMultipleChoices {
+		return errors.Errorf("The server answered %s", response.Status)
+	}
+
+	return json.NewDecoder(response.Body).Decode(value)
+}
+
+// tokenFileProvider reads a token from a file
+type tokenFileProvider struct {
+	path string
+}
+
+// NewTokenFileProvider authenticates with the token held by a file (ex: a personal access token mounted by a secret manager),
+// the file is read once and again when the credentials are reloaded
+func NewTokenFileProvider(path string) AuthProvider {
+	return tokenFileProvider{path: path}
+}
+
+func (provider tokenFileProvider) Token(ctx context.Context) (*oauth2.Token, error) {
+	content, err := ioutil.ReadFile(provider.path)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Error reading the token file")
+	}
+
+	token := strings.TrimSpace(string(content))
+
+	if token == "" {
+		return nil, errors.Errorf("Token file %s is empty", provider.path)
+	}
+
+	return &oauth2.Token{AccessToken: token}, nil
+}
+
+// commandProvider runs an external command printing a github token
+type commandProvider struct {
+	name string
+	args []string
+}
+
+// NewCommandProvider gets the tokens from an external command (ex: the cli of a credential broker)
+// The command prints the token alone or as a json object with its expiry ({"token": "...", "expires_at": "2006-01-02T15:04:05Z"}),
+// a token without expiry is used for the whole run
+func NewCommandProvider(name string, args ...string) AuthProvider {
+	return &commandProvider{name: name, args: args}
+}
+
+func (provider *commandProvider) Token(ctx context.Context) (*oauth2.Token, error) {
+	ctx, cancel := context.WithTimeout(ctx, providerTimeout)
+	defer cancel()
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	command := exec.CommandContext(ctx, provider.name, provider.args...)
+	command.Stdout = stdout
+	command.Stderr = stderr
+
+	err := command.Run()
+
+	if message := strings.TrimSpace(stderr.String()); err != nil && message != "" {
+		return nil, errors.Wrapf(err, "Error running the auth command %s (%s)", provider.name, message)
+	}
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error running the auth command %s", provider.name)
+	}
+
+	output := strings.TrimSpace(stdout.String())
+	token := brokerToken{Token: output}
+
+	if strings.HasPrefix(output, "{") {
+		token = brokerToken{}
+		err = json.Unmarshal([]byte(output), &token)
+
+		if err != nil {
+			return nil, errors.Wrapf(err, "Error reading the token printed by the auth command %s", provider.name)
+		}
+	}
+
+	oauthToken, err := token.oauth2()
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error reading the token printed by the auth command %s", provider.name)
+	}
+
+	return oauthToken, nil
+}
@@ -0,0 +1,116 @@
+package github
+
+import (
+	"log"
+	"sort"
+
+	"github.com/michaelmass/github-settings/pkg/diff"
+)
+
+// Prune selects per section whether the live resources missing from the settings are deleted
+// An unset section follows the client default (see WithPrune)
+type Prune struct {
+	Labels        *bool `yaml:",omitempty"`
+	Branches      *bool `yaml:",omitempty"`
+	Webhooks      *bool `yaml:",omitempty"`
+	Topics        *bool `yaml:",omitempty"`
+	Collaborators *bool `yaml:",omitempty"`
+	Teams         *bool `yaml:",omitempty"`
+	Secrets       *bool `yaml:",omitempty"`
+	Variables     *bool `yaml:",omitempty"`
+	// DependabotSecrets prunes the dependabot secrets
+	DependabotSecrets *bool `yaml:",omitempty"`
+	// Environments also prunes the secrets and variables of the environments
+	Environments *bool `yaml:",omitempty"`
+	Rulesets     *bool `yaml:",omitempty"`
+}
+
+// enabled returns true when the resources of the section missing from the settings are deleted
+func (prune Prune) enabled(resource string, fallback bool) bool {
+	sections := map[string]*bool{
+		ResourceLabels:               prune.Labels,
+		ResourceBranches:             prune.Branches,
+		ResourceWebhooks:             prune.Webhooks,
+		ResourceTopics:               prune.Topics,
+		ResourceCollaborators:        prune.Collaborators,
+		ResourceTeams:                prune.Teams,
+		ResourceSecrets:              prune.Secrets,
+		ResourceVariables:            prune.Variables,
+		ResourceDependabotSecrets:    prune.DependabotSecrets,
+		ResourceEnvironments:         prune.Environments,
+		ResourceEnvironmentSecrets:   prune.Environments,
+		ResourceEnvironmentVariables: prune.Environments,
+		ResourceRulesets:             prune.Rulesets,
+	}
+
+	if section := sections[resource]; section != nil {
+		return *section
+	}
+
+	return fallback
+}
+
+// pruneChanges drops the deletions of the sections that are not pruned
+// Topics are replaced as a whole, the live topics missing from the settings are kept instead
+func pruneChanges(changes []Change, prune Prune, fallback bool) []Change {
+	pruned := []Change{}
+
+	for _, change := range changes {
+		if prune.enabled(change.Resource, fallback) {
+			pruned = append(pruned, change)
+			continue
+		}
+
+		switch {
+		case change.Action == ActionDelete:
+			log.Printf("[INFO] Keeping %s %s missing from the settings, pruning is disabled\n", change.Resource, change.Name)
+		case change.Resource == ResourceTopics:
+			pruned = append(pruned, keepTopics(change)...)
+		default:
+			pruned = append(pruned, change)
+		}
+	}
+
+	return pruned
+}
+
+// keepTopics adds the live topics to the desired topics of a topics change
+func keepTopics(change Change) []Change {
+	githubTopics, _ := change.current.([]string)
+	topicsSettings, _ := change.desired.([]string)
+	topics := append([]string{}, topicsSettings...)
+
+	for _, githubTopic := range githubTopics {
+		found := false
+
+		for _, topic := range topicsSettings {
+			found = found || topic == githubTopic
+		}
+
+		if !found {
+			log.Printf("[INFO] Keeping topic %s missing from the settings, pruning is disabled\n", githubTopic)
+			topics = append(topics, githubTopic)
+		}
+	}
+
+	sort.Strings(topics)
+
+	if diff.Equal(githubTopics, topics) {
+		return nil
+	}
+
+	return planTopics(false, githubTopics, topics)
+}
+
+// Deletions returns the changes deleting a live resource
+func (plan *Plan) Deletions() []Change {
+	deletions := []Change{}
+
+	for _, change := range plan.Changes {
+		if change.Action == ActionDelete {
+			deletions = append(deletions, change)
+		}
+	}
+
+	return deletions
+}
@@ -0,0 +1,130 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v75/github"
+)
+
+// DefaultMaxRetries is the number of times a rate limited request is retried
+const DefaultMaxRetries = 5
+
+// Backoff between retries of a rate limited request that does not say when to retry
+const (
+	retryBackoff    = time.Second
+	maxRetryBackoff = time.Minute
+)
+
+// retryTransport retries the requests rejected by the primary or secondary rate limits of github
+// It waits for the time github asks for (Retry-After, X-RateLimit-Reset) or backs off exponentially
+type retryTransport struct {
+	base       http.RoundTripper
+	maxRetries int
+	clock      Clock
+}
+
+func (transport *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		attemptReq := req
+
+		// The body was consumed by the previous attempt
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+
+			if err != nil {
+				return nil, err
+			}
+
+			attemptReq = req.Clone(req.Context())
+			attemptReq.Body = body
+		}
+
+		response, err := transport.base.RoundTrip(attemptReq)
+
+		if err != nil || attempt >= transport.maxRetries || (req.Body != nil && req.GetBody == nil) {
+			return response, err
+		}
+
+		wait, limited := rateLimitWait(response, attempt, transport.clock.Now())
+
+		if !limited {
+			return response, nil
+		}
+
+		_ = response.Body.Close()
+
+		log.Printf("[WARN] Rate limited by github, retrying %s %s in %s\n", req.Method, req.URL.Path, wait)
+
+		err = sleep(req.Context(), transport.clock, wait)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// rateLimitWait returns the time to wait before retrying a response rejected by a rate limit
+func rateLimitWait(response *http.Response, attempt int, now time.Time) (time.Duration, bool) {
+	if response.StatusCode != http.StatusForbidden && response.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+
+	if retryAfter, err := strconv.Atoi(response.Header.Get("Retry-After")); err == nil {
+		return time.Duration(retryAfter) * time.Second, true
+	}
+
+	// The primary rate limit is exhausted until its reset
+	if response.Header.Get("X-RateLimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(response.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			return time.Unix(reset, 0).Sub(now) + time.Second, true
+		}
+	}
+
+	if response.StatusCode == http.StatusForbidden && !isSecondaryRateLimit(response) {
+		return 0, false
+	}
+
+	backoff := retryBackoff << uint(attempt)
+
+	if backoff > maxRetryBackoff {
+		backoff = maxRetryBackoff
+	}
+
+	return backoff, true
+}
+
+// isSecondaryRateLimit reads the body of a forbidden response to tell a secondary rate limit from a permission error
+// The body is restored so the response can still be decoded
+func isSecondaryRateLimit(response *http.Response) bool {
+	body, err := ioutil.ReadAll(response.Body)
+	_ = response.Body.Close()
+	response.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	return err == nil && strings.Contains(strings.ToLower(string(body)), "secondary rate limit")
+}
+
+func sleep(ctx context.Context, clock Clock, duration time.Duration) error {
+	if duration <= 0 {
+		return nil
+	}
+
+	select {
+	case <-clock.After(duration):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// withRetries lets the requests of the context reach the retry transport when the primary rate limit is exhausted
+// go-github would otherwise fail them without sending them until the rate limit resets
+func withRetries(ctx context.Context) context.Context {
+	return context.WithValue(ctx, github.BypassRateLimitCheck, true)
+}
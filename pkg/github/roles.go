@@ -0,0 +1,152 @@
+package github
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// roleCache keeps the custom repository roles of each org for the lifetime of the client, the repositories of an org share them
+type roleCache struct {
+	mutex sync.Mutex
+	roles map[string][]string
+}
+
+func newRoleCache() *roleCache {
+	return &roleCache{roles: map[string][]string{}}
+}
+
+// customRoles returns the names of the custom repository roles of an org
+// Orgs without custom roles, or whose roles the token can't read, have none
+func (client *Client) customRoles(ctx context.Context, org string) ([]string, error) {
+	client.roles.mutex.Lock()
+	defer client.roles.mutex.Unlock()
+
+	if roles, ok := client.roles.roles[org]; ok {
+		return roles, nil
+	}
+
+	githubRoles, _, err := client.github.Organizations.ListCustomRepoRoles(ctx, org)
+
+	if isNotFound(err) || isForbidden(err) {
+		client.roles.roles[org] = nil
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Error while listing custom repository roles")
+	}
+
+	roles := []string{}
+
+	for _, role := range githubRoles.CustomRepoRoles {
+		roles = append(roles, role.GetName())
+	}
+
+	sort.Strings(roles)
+	client.roles.roles[org] = roles
+
+	return roles, nil
+}
+
+// resolveRoles replaces the custom roles granted to collaborators and teams by the role names of the org
+// Role names are case insensitive, a permission matching no built-in level nor custom role fails the plan
+func (client *Client) resolveRoles(ctx context.Context, settings *Settings) (*Settings, error) {
+	custom := false
+
+	for _, collaboratorSettings := range settings.Collaborators {
+		custom = custom || (!settings.Disable.Collaborators && !builtinPermission(collaboratorSettings.Permission))
+	}
+
+	for _, teamSettings := range settings.Teams {
+		custom = custom || (!settings.Disable.Teams && !builtinPermission(teamSettings.Permission))
+	}
+
+	if !custom {
+		return settings, nil
+	}
+
+	roles, err := client.customRoles(ctx, settings.Repository.Owner)
+
+	if err != nil {
+		return nil, err
+	}
+
+	resolved := *settings
+
+	// A missing section stays unmanaged
+	if settings.Collaborators != nil {
+		resolved.Collaborators = make([]collaborator, 0, len(settings.Collaborators))
+	}
+
+	if settings.Teams != nil {
+		resolved.Teams = make([]team, 0, len(settings.Teams))
+	}
+
+	for _, collaboratorSettings := range settings.Collaborators {
+		collaboratorSettings.Permission, err = resolveRole(collaboratorSettings.Permission, roles)
+
+		if err != nil {
+			return nil, errors.Wrapf(err, "Error resolving the permission of collaborator %s", collaboratorSettings.Username)
+		}
+
+		resolved.Collaborators = append(resolved.Collaborators, collaboratorSettings)
+	}
+
+	for _, teamSettings := range settings.Teams {
+		teamSettings.Permission, err = resolveRole(teamSettings.Permission, roles)
+
+		if err != nil {
+			return nil, errors.Wrapf(err, "Error resolving the permission of team %s", teamSettings.Slug)
+		}
+
+		resolved.Teams = append(resolved.Teams, teamSettings)
+	}
+
+	return &resolved, nil
+}
+
+// resolveRole returns the built-in permission or the name of the custom role matching a permission
+func resolveRole(value permission, roles []string) (permission, error) {
+	if builtinPermission(value) {
+		return value, nil
+	}
+
+	allowed := []string{string(permissionPull), string(permissionTriage), string(permissionPush), string(permissionMaintain), string(permissionAdmin)}
+
+	for _, role := range roles {
+		if strings.EqualFold(role, string(value)) {
+			return permission(role), nil
+		}
+	}
+
+	allowed = append(allowed, roles...)
+
+	return "", errors.Errorf("Unknown permission %q, did you mean %q? (built-in permissions and custom repository roles of the org: %s)", value, closest(string(value), allowed), strings.Join(allowed, ", "))
+}
+
+// getTeamRoles reads the custom roles of the teams, github lists the teams of a repository with the base permission of their role
+func (client *Client) getTeamRoles(ctx context.Context, owner, name string, teams []team) ([]team, error) {
+	roles, err := client.customRoles(ctx, owner)
+
+	if err != nil || len(roles) == 0 {
+		return teams, err
+	}
+
+	for i, githubTeam := range teams {
+		repository, _, err := client.github.Teams.IsTeamRepoBySlug(ctx, owner, githubTeam.Slug, owner, name)
+
+		if err != nil {
+			return nil, errors.Wrapf(err, "Error while getting the role of team %s", githubTeam.Slug)
+		}
+
+		if role := repository.GetRoleName(); role != "" {
+			teams[i].Permission = toPermission(role)
+		}
+	}
+
+	return teams, nil
+}
@@ -0,0 +1,518 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/google/go-github/v75/github"
+	"github.com/michaelmass/github-settings/pkg/diff"
+	"github.com/pkg/errors"
+)
+
+// ResourceRulesets is the resource kind of the repository rulesets
+const ResourceRulesets = "rulesets"
+
+// Special branch patterns of the rulesets
+const (
+	branchPatternAll     = "~ALL"
+	branchPatternDefault = "~DEFAULT_BRANCH"
+	branchRefPrefix      = "refs/heads/"
+)
+
+// ruleset is a repository ruleset, unlike branch protection it also protects the branches created after it
+type ruleset struct {
+	Name string
+	// Branches are the patterns of the branches the rules apply to (ex: main, release/**, ~DEFAULT_BRANCH, ~ALL)
+	Branches []string
+	// ExcludeBranches are the patterns of the branches left out
+	ExcludeBranches []string `yaml:",omitempty"`
+	// Mode is active (the default), evaluate or disabled
+	Mode  rulesetMode `yaml:",omitempty"`
+	Rules rulesetRules
+	// BypassActors are the apps, teams and organization admins allowed to bypass the rules
+	BypassActors []bypassActor `yaml:",omitempty"`
+	annotations  `yaml:",inline" diff:"-"`
+
+	id int64
+}
+
+type rulesetRules struct {
+	// Creation, Update and Deletion restrict who can create, push to or delete the branches
+	Creation              bool
+	Update                bool
+	Deletion              bool
+	NonFastForward        bool
+	RequiredLinearHistory bool
+	RequiredSignatures    bool
+	PullRequest           rulesetPullRequest
+	// RequiredStatusChecks is enforced when it has contexts
+	RequiredStatusChecks requiredStatusChecks
+}
+
+type rulesetPullRequest struct {
+	// Required requires the changes to be merged through a pull request
+	Required                       bool
+	RequiredApprovingReviewCount   int
+	DismissStaleReviewsOnPush      bool
+	RequireCodeOwnerReview         bool
+	RequireLastPushApproval        bool
+	RequiredReviewThreadResolution bool
+}
+
+// withServerDefaults fills the mode github defaults to so both sides of a comparison match
+func (rulesetSettings ruleset) withServerDefaults() ruleset {
+	if rulesetSettings.Mode == "" {
+		rulesetSettings.Mode = rulesetModeActive
+	}
+
+	rulesetSettings.Branches = emptyToNil(rulesetSettings.Branches)
+	rulesetSettings.ExcludeBranches = emptyToNil(rulesetSettings.ExcludeBranches)
+	rulesetSettings.Rules.RequiredStatusChecks.Contexts = emptyToNil(rulesetSettings.Rules.RequiredStatusChecks.Contexts)
+	rulesetSettings.BypassActors = sortedBypassActors(rulesetSettings.BypassActors)
+
+	return rulesetSettings
+}
+
+// covers returns true when the ruleset is active and its patterns match the branch
+func (rulesetSettings ruleset) covers(branchName, defaultBranch string) bool {
+	if rulesetSettings.withServerDefaults().Mode != rulesetModeActive {
+		return false
+	}
+
+	return matchBranch(rulesetSettings.Branches, branchName, defaultBranch) && !matchBranch(rulesetSettings.ExcludeBranches, branchName, defaultBranch)
+}
+
+// matchBranch returns true when a pattern matches the branch, ** matches across slashes and * within a path segment
+func matchBranch(patterns []string, branchName, defaultBranch string) bool {
+	for _, pattern := range patterns {
+		switch pattern {
+		case branchPatternAll:
+			return true
+		case branchPatternDefault:
+			if branchName == defaultBranch {
+				return true
+			}
+
+			continue
+		}
+
+		if branchPattern(pattern).MatchString(branchName) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func branchPattern(pattern string) *regexp.Regexp {
+	builder := &strings.Builder{}
+	builder.WriteString("^")
+
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case strings.HasPrefix(pattern[i:], "**"):
+			builder.WriteString(".*")
+			i++
+		case pattern[i] == '*':
+			builder.WriteString("[^/]*")
+		case pattern[i] == '?':
+			builder.WriteString("[^/]")
+		default:
+			builder.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+
+	builder.WriteString("$")
+
+	return regexp.MustCompile(builder.String())
+}
+
+// uncoveredBranches warns about the live branches matching a ruleset of the settings that github does not protect with it yet
+func uncoveredBranches(disabled bool, githubSettings, settings *Settings) []string {
+	if disabled {
+		return nil
+	}
+
+	warnings := []string{}
+	githubRulesets := map[string]ruleset{}
+
+	for _, githubRuleset := range githubSettings.Rulesets {
+		githubRulesets[githubRuleset.Name] = githubRuleset
+	}
+
+	for _, rulesetSettings := range settings.Rulesets {
+		githubRuleset, ok := githubRulesets[rulesetSettings.Name]
+
+		for _, githubBranch := range githubSettings.Branches {
+			if !rulesetSettings.covers(githubBranch.Name, githubSettings.Repository.DefaultBranch) {
+				continue
+			}
+
+			if !ok || !githubRuleset.covers(githubBranch.Name, githubSettings.Repository.DefaultBranch) {
+				warnings = append(warnings, fmt.Sprintf("branch %s matches ruleset %s but is not protected by it yet", githubBranch.Name, rulesetSettings.Name))
+			}
+		}
+	}
+
+	return warnings
+}
+
+// overlappingProtections warns about the branches protected by both a branch protection and a ruleset of the settings
+// github requires the rules of both, conflicting or redundant requirements are hard to understand when a merge is blocked
+func overlappingProtections(disabled bool, githubSettings, settings *Settings) []string {
+	if disabled || settings.Disable.Branches {
+		return nil
+	}
+
+	defaultBranch := settings.Repository.DefaultBranch
+
+	if defaultBranch == "" {
+		defaultBranch = githubSettings.Repository.DefaultBranch
+	}
+
+	warnings := []string{}
+
+	for _, branchSettings := range settings.Branches {
+		for _, rulesetSettings := range settings.Rulesets {
+			if !rulesetSettings.covers(branchSettings.Name, defaultBranch) {
+				continue
+			}
+
+			conflicts, redundancies := compareRequirements(branchSettings.Protection, rulesetSettings.Rules)
+
+			if len(conflicts) != 0 {
+				warnings = append(warnings, fmt.Sprintf("branch %s has conflicting requirements in its protection and ruleset %s, github enforces both: %s", branchSettings.Name, rulesetSettings.Name, strings.Join(conflicts, "; ")))
+			}
+
+			if len(redundancies) != 0 {
+				warnings = append(warnings, fmt.Sprintf("branch %s has the same requirements in its protection and ruleset %s, keep them in one place: %s", branchSettings.Name, rulesetSettings.Name, strings.Join(redundancies, "; ")))
+			}
+		}
+	}
+
+	return warnings
+}
+
+// compareRequirements returns the requirements of a branch protection that conflict with the rules of a ruleset and those they both require
+func compareRequirements(protectionSettings protection, rules rulesetRules) ([]string, []string) {
+	conflicts := []string{}
+	redundancies := []string{}
+
+	protectionContexts := sortedOrNil(protectionSettings.RequiredStatusChecks.Contexts)
+	rulesetContexts := sortedOrNil(rules.RequiredStatusChecks.Contexts)
+
+	switch {
+	case len(protectionContexts) == 0 || len(rulesetContexts) == 0:
+	case diff.Equal(protectionContexts, rulesetContexts):
+		redundancies = append(redundancies, fmt.Sprintf("status checks %v", protectionContexts))
+	default:
+		conflicts = append(conflicts, fmt.Sprintf("status checks %v and %v are all required", protectionContexts, rulesetContexts))
+	}
+
+	protectionReviews := protectionSettings.RequiredApprovingReviewCount.RequiredApprovingReviewCount
+	rulesetReviews := rules.PullRequest.RequiredApprovingReviewCount
+
+	switch {
+	case protectionReviews == 0 || !rules.PullRequest.Required || rulesetReviews == 0:
+	case protectionReviews == rulesetReviews:
+		redundancies = append(redundancies, fmt.Sprintf("%d approving reviews", protectionReviews))
+	default:
+		conflicts = append(conflicts, fmt.Sprintf("%d and %d approving reviews, the highest count applies", protectionReviews, rulesetReviews))
+	}
+
+	if protectionSettings.AllowForcePushes && rules.NonFastForward {
+		conflicts = append(conflicts, "force pushes allowed by the protection are blocked by the ruleset")
+	}
+
+	if protectionSettings.AllowDeletions && rules.Deletion {
+		conflicts = append(conflicts, "deletion allowed by the protection is blocked by the ruleset")
+	}
+
+	if protectionSettings.RequiredLinearHistory && rules.RequiredLinearHistory {
+		redundancies = append(redundancies, "linear history")
+	}
+
+	if protectionSettings.RequiredSignatures && rules.RequiredSignatures {
+		redundancies = append(redundancies, "signed commits")
+	}
+
+	return conflicts, redundancies
+}
+
+func (client *Client) getRulesets(ctx context.Context, owner, name string) ([]ruleset, error) {
+	supported, err := client.supports(ctx, FeatureRulesets)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !supported {
+		log.Printf("[WARN] Skipping rulesets of %s/%s, they are unsupported on your server\n", owner, name)
+		return nil, nil
+	}
+
+	githubRulesets, err := listAll(func(opts github.ListOptions) ([]*github.RepositoryRuleset, *github.Response, error) {
+		return client.github.Repositories.GetAllRulesets(ctx, owner, name, &github.RepositoryListRulesetsOptions{ListOptions: opts})
+	})
+
+	// Rulesets are not available on the private repositories of the free plans
+	if isForbidden(err) {
+		log.Printf("[WARN] Skipping rulesets of %s/%s, they are not available on its plan\n", owner, name)
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Error while listing rulesets")
+	}
+
+	rulesets := []ruleset{}
+
+	for _, githubRuleset := range githubRulesets {
+		// Rulesets of the organization are listed with the repository ones and tag rulesets are not managed
+		if githubRuleset.GetSourceType() != nil && *githubRuleset.SourceType != github.RulesetSourceTypeRepository {
+			continue
+		}
+
+		if githubRuleset.GetTarget() != nil && *githubRuleset.Target != github.RulesetTargetBranch {
+			continue
+		}
+
+		detailed, _, err := client.github.Repositories.GetRuleset(ctx, owner, name, githubRuleset.GetID(), false)
+
+		if err != nil {
+			return nil, errors.Wrapf(err, "Error while getting ruleset %s", githubRuleset.Name)
+		}
+
+		rulesets = append(rulesets, newRuleset(detailed))
+	}
+
+	return rulesets, nil
+}
+
+func newRuleset(githubRuleset *github.RepositoryRuleset) ruleset {
+	rulesetSettings := ruleset{
+		Name:         githubRuleset.Name,
+		Mode:         rulesetMode(githubRuleset.Enforcement),
+		BypassActors: newBypassActors(githubRuleset.BypassActors),
+		id:           githubRuleset.GetID(),
+	}
+
+	if refName := githubRuleset.GetConditions().GetRefName(); refName != nil {
+		rulesetSettings.Branches = emptyToNil(fromRefPatterns(refName.Include))
+		rulesetSettings.ExcludeBranches = emptyToNil(fromRefPatterns(refName.Exclude))
+	}
+
+	rules := githubRuleset.Rules
+
+	if rules == nil {
+		return rulesetSettings
+	}
+
+	rulesetSettings.Rules = rulesetRules{
+		Creation:              rules.Creation != nil,
+		Update:                rules.Update != nil,
+		Deletion:              rules.Deletion != nil,
+		NonFastForward:        rules.NonFastForward != nil,
+		RequiredLinearHistory: rules.RequiredLinearHistory != nil,
+		RequiredSignatures:    rules.RequiredSignatures != nil,
+	}
+
+	if pullRequest := rules.PullRequest; pullRequest != nil {
+		rulesetSettings.Rules.PullRequest = rulesetPullRequest{
+			Required:                       true,
+			RequiredApprovingReviewCount:   pullRequest.RequiredApprovingReviewCount,
+			DismissStaleReviewsOnPush:      pullRequest.DismissStaleReviewsOnPush,
+			RequireCodeOwnerReview:         pullRequest.RequireCodeOwnerReview,
+			RequireLastPushApproval:        pullRequest.RequireLastPushApproval,
+			RequiredReviewThreadResolution: pullRequest.RequiredReviewThreadResolution,
+		}
+	}
+
+	if statusChecks := rules.RequiredStatusChecks; statusChecks != nil {
+		rulesetSettings.Rules.RequiredStatusChecks.Strict = statusChecks.StrictRequiredStatusChecksPolicy
+
+		for _, check := range statusChecks.RequiredStatusChecks {
+			rulesetSettings.Rules.RequiredStatusChecks.Contexts = append(rulesetSettings.Rules.RequiredStatusChecks.Contexts, check.Context)
+		}
+	}
+
+	return rulesetSettings
+}
+
+// toGithub converts the ruleset to the payload of the github api
+func (rulesetSettings ruleset) toGithub() github.RepositoryRuleset {
+	rulesetSettings = rulesetSettings.withServerDefaults()
+	settingsRules := rulesetSettings.Rules
+	rules := &github.RepositoryRulesetRules{}
+
+	if settingsRules.Creation {
+		rules.Creation = &github.EmptyRuleParameters{}
+	}
+
+	if settingsRules.Update {
+		rules.Update = &github.UpdateRuleParameters{}
+	}
+
+	if settingsRules.Deletion {
+		rules.Deletion = &github.EmptyRuleParameters{}
+	}
+
+	if settingsRules.NonFastForward {
+		rules.NonFastForward = &github.EmptyRuleParameters{}
+	}
+
+	if settingsRules.RequiredLinearHistory {
+		rules.RequiredLinearHistory = &github.EmptyRuleParameters{}
+	}
+
+	if settingsRules.RequiredSignatures {
+		rules.RequiredSignatures = &github.EmptyRuleParameters{}
+	}
+
+	if pullRequest := settingsRules.PullRequest; pullRequest.Required {
+		rules.PullRequest = &github.PullRequestRuleParameters{
+			AllowedMergeMethods:            []github.PullRequestMergeMethod{github.PullRequestMergeMethodMerge, github.PullRequestMergeMethodSquash, github.PullRequestMergeMethodRebase},
+			RequiredApprovingReviewCount:   pullRequest.RequiredApprovingReviewCount,
+			DismissStaleReviewsOnPush:      pullRequest.DismissStaleReviewsOnPush,
+			RequireCodeOwnerReview:         pullRequest.RequireCodeOwnerReview,
+			RequireLastPushApproval:        pullRequest.RequireLastPushApproval,
+			RequiredReviewThreadResolution: pullRequest.RequiredReviewThreadResolution,
+		}
+	}
+
+	if statusChecks := settingsRules.RequiredStatusChecks; len(statusChecks.Contexts) != 0 {
+		rules.RequiredStatusChecks = &github.RequiredStatusChecksRuleParameters{StrictRequiredStatusChecksPolicy: statusChecks.Strict}
+
+		for _, check := range statusChecks.Contexts {
+			rules.RequiredStatusChecks.RequiredStatusChecks = append(rules.RequiredStatusChecks.RequiredStatusChecks, &github.RuleStatusCheck{Context: check})
+		}
+	}
+
+	target := github.RulesetTargetBranch
+
+	return github.RepositoryRuleset{
+		Name:        rulesetSettings.Name,
+		Target:      &target,
+		Enforcement: github.RulesetEnforcement(rulesetSettings.Mode),
+		Conditions: &github.RepositoryRulesetConditions{
+			RefName: &github.RepositoryRulesetRefConditionParameters{
+				Include: toRefPatterns(rulesetSettings.Branches),
+				Exclude: toRefPatterns(rulesetSettings.ExcludeBranches),
+			},
+		},
+		Rules:        rules,
+		BypassActors: toGithubBypassActors(rulesetSettings.BypassActors),
+	}
+}
+
+// toRefPatterns converts branch patterns to the ref patterns of the github api (ex: main becomes refs/heads/main)
+func toRefPatterns(patterns []string) []string {
+	refs := make([]string, 0, len(patterns))
+
+	for _, pattern := range patterns {
+		if strings.HasPrefix(pattern, "~") || strings.HasPrefix(pattern, "refs/") {
+			refs = append(refs, pattern)
+			continue
+		}
+
+		refs = append(refs, branchRefPrefix+pattern)
+	}
+
+	return refs
+}
+
+func fromRefPatterns(refs []string) []string {
+	patterns := make([]string, 0, len(refs))
+
+	for _, ref := range refs {
+		patterns = append(patterns, strings.TrimPrefix(ref, branchRefPrefix))
+	}
+
+	return patterns
+}
+
+func planRulesets(disabled bool, githubRulesets, rulesetsSettings []ruleset) []Change {
+	if disabled {
+		log.Print("[INFO] Skipping disabled repository rulesets\n")
+		return nil
+	}
+
+	// Without a rulesets section the rulesets are left alone, an empty list removes them all
+	if rulesetsSettings == nil {
+		log.Print("[INFO] Skipping unmanaged repository rulesets\n")
+		return nil
+	}
+
+	changes := []Change{}
+	rulesetsToUpdate := []Change{}
+	deleteRulesetsMap := map[string]ruleset{}
+
+	for _, githubRuleset := range githubRulesets {
+		deleteRulesetsMap[githubRuleset.Name] = githubRuleset
+	}
+
+	for _, rulesetSettings := range rulesetsSettings {
+		rulesetSettings = rulesetSettings.withServerDefaults()
+		githubRuleset, ok := deleteRulesetsMap[rulesetSettings.Name]
+
+		if !ok {
+			changes = append(changes, newChange(ResourceRulesets, rulesetSettings.Name, ActionCreate, ruleset{}, rulesetSettings))
+			continue
+		}
+
+		delete(deleteRulesetsMap, rulesetSettings.Name)
+		githubRuleset = githubRuleset.withServerDefaults()
+
+		if !diff.Equal(githubRuleset, rulesetSettings) {
+			rulesetsToUpdate = append(rulesetsToUpdate, newChange(ResourceRulesets, rulesetSettings.Name, ActionUpdate, githubRuleset, rulesetSettings))
+		}
+	}
+
+	for _, rulesetToDeleteName := range sortedKeys(deleteRulesetsMap) {
+		rulesetToDelete := deleteRulesetsMap[rulesetToDeleteName]
+		changes = append(changes, newChange(ResourceRulesets, rulesetToDeleteName, ActionDelete, rulesetToDelete, ruleset{}))
+	}
+
+	return append(changes, rulesetsToUpdate...)
+}
+
+func (client *Client) updateRuleset(ctx context.Context, report reporter, owner, name string, action Action, githubRuleset, rulesetSettings ruleset) error {
+	switch action {
+	case ActionDelete:
+		report.changed(ResourceRulesets, "Deleting ruleset %s\n", githubRuleset.Name)
+
+		_, err := client.github.Repositories.DeleteRuleset(ctx, owner, name, githubRuleset.id)
+
+		if err != nil {
+			return errors.Wrap(err, "Error deleting a ruleset\n")
+		}
+	case ActionCreate:
+		report.changed(ResourceRulesets, "Creating ruleset %s\n", rulesetSettings.Name)
+
+		_, _, err := client.github.Repositories.CreateRuleset(ctx, owner, name, rulesetSettings.toGithub())
+
+		if err != nil {
+			return errors.Wrap(err, "Error creating a ruleset\n")
+		}
+	case ActionUpdate:
+		report.changed(ResourceRulesets, "Updating ruleset %s\n", rulesetSettings.Name)
+
+		update := client.github.Repositories.UpdateRuleset
+
+		// UpdateRuleset omits an empty list of bypass actors and github keeps the current ones
+		if len(rulesetSettings.BypassActors) == 0 {
+			update = client.github.Repositories.UpdateRulesetNoBypassActor
+		}
+
+		_, _, err := update(ctx, owner, name, githubRuleset.id, rulesetSettings.toGithub())
+
+		if err != nil {
+			return errors.Wrap(err, "Error updating a ruleset\n")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,142 @@
+package github
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/michaelmass/github-settings/pkg/config"
+)
+
+// schemaVersion is the JSON Schema draft the schema follows
+const schemaVersion = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema returns the JSON Schema of the settings files, a single repository or many repositories
+// Editors use it to complete and check the files, see Validate for the checks it can't express
+func Schema() map[string]interface{} {
+	settings := schemaOf(reflect.TypeOf(Settings{}))
+	multi := schemaOf(reflect.TypeOf(multiSchema{}))
+
+	multiProperties := multi["properties"].(map[string]interface{})
+	multiProperties["defaults"] = map[string]interface{}{"$ref": "#/$defs/settings"}
+	multiProperties["repositories"] = map[string]interface{}{"type": "array", "items": map[string]interface{}{"$ref": "#/$defs/settings"}}
+
+	for _, definition := range []map[string]interface{}{settings, multi} {
+		properties := definition["properties"].(map[string]interface{})
+		properties[config.ExtendsKey] = directiveSchema()
+		properties[config.IncludeKey] = directiveSchema()
+	}
+
+	return map[string]interface{}{
+		"$schema": schemaVersion,
+		"title":   "github-settings",
+		"anyOf": []interface{}{
+			map[string]interface{}{"$ref": "#/$defs/settings"},
+			map[string]interface{}{"$ref": "#/$defs/multi"},
+		},
+		"$defs": map[string]interface{}{
+			"settings": settings,
+			"multi":    multi,
+		},
+	}
+}
+
+func schemaOf(typ reflect.Type) map[string]interface{} {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	if values := enumValues(typ); values != nil {
+		return map[string]interface{}{"type": "string", "enum": values}
+	}
+
+	// Permissions also take the names of the custom repository roles of the org, the built-in levels are only suggested
+	if typ == reflect.TypeOf(permission("")) {
+		return map[string]interface{}{"type": "string", "examples": []interface{}{permissionPull, permissionTriage, permissionPush, permissionMaintain, permissionAdmin}}
+	}
+
+	switch typ.Kind() {
+	case reflect.Struct:
+		properties := map[string]interface{}{}
+
+		for _, field := range settingsFields(typ) {
+			name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+
+			if name == "" {
+				name = strings.ToLower(field.Name)
+			}
+
+			property := schemaOf(field.Type)
+
+			if typ == reflect.TypeOf(label{}) && name == "color" {
+				property["pattern"] = "^$|" + labelColor.String()
+			}
+
+			properties[name] = property
+		}
+
+		return map[string]interface{}{"type": "object", "properties": properties, "additionalProperties": false}
+	case reflect.Slice:
+		return map[string]interface{}{"type": "array", "items": schemaOf(typ.Elem())}
+	case reflect.Map, reflect.Interface:
+		return map[string]interface{}{"type": "object"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int64:
+		return map[string]interface{}{"type": "integer"}
+	default:
+		return map[string]interface{}{"type": "string"}
+	}
+}
+
+// settingsFields returns the exported fields of a settings struct, including the fields of its embedded annotations
+func settingsFields(typ reflect.Type) []reflect.StructField {
+	fields := []reflect.StructField{}
+
+	for _, field := range reflect.VisibleFields(typ) {
+		if field.Anonymous || !field.IsExported() {
+			continue
+		}
+
+		fields = append(fields, field)
+	}
+
+	return fields
+}
+
+// enumValues returns the values allowed for the enum types of the settings, the empty value leaves github defaults
+func enumValues(typ reflect.Type) []interface{} {
+	switch typ {
+	case reflect.TypeOf(contentType("")):
+		return []interface{}{"", contentTypeJSON, contentTypeForm}
+	case reflect.TypeOf(matchBy("")):
+		return []interface{}{"", matchByURL, matchByID, matchByName}
+	case reflect.TypeOf(enforcement("")):
+		return []interface{}{"", enforcementEnforce, enforcementReport}
+	case reflect.TypeOf(rulesetMode("")):
+		return []interface{}{"", rulesetModeActive, rulesetModeEvaluate, rulesetModeDisabled}
+	case reflect.TypeOf(bypassMode("")):
+		return []interface{}{"", bypassModeAlways, bypassModePullRequest}
+	case reflect.TypeOf(visibility("")):
+		return []interface{}{"", visibilityPublic, visibilityPrivate, visibilityInternal}
+	case reflect.TypeOf(mergeCommitTitle("")):
+		return []interface{}{"", mergeCommitTitlePRTitle, mergeCommitTitleMergeMessage}
+	case reflect.TypeOf(mergeCommitMessage("")):
+		return []interface{}{"", mergeCommitMessagePRBody, mergeCommitMessagePRTitle, mergeCommitMessageBlank}
+	case reflect.TypeOf(squashMergeCommitTitle("")):
+		return []interface{}{"", squashMergeCommitTitlePRTitle, squashMergeCommitTitleCommitOrPRTitle}
+	case reflect.TypeOf(squashMergeCommitMessage("")):
+		return []interface{}{"", squashMergeCommitMessagePRBody, squashMergeCommitMessageCommitMessages, squashMergeCommitMessageBlank}
+	default:
+		return nil
+	}
+}
+
+// directiveSchema is the schema of the extends and include keys, a file path or url or a list of them
+func directiveSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"anyOf": []interface{}{
+			map[string]interface{}{"type": "string"},
+			map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+		},
+	}
+}
@@ -0,0 +1,164 @@
+package github
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v75/github"
+	"github.com/pkg/errors"
+)
+
+// activeCommitterWindow is how long a committer of a repository with advanced security consumes a seat after their last commit
+const activeCommitterWindow = 90 * 24 * time.Hour
+
+// SecurityFeatures are the security features of the settings counted by the seat usage, named like their yaml key
+// nolint:gochecknoglobals
+var SecurityFeatures = []string{"advancedsecurity", "secretscanning", "secretscanningpushprotection"}
+
+// SeatUsage is the github advanced security seat consumption of an org and the seats the settings would add to it
+type SeatUsage struct {
+	Org string
+	// Committers are the active committers consuming a seat, Maximum the most of the billing cycle and Purchased the seats
+	// bought, 0 when github does not report them
+	Committers int
+	Maximum    int
+	Purchased  int
+	// Features counts the repositories of the org each security feature is enabled on by the settings, keyed by SecurityFeatures
+	Features map[string]int
+	// Enabling are the repositories the settings enable advanced security on, it is not enabled yet
+	Enabling []string
+	// NewCommitters are the active committers of the repositories enabled that consume no seat yet, each would consume one
+	NewCommitters []string
+}
+
+// SeatUsage plans the settings and reports, for each org, the seats consumed by the repositories with advanced security and
+// the seats the repositories the settings enable it on would add
+func (client *Client) SeatUsage(ctx context.Context, allSettings []*Settings, concurrency int) ([]SeatUsage, error) {
+	planned, err := client.PlanAll(ctx, allSettings, concurrency)
+
+	if err != nil {
+		return nil, err
+	}
+
+	usages := []*SeatUsage{}
+	orgs := map[string]*SeatUsage{}
+
+	for i, settings := range allSettings {
+		owner := strings.ToLower(settings.Repository.Owner)
+
+		if orgs[owner] == nil {
+			orgs[owner] = &SeatUsage{Org: settings.Repository.Owner, Features: map[string]int{}}
+			usages = append(usages, orgs[owner])
+		}
+
+		usage := orgs[owner]
+		securitySettings := settings.Repository.Security
+
+		for j, enabled := range []*bool{securitySettings.AdvancedSecurity, securitySettings.SecretScanning, securitySettings.SecretScanningPushProtection} {
+			if enabled != nil && *enabled {
+				usage.Features[SecurityFeatures[j]]++
+			}
+		}
+
+		if enablesAdvancedSecurity(planned[i].Plan) {
+			usage.Enabling = append(usage.Enabling, settings.Repository.Name)
+		}
+	}
+
+	results := make([]SeatUsage, 0, len(usages))
+
+	for _, usage := range usages {
+		err := client.countSeats(ctx, usage)
+
+		if err != nil {
+			return nil, err
+		}
+
+		results = append(results, *usage)
+	}
+
+	return results, nil
+}
+
+// enablesAdvancedSecurity returns true when the plan enables advanced security on the repository
+func enablesAdvancedSecurity(plan *Plan) bool {
+	if plan == nil {
+		return false
+	}
+
+	for _, change := range plan.Changes {
+		current, _ := change.current.(repository)
+		desired, ok := change.desired.(repository)
+
+		if change.Resource == ResourceRepository && ok && enables(current.Security.AdvancedSecurity, desired.Security.AdvancedSecurity) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// countSeats reads the active committers of the org and finds the committers of the repositories enabled consuming no seat yet
+func (client *Client) countSeats(ctx context.Context, usage *SeatUsage) error {
+	committers := map[string]bool{}
+
+	err := eachPage(func(opts github.ListOptions) ([]*github.RepositoryActiveCommitters, *github.Response, error) {
+		activeCommitters, response, err := client.github.Billing.GetAdvancedSecurityActiveCommittersOrg(ctx, usage.Org, &opts)
+
+		if err != nil {
+			return nil, response, err
+		}
+
+		usage.Committers = activeCommitters.TotalAdvancedSecurityCommitters
+		usage.Maximum = activeCommitters.MaximumAdvancedSecurityCommitters
+		usage.Purchased = activeCommitters.PurchasedAdvancedSecurityCommitters
+
+		return activeCommitters.Repositories, response, nil
+	}, func(page []*github.RepositoryActiveCommitters) error {
+		for _, repo := range page {
+			for _, committer := range repo.AdvancedSecurityCommittersBreakdown {
+				committers[strings.ToLower(committer.GetUserLogin())] = true
+			}
+		}
+
+		return nil
+	})
+
+	// Personal accounts and orgs without advanced security have no active committers
+	if err != nil && !isNotFound(err) && !isForbidden(err) {
+		return errors.Wrapf(err, "Error while getting the advanced security committers of %s", usage.Org)
+	}
+
+	since := client.clock.Now().Add(-activeCommitterWindow)
+	added := map[string]bool{}
+
+	for _, name := range usage.Enabling {
+		commits, err := listAll(func(opts github.ListOptions) ([]*github.RepositoryCommit, *github.Response, error) {
+			return client.github.Repositories.ListCommits(ctx, usage.Org, name, &github.CommitsListOptions{Since: since, ListOptions: opts})
+		})
+
+		// A repository planned for creation or empty has no commits yet
+		if isNotFound(err) || isConflict(err) {
+			continue
+		}
+
+		if err != nil {
+			return errors.Wrapf(err, "Error while listing the commits of %s/%s", usage.Org, name)
+		}
+
+		for _, commit := range commits {
+			login := commit.GetAuthor().GetLogin()
+
+			if login != "" && !committers[strings.ToLower(login)] && !added[strings.ToLower(login)] {
+				added[strings.ToLower(login)] = true
+				usage.NewCommitters = append(usage.NewCommitters, login)
+			}
+		}
+	}
+
+	sort.Strings(usage.NewCommitters)
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package github
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestApplySecurityThenPlanHasNoChanges(t *testing.T) {
+	server, client := newTestClient(t)
+	server.AddRepository("acme", "api")
+
+	settings := settingsFromYAML(t, `
+repository:
+  owner: acme
+  name: api
+  security: {advancedsecurity: true, secretscanning: true}
+`)
+
+	plan := planOf(t, client, settings)
+
+	if !slices.Contains(plan.Warnings, "Enabling advanced security consumes a seat for each committer of the last 90 days without one, report seats counts them") {
+		t.Errorf("Expected a warning about the seats, got %v", plan.Warnings)
+	}
+
+	if _, err := client.Apply(context.Background(), settings); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := server.Repository("acme", "api")
+	security := []string{repo.SecurityStatus("advanced_security"), repo.SecurityStatus("secret_scanning"), repo.SecurityStatus("secret_scanning_push_protection")}
+
+	if !slices.Equal(security, []string{"enabled", "enabled", ""}) {
+		t.Errorf("Expected advanced security and secret scanning enabled only, got %v", security)
+	}
+
+	if names := changeNames(planOf(t, client, settings)); len(names) != 0 {
+		t.Errorf("Expected no changes once applied, got %v", names)
+	}
+}
+
+func TestSeatUsageCountsTheCommittersWithoutSeat(t *testing.T) {
+	server, client := newTestClient(t)
+	server.AddRepository("acme", "web")
+	repo := server.AddRepository("acme", "api")
+	repo.AddCommit("alice", time.Now().Add(-time.Hour))
+	repo.AddCommit("bob", time.Now().Add(-2*time.Hour))
+	repo.AddCommit("bob", time.Now().Add(-3*time.Hour))
+	repo.AddCommit("carol", time.Now().Add(-100*24*time.Hour))
+
+	server.SetActiveCommitters("acme", 2, 10, map[string][]string{"acme/web": {"alice"}})
+
+	allSettings := []*Settings{
+		settingsFromYAML(t, "repository: {owner: acme, name: api, security: {advancedsecurity: true, secretscanning: true}}\n"),
+		settingsFromYAML(t, "repository: {owner: acme, name: web, security: {secretscanning: true}}\n"),
+	}
+
+	usages, err := client.SeatUsage(context.Background(), allSettings, 1)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(usages) != 1 {
+		t.Fatalf("Expected the usage of one org, got %+v", usages)
+	}
+
+	usage := usages[0]
+
+	if usage.Committers != 1 || usage.Maximum != 2 || usage.Purchased != 10 {
+		t.Errorf("Expected the seats reported by github, got %+v", usage)
+	}
+
+	if !reflect.DeepEqual(usage.Features, map[string]int{"advancedsecurity": 1, "secretscanning": 2}) {
+		t.Errorf("Expected the repositories of each feature, got %v", usage.Features)
+	}
+
+	if !reflect.DeepEqual(usage.Enabling, []string{"api"}) || !reflect.DeepEqual(usage.NewCommitters, []string{"bob"}) {
+		t.Errorf("Expected bob to consume a new seat for api, got %v and %v", usage.Enabling, usage.NewCommitters)
+	}
+}
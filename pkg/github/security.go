@@ -0,0 +1,92 @@
+package github
+
+import (
+	"github.com/google/go-github/v75/github"
+)
+
+// Statuses of the security features of a repository
+const (
+	securityEnabled  = "enabled"
+	securityDisabled = "disabled"
+)
+
+// security enables the github advanced security features of a repository, the features left unset keep their status
+type security struct {
+	// AdvancedSecurity consumes a github advanced security seat for each active committer of a private repository
+	AdvancedSecurity             *bool `yaml:",omitempty"`
+	SecretScanning               *bool `yaml:",omitempty"`
+	SecretScanningPushProtection *bool `yaml:",omitempty"`
+}
+
+// newSecurity reads the status of the security features, the features github does not report are left unset
+func newSecurity(githubSecurity *github.SecurityAndAnalysis) security {
+	status := func(value string) *bool {
+		if value == "" {
+			return nil
+		}
+
+		return github.Bool(value == securityEnabled)
+	}
+
+	return security{
+		AdvancedSecurity:             status(githubSecurity.GetAdvancedSecurity().GetStatus()),
+		SecretScanning:               status(githubSecurity.GetSecretScanning().GetStatus()),
+		SecretScanningPushProtection: status(githubSecurity.GetSecretScanningPushProtection().GetStatus()),
+	}
+}
+
+// withServerDefaults fills the features left unset with their current status
+func (securitySettings security) withServerDefaults(githubSecurity security) security {
+	if securitySettings.AdvancedSecurity == nil {
+		securitySettings.AdvancedSecurity = githubSecurity.AdvancedSecurity
+	}
+
+	if securitySettings.SecretScanning == nil {
+		securitySettings.SecretScanning = githubSecurity.SecretScanning
+	}
+
+	if securitySettings.SecretScanningPushProtection == nil {
+		securitySettings.SecretScanningPushProtection = githubSecurity.SecretScanningPushProtection
+	}
+
+	return securitySettings
+}
+
+// githubSecurity returns the security features to change, nil when none is set
+func (securitySettings security) githubSecurity() *github.SecurityAndAnalysis {
+	status := func(value *bool) *string {
+		switch {
+		case value == nil:
+			return nil
+		case *value:
+			return github.String(securityEnabled)
+		default:
+			return github.String(securityDisabled)
+		}
+	}
+
+	if securitySettings == (security{}) {
+		return nil
+	}
+
+	githubSecurity := &github.SecurityAndAnalysis{}
+
+	if value := status(securitySettings.AdvancedSecurity); value != nil {
+		githubSecurity.AdvancedSecurity = &github.AdvancedSecurity{Status: value}
+	}
+
+	if value := status(securitySettings.SecretScanning); value != nil {
+		githubSecurity.SecretScanning = &github.SecretScanning{Status: value}
+	}
+
+	if value := status(securitySettings.SecretScanningPushProtection); value != nil {
+		githubSecurity.SecretScanningPushProtection = &github.SecretScanningPushProtection{Status: value}
+	}
+
+	return githubSecurity
+}
+
+// enables returns true when a feature is set to enabled while it is not currently
+func enables(current, desired *bool) bool {
+	return desired != nil && *desired && (current == nil || !*current)
+}
@@ -0,0 +1,386 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v75/github"
+	"github.com/pkg/errors"
+)
+
+// DefaultServeInterval is the time between two reconciliations of every repository of the settings
+const DefaultServeInterval = 15 * time.Minute
+
+// DefaultSweepInterval is the minimum time between two reconciliations of every repository when the webhook deliveries
+// already reconcile the repositories that changed
+const DefaultSweepInterval = 6 * time.Hour
+
+// WebhookPath is the path the webhook deliveries are sent to
+const WebhookPath = "/webhook"
+
+// serveQueueSize is the number of distinct repositories waiting to be reconciled after webhook deliveries, every repository
+// is reconciled instead of the waiting ones when more are delivered
+const serveQueueSize = 100
+
+// shutdownTimeout is the time given to the webhook deliveries in flight when Serve stops
+const shutdownTimeout = 10 * time.Second
+
+// driftEvents are the webhook events that may change the settings of a repository, other events are acknowledged and ignored
+// nolint:gochecknoglobals
+var driftEvents = map[string]bool{
+	"repository":             true,
+	"label":                  true,
+	"branch_protection_rule": true,
+	"member":                 true,
+	"team_add":               true,
+	"meta":                   true,
+	"public":                 true,
+	"push":                   true,
+	"create":                 true,
+	"delete":                 true,
+	"repository_ruleset":     true,
+}
+
+// ServeOptions configures Serve
+type ServeOptions struct {
+	// Config is the settings file, it is loaded again on every reconciliation so its changes are picked up
+	Config string
+	// Addr is the address of the webhook server, no server is started when empty
+	Addr string
+	// WebhookSecret validates the signature of the webhook deliveries, it is required when Addr is set
+	WebhookSecret string
+	// Interval between two reconciliations of every repository, 0 only reconciles on webhook deliveries
+	Interval time.Duration
+	// SweepInterval is the minimum time between two reconciliations of every repository when the webhook server runs, the
+	// interval ticks within it are skipped since the deliveries reconcile the repositories that changed. 0 never skips them
+	SweepInterval time.Duration
+	// Enforce applies the drift, it is only reported otherwise
+	Enforce bool
+	// EnforceCreated applies the settings of the repositories as soon as github delivers their creation, even when the drift is not enforced
+	EnforceCreated bool
+	// MaintenanceWindows are the periods during which the drift is enforced, it is only reported outside of them. The drift
+	// is enforced at any time without windows
+	MaintenanceWindows []MaintenanceWindow
+	// History records the outcome of every repository reconciled when set
+	History *History
+	// Dashboard serves a read-only page with the status of the repositories of the history on the webhook server
+	Dashboard   bool
+	Concurrency int
+	Notifier    Notifier
+}
+
+// reconciliation lists the repositories Serve reconciles
+type reconciliation struct {
+	// repositories are the full names (owner/name) of the repositories to reconcile, every repository is reconciled when nil
+	repositories []string
+	// created are the repositories just created, they have none of the settings yet
+	created map[string]bool
+}
+
+// pendingRepositories coalesces the webhook deliveries waiting to be reconciled by repository, so a burst of events
+// reconciles a repository once and the deliveries are never blocked by a reconciliation in progress
+type pendingRepositories struct {
+	mu           sync.Mutex
+	repositories map[string]bool
+	// all is set once more than serveQueueSize repositories are waiting, every repository is reconciled instead
+	all     bool
+	created map[string]bool
+	// ready is signaled when repositories are waiting
+	ready chan struct{}
+}
+
+func newPendingRepositories() *pendingRepositories {
+	return &pendingRepositories{
+		repositories: map[string]bool{},
+		created:      map[string]bool{},
+		ready:        make(chan struct{}, 1),
+	}
+}
+
+// add queues a repository and reports whether it was coalesced with a delivery already waiting
+func (pending *pendingRepositories) add(repository string, created bool) bool {
+	pending.mu.Lock()
+	defer pending.mu.Unlock()
+
+	if created {
+		pending.created[repository] = true
+	}
+
+	coalesced := pending.all || pending.repositories[repository]
+
+	switch {
+	case coalesced:
+	case len(pending.repositories) >= serveQueueSize:
+		log.Printf("[WARN] More than %d repositories are waiting, reconciling every repository instead\n", serveQueueSize)
+		pending.all = true
+		pending.repositories = map[string]bool{}
+	default:
+		pending.repositories[repository] = true
+	}
+
+	select {
+	case pending.ready <- struct{}{}:
+	default:
+	}
+
+	return coalesced
+}
+
+// take returns the waiting repositories and empties the queue
+func (pending *pendingRepositories) take() reconciliation {
+	pending.mu.Lock()
+	defer pending.mu.Unlock()
+
+	next := reconciliation{created: pending.created}
+
+	if !pending.all {
+		next.repositories = sortedKeys(pending.repositories)
+	}
+
+	pending.repositories = map[string]bool{}
+	pending.all = false
+	pending.created = map[string]bool{}
+
+	return next
+}
+
+// Serve runs until the context is cancelled and reconciles the repositories of the settings file on every interval
+// and on the webhook deliveries of their repositories. The drift is applied when enforced and always sent to the notifier
+// The webhook server also publishes the stats on /debug/vars and the dashboard on / when enabled
+func (client *Client) Serve(ctx context.Context, options ServeOptions) error {
+	if options.Notifier == nil {
+		options.Notifier = logNotifier{}
+	}
+
+	if options.Addr != "" && options.WebhookSecret == "" {
+		return errors.New("Refusing to serve unvalidated webhook deliveries, set a webhook secret or no address to only reconcile on the interval")
+	}
+
+	if options.Dashboard && (options.History == nil || options.Addr == "") {
+		return errors.New("The dashboard requires a history and the address of the webhook server")
+	}
+
+	pending := newPendingRepositories()
+	serverErrors := make(chan error, 1)
+
+	if options.Addr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/debug/vars", expvar.Handler())
+		mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+		mux.HandleFunc(WebhookPath, webhookHandler(options.WebhookSecret, pending))
+
+		if options.Dashboard {
+			mux.HandleFunc(DashboardPath, dashboardHandler(options.History))
+		}
+
+		server := &http.Server{Addr: options.Addr, Handler: mux, ReadHeaderTimeout: notifyTimeout}
+
+		go func() {
+			log.Printf("[INFO] Listening for webhook deliveries on %s%s\n", options.Addr, WebhookPath)
+			serverErrors <- server.ListenAndServe()
+		}()
+
+		defer func() {
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+			defer cancel()
+
+			_ = server.Shutdown(shutdownCtx)
+		}()
+	}
+
+	lastSweep := client.clock.Now()
+	client.reconcile(ctx, options, reconciliation{})
+
+	// The next tick is scheduled once a tick is handled, a slow reconciliation delays the next one instead of queuing it
+	var ticks <-chan time.Time
+
+	if options.Interval > 0 {
+		ticks = client.clock.After(options.Interval)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-serverErrors:
+			return errors.Wrap(err, "Error serving webhook deliveries")
+		case now := <-ticks:
+			if options.Addr != "" && now.Sub(lastSweep) < options.SweepInterval {
+				log.Printf("[INFO] Skipping the reconciliation of every repository, the last one ran %s ago\n", now.Sub(lastSweep).Round(time.Second))
+			} else {
+				lastSweep = now
+				client.reconcile(ctx, options, reconciliation{})
+			}
+
+			ticks = client.clock.After(options.Interval)
+		case <-pending.ready:
+			next := pending.take()
+
+			if next.repositories == nil {
+				lastSweep = client.clock.Now()
+			}
+
+			client.reconcile(ctx, options, next)
+		}
+	}
+}
+
+// reconcile plans the repositories of the settings, or only the requested repositories, then applies and notifies the drift
+func (client *Client) reconcile(ctx context.Context, options ServeOptions, next reconciliation) {
+	if next.repositories == nil {
+		stats.Add(StatSweeps, 1)
+	} else {
+		stats.Add(StatDirtyRepositories, int64(len(next.repositories)))
+	}
+
+	allSettings, err := client.requestedSettings(ctx, options, next.repositories)
+
+	if err != nil {
+		log.Printf("[WARN] Skipping reconciliation, %s\n", err)
+		return
+	}
+
+	if len(allSettings) == 0 {
+		return
+	}
+
+	// The repositories that failed are notified with their result, the MultiError of the run is not needed
+	planned, _ := client.PlanAll(ctx, allSettings, options.Concurrency)
+	results := Results{}
+	unchanged := Results{}
+
+	for _, planned := range planned {
+		if planned.Err == nil && planned.Plan.Empty() {
+			unchanged = append(unchanged, planned)
+			continue
+		}
+
+		results = append(results, planned)
+	}
+
+	if !inMaintenanceWindows(options.MaintenanceWindows, client.clock.Now()) && (options.Enforce || options.EnforceCreated) {
+		log.Printf("[INFO] Outside the maintenance windows, only reporting the drift\n")
+
+		options.Enforce = false
+		options.EnforceCreated = false
+	}
+
+	switch {
+	case options.Enforce:
+		results, _ = client.ApplyPlans(ctx, results, options.Concurrency)
+	case options.EnforceCreated && len(next.created) != 0:
+		created := Results{}
+		drifted := Results{}
+
+		for _, result := range results {
+			if next.created[result.Repository] {
+				created = append(created, result)
+			} else {
+				drifted = append(drifted, result)
+			}
+		}
+
+		applied, _ := client.ApplyPlans(ctx, created, options.Concurrency)
+		results = append(applied, drifted...)
+	}
+
+	log.Printf("[INFO] Reconciled %d repositories, %d drifted or failed\n", len(allSettings), len(results))
+
+	if options.History != nil {
+		err := options.History.Record(ctx, append(unchanged, results...), client.clock.Now())
+
+		if err != nil {
+			log.Printf("[WARN] %s\n", err)
+		}
+	}
+
+	for _, result := range results {
+		err := options.Notifier.Notify(ctx, result)
+
+		if err != nil {
+			log.Printf("[WARN] Error notifying the drift of %s: %s\n", result.Repository, err)
+		}
+	}
+}
+
+// requestedSettings loads the settings of every repository, or only of the requested repositories without listing the organization
+// since a repository created a moment ago may not be listed yet
+func (client *Client) requestedSettings(ctx context.Context, options ServeOptions, repositories []string) ([]*Settings, error) {
+	if repositories == nil {
+		return client.GetAllSettingsFromFile(ctx, options.Config)
+	}
+
+	allSettings := []*Settings{}
+
+	for _, repository := range repositories {
+		parts := strings.SplitN(repository, "/", 2)
+
+		if len(parts) != 2 {
+			log.Printf("[WARN] Ignoring webhook delivery of %s, expected owner/repo\n", repository)
+			continue
+		}
+
+		settings, err := client.GetRepositorySettingsFromFile(ctx, options.Config, parts[0], parts[1])
+
+		if err != nil {
+			return nil, err
+		}
+
+		if settings == nil {
+			log.Printf("[INFO] Ignoring webhook delivery of %s, the repository is not in the settings\n", repository)
+			continue
+		}
+
+		allSettings = append(allSettings, settings)
+	}
+
+	return allSettings, nil
+}
+
+// webhookHandler validates the webhook deliveries and queues the repositories whose settings may have changed
+func webhookHandler(secret string, pending *pendingRepositories) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		payload, err := github.ValidatePayload(r, []byte(secret))
+
+		if err != nil {
+			log.Printf("[WARN] Rejecting webhook delivery, %s\n", err)
+			http.Error(w, "Invalid webhook delivery", http.StatusBadRequest)
+
+			return
+		}
+
+		event := github.WebHookType(r)
+
+		if !driftEvents[event] {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		delivery := struct {
+			Action     string             `json:"action"`
+			Repository *github.Repository `json:"repository"`
+		}{}
+		err = json.Unmarshal(payload, &delivery)
+
+		if err != nil || delivery.Repository.GetFullName() == "" || delivery.Repository.GetArchived() {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		created := event == "repository" && delivery.Action == "created"
+
+		if pending.add(delivery.Repository.GetFullName(), created) {
+			log.Printf("[INFO] Coalesced %s event of %s with the deliveries waiting\n", event, delivery.Repository.GetFullName())
+		} else {
+			log.Printf("[INFO] Queued %s after a %s event\n", delivery.Repository.GetFullName(), event)
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
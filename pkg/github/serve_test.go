@@ -0,0 +1,218 @@
+package github
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"expvar"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// writeSettings writes a settings file in a temporary directory and returns its path
+func writeSettings(tb testing.TB, content string) string {
+	tb.Helper()
+
+	path := filepath.Join(tb.TempDir(), "settings.yml")
+
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		tb.Fatal(err)
+	}
+
+	return path
+}
+
+// recordingNotifier keeps the results it is notified of
+type recordingNotifier struct {
+	mu      sync.Mutex
+	results []RepositoryResult
+}
+
+func (notifier *recordingNotifier) Notify(ctx context.Context, result RepositoryResult) error {
+	notifier.mu.Lock()
+	defer notifier.mu.Unlock()
+
+	notifier.results = append(notifier.results, result)
+
+	return nil
+}
+
+// delivery builds a signed webhook delivery of a repository event
+func delivery(secret, event, repository, action string) *http.Request {
+	payload := fmt.Sprintf(`{"action": %q, "repository": {"full_name": %q}}`, action, repository)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+
+	request := httptest.NewRequest(http.MethodPost, WebhookPath, strings.NewReader(payload))
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("X-GitHub-Event", event)
+	request.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+
+	return request
+}
+
+func TestServeRequiresWebhookSecret(t *testing.T) {
+	_, client := newTestClient(t)
+
+	err := client.Serve(context.Background(), ServeOptions{Config: "settings.yml", Addr: "127.0.0.1:0"})
+
+	if err == nil || !strings.Contains(err.Error(), "unvalidated webhook deliveries") {
+		t.Errorf("Serve without a webhook secret failed with %v, want a refusal", err)
+	}
+}
+
+func TestWebhookHandlerCoalescesRepositories(t *testing.T) {
+	pending := newPendingRepositories()
+	handler := webhookHandler("secret", pending)
+
+	for _, request := range []*http.Request{
+		delivery("secret", "label", "acme/api", "created"),
+		delivery("secret", "push", "acme/web", ""),
+		delivery("secret", "label", "acme/api", "deleted"),
+		delivery("secret", "repository", "acme/new", "created"),
+		delivery("wrong", "label", "acme/forged", "created"),
+	} {
+		handler(httptest.NewRecorder(), request)
+	}
+
+	select {
+	case <-pending.ready:
+	default:
+		t.Fatal("No reconciliation signaled after the deliveries")
+	}
+
+	next := pending.take()
+
+	if strings.Join(next.repositories, ",") != "acme/api,acme/new,acme/web" {
+		t.Errorf("Repositories waiting are %v, want [acme/api acme/new acme/web]", next.repositories)
+	}
+
+	if len(next.created) != 1 || !next.created["acme/new"] {
+		t.Errorf("Created repositories are %v, want acme/new", next.created)
+	}
+
+	if next = pending.take(); len(next.repositories) != 0 || next.repositories == nil {
+		t.Errorf("Repositories still waiting after take are %v", next.repositories)
+	}
+}
+
+func TestPendingRepositoriesOverflowReconcilesEveryRepository(t *testing.T) {
+	pending := newPendingRepositories()
+
+	for i := 0; i <= serveQueueSize; i++ {
+		pending.add(fmt.Sprintf("acme/repo-%d", i), false)
+	}
+
+	if !pending.add("acme/repo-0", true) {
+		t.Error("Delivery after the overflow is not coalesced")
+	}
+
+	next := pending.take()
+
+	if next.repositories != nil {
+		t.Errorf("Overflowing deliveries reconcile %d repositories, want every repository", len(next.repositories))
+	}
+
+	if !next.created["acme/repo-0"] {
+		t.Error("Created repository is forgotten by the overflow")
+	}
+}
+
+// statValue returns a counter of the stats map
+func statValue(name string) int64 {
+	if value, ok := stats.Get(name).(*expvar.Int); ok {
+		return value.Value()
+	}
+
+	return 0
+}
+
+func TestServeRateLimitsSweepsWithWebhooks(t *testing.T) {
+	config := writeSettings(t, "repository: {owner: acme, name: api}\ndisable: {repository: true}\n")
+
+	for _, test := range []struct {
+		sweepInterval time.Duration
+		sweeps        []int64
+	}{
+		{3 * time.Hour, []int64{1, 1, 1, 2}},
+		{0, []int64{1, 2, 3, 4}},
+	} {
+		clock := newFakeClock(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC))
+		server, client := newTestClient(t, WithClock(clock))
+		server.AddRepository("acme", "api")
+
+		sweeps := statValue(StatSweeps)
+		ctx, cancel := context.WithCancel(context.Background())
+		served := make(chan error, 1)
+
+		go func() {
+			served <- client.Serve(ctx, ServeOptions{
+				Config:        config,
+				Addr:          "127.0.0.1:0",
+				WebhookSecret: "secret",
+				Interval:      time.Hour,
+				SweepInterval: test.sweepInterval,
+				Notifier:      &recordingNotifier{},
+			})
+		}()
+
+		// Serve waits on the clock once the previous tick is handled
+		for i, expected := range test.sweeps {
+			if i > 0 {
+				clock.Advance(time.Hour)
+			}
+
+			clock.wait(t)
+
+			if swept := statValue(StatSweeps) - sweeps; swept != expected {
+				t.Errorf("Serve with a sweep interval of %s reconciled every repository %d times after %dh, want %d", test.sweepInterval, swept, i, expected)
+			}
+		}
+
+		cancel()
+
+		if err := <-served; err != nil {
+			t.Fatalf("Error serving: %v", err)
+		}
+	}
+}
+
+func TestServeEnforcesTheDriftInMaintenanceWindows(t *testing.T) {
+	window, err := ParseMaintenanceWindow("0 2 * * * 2h UTC")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := writeSettings(t, "repository: {owner: acme, name: api}\ndisable: {repository: true}\nlabels: [{name: bug, color: d73a4a}]\n")
+
+	for _, test := range []struct {
+		now     time.Time
+		applied bool
+	}{
+		{time.Date(2024, 3, 1, 3, 0, 0, 0, time.UTC), true},
+		{time.Date(2024, 3, 1, 5, 0, 0, 0, time.UTC), false},
+	} {
+		server, client := newTestClient(t, WithClock(newFakeClock(test.now)))
+		repo := server.AddRepository("acme", "api")
+
+		client.reconcile(context.Background(), ServeOptions{
+			Config:             config,
+			Enforce:            true,
+			MaintenanceWindows: []MaintenanceWindow{window},
+			Notifier:           &recordingNotifier{},
+		}, reconciliation{})
+
+		if _, applied := repo.Label("bug"); applied != test.applied {
+			t.Errorf("Reconciling at %s applied the drift: %t, want %t", test.now.Format(time.Kitchen), applied, test.applied)
+		}
+	}
+}
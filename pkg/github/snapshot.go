@@ -0,0 +1,153 @@
+package github
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Snapshot holds the live settings of the repositories as they were planned, so the settings can be planned again against
+// them later without calling github (ex: to review what changed since the last known good state while rate limited)
+type Snapshot struct {
+	mutex sync.Mutex
+	// Time is when the snapshot was taken
+	Time time.Time
+	// Repositories are the live settings keyed by full name (owner/name)
+	Repositories map[string]*snapshotRepository
+}
+
+// snapshotRepository is the live settings of a repository with the fields the settings files do not declare
+type snapshotRepository struct {
+	Settings *Settings
+	// SecretUpdates are when the secrets were last written, keyed by name or by environment/name
+	SecretUpdates map[string]time.Time `json:",omitempty"`
+	// DependabotSecretUpdates are when the dependabot secrets were last written, keyed by name
+	DependabotSecretUpdates map[string]time.Time `json:",omitempty"`
+}
+
+// NewSnapshot returns an empty snapshot taken at a time
+func NewSnapshot(now time.Time) *Snapshot {
+	return &Snapshot{Time: now.UTC(), Repositories: map[string]*snapshotRepository{}}
+}
+
+// LoadSnapshot reads a snapshot saved by Save
+func LoadSnapshot(path string) (*Snapshot, error) {
+	content, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error while reading snapshot %s", path)
+	}
+
+	snapshot := &Snapshot{}
+	err = json.Unmarshal(content, snapshot)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error while unmarshal snapshot %s", path)
+	}
+
+	if snapshot.Repositories == nil {
+		snapshot.Repositories = map[string]*snapshotRepository{}
+	}
+
+	return snapshot, nil
+}
+
+// Save writes the snapshot to a json file
+func (snapshot *Snapshot) Save(path string) error {
+	snapshot.mutex.Lock()
+	defer snapshot.mutex.Unlock()
+
+	content, err := json.MarshalIndent(snapshot, "", "  ")
+
+	if err != nil {
+		return errors.Wrap(err, "Error while marshal snapshot")
+	}
+
+	err = ioutil.WriteFile(path, content, cacheFilePermission)
+
+	if err != nil {
+		return errors.Wrapf(err, "Error while writing snapshot %s", path)
+	}
+
+	return nil
+}
+
+// record keeps a copy of the live settings of a repository
+func (snapshot *Snapshot) record(githubSettings *Settings) error {
+	content, err := json.Marshal(githubSettings)
+
+	if err != nil {
+		return errors.Wrap(err, "Error while marshal snapshot")
+	}
+
+	recorded := &snapshotRepository{Settings: &Settings{}, SecretUpdates: map[string]time.Time{}, DependabotSecretUpdates: map[string]time.Time{}}
+	err = json.Unmarshal(content, recorded.Settings)
+
+	if err != nil {
+		return errors.Wrap(err, "Error while marshal snapshot")
+	}
+
+	for _, secretSettings := range githubSettings.Secrets {
+		recorded.SecretUpdates[secretSettings.Name] = secretSettings.updatedAt
+	}
+
+	for _, secretSettings := range githubSettings.DependabotSecrets {
+		recorded.DependabotSecretUpdates[secretSettings.Name] = secretSettings.updatedAt
+	}
+
+	for _, environmentSettings := range githubSettings.Environments {
+		for _, secretSettings := range environmentSettings.Secrets {
+			recorded.SecretUpdates[environmentSettings.Name+"/"+secretSettings.Name] = secretSettings.updatedAt
+		}
+	}
+
+	snapshot.mutex.Lock()
+	defer snapshot.mutex.Unlock()
+
+	snapshot.Repositories[githubSettings.Repository.Owner+"/"+githubSettings.Repository.Name] = recorded
+
+	return nil
+}
+
+// settings returns a copy of the live settings of a repository, planning modifies them
+func (snapshot *Snapshot) settings(owner, name string) (*Settings, error) {
+	snapshot.mutex.Lock()
+	recorded, ok := snapshot.Repositories[owner+"/"+name]
+	snapshot.mutex.Unlock()
+
+	if !ok || recorded.Settings == nil {
+		return nil, errors.Errorf("Repository %s/%s is not in the snapshot of %s", owner, name, snapshot.Time.Format(time.RFC3339))
+	}
+
+	content, err := json.Marshal(recorded.Settings)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Error while reading snapshot")
+	}
+
+	githubSettings := &Settings{}
+	err = json.Unmarshal(content, githubSettings)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Error while reading snapshot")
+	}
+
+	for i, secretSettings := range githubSettings.Secrets {
+		githubSettings.Secrets[i].updatedAt = recorded.SecretUpdates[secretSettings.Name]
+	}
+
+	for i, secretSettings := range githubSettings.DependabotSecrets {
+		githubSettings.DependabotSecrets[i].updatedAt = recorded.DependabotSecretUpdates[secretSettings.Name]
+	}
+
+	for _, environmentSettings := range githubSettings.Environments {
+		for i, secretSettings := range environmentSettings.Secrets {
+			environmentSettings.Secrets[i].updatedAt = recorded.SecretUpdates[environmentSettings.Name+"/"+secretSettings.Name]
+		}
+	}
+
+	return githubSettings, nil
+}
@@ -0,0 +1,58 @@
+package github
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPlanAgainstSnapshotDoesNotCallGithub(t *testing.T) {
+	secretValues := WithSecretValues(map[string]string{"DEPLOY_TOKEN": "token", "DEPLOY_KEY": "key"})
+	server, client := newTestClient(t, secretValues)
+	server.AddRepository("acme", "api")
+
+	_, err := client.Apply(context.Background(), settingsFromYAML(t, convergingSettings))
+
+	if err != nil {
+		t.Fatalf("Error applying settings: %v", err)
+	}
+
+	// The snapshot is recorded planning the labels only, it holds the other resources as well
+	snapshot := NewSnapshot(time.Now())
+	recording := newServerClient(t, server, secretValues, WithSnapshot(snapshot))
+	planOf(t, recording, settingsFromYAML(t, "repository: {owner: acme, name: api}\nlabels: [{name: bug, color: d73a4a}]\n"))
+
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+
+	if err := snapshot.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	server.Repository("acme", "api").DeleteLabel("bug")
+
+	if plan := planOf(t, client, settingsFromYAML(t, convergingSettings)); strings.Join(changeNames(plan), ",") != "labels create bug" {
+		t.Fatalf("Live plan changes are %v, want the deleted label created", changeNames(plan))
+	}
+
+	server.Close()
+
+	saved, err := LoadSnapshot(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	against := newServerClient(t, server, secretValues, WithAgainst(saved))
+
+	if plan := planOf(t, against, settingsFromYAML(t, convergingSettings)); !plan.Empty() {
+		t.Errorf("Plan against the snapshot has changes %v:\n%s", changeNames(plan), plan)
+	}
+
+	_, err = against.Plan(context.Background(), settingsFromYAML(t, "repository: {owner: acme, name: web}\n"))
+
+	if err == nil || !strings.Contains(err.Error(), "not in the snapshot") {
+		t.Errorf("Plan of a repository missing from the snapshot failed with %v", err)
+	}
+}
@@ -0,0 +1,332 @@
+package github
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// AdoptSections are the resource kinds whose live resources can be adopted, only the resources of the state store are pruned
+// nolint:gochecknoglobals
+var AdoptSections = []string{
+	ResourceLabels,
+	ResourceBranches,
+	ResourceWebhooks,
+	ResourceCollaborators,
+	ResourceTeams,
+	ResourceSecrets,
+	ResourceVariables,
+	ResourceEnvironments,
+	ResourceRulesets,
+}
+
+// StateStore records the live resources managed by the settings of each repository, those applied from the settings and
+// those adopted. With a state store only the managed resources missing from the settings are deleted, the resources the
+// tool did not create nor adopt are left alone
+type StateStore struct {
+	mutex sync.Mutex
+	path  string
+	// repositories maps the full name of a repository to the names of its managed resources by resource kind
+	repositories map[string]map[string][]string
+	// webhooks maps the full name of a repository to the ids of its webhooks matched by name
+	webhooks map[string]map[string]int64
+}
+
+// stateFile is the content of the file of a state store
+type stateFile struct {
+	Repositories map[string]map[string][]string `json:"repositories"`
+	Webhooks     map[string]map[string]int64    `json:"webhooks,omitempty"`
+}
+
+// LoadStateStore reads the state store of a file, a missing file is an empty state store
+func LoadStateStore(path string) (*StateStore, error) {
+	store := &StateStore{path: path, repositories: map[string]map[string][]string{}, webhooks: map[string]map[string]int64{}}
+	content, err := ioutil.ReadFile(path)
+
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error while reading state store %s", path)
+	}
+
+	file := stateFile{Repositories: store.repositories, Webhooks: store.webhooks}
+	err = json.Unmarshal(content, &file)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error while unmarshal state store %s", path)
+	}
+
+	if file.Repositories != nil {
+		store.repositories = file.Repositories
+	}
+
+	if file.Webhooks != nil {
+		store.webhooks = file.Webhooks
+	}
+
+	return store, nil
+}
+
+// Save writes the state store to its file
+func (store *StateStore) Save() error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	content, err := json.MarshalIndent(stateFile{Repositories: store.repositories, Webhooks: store.webhooks}, "", "  ")
+
+	if err != nil {
+		return errors.Wrap(err, "Error while marshal state store")
+	}
+
+	err = ioutil.WriteFile(store.path, content, cacheFilePermission)
+
+	if err != nil {
+		return errors.Wrapf(err, "Error while writing state store %s", store.path)
+	}
+
+	return nil
+}
+
+// Managed returns true when a resource of a repository is recorded in the state store, names are compared without case
+func (store *StateStore) Managed(repository, resource, name string) bool {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	for _, managed := range store.repositories[strings.ToLower(repository)][resource] {
+		if strings.EqualFold(managed, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// add records resources of a repository as managed
+func (store *StateStore) add(repository, resource string, names ...string) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	repository = strings.ToLower(repository)
+
+	if store.repositories[repository] == nil {
+		store.repositories[repository] = map[string][]string{}
+	}
+
+	managed := store.repositories[repository][resource]
+
+	for _, name := range names {
+		found := false
+
+		for _, existing := range managed {
+			found = found || strings.EqualFold(existing, name)
+		}
+
+		if !found {
+			managed = append(managed, name)
+		}
+	}
+
+	sort.Strings(managed)
+	store.repositories[repository][resource] = managed
+}
+
+// remove forgets a resource of a repository once deleted
+func (store *StateStore) remove(repository, resource, name string) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	resources := store.repositories[strings.ToLower(repository)]
+	kept := []string{}
+
+	for _, managed := range resources[resource] {
+		if !strings.EqualFold(managed, name) {
+			kept = append(kept, managed)
+		}
+	}
+
+	if resources != nil {
+		resources[resource] = kept
+	}
+}
+
+// webhookID returns the id of the webhook of a repository matched by a name, 0 when it was never applied
+func (store *StateStore) webhookID(repository, name string) int64 {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	return store.webhooks[strings.ToLower(repository)][name]
+}
+
+// setWebhookID records the id of the webhook of a repository matched by a name
+func (store *StateStore) setWebhookID(repository, name string, id int64) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	repository = strings.ToLower(repository)
+
+	if store.webhooks[repository] == nil {
+		store.webhooks[repository] = map[string]int64{}
+	}
+
+	store.webhooks[repository][name] = id
+}
+
+// resolveWebhookNames sets the id of the webhooks matched by name to the live webhook recorded for their name in the state
+// store, a webhook whose name is not recorded yet or whose webhook was deleted matches the oldest live webhook of its url
+func (client *Client) resolveWebhookNames(githubSettings, settings *Settings) (*Settings, error) {
+	if settings.Disable.Webhooks {
+		return settings, nil
+	}
+
+	resolved := *settings
+	resolved.Webhooks = make([]webhook, 0, len(settings.Webhooks))
+	repository := settings.Repository.Owner + "/" + settings.Repository.Name
+	claimed := map[int64]bool{}
+	live := map[int64]webhook{}
+
+	for _, githubWebhook := range githubSettings.Webhooks {
+		live[githubWebhook.ID] = githubWebhook
+	}
+
+	for _, webhookSettings := range settings.Webhooks {
+		if webhookSettings.MatchBy != matchByName {
+			resolved.Webhooks = append(resolved.Webhooks, webhookSettings)
+			continue
+		}
+
+		if client.state == nil {
+			return nil, errors.Errorf("Webhook %s is matched by name, it requires a state store (see --state)", webhookSettings.Name)
+		}
+
+		webhookSettings.ID = 0
+
+		if id := client.state.webhookID(repository, webhookSettings.Name); id != 0 && !claimed[id] {
+			if _, ok := live[id]; ok {
+				webhookSettings.ID = id
+			}
+		}
+
+		for _, id := range sortedKeys(live) {
+			if webhookSettings.ID == 0 && !claimed[id] && live[id].URL == webhookSettings.URL {
+				webhookSettings.ID = id
+			}
+		}
+
+		if webhookSettings.ID != 0 {
+			claimed[webhookSettings.ID] = true
+		}
+
+		resolved.Webhooks = append(resolved.Webhooks, webhookSettings)
+	}
+
+	return &resolved, nil
+}
+
+// resourceNames returns the names of the resources of the settings by resource kind, as named by the changes of a plan
+func resourceNames(settings *Settings) map[string][]string {
+	names := map[string][]string{}
+
+	for _, labelSettings := range settings.Labels {
+		names[ResourceLabels] = append(names[ResourceLabels], labelSettings.Name)
+	}
+
+	for _, branchSettings := range settings.Branches {
+		if branchSettings.Protection.Enabled {
+			names[ResourceBranches] = append(names[ResourceBranches], branchSettings.Name)
+		}
+	}
+
+	for _, webhookSettings := range settings.Webhooks {
+		names[ResourceWebhooks] = append(names[ResourceWebhooks], webhookSettings.URL)
+	}
+
+	for _, collaboratorSettings := range settings.Collaborators {
+		names[ResourceCollaborators] = append(names[ResourceCollaborators], collaboratorSettings.Username)
+	}
+
+	for _, teamSettings := range settings.Teams {
+		names[ResourceTeams] = append(names[ResourceTeams], teamSettings.Slug)
+	}
+
+	for _, secretSettings := range settings.Secrets {
+		names[ResourceSecrets] = append(names[ResourceSecrets], secretSettings.Name)
+	}
+
+	for _, variableSettings := range settings.Variables {
+		names[ResourceVariables] = append(names[ResourceVariables], variableSettings.Name)
+	}
+
+	for _, environmentSettings := range settings.Environments {
+		names[ResourceEnvironments] = append(names[ResourceEnvironments], environmentSettings.Name)
+	}
+
+	for _, rulesetSettings := range settings.Rulesets {
+		names[ResourceRulesets] = append(names[ResourceRulesets], rulesetSettings.Name)
+	}
+
+	return names
+}
+
+// keepUnmanaged drops the deletions of the resources missing from the state store of the client, every deletion is kept without state store
+func (client *Client) keepUnmanaged(plan *Plan) {
+	if client.state == nil {
+		return
+	}
+
+	kept := []Change{}
+
+	for _, change := range plan.Changes {
+		if change.Action == ActionDelete && isAdoptable(change.Resource) && !client.state.Managed(plan.target(), change.Resource, change.Name) {
+			log.Printf("[INFO] Keeping %s %s missing from the settings, it is not managed (see adopt)\n", change.Resource, change.Name)
+			continue
+		}
+
+		kept = append(kept, change)
+	}
+
+	plan.Changes = kept
+}
+
+// rememberManaged records the resources of the settings of an applied plan in the state store of the client and forgets those deleted
+func (client *Client) rememberManaged(plan *Plan, result *Result) {
+	if client.state == nil || plan.settings == nil {
+		return
+	}
+
+	for resource, names := range resourceNames(plan.settings) {
+		if plan.settings.manages(resource) {
+			client.state.add(plan.target(), resource, names...)
+		}
+	}
+
+	for _, change := range result.Applied {
+		if change.Action == ActionDelete && isAdoptable(change.Resource) {
+			client.state.remove(plan.target(), change.Resource, change.Name)
+		}
+	}
+
+	// The webhooks matched by name record the live webhook they matched, those created record theirs once created
+	for _, webhookSettings := range plan.settings.Webhooks {
+		if webhookSettings.MatchBy == matchByName && webhookSettings.ID != 0 {
+			client.state.setWebhookID(plan.target(), webhookSettings.Name, webhookSettings.ID)
+		}
+	}
+}
+
+func isAdoptable(resource string) bool {
+	for _, section := range AdoptSections {
+		if section == resource {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,43 @@
+package github
+
+import (
+	"expvar"
+	"net/http"
+)
+
+// StatsName is the expvar map holding the counters of every client, published on /debug/vars by the expvar package
+const StatsName = "github_settings"
+
+// Counters of the stats map
+const (
+	StatPlans         = "plans"
+	StatPlanFailures  = "plan_failures"
+	StatDrift         = "drift"
+	StatApplies       = "applies"
+	StatApplyFailures = "apply_failures"
+	StatAPICalls      = "api_calls"
+	// StatSweeps counts the reconciliations of every repository by serve
+	StatSweeps = "sweeps"
+	// StatDirtyRepositories counts the repositories reconciled by serve after their webhook deliveries
+	StatDirtyRepositories = "dirty_repositories"
+)
+
+// nolint:gochecknoglobals
+var stats = expvar.NewMap(StatsName)
+
+// countingTransport counts the requests sent to the github api
+type countingTransport struct {
+	base http.RoundTripper
+}
+
+func (transport *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	stats.Add(StatAPICalls, 1)
+
+	base := transport.base
+
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return base.RoundTrip(req)
+}
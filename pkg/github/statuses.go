@@ -0,0 +1,82 @@
+package github
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/go-github/v75/github"
+	"github.com/pkg/errors"
+)
+
+// StatusContextPrefix prefixes the full name of the repository in the context of its commit status (ex: settings/acme/api)
+const StatusContextPrefix = "settings/"
+
+// maxStatusDescription is the longest description github accepts on a commit status
+const maxStatusDescription = 140
+
+// Commit status states
+const (
+	statusSuccess = "success"
+	statusFailure = "failure"
+)
+
+// StatusOptions identifies the commit of the config repository the statuses are posted on
+type StatusOptions struct {
+	Owner string
+	Name  string
+	SHA   string
+	// TargetURL links the statuses to the run that planned or applied the settings (ex: the ci job)
+	TargetURL string
+}
+
+// PostStatuses posts a commit status per repository on a commit of the config repository with the outcome of its plan or apply
+func (client *Client) PostStatuses(ctx context.Context, options StatusOptions, results []RepositoryResult) error {
+	ctx = withRetries(ctx)
+
+	for _, result := range results {
+		state, description := resultStatus(result)
+		status := &github.RepoStatus{
+			State:       github.String(state),
+			Description: github.String(description),
+			Context:     github.String(StatusContextPrefix + result.Repository),
+		}
+
+		if options.TargetURL != "" {
+			status.TargetURL = github.String(options.TargetURL)
+		}
+
+		_, _, err := client.github.Repositories.CreateStatus(ctx, options.Owner, options.Name, options.SHA, status)
+
+		if err != nil {
+			return errors.Wrapf(err, "Error posting the status of %s on %s/%s@%s", result.Repository, options.Owner, options.Name, options.SHA)
+		}
+	}
+
+	return nil
+}
+
+// resultStatus returns the state and description of the commit status of a repository, drift only fails the status once apply could not correct it
+func resultStatus(result RepositoryResult) (string, string) {
+	state, description := statusSuccess, "in sync"
+
+	switch {
+	case result.Err != nil:
+		state, description = statusFailure, "error: "+result.Err.Error()
+	case result.Result != nil && len(result.Result.Failed)+len(result.Result.Skipped)+len(result.Result.Unverified) != 0:
+		state = statusFailure
+		description = fmt.Sprintf("failed: %d changes applied, %d failed, %d skipped and %d not verified",
+			len(result.Result.Applied), len(result.Result.Failed), len(result.Result.Skipped), len(result.Result.Unverified))
+	case result.Result != nil && len(result.Result.Applied) != 0:
+		description = fmt.Sprintf("applied: %d changes", len(result.Result.Applied))
+	case result.Result != nil && len(result.Result.Reported) != 0:
+		description = fmt.Sprintf("drift: %d changes reported only", len(result.Result.Reported))
+	case result.Result == nil && result.Plan != nil && !result.Plan.Empty():
+		description = fmt.Sprintf("drift: %d changes planned", len(result.Plan.Changes))
+	}
+
+	if runes := []rune(description); len(runes) > maxStatusDescription {
+		description = string(runes[:maxStatusDescription-3]) + "..."
+	}
+
+	return state, description
+}
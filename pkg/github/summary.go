@@ -0,0 +1,154 @@
+package github
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// maxSummaryNames is the number of resource names listed in a summary line before they are elided
+const maxSummaryNames = 5
+
+// SummaryEntry groups the same kind of change planned across repositories
+// Creations and deletions are grouped by resource kind, updates by resource kind and field
+type SummaryEntry struct {
+	Resource string
+	Action   Action
+	// Field is the updated field, it is empty for creations and deletions
+	Field string
+	// Names are the distinct resources changed (label names, branch names)
+	Names []string
+	// Repositories are the full names of the repositories with this change
+	Repositories []string
+}
+
+// Summarize groups the changes of many plans by kind, the repositories that failed planning are ignored
+func Summarize(results []RepositoryResult) []SummaryEntry {
+	entries := map[string]*SummaryEntry{}
+
+	for _, result := range results {
+		if result.Err != nil || result.Plan == nil {
+			continue
+		}
+
+		for _, change := range result.Plan.Changes {
+			fields := []string{""}
+
+			if change.Action == ActionUpdate && len(change.Fields) != 0 {
+				fields = fields[:0]
+
+				for _, field := range change.Fields {
+					fields = append(fields, field.Field)
+				}
+			}
+
+			for _, field := range fields {
+				key := change.Resource + "/" + string(change.Action) + "/" + field
+				entry, ok := entries[key]
+
+				if !ok {
+					entry = &SummaryEntry{Resource: change.Resource, Action: change.Action, Field: field}
+					entries[key] = entry
+				}
+
+				entry.Names = appendDistinct(entry.Names, change.Name)
+				entry.Repositories = appendDistinct(entry.Repositories, result.Repository)
+			}
+		}
+	}
+
+	summary := make([]SummaryEntry, 0, len(entries))
+
+	for _, entry := range entries {
+		sort.Strings(entry.Names)
+		sort.Strings(entry.Repositories)
+		summary = append(summary, *entry)
+	}
+
+	// The most widespread changes come first
+	sort.Slice(summary, func(i, j int) bool {
+		if len(summary[i].Repositories) != len(summary[j].Repositories) {
+			return len(summary[i].Repositories) > len(summary[j].Repositories)
+		}
+
+		return summary[i].key() < summary[j].key()
+	})
+
+	return summary
+}
+
+func (entry SummaryEntry) key() string {
+	return entry.Resource + "/" + string(entry.Action) + "/" + entry.Field
+}
+
+// String renders the entry as a single line (ex: - 3 labels deleted in 12 repositories: bug, wontfix, question)
+func (entry SummaryEntry) String() string {
+	repositories := fmt.Sprintf("%d repositories", len(entry.Repositories))
+
+	if len(entry.Repositories) == 1 {
+		repositories = entry.Repositories[0]
+	}
+
+	if entry.Action == ActionUpdate {
+		subject := entry.Resource
+
+		if entry.Field != "" && entry.Resource != ResourceTopics {
+			subject += " " + entry.Field
+		}
+
+		line := fmt.Sprintf("%s %s updated in %s", actionSymbols[entry.Action], subject, repositories)
+
+		// Repository settings are named after the repository itself
+		if names := summaryNames(entry.Names); names != "" && entry.Resource != ResourceRepository {
+			line += ": " + names
+		}
+
+		return line
+	}
+
+	return fmt.Sprintf("%s %d %s %sd in %s: %s", actionSymbols[entry.Action], len(entry.Names), entry.Resource, entry.Action, repositories, summaryNames(entry.Names))
+}
+
+// SummaryString renders the summary of many plans, one line per kind of change
+func SummaryString(summary []SummaryEntry) string {
+	builder := &strings.Builder{}
+
+	if len(summary) == 0 {
+		fmt.Fprint(builder, "Summary: no changes\n")
+		return builder.String()
+	}
+
+	fmt.Fprint(builder, "Summary:\n")
+
+	for _, entry := range summary {
+		fmt.Fprintf(builder, "  %s\n", entry)
+	}
+
+	return builder.String()
+}
+
+func summaryNames(names []string) string {
+	nonEmpty := []string{}
+
+	for _, name := range names {
+		if name != "" {
+			nonEmpty = append(nonEmpty, name)
+		}
+	}
+
+	if len(nonEmpty) > maxSummaryNames {
+		return strings.Join(nonEmpty[:maxSummaryNames], ", ") + fmt.Sprintf(" and %d more", len(nonEmpty)-maxSummaryNames)
+	}
+
+	return strings.Join(nonEmpty, ", ")
+}
+
+func appendDistinct(values []string, value string) []string {
+	for _, existing := range values {
+		if existing == value {
+			return values
+		}
+	}
+
+	return append(values, value)
+}
@@ -3,58 +3,275 @@ package github
 import (
 	"context"
 	"log"
-	"sort"
+	"strings"
 
-	"reflect"
-
-	"github.com/google/go-github/v28/github"
+	"github.com/google/go-github/v75/github"
 	"github.com/pkg/errors"
 )
 
-func (client *Client) updateTopicsSettings(disabled bool, owner, name string, githubTopics, topics []string) error {
-	if disabled {
-		log.Print("[INFO] Skipping disabled repository topics\n")
-		return nil
+// nolint:gochecknoglobals
+var resourceErrors = map[string]string{
+	ResourceRepository:           "Error updating repository settings",
+	ResourceLabels:               "Error updating repository labels",
+	ResourceBranches:             "Error updating repository branches protection",
+	ResourceWebhooks:             "Error updating repository webhooks",
+	ResourceTopics:               "Error updating repository topics",
+	ResourceCollaborators:        "Error updating repository collaborators",
+	ResourceTeams:                "Error updating repository teams",
+	ResourceSecrets:              "Error updating repository secrets",
+	ResourceVariables:            "Error updating repository variables",
+	ResourceDependabotSecrets:    "Error updating repository dependabot secrets",
+	ResourceEnvironments:         "Error updating repository environments",
+	ResourceEnvironmentSecrets:   "Error updating repository environment secrets",
+	ResourceEnvironmentVariables: "Error updating repository environment variables",
+	ResourceFiles:                "Error updating repository files",
+	ResourceRulesets:             "Error updating repository rulesets",
+}
+
+// Result is the outcome of applying a plan to a repository
+type Result struct {
+	Repository string
+	// Applied are the changes made on github
+	Applied []Change
+	// Skipped are the changes not attempted because their resource kind kept failing or the apply stopped before them
+	Skipped []Change
+	// Failed are the changes github rejected, the apply stops at the first one
+	Failed []Change
+	// Reported are the changes left unapplied since the enforcement of their resource is report
+	Reported []Change
+	// Unverified are the applied changes github still did not reflect when the repository was verified after apply
+	Unverified []Change
+}
+
+// Changed returns true when at least one change was made on github
+func (result *Result) Changed() bool {
+	return result != nil && len(result.Applied) != 0
+}
+
+// ApplyPlan executes the changes of a plan computed by Plan and fails when changes were skipped
+func (client *Client) ApplyPlan(ctx context.Context, plan *Plan) (*Result, error) {
+	result, err := client.executePlan(ctx, plan, nil)
+
+	if err == nil {
+		err = skippedError(result.Skipped)
 	}
 
-	sort.Strings(githubTopics)
-	sort.Strings(topics)
+	return result, err
+}
 
-	if reflect.DeepEqual(githubTopics, topics) {
-		return nil
+// executePlan applies a plan and records the outcome in the stats
+// Changes skipped after repeated server failures are not an error, they are listed in the result
+func (client *Client) executePlan(ctx context.Context, plan *Plan, report reporter) (*Result, error) {
+	stats.Add(StatApplies, 1)
+
+	result := &Result{
+		Repository: plan.target(),
+		Applied:    []Change{},
+		Skipped:    []Change{},
+		Failed:     []Change{},
+		Reported:   []Change{},
+		Unverified: []Change{},
 	}
 
-	log.Print("[INFO] Updating repository topics\n")
+	err := client.applyPlan(ctx, plan, report, result)
+
+	if client.verify && plan.settings != nil && len(result.Applied) != 0 {
+		result.Unverified = client.verifyApplied(ctx, plan, result.Applied)
+	}
+
+	if err != nil || len(result.Skipped) != 0 {
+		stats.Add(StatApplyFailures, 1)
+	}
 
-	_, _, err := client.github.Repositories.ReplaceAllTopics(context.Background(), owner, name, topics)
+	client.rememberApplied(ctx, plan, result, err)
+	client.rememberManaged(plan, result)
+
+	return result, err
+}
+
+// applyPlan executes the changes of a plan and records each of them in the result
+func (client *Client) applyPlan(ctx context.Context, plan *Plan, report reporter, result *Result) error {
+	err := client.checkManagedBy(plan)
 
 	if err != nil {
-		return errors.Wrap(err, "Error updating repository topics\n")
+		result.Skipped = append(result.Skipped, plan.Changes...)
+		return err
+	}
+
+	branchesToCreate := []string{}
+
+	// Deletions are listed before anything is changed
+	for _, deletion := range plan.Deletions() {
+		if rationale := deletion.Rationale(); rationale != "" {
+			log.Printf("[INFO] Planned deletion of %s %s on %s/%s for %s\n", deletion.Resource, deletion.Name, plan.Owner, plan.Name, rationale)
+			continue
+		}
+
+		log.Printf("[INFO] Planned deletion of %s %s on %s/%s\n", deletion.Resource, deletion.Name, plan.Owner, plan.Name)
+	}
+
+	for _, change := range plan.Changes {
+		if change.Resource == ResourceBranches && change.Action == ActionCreate && !change.ReportOnly {
+			branchesToCreate = append(branchesToCreate, change.Name)
+		}
+	}
+
+	for i, change := range plan.Changes {
+		if ctx.Err() != nil {
+			result.Skipped = append(result.Skipped, plan.Changes[i:]...)
+			return ctx.Err()
+		}
+
+		if change.ReportOnly {
+			log.Printf("[INFO] Reporting drift of %s %s without applying it, its enforcement is report\n", change.Resource, change.Name)
+			result.Reported = append(result.Reported, change)
+
+			continue
+		}
+
+		if client.breaker.open(change.Resource) {
+			log.Printf("[WARN] Skipping %s %s after repeated server failures\n", change.Resource, change.Name)
+			result.Skipped = append(result.Skipped, change)
+			continue
+		}
+
+		// New branches are pushed together before their protection is applied
+		if len(branchesToCreate) != 0 && change.Resource == ResourceBranches {
+			err := client.runChange(ctx, ResourceBranches, func(ctx context.Context) error {
+				return client.createBranches(ctx, report, plan.Owner, plan.Name, branchesToCreate)
+			})
+
+			if err != nil {
+				result.Failed = append(result.Failed, change)
+				result.Skipped = append(result.Skipped, plan.Changes[i+1:]...)
+
+				return errors.Wrap(err, resourceErrors[ResourceBranches])
+			}
+
+			branchesToCreate = nil
+		}
+
+		err := client.runChange(ctx, change.Resource, func(ctx context.Context) error {
+			return client.applyChange(ctx, report.forChange(change), plan.Owner, plan.Name, change)
+		})
+
+		if err != nil {
+			result.Failed = append(result.Failed, change)
+			result.Skipped = append(result.Skipped, plan.Changes[i+1:]...)
+
+			return errors.Wrap(err, resourceErrors[change.Resource])
+		}
+
+		result.Applied = append(result.Applied, change)
+
+		if rationale := change.Rationale(); rationale != "" {
+			log.Printf("[INFO] Applied %s on %s/%s for %s\n", strings.TrimSpace(change.Resource+" "+change.Name), plan.Owner, plan.Name, rationale)
+		}
+
+		// github fills new repositories with defaults, the rest of the settings is planned again once it exists
+		if change.Resource == ResourceRepository && change.Action == ActionCreate && plan.settings != nil {
+			created, err := client.Plan(ctx, plan.settings)
+
+			if err != nil {
+				return errors.Wrap(err, "Error planning the created repository")
+			}
+
+			return client.applyPlan(ctx, created, report, result)
+		}
 	}
 
 	return nil
 }
 
-func (client *Client) updateRepoSettings(disabled bool, owner, name string, githubRepo, repo repository) error {
-	if disabled {
-		log.Print("[INFO] Skipping disabled repository settings\n")
-		return nil
+// runChange bounds a change with the resource timeout and records its outcome in the circuit breaker
+func (client *Client) runChange(ctx context.Context, resource string, run func(context.Context) error) error {
+	ctx = withRetries(ctx)
+
+	if client.resourceTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, client.resourceTimeout)
+		defer cancel()
 	}
 
-	if reflect.DeepEqual(githubRepo, repo) {
+	err := run(ctx)
+	client.breaker.record(resource, err)
+
+	return err
+}
+
+func skippedError(skipped []Change) error {
+	if len(skipped) == 0 {
 		return nil
 	}
 
-	log.Print("[INFO] Updating repository settings\n")
+	return errors.Errorf("Skipped %d changes after repeated server failures", len(skipped))
+}
+
+func (client *Client) applyChange(ctx context.Context, report reporter, owner, name string, change Change) error {
+	switch change.Resource {
+	case ResourceRepository:
+		if change.Action == ActionCreate {
+			return client.createRepository(ctx, report, owner, name, change.desired.(repository))
+		}
+
+		return client.updateRepoSettings(ctx, report, owner, name, change.desired.(repository))
+	case ResourceLabels:
+		return client.updateLabel(ctx, report, owner, name, change.Action, change.current.(label), change.desired.(label))
+	case ResourceBranches:
+		return client.updateBranch(ctx, report, owner, name, change.Action, change.current.(branch), change.desired.(branch))
+	case ResourceWebhooks:
+		return client.updateWebhook(ctx, report, owner, name, change.Action, change.current.(webhook), change.desired.(webhook))
+	case ResourceTopics:
+		return client.updateTopicsSettings(ctx, report, owner, name, change.desired.([]string))
+	case ResourceCollaborators:
+		return client.updateCollaborator(ctx, report, owner, name, change.Action, change.current.(collaborator), change.desired.(collaborator))
+	case ResourceTeams:
+		return client.updateTeam(ctx, report, owner, name, change.Action, change.current.(team), change.desired.(team))
+	case ResourceSecrets:
+		return client.updateSecret(ctx, report, owner, name, change.Action, change.current.(secret), change.desired.(secret))
+	case ResourceVariables:
+		return client.updateVariable(ctx, report, owner, name, change.Action, change.current.(variable), change.desired.(variable))
+	case ResourceDependabotSecrets:
+		return client.updateDependabotSecret(ctx, report, owner, name, change.Action, change.current.(secret), change.desired.(secret))
+	case ResourceEnvironments:
+		return client.updateEnvironment(ctx, report, owner, name, change.Action, change.current.(environment), change.desired.(environment))
+	case ResourceEnvironmentSecrets:
+		return client.updateEnvironmentSecret(ctx, report, owner, name, change.Action, change.current.(environmentSecret), change.desired.(environmentSecret))
+	case ResourceEnvironmentVariables:
+		return client.updateEnvironmentVariable(ctx, report, owner, name, change.Action, change.current.(environmentVariable), change.desired.(environmentVariable))
+	case ResourceRulesets:
+		return client.updateRuleset(ctx, report, owner, name, change.Action, change.current.(ruleset), change.desired.(ruleset))
+	case ResourceFiles:
+		return client.updateFile(ctx, report, owner, name, change.desired.(file))
+	case ResourceSecurityManagers:
+		return client.updateSecurityManager(ctx, report, owner, change.Action, change.current.(securityManager), change.desired.(securityManager))
+	}
+
+	return errors.Errorf("Unknown resource %s", change.Resource)
+}
+
+func (client *Client) updateTopicsSettings(ctx context.Context, report reporter, owner, name string, topics []string) error {
+	report.changed(ResourceTopics, "Updating repository topics\n")
+
+	_, _, err := client.github.Repositories.ReplaceAllTopics(ctx, owner, name, topics)
+
+	if err != nil {
+		return errors.Wrap(err, "Error updating repository topics\n")
+	}
+
+	return nil
+}
+
+func (client *Client) updateRepoSettings(ctx context.Context, report reporter, owner, name string, repo repository) error {
+	report.changed(ResourceRepository, "Updating repository settings\n")
 
-	_, _, err := client.github.Repositories.Edit(context.Background(), owner, name, &github.Repository{
+	_, _, err := client.github.Repositories.Edit(ctx, owner, name, &github.Repository{
 		Description:      github.String(repo.Description),
 		Homepage:         github.String(repo.Homepage),
 		DefaultBranch:    github.String(repo.DefaultBranch),
 		Private:          github.Bool(repo.Private),
 		HasIssues:        github.Bool(repo.HasIssues),
 		HasProjects:      github.Bool(repo.HasProjects),
-		HasPages:         github.Bool(repo.HasPages),
 		HasWiki:          github.Bool(repo.HasWiki),
 		HasDownloads:     github.Bool(repo.HasDownloads),
 		IsTemplate:       github.Bool(repo.IsTemplate),
@@ -62,6 +279,13 @@ func (client *Client) updateRepoSettings(disabled bool, owner, name string, gith
 		AllowSquashMerge: github.Bool(repo.AllowSquashMerge),
 		AllowMergeCommit: github.Bool(repo.AllowMergeCommit),
 		AllowRebaseMerge: github.Bool(repo.AllowRebaseMerge),
+		Visibility:       optionalString(string(repo.Visibility)),
+
+		MergeCommitTitle:         optionalString(string(repo.MergeCommitTitle)),
+		MergeCommitMessage:       optionalString(string(repo.MergeCommitMessage)),
+		SquashMergeCommitTitle:   optionalString(string(repo.SquashMergeCommitTitle)),
+		SquashMergeCommitMessage: optionalString(string(repo.SquashMergeCommitMessage)),
+		SecurityAndAnalysis:      repo.Security.githubSecurity(),
 	})
 
 	if err != nil {
@@ -71,65 +295,35 @@ func (client *Client) updateRepoSettings(disabled bool, owner, name string, gith
 	return nil
 }
 
-func (client *Client) updateLabels(disabled bool, owner, name string, githubLabels, labelsSettings []label) error {
-	if disabled {
-		log.Print("[INFO] Skipping disabled repository labels\n")
-		return nil
-	}
-
-	labelsToCreate := []label{}
-	labelsToUpdate := []label{}
-	deleteLabelMap := map[string]label{}
-
-	for _, githubLabel := range githubLabels {
-		deleteLabelMap[githubLabel.Name] = githubLabel
-	}
-
-	for _, labelSetting := range labelsSettings {
-		githubLabel, ok := deleteLabelMap[labelSetting.Name]
-
-		if !ok {
-			labelsToCreate = append(labelsToCreate, labelSetting)
-		} else {
-			delete(deleteLabelMap, labelSetting.Name)
-
-			if labelSetting != githubLabel {
-				labelsToUpdate = append(labelsToUpdate, labelSetting)
-			}
-		}
-	}
-
-	for labelName := range deleteLabelMap {
-		log.Printf("[INFO] Deleting label %s\n", labelName)
+func (client *Client) updateLabel(ctx context.Context, report reporter, owner, name string, action Action, githubLabel, labelSetting label) error {
+	switch action {
+	case ActionDelete:
+		report.changed(ResourceLabels, "Deleting label %s\n", githubLabel.Name)
 
-		_, err := client.github.Issues.DeleteLabel(context.Background(), owner, name, labelName)
+		_, err := client.github.Issues.DeleteLabel(ctx, owner, name, githubLabel.Name)
 
 		if err != nil {
 			return errors.Wrap(err, "Error deleting a label\n")
 		}
-	}
+	case ActionCreate:
+		report.changed(ResourceLabels, "Creating label %s\n", labelSetting.Name)
 
-	for _, newLabel := range labelsToCreate {
-		log.Printf("[INFO] Creating label %s\n", newLabel.Name)
-
-		_, _, err := client.github.Issues.CreateLabel(context.Background(), owner, name, &github.Label{
-			Name:        github.String(newLabel.Name),
-			Color:       github.String(newLabel.Color),
-			Description: github.String(newLabel.Description),
+		_, _, err := client.github.Issues.CreateLabel(ctx, owner, name, &github.Label{
+			Name:        github.String(labelSetting.Name),
+			Color:       github.String(labelSetting.Color),
+			Description: github.String(labelSetting.Description),
 		})
 
 		if err != nil {
 			return errors.Wrap(err, "Error creating a label\n")
 		}
-	}
+	case ActionUpdate:
+		report.changed(ResourceLabels, "Updating label %s\n", labelSetting.Name)
 
-	for _, updateLabel := range labelsToUpdate {
-		log.Printf("[INFO] Updating label %s\n", updateLabel.Name)
-
-		_, _, err := client.github.Issues.EditLabel(context.Background(), owner, name, updateLabel.Name, &github.Label{
-			Name:        github.String(updateLabel.Name),
-			Color:       github.String(updateLabel.Color),
-			Description: github.String(updateLabel.Description),
+		_, _, err := client.github.Issues.EditLabel(ctx, owner, name, labelSetting.Name, &github.Label{
+			Name:        github.String(labelSetting.Name),
+			Color:       github.String(labelSetting.Color),
+			Description: github.String(labelSetting.Description),
 		})
 
 		if err != nil {
@@ -140,155 +334,159 @@ func (client *Client) updateLabels(disabled bool, owner, name string, githubLabe
 	return nil
 }
 
-func (client *Client) updateBranchSettings(disabled bool, owner string, name string, githubBranches []branch, branchesSettings []branch) error {
-	if disabled {
-		log.Print("[INFO] Skipping disabled repository branches\n")
-		return nil
-	}
+func (client *Client) createBranches(ctx context.Context, report reporter, owner, name string, branches []string) error {
+	report.changed(ResourceBranches, "Creating new branches\n")
 
-	branchesToUpdate := []branch{}
-	branchesToCreate := []string{}
-	deleteBranchesMap := map[string]branch{}
+	token, err := client.currentToken()
 
-	// Add all github branch that exist except the branch without protection
-	for _, githubBranch := range githubBranches {
-		deleteBranchesMap[githubBranch.Name] = githubBranch
+	if err != nil {
+		return err
 	}
 
-	for _, branchSettings := range branchesSettings {
-		githubBranch, ok := deleteBranchesMap[branchSettings.Name]
+	err = client.createBranch(ctx, branches, fmtGithubURL(client.host, owner, name, token))
 
-		if !ok {
-			branchesToCreate = append(branchesToCreate, branchSettings.Name)
-			branchesToUpdate = append(branchesToUpdate, branchSettings)
-		} else {
-			delete(deleteBranchesMap, branchSettings.Name)
-
-			if !reflect.DeepEqual(githubBranch, branchSettings) {
-				branchesToUpdate = append(branchesToUpdate, branchSettings)
-			}
-		}
+	if err != nil {
+		return errors.Wrap(err, "Error creating branches\n")
 	}
 
-	if len(branchesToCreate) != 0 {
-		log.Print("[INFO] Creating new branches\n")
-		err := client.createBranch(branchesToCreate, fmtGithubURL(owner, name, client.token))
+	return nil
+}
+
+func (client *Client) updateBranch(ctx context.Context, report reporter, owner, name string, action Action, githubBranch, branchSettings branch) error {
+	if action == ActionDelete {
+		report.changed(ResourceBranches, "Removing branch protection for %s\n", branchSettings.Name)
+
+		_, err := client.github.Repositories.RemoveBranchProtection(ctx, owner, name, branchSettings.Name)
 
 		if err != nil {
-			return errors.Wrap(err, "Error creating branches\n")
+			return errors.Wrap(err, "Error removing branch protection\n")
 		}
-	}
 
-	for branchToDeleteName, branchToDelete := range deleteBranchesMap {
-		if !branchToDelete.Protection.Enabled {
-			continue
-		}
+		return nil
+	}
 
-		log.Printf("[INFO] Removing branch protection for %s\n", branchToDeleteName)
+	report.changed(ResourceBranches, "Updating branch protection for %s\n", branchSettings.Name)
 
-		_, err := client.github.Repositories.RemoveBranchProtection(context.Background(), owner, name, branchToDeleteName)
+	var requiredReviews *github.PullRequestReviewsEnforcementRequest
 
-		if err != nil {
-			return errors.Wrap(err, "Error removing branch protection\n")
+	if branchSettings.Protection.RequiredApprovingReviewCount.RequiredApprovingReviewCount == 0 {
+		requiredReviews = nil
+	} else {
+		requiredReviews = &github.PullRequestReviewsEnforcementRequest{
+			DismissStaleReviews:          branchSettings.Protection.RequiredApprovingReviewCount.DismissStaleReviews,
+			RequireCodeOwnerReviews:      branchSettings.Protection.RequiredApprovingReviewCount.RequireCodeOwnerReviews,
+			RequiredApprovingReviewCount: branchSettings.Protection.RequiredApprovingReviewCount.RequiredApprovingReviewCount,
 		}
-	}
 
-	for _, branchSettings := range branchesToUpdate {
-		log.Printf("[INFO] Updating branch protection for %s\n", branchSettings.Name)
+		if dismissal := branchSettings.Protection.RequiredApprovingReviewCount.DismissalRestrictions; dismissal != nil {
+			users := append([]string{}, dismissal.Users...)
+			teams := append([]string{}, dismissal.Teams...)
+			apps := append([]string{}, dismissal.Apps...)
 
-		var requiredReviews *github.PullRequestReviewsEnforcementRequest
+			requiredReviews.DismissalRestrictionsRequest = &github.DismissalRestrictionsRequest{
+				Users: &users,
+				Teams: &teams,
+				Apps:  &apps,
+			}
+		}
 
-		if branchSettings.Protection.RequiredApprovingReviewCount.RequiredApprovingReviewCount == 0 {
-			requiredReviews = nil
-		} else {
-			requiredReviews = &github.PullRequestReviewsEnforcementRequest{
-				DismissStaleReviews:          branchSettings.Protection.RequiredApprovingReviewCount.DismissStaleReviews,
-				RequireCodeOwnerReviews:      branchSettings.Protection.RequiredApprovingReviewCount.RequireCodeOwnerReviews,
-				RequiredApprovingReviewCount: branchSettings.Protection.RequiredApprovingReviewCount.RequiredApprovingReviewCount,
+		if bypass := branchSettings.Protection.RequiredApprovingReviewCount.BypassPullRequestAllowances; bypass != nil {
+			requiredReviews.BypassPullRequestAllowancesRequest = &github.BypassPullRequestAllowancesRequest{
+				Users: append([]string{}, bypass.Users...),
+				Teams: append([]string{}, bypass.Teams...),
+				Apps:  append([]string{}, bypass.Apps...),
 			}
 		}
+	}
 
-		_, _, err := client.github.Repositories.UpdateBranchProtection(context.Background(), owner, name, branchSettings.Name, &github.ProtectionRequest{
-			EnforceAdmins: branchSettings.Protection.EnforceAdmins,
-			RequiredStatusChecks: &github.RequiredStatusChecks{
-				Strict:   branchSettings.Protection.RequiredStatusChecks.Strict,
-				Contexts: branchSettings.Protection.RequiredStatusChecks.Contexts,
-			},
-			RequiredPullRequestReviews: requiredReviews,
-		})
+	var pushRestrictions *github.BranchRestrictionsRequest
 
-		if err != nil {
-			return errors.Wrap(err, "Error updating branch protection\n")
+	if restrictions := branchSettings.Protection.Restrictions; restrictions != nil {
+		pushRestrictions = &github.BranchRestrictionsRequest{
+			Users: append([]string{}, restrictions.Users...),
+			Teams: append([]string{}, restrictions.Teams...),
+			Apps:  append([]string{}, restrictions.Apps...),
 		}
 	}
 
-	return nil
-}
+	contexts := append([]string{}, branchSettings.Protection.RequiredStatusChecks.Contexts...)
+
+	_, _, err := client.github.Repositories.UpdateBranchProtection(ctx, owner, name, branchSettings.Name, &github.ProtectionRequest{
+		EnforceAdmins: branchSettings.Protection.EnforceAdmins,
+		RequiredStatusChecks: &github.RequiredStatusChecks{
+			Strict:   branchSettings.Protection.RequiredStatusChecks.Strict,
+			Contexts: &contexts,
+		},
+		RequiredPullRequestReviews:     requiredReviews,
+		Restrictions:                   pushRestrictions,
+		RequireLinearHistory:           github.Bool(branchSettings.Protection.RequiredLinearHistory),
+		AllowForcePushes:               github.Bool(branchSettings.Protection.AllowForcePushes),
+		AllowDeletions:                 github.Bool(branchSettings.Protection.AllowDeletions),
+		RequiredConversationResolution: github.Bool(branchSettings.Protection.RequiredConversationResolution),
+	})
 
-func (client *Client) updateWebhooks(disabled bool, owner string, name string, githubWebhooks []webhook, webhooksSettings []webhook) error {
-	if disabled {
-		log.Print("[INFO] Skipping disabled repository webhooks\n")
-		return nil
+	if err != nil {
+		return errors.Wrap(err, "Error updating branch protection\n")
 	}
 
-	webhooksToUpdate := []webhook{}
-	deleteWebhooksMap := map[string]webhook{}
-
-	for _, githubWebhook := range githubWebhooks {
-		deleteWebhooksMap[githubWebhook.URL] = githubWebhook
+	// Signed commits are managed by a separate endpoint, it is only called when the setting changes
+	if githubBranch.Protection.RequiredSignatures == branchSettings.Protection.RequiredSignatures {
+		return nil
 	}
 
-	for _, webhookSettings := range webhooksSettings {
-		githubWebhook, ok := deleteWebhooksMap[webhookSettings.URL]
+	if branchSettings.Protection.RequiredSignatures {
+		_, _, err = client.github.Repositories.RequireSignaturesOnProtectedBranch(ctx, owner, name, branchSettings.Name)
+	} else {
+		_, err = client.github.Repositories.OptionalSignaturesOnProtectedBranch(ctx, owner, name, branchSettings.Name)
+	}
 
-		if !ok {
-			log.Printf("[INFO] Creating new webhook %s\n", webhookSettings.URL)
+	if err != nil {
+		return errors.Wrap(err, "Error updating branch required signatures\n")
+	}
 
-			_, _, err := client.github.Repositories.CreateHook(context.Background(), owner, name, &github.Hook{
-				Events: webhookSettings.Events,
-				Active: github.Bool(true),
-				Config: map[string]interface{}{
-					"content_type": webhookSettings.ContentType,
-					"secret":       webhookSettings.Secret,
-					"url":          webhookSettings.URL,
-				},
-			})
+	return nil
+}
 
-			if err != nil {
-				return errors.Wrap(err, "Error creating webhook\n")
-			}
-		} else {
-			delete(deleteWebhooksMap, webhookSettings.URL)
+func (client *Client) updateWebhook(ctx context.Context, report reporter, owner, name string, action Action, githubWebhook, webhookSettings webhook) error {
+	switch action {
+	case ActionCreate:
+		report.changed(ResourceWebhooks, "Creating new webhook %s\n", webhookSettings.URL)
 
-			webhookSettings.ID = githubWebhook.ID
-			githubWebhook.Secret = webhookSettings.Secret
+		hook, _, err := client.github.Repositories.CreateHook(ctx, owner, name, &github.Hook{
+			Events: webhookSettings.Events,
+			Active: github.Bool(true),
+			Config: &github.HookConfig{
+				ContentType: github.String(string(webhookSettings.ContentType)),
+				Secret:      github.String(webhookSettings.Secret),
+				URL:         github.String(webhookSettings.URL),
+			},
+		})
 
-			if !reflect.DeepEqual(githubWebhook, webhookSettings) {
-				webhooksToUpdate = append(webhooksToUpdate, webhookSettings)
-			}
+		if err != nil {
+			return errors.Wrap(err, "Error creating webhook\n")
 		}
-	}
 
-	for _, webhookToDelete := range deleteWebhooksMap {
-		log.Printf("[INFO] Removing webhook %s\n", webhookToDelete.URL)
+		if webhookSettings.MatchBy == matchByName && client.state != nil {
+			client.state.setWebhookID(owner+"/"+name, webhookSettings.Name, hook.GetID())
+		}
+	case ActionDelete:
+		report.changed(ResourceWebhooks, "Removing webhook %s\n", githubWebhook.URL)
 
-		_, err := client.github.Repositories.DeleteHook(context.Background(), owner, name, webhookToDelete.ID)
+		_, err := client.github.Repositories.DeleteHook(ctx, owner, name, githubWebhook.ID)
 
 		if err != nil {
 			return errors.Wrap(err, "Error removing webhook\n")
 		}
-	}
-
-	for _, webhookToUpdate := range webhooksToUpdate {
-		log.Printf("[INFO] Updating webhook %s\n", webhookToUpdate.URL)
+	case ActionUpdate:
+		report.changed(ResourceWebhooks, "Updating webhook %s\n", webhookSettings.URL)
 
-		_, _, err := client.github.Repositories.EditHook(context.Background(), owner, name, webhookToUpdate.ID, &github.Hook{
-			Events: webhookToUpdate.Events,
+		_, _, err := client.github.Repositories.EditHook(ctx, owner, name, webhookSettings.ID, &github.Hook{
+			Events: webhookSettings.Events,
 			Active: github.Bool(true),
-			Config: map[string]interface{}{
-				"content_type": webhookToUpdate.ContentType,
-				"secret":       webhookToUpdate.Secret,
-				"url":          webhookToUpdate.URL,
+			Config: &github.HookConfig{
+				ContentType: github.String(string(webhookSettings.ContentType)),
+				Secret:      github.String(webhookSettings.Secret),
+				URL:         github.String(webhookSettings.URL),
 			},
 		})
 
@@ -0,0 +1,128 @@
+package github
+
+import (
+	"context"
+	"testing"
+)
+
+// convergingSettings declares every kind of resource apply manages
+const convergingSettings = `
+repository:
+  owner: acme
+  name: api
+  description: The api
+  homepage: https://acme.dev
+  defaultbranch: main
+  hasissues: true
+  haswiki: false
+  allowsquashmerge: true
+  visibility: private
+  squashmergecommittitle: COMMIT_OR_PR_TITLE
+  squashmergecommitmessage: PR_BODY
+labels:
+  - name: bug
+    color: d73a4a
+    description: Something is broken
+  - name: enhancement
+    color: a2eeef
+branches:
+  - name: main
+    protection:
+      enforceadmins: true
+      requiredlinearhistory: true
+      requiredapprovingreviewcount: {requiredapprovingreviewcount: 2, dismissstalereviews: true}
+      requiredstatuschecks: {strict: true, contexts: [ci, lint]}
+webhooks:
+  - url: https://hooks.acme.dev/github
+    contenttype: json
+    events: [push, pull_request]
+topics: [go, api]
+collaborators:
+  - username: alice
+    permission: write
+  - username: bob
+    permission: Admin
+teams:
+  - slug: platform
+    permission: maintain
+secrets:
+  - name: deploy_token
+variables:
+  - name: region
+    value: eu
+environments:
+  - name: production
+    waittimer: 5
+    reviewers:
+      users: [alice]
+      teams: [platform]
+    deploymentbranchpolicy:
+      protectedbranches: true
+    secrets:
+      - name: deploy_key
+    variables:
+      - name: cluster
+        value: prod-eu
+  - name: staging
+rulesets:
+  - name: release
+    branches: ["release/**", "~DEFAULT_BRANCH"]
+    rules:
+      deletion: true
+      nonfastforward: true
+      pullrequest:
+        required: true
+        requiredapprovingreviewcount: 1
+      requiredstatuschecks:
+        strict: true
+        contexts: [ci]
+`
+
+func TestApplyThenPlanHasNoChanges(t *testing.T) {
+	server, client := newTestClient(t, WithSecretValues(map[string]string{"DEPLOY_TOKEN": "token", "DEPLOY_KEY": "key"}))
+	server.AddRepository("acme", "api")
+	settings := settingsFromYAML(t, convergingSettings)
+
+	result, err := client.Apply(context.Background(), settings)
+
+	if err != nil {
+		t.Fatalf("Error applying settings: %v", err)
+	}
+
+	if len(result.Applied) == 0 || len(result.Failed) != 0 || len(result.Skipped) != 0 {
+		t.Fatalf("Apply made %d changes, %d failed and %d were skipped", len(result.Applied), len(result.Failed), len(result.Skipped))
+	}
+
+	repo := server.Repository("acme", "api")
+
+	if len(repo.Rulesets()) != 1 || len(repo.Environments) != 2 || !repo.Protected("main") {
+		t.Fatalf("Apply left %d rulesets, %d environments and main protected %t", len(repo.Rulesets()), len(repo.Environments), repo.Protected("main"))
+	}
+
+	plan := planOf(t, client, settingsFromYAML(t, convergingSettings))
+
+	if !plan.Empty() {
+		t.Errorf("Plan after apply has changes:\n%s", plan)
+	}
+}
+
+func TestApplyIsIdempotent(t *testing.T) {
+	server, client := newTestClient(t, WithSecretValues(map[string]string{"DEPLOY_TOKEN": "token", "DEPLOY_KEY": "key"}))
+	server.AddRepository("acme", "api")
+
+	_, err := client.Apply(context.Background(), settingsFromYAML(t, convergingSettings))
+
+	if err != nil {
+		t.Fatalf("Error applying settings: %v", err)
+	}
+
+	result, err := client.Apply(context.Background(), settingsFromYAML(t, convergingSettings))
+
+	if err != nil {
+		t.Fatalf("Error applying settings again: %v", err)
+	}
+
+	if result.Changed() {
+		t.Errorf("Second apply made the changes %v", result.Applied)
+	}
+}
@@ -0,0 +1,351 @@
+package github
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/michaelmass/github-settings/pkg/config"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// maxRequiredApprovingReviewCount is the highest number of approving reviews github can require
+const maxRequiredApprovingReviewCount = 6
+
+// nolint:gochecknoglobals
+var labelColor = regexp.MustCompile("^[0-9a-fA-F]{6}$")
+
+// Problem is an invalid value found while validating a settings file
+type Problem struct {
+	Line    int
+	Column  int
+	Path    string
+	Message string
+}
+
+func (problem Problem) String() string {
+	if problem.Path == "" {
+		return fmt.Sprintf("line %d: %s", problem.Line, problem.Message)
+	}
+
+	return fmt.Sprintf("line %d: %s: %s", problem.Line, problem.Path, problem.Message)
+}
+
+// multiSchema is the layout of a settings file targeting many repositories, as validated
+type multiSchema struct {
+	Org          OrgSettings
+	Defaults     Settings
+	Repositories []Settings
+	Anchors      map[string]interface{}
+}
+
+// Validate strictly parses the content of a settings file and returns every problem found with its line
+// Unlike GetSettingsFromBytes it rejects unknown fields and checks the values github would refuse
+// The file is validated as written, the files it extends are not fetched and values with variables are not checked
+func Validate(content []byte) ([]Problem, error) {
+	var document yaml.Node
+	err := yaml.Unmarshal(content, &document)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Error while unmarshal settings")
+	}
+
+	if len(document.Content) == 0 {
+		return nil, nil
+	}
+
+	root := resolveAlias(document.Content[0])
+	schema := reflect.TypeOf(Settings{})
+
+	if isMultiNode(root) {
+		schema = reflect.TypeOf(multiSchema{})
+	}
+
+	problems := validateNode(root, schema, "")
+	problems = append(problems, lintSecrets(root, "")...)
+
+	sort.SliceStable(problems, func(i, j int) bool {
+		return problems[i].Line < problems[j].Line
+	})
+
+	return problems, nil
+}
+
+// isMultiNode returns true when the document targets many repositories
+func isMultiNode(node *yaml.Node) bool {
+	if node.Kind != yaml.MappingNode {
+		return false
+	}
+
+	for i := 0; i < len(node.Content); i += 2 {
+		switch node.Content[i].Value {
+		case "org", "defaults", "repositories":
+			return true
+		}
+	}
+
+	return false
+}
+
+func validateNode(node *yaml.Node, typ reflect.Type, path string) []Problem {
+	node = resolveAlias(node)
+
+	if node.Tag == "!!null" {
+		return nil
+	}
+
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	switch typ.Kind() {
+	case reflect.Struct:
+		// The org is its name alone or a mapping with its settings
+		if typ == reflect.TypeOf(OrgSettings{}) && node.Kind == yaml.ScalarNode {
+			return nil
+		}
+
+		if node.Kind != yaml.MappingNode {
+			return []Problem{newProblem(node, path, "expected a mapping")}
+		}
+
+		problems := validateFields(node, typ, path)
+
+		return append(problems, validateValue(node, typ, path)...)
+	case reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			return []Problem{newProblem(node, path, "expected a list")}
+		}
+
+		problems := []Problem{}
+
+		for i, item := range node.Content {
+			problems = append(problems, validateNode(item, typ.Elem(), fmt.Sprintf("%s[%d]", path, i))...)
+		}
+
+		return problems
+	case reflect.Map, reflect.Interface:
+		return nil
+	}
+
+	if node.Kind != yaml.ScalarNode {
+		return []Problem{newProblem(node, path, "expected a single value")}
+	}
+
+	// Variables are substituted when the file is loaded, their values are only known then
+	if strings.Contains(node.Value, "${") {
+		return nil
+	}
+
+	err := node.Decode(reflect.New(typ).Interface())
+
+	if _, ok := err.(*yaml.TypeError); ok {
+		return []Problem{newProblem(node, path, fmt.Sprintf("invalid value %q, expected a %s", node.Value, typ.Kind()))}
+	}
+
+	if err != nil {
+		return []Problem{newProblem(node, path, err.Error())}
+	}
+
+	return nil
+}
+
+// validateFields rejects the keys of a mapping that are not fields of the struct and validates the others
+func validateFields(node *yaml.Node, typ reflect.Type, path string) []Problem {
+	fields := map[string]reflect.StructField{}
+	names := []string{}
+
+	for _, field := range settingsFields(typ) {
+		name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+
+		fields[name] = field
+		names = append(names, name)
+	}
+
+	problems := []Problem{}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+
+		// Merge keys (<<: *name) bring the fields of another mapping
+		if key.Value == "<<" {
+			problems = append(problems, validateMerge(value, typ, path)...)
+			continue
+		}
+
+		// The directives composing the file are resolved by the config loader
+		if path == "" && (key.Value == config.ExtendsKey || key.Value == config.IncludeKey) {
+			continue
+		}
+
+		field, ok := fields[key.Value]
+
+		if !ok {
+			problems = append(problems, newProblem(key, path, fmt.Sprintf("unknown field %q, did you mean %q?", key.Value, closest(key.Value, names))))
+			continue
+		}
+
+		problems = append(problems, validateNode(value, field.Type, joinPath(path, key.Value))...)
+	}
+
+	return problems
+}
+
+func validateMerge(node *yaml.Node, typ reflect.Type, path string) []Problem {
+	node = resolveAlias(node)
+
+	if node.Kind != yaml.SequenceNode {
+		return validateFields(node, typ, path)
+	}
+
+	problems := []Problem{}
+
+	for _, item := range node.Content {
+		problems = append(problems, validateFields(resolveAlias(item), typ, path)...)
+	}
+
+	return problems
+}
+
+// validateValue checks the combinations of values github refuses or ignores
+func validateValue(node *yaml.Node, typ reflect.Type, path string) []Problem {
+	switch typ {
+	case reflect.TypeOf(label{}):
+		var value label
+
+		if node.Decode(&value) != nil {
+			return nil
+		}
+
+		if value.Color != "" && !strings.Contains(value.Color, "${") && !labelColor.MatchString(value.Color) {
+			return []Problem{newProblem(mappingValue(node, "color"), joinPath(path, "color"), fmt.Sprintf("invalid label color %q, expected 6 hexadecimal digits without # (ex: d73a4a)", value.Color))}
+		}
+	case reflect.TypeOf(protection{}):
+		var value protection
+
+		if node.Decode(&value) != nil {
+			return nil
+		}
+
+		return validateProtection(node, value, path)
+	case reflect.TypeOf(deploymentBranchPolicy{}):
+		var value deploymentBranchPolicy
+
+		if node.Decode(&value) != nil {
+			return nil
+		}
+
+		if value.ProtectedBranches && len(value.Branches) != 0 {
+			return []Problem{newProblem(mappingValue(node, "branches"), joinPath(path, "branches"), "branches cannot be set with protectedbranches, github accepts one deployment branch policy")}
+		}
+	case reflect.TypeOf(file{}):
+		var value file
+
+		if node.Decode(&value) != nil {
+			return nil
+		}
+
+		if value.Source != "" && value.Content != "" {
+			return []Problem{newProblem(mappingValue(node, "content"), joinPath(path, "content"), "content is ignored when source is set")}
+		}
+	case reflect.TypeOf(webhook{}):
+		var value webhook
+
+		if node.Decode(&value) != nil {
+			return nil
+		}
+
+		if value.MatchBy == matchByName && value.Name == "" {
+			return []Problem{newProblem(mappingValue(node, "matchby"), joinPath(path, "matchby"), "matchby name requires the name of the webhook")}
+		}
+	case reflect.TypeOf(bypassActor{}):
+		var value bypassActor
+
+		if node.Decode(&value) != nil {
+			return nil
+		}
+
+		actors := 0
+
+		for _, set := range []bool{value.App != "", value.Team != "", value.OrganizationAdmin} {
+			if set {
+				actors++
+			}
+		}
+
+		if actors != 1 {
+			return []Problem{newProblem(node, path, "expected exactly one of app, team or organizationadmin")}
+		}
+	}
+
+	return nil
+}
+
+func validateProtection(node *yaml.Node, value protection, path string) []Problem {
+	problems := []Problem{}
+	reviews := value.RequiredApprovingReviewCount
+	reviewsPath := joinPath(path, "requiredapprovingreviewcount")
+
+	if reviews.RequiredApprovingReviewCount < 0 || reviews.RequiredApprovingReviewCount > maxRequiredApprovingReviewCount {
+		problems = append(problems, newProblem(mappingValue(node, "requiredapprovingreviewcount", "requiredapprovingreviewcount"), joinPath(reviewsPath, "requiredapprovingreviewcount"), fmt.Sprintf("expected between 0 and %d approving reviews", maxRequiredApprovingReviewCount)))
+	}
+
+	if reviews.RequiredApprovingReviewCount == 0 && (reviews.DismissStaleReviews || reviews.RequireCodeOwnerReviews || reviews.DismissalRestrictions != nil || reviews.BypassPullRequestAllowances != nil) {
+		problems = append(problems, newProblem(mappingValue(node, "requiredapprovingreviewcount"), reviewsPath, "dismissstalereviews, requirecodeownerreviews, dismissalrestrictions and bypasspullrequestallowances are ignored by github unless requiredapprovingreviewcount is at least 1"))
+	}
+
+	if value.RequiredStatusChecks.Strict && len(value.RequiredStatusChecks.Contexts) == 0 {
+		problems = append(problems, newProblem(mappingValue(node, "requiredstatuschecks"), joinPath(path, "requiredstatuschecks"), "strict requires at least one status check context"))
+	}
+
+	return problems
+}
+
+// mappingValue returns the node of a nested key of a mapping, or the deepest mapping found to locate a problem
+func mappingValue(node *yaml.Node, keys ...string) *yaml.Node {
+	for _, key := range keys {
+		found := false
+
+		for i := 0; i+1 < len(node.Content) && node.Kind == yaml.MappingNode; i += 2 {
+			if node.Content[i].Value == key {
+				node = resolveAlias(node.Content[i+1])
+				found = true
+
+				break
+			}
+		}
+
+		if !found {
+			return node
+		}
+	}
+
+	return node
+}
+
+func resolveAlias(node *yaml.Node) *yaml.Node {
+	for node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+
+	return node
+}
+
+func newProblem(node *yaml.Node, path string, message string) Problem {
+	return Problem{Line: node.Line, Column: node.Column, Path: path, Message: message}
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+
+	return path + "." + key
+}
@@ -0,0 +1,86 @@
+package github
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// verifyAttempts is the number of times a repository is planned again before its applied changes are reported as unverified
+const verifyAttempts = 3
+
+// verifyDelay is the wait before the first verification, it doubles after each attempt since github may serve the previous state for a moment after a write
+const verifyDelay = time.Second
+
+// verifyApplied plans the repository again and returns the applied changes still planned, github did not reflect them
+func (client *Client) verifyApplied(ctx context.Context, plan *Plan, applied []Change) []Change {
+	pending := []Change{}
+
+	for _, change := range applied {
+		if verifiable(change) {
+			pending = append(pending, change)
+		}
+	}
+
+	delay := verifyDelay
+
+	for attempt := 1; attempt <= verifyAttempts && len(pending) != 0; attempt++ {
+		select {
+		case <-ctx.Done():
+			return pending
+		case <-client.clock.After(delay):
+		}
+
+		delay *= 2
+
+		replanned, err := client.plan(ctx, plan.settings)
+
+		if err != nil {
+			log.Printf("[WARN] Error verifying %s/%s: %s\n", plan.Owner, plan.Name, err)
+			continue
+		}
+
+		pending = stillPlanned(pending, replanned.Changes)
+	}
+
+	for _, change := range pending {
+		log.Printf("[WARN] Verification of %s %s on %s/%s failed, github does not reflect the applied change\n", change.Resource, change.Name, plan.Owner, plan.Name)
+	}
+
+	return pending
+}
+
+// verifiable returns false for the changes planned again on every apply, secret values are write only
+// and the files proposed through a pull request only reach the branch once it is merged
+func verifiable(change Change) bool {
+	if change.Action == ActionUpdate && (change.Resource == ResourceSecrets || change.Resource == ResourceEnvironmentSecrets || change.Resource == ResourceDependabotSecrets) {
+		return false
+	}
+
+	if desired, ok := change.desired.(file); ok && desired.PullRequest {
+		return false
+	}
+
+	return true
+}
+
+// stillPlanned returns the changes whose resource has a change planned again
+func stillPlanned(changes, replanned []Change) []Change {
+	planned := map[string]bool{}
+
+	for _, change := range replanned {
+		if !change.ReportOnly {
+			planned[change.Resource+"/"+change.Name] = true
+		}
+	}
+
+	pending := []Change{}
+
+	for _, change := range changes {
+		if planned[change.Resource+"/"+change.Name] {
+			pending = append(pending, change)
+		}
+	}
+
+	return pending
+}
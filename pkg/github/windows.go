@@ -0,0 +1,199 @@
+package github
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// maxWindowDuration is the longest maintenance window, a longer one would never close
+const maxWindowDuration = 7 * 24 * time.Hour
+
+// MaintenanceWindow is a recurring period during which serve applies the drift, it is only reported outside the windows
+type MaintenanceWindow struct {
+	schedule cronSchedule
+	duration time.Duration
+	location *time.Location
+	value    string
+}
+
+// ParseMaintenanceWindow parses a window written as a cron expression (minute hour day-of-month month day-of-week), its
+// duration and optionally its timezone, utc by default (ex: "0 22 * * 1-5 2h Europe/Paris" opens at 22:00 on weekdays for 2 hours)
+func ParseMaintenanceWindow(value string) (MaintenanceWindow, error) {
+	fields := strings.Fields(value)
+
+	if len(fields) != 6 && len(fields) != 7 {
+		return MaintenanceWindow{}, errors.Errorf("Invalid maintenance window %q, expected a cron expression, a duration and a timezone (ex: 0 22 * * 1-5 2h Europe/Paris)", value)
+	}
+
+	schedule, err := parseCron(fields[:5])
+
+	if err != nil {
+		return MaintenanceWindow{}, errors.Wrapf(err, "Invalid maintenance window %q", value)
+	}
+
+	duration, err := time.ParseDuration(fields[5])
+
+	if err != nil || duration < time.Minute || duration > maxWindowDuration {
+		return MaintenanceWindow{}, errors.Errorf("Invalid maintenance window %q, expected a duration between 1m and %s", value, maxWindowDuration)
+	}
+
+	location := time.UTC
+
+	if len(fields) == 7 {
+		location, err = time.LoadLocation(fields[6])
+
+		if err != nil {
+			return MaintenanceWindow{}, errors.Wrapf(err, "Invalid maintenance window %q", value)
+		}
+	}
+
+	return MaintenanceWindow{schedule: schedule, duration: duration, location: location, value: value}, nil
+}
+
+func (window MaintenanceWindow) String() string {
+	return window.value
+}
+
+// Contains returns true when a window opened at most its duration before the time
+func (window MaintenanceWindow) Contains(now time.Time) bool {
+	now = now.In(window.location)
+	start := now.Truncate(time.Minute)
+
+	for opened := time.Duration(0); opened < window.duration; opened += time.Minute {
+		if window.schedule.matches(start.Add(-opened)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// inMaintenanceWindows returns true when there are no windows or one of them is open
+func inMaintenanceWindows(windows []MaintenanceWindow, now time.Time) bool {
+	for _, window := range windows {
+		if window.Contains(now) {
+			return true
+		}
+	}
+
+	return len(windows) == 0
+}
+
+// cronSchedule holds the values matched by each field of a cron expression as bit sets
+type cronSchedule struct {
+	minutes, hours, days, months, weekdays uint64
+	// anyDay and anyWeekday are set when the field is *, cron matches either day field when both are restricted
+	anyDay, anyWeekday bool
+}
+
+// cronField is the range of values of a field of a cron expression
+type cronField struct {
+	name     string
+	min, max int
+}
+
+// nolint:gochecknoglobals
+var cronFields = []cronField{{"minute", 0, 59}, {"hour", 0, 23}, {"day of month", 1, 31}, {"month", 1, 12}, {"day of week", 0, 7}}
+
+func parseCron(fields []string) (cronSchedule, error) {
+	sets := make([]uint64, len(fields))
+
+	for i, field := range fields {
+		set, err := parseCronField(field, cronFields[i])
+
+		if err != nil {
+			return cronSchedule{}, err
+		}
+
+		sets[i] = set
+	}
+
+	// Sunday is both 0 and 7
+	if sets[4]&(1<<7) != 0 {
+		sets[4] |= 1
+	}
+
+	return cronSchedule{
+		minutes:    sets[0],
+		hours:      sets[1],
+		days:       sets[2],
+		months:     sets[3],
+		weekdays:   sets[4],
+		anyDay:     fields[2] == "*",
+		anyWeekday: fields[4] == "*",
+	}, nil
+}
+
+// parseCronField parses a list of values, ranges (1-5) and steps (*/15, 0-30/10) of a field
+func parseCronField(value string, field cronField) (uint64, error) {
+	var set uint64
+
+	for _, part := range strings.Split(value, ",") {
+		rangeValue, stepValue, stepped := strings.Cut(part, "/")
+		step := 1
+		low, high := field.min, field.max
+
+		if stepped {
+			parsed, err := strconv.Atoi(stepValue)
+
+			if err != nil || parsed < 1 {
+				return 0, fmt.Errorf("invalid step %q of the %s", stepValue, field.name)
+			}
+
+			step = parsed
+		}
+
+		if rangeValue != "*" {
+			lowValue, highValue, ranged := strings.Cut(rangeValue, "-")
+			var err error
+
+			low, err = strconv.Atoi(lowValue)
+
+			if err != nil {
+				return 0, fmt.Errorf("invalid %s %q", field.name, part)
+			}
+
+			high = low
+
+			if ranged {
+				high, err = strconv.Atoi(highValue)
+
+				if err != nil {
+					return 0, fmt.Errorf("invalid %s %q", field.name, part)
+				}
+			} else if stepped {
+				high = field.max
+			}
+		}
+
+		if low < field.min || high > field.max || low > high {
+			return 0, fmt.Errorf("%s %q is out of range %d-%d", field.name, part, field.min, field.max)
+		}
+
+		for i := low; i <= high; i += step {
+			set |= 1 << uint(i)
+		}
+	}
+
+	return set, nil
+}
+
+// matches returns true when the minute of the time is matched by the schedule
+func (schedule cronSchedule) matches(t time.Time) bool {
+	if schedule.minutes&(1<<uint(t.Minute())) == 0 || schedule.hours&(1<<uint(t.Hour())) == 0 || schedule.months&(1<<uint(t.Month())) == 0 {
+		return false
+	}
+
+	day := schedule.days&(1<<uint(t.Day())) != 0
+	weekday := schedule.weekdays&(1<<uint(t.Weekday())) != 0
+
+	if schedule.anyDay || schedule.anyWeekday {
+		return day && weekday
+	}
+
+	return day || weekday
+}
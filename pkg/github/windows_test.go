@@ -0,0 +1,110 @@
+package github
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMaintenanceWindowContains(t *testing.T) {
+	window, err := ParseMaintenanceWindow("0 22 * * 1-5 2h Europe/Paris")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	paris, _ := time.LoadLocation("Europe/Paris")
+
+	for _, test := range []struct {
+		time     time.Time
+		contains bool
+	}{
+		{time.Date(2026, 10, 16, 22, 0, 0, 0, paris), true},
+		{time.Date(2026, 10, 16, 23, 59, 59, 0, paris), true},
+		{time.Date(2026, 10, 17, 0, 0, 0, 0, paris), false},
+		{time.Date(2026, 10, 16, 21, 59, 0, 0, paris), false},
+		// Saturday
+		{time.Date(2026, 10, 17, 22, 30, 0, 0, paris), false},
+		// 22:30 in Paris on a Friday
+		{time.Date(2026, 10, 16, 20, 30, 0, 0, time.UTC), true},
+		{time.Date(2026, 10, 16, 22, 30, 0, 0, time.UTC), false},
+	} {
+		if contains := window.Contains(test.time); contains != test.contains {
+			t.Errorf("Window %s contains %s is %t, want %t", window, test.time, contains, test.contains)
+		}
+	}
+}
+
+func TestCronDayFields(t *testing.T) {
+	for _, test := range []struct {
+		cron    string
+		time    time.Time
+		matches bool
+	}{
+		// Both day fields restricted matches either of them
+		{"0 0 1 * 0", time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC), true},
+		{"0 0 1 * 0", time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC), true},
+		{"0 0 1 * 0", time.Date(2026, 10, 2, 0, 0, 0, 0, time.UTC), false},
+		{"0 0 * * 7", time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC), true},
+		{"*/15 0 * 10 *", time.Date(2026, 10, 18, 0, 45, 0, 0, time.UTC), true},
+		{"*/15 0 * 10 *", time.Date(2026, 10, 18, 0, 50, 0, 0, time.UTC), false},
+		{"10-30/10 0 * * *", time.Date(2026, 10, 18, 0, 20, 0, 0, time.UTC), true},
+		{"10-30/10 0 * * *", time.Date(2026, 10, 18, 0, 40, 0, 0, time.UTC), false},
+	} {
+		schedule, err := parseCron(strings.Fields(test.cron))
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if matches := schedule.matches(test.time); matches != test.matches {
+			t.Errorf("Cron %q matches %s is %t, want %t", test.cron, test.time, matches, test.matches)
+		}
+	}
+}
+
+func TestParseMaintenanceWindowErrors(t *testing.T) {
+	for _, value := range []string{
+		"0 22 * * 1-5",
+		"60 22 * * * 2h",
+		"0 22 * * 5-1 2h",
+		"0 22 * * */0 2h",
+		"0 22 * * * 30s",
+		"0 22 * * * 2h Mars/Olympus",
+	} {
+		if _, err := ParseMaintenanceWindow(value); err == nil {
+			t.Errorf("Maintenance window %q is parsed, want an error", value)
+		}
+	}
+}
+
+func TestReconcileOutsideMaintenanceWindowsOnlyReports(t *testing.T) {
+	server, client := newTestClient(t)
+	server.AddRepository("acme", "api")
+
+	window, err := ParseMaintenanceWindow("0 0 1 1 * 1m")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := writeSettings(t, "repository: {owner: acme, name: api}\ndisable: {repository: true}\nlabels: [{name: bug, color: d73a4a}]\n")
+	notifier := &recordingNotifier{}
+
+	client.reconcile(context.Background(), ServeOptions{
+		Config:             config,
+		Enforce:            true,
+		EnforceCreated:     true,
+		MaintenanceWindows: []MaintenanceWindow{window},
+		Notifier:           notifier,
+	}, reconciliation{created: map[string]bool{"acme/api": true}})
+
+	if _, ok := server.Repository("acme", "api").Label("bug"); ok {
+		t.Error("Label bug is created outside the maintenance windows")
+	}
+
+	if len(notifier.results) != 1 || notifier.results[0].Plan.Empty() {
+		t.Errorf("Notified results are %+v, want the drift of acme/api", notifier.results)
+	}
+}
@@ -0,0 +1,280 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/go-github/v75/github"
+)
+
+// Label is a label of a fake repository
+type Label struct {
+	Name        string
+	Color       string
+	Description string
+}
+
+// Hook is a webhook of a fake repository
+type Hook struct {
+	ID          int64
+	URL         string
+	ContentType string
+	Events      []string
+}
+
+// Ruleset is a ruleset of a fake repository
+type Ruleset struct {
+	ID   int64
+	Name string
+	// Rules are the types of the rules of the ruleset (ex: deletion, pull_request)
+	Rules        []string
+	BypassActors []BypassActor
+}
+
+// BypassActor is an actor allowed to bypass a ruleset
+type BypassActor struct {
+	// Type is Integration, OrganizationAdmin, RepositoryRole, Team or DeployKey
+	Type string
+	ID   int64
+	// Mode is always or pull_request
+	Mode string
+}
+
+// SetAttributes sets attributes of the repository by their rest api name (ex: description, has_wiki, pushed_at, custom_properties)
+func (repo *Repository) SetAttributes(attributes map[string]interface{}) {
+	content, err := json.Marshal(attributes)
+
+	if err != nil {
+		panic(err)
+	}
+
+	if err := json.Unmarshal(content, repo.repository); err != nil {
+		panic(err)
+	}
+}
+
+// Attribute returns an attribute of the repository by its rest api name decoded from json, nil when it is not set
+func (repo *Repository) Attribute(name string) interface{} {
+	content, err := json.Marshal(repo.repository)
+
+	if err != nil {
+		panic(err)
+	}
+
+	attributes := map[string]interface{}{}
+
+	if err := json.Unmarshal(content, &attributes); err != nil {
+		panic(err)
+	}
+
+	return attributes[name]
+}
+
+// Topics returns the topics of the repository
+func (repo *Repository) Topics() []string {
+	return append([]string{}, repo.repository.Topics...)
+}
+
+// SecurityStatus returns the status (enabled or disabled) of a security feature of the repository by its rest api name (ex: advanced_security), empty when it was never set
+func (repo *Repository) SecurityStatus(feature string) string {
+	security, _ := repo.Attribute("security_and_analysis").(map[string]interface{})
+	status, _ := security[feature].(map[string]interface{})
+	value, _ := status["status"].(string)
+
+	return value
+}
+
+// SetLabel creates or replaces a label of the repository
+func (repo *Repository) SetLabel(name, color, description string) {
+	repo.labels[name] = &github.Label{Name: github.String(name), Color: github.String(color), Description: github.String(description)}
+}
+
+// DeleteLabel deletes a label of the repository
+func (repo *Repository) DeleteLabel(name string) {
+	delete(repo.labels, name)
+}
+
+// Label returns a label of the repository and whether it exists
+func (repo *Repository) Label(name string) (Label, bool) {
+	label, ok := repo.labels[name]
+
+	if !ok {
+		return Label{}, false
+	}
+
+	return Label{Name: label.GetName(), Color: label.GetColor(), Description: label.GetDescription()}, true
+}
+
+// LabelNames returns the sorted names of the labels of the repository
+func (repo *Repository) LabelNames() []string {
+	return sortedKeys(repo.labels)
+}
+
+// AddHook creates an active json webhook of the repository and returns its id, it listens to push when no events are given
+func (repo *Repository) AddHook(url string, events ...string) int64 {
+	repo.server.mutex.Lock()
+	defer repo.server.mutex.Unlock()
+
+	if len(events) == 0 {
+		events = []string{"push"}
+	}
+
+	id := repo.server.nextHookID
+	repo.server.nextHookID++
+	repo.hooks[id] = &github.Hook{
+		ID:     github.Int64(id),
+		Active: github.Bool(true),
+		Events: events,
+		Config: &github.HookConfig{URL: github.String(url), ContentType: github.String("json")},
+	}
+
+	return id
+}
+
+// Hooks returns the webhooks of the repository ordered by id
+func (repo *Repository) Hooks() []Hook {
+	hooks := make([]Hook, 0, len(repo.hooks))
+
+	for _, hook := range repo.hooks {
+		hooks = append(hooks, Hook{
+			ID:          hook.GetID(),
+			URL:         hook.GetConfig().GetURL(),
+			ContentType: hook.GetConfig().GetContentType(),
+			Events:      append([]string{}, hook.Events...),
+		})
+	}
+
+	sort.Slice(hooks, func(i, j int) bool { return hooks[i].ID < hooks[j].ID })
+
+	return hooks
+}
+
+// SetHookDeliveries sets the status codes of the recent deliveries of a webhook, the most recent first
+// The deliveries answered with a 2xx status code are OK, the others are described like github (ex: Invalid HTTP Response: 500)
+func (repo *Repository) SetHookDeliveries(id int64, statusCodes ...int) {
+	deliveries := make([]*github.HookDelivery, 0, len(statusCodes))
+
+	for _, statusCode := range statusCodes {
+		status := "OK"
+
+		if statusCode < 200 || statusCode >= 300 {
+			status = fmt.Sprintf("Invalid HTTP Response: %d", statusCode)
+		}
+
+		deliveries = append(deliveries, &github.HookDelivery{StatusCode: github.Int(statusCode), Status: github.String(status)})
+	}
+
+	repo.hookDeliveries[id] = deliveries
+}
+
+// Protected returns whether a branch of the repository is protected
+func (repo *Repository) Protected(branch string) bool {
+	return repo.branches[branch] != nil
+}
+
+// PullRequestBypassApps returns the slugs of the apps allowed to bypass the pull request reviews of a protected branch
+func (repo *Repository) PullRequestBypassApps(branch string) []string {
+	slugs := []string{}
+
+	for _, app := range repo.branches[branch].GetRequiredPullRequestReviews().GetBypassPullRequestAllowances().Apps {
+		slugs = append(slugs, app.GetSlug())
+	}
+
+	return slugs
+}
+
+// AddRuleset creates an empty branch ruleset of the repository and returns its id
+func (repo *Repository) AddRuleset(name string) int64 {
+	repo.server.mutex.Lock()
+	defer repo.server.mutex.Unlock()
+
+	id := repo.server.nextRulesetID
+	repo.server.nextRulesetID++
+	repo.rulesets[id] = &github.RepositoryRuleset{
+		ID:         github.Int64(id),
+		Name:       name,
+		Target:     github.Ptr(github.RulesetTargetBranch),
+		SourceType: github.Ptr(github.RulesetSourceTypeRepository),
+		Source:     repo.repository.GetFullName(),
+	}
+
+	return id
+}
+
+// Rulesets returns the rulesets of the repository ordered by id
+func (repo *Repository) Rulesets() []Ruleset {
+	rulesets := make([]Ruleset, 0, len(repo.rulesets))
+
+	for _, githubRuleset := range repo.rulesets {
+		ruleset := Ruleset{ID: githubRuleset.GetID(), Name: githubRuleset.Name, Rules: []string{}, BypassActors: []BypassActor{}}
+
+		for _, actor := range githubRuleset.BypassActors {
+			bypassActor := BypassActor{ID: actor.GetActorID()}
+
+			if actor.ActorType != nil {
+				bypassActor.Type = string(*actor.ActorType)
+			}
+
+			if actor.BypassMode != nil {
+				bypassActor.Mode = string(*actor.BypassMode)
+			}
+
+			ruleset.BypassActors = append(ruleset.BypassActors, bypassActor)
+		}
+
+		if githubRuleset.Rules != nil {
+			content, err := json.Marshal(githubRuleset.Rules)
+
+			if err != nil {
+				panic(err)
+			}
+
+			rules := []struct {
+				Type string `json:"type"`
+			}{}
+
+			if err := json.Unmarshal(content, &rules); err != nil {
+				panic(err)
+			}
+
+			for _, rule := range rules {
+				ruleset.Rules = append(ruleset.Rules, rule.Type)
+			}
+		}
+
+		rulesets = append(rulesets, ruleset)
+	}
+
+	sort.Slice(rulesets, func(i, j int) bool { return rulesets[i].ID < rulesets[j].ID })
+
+	return rulesets
+}
+
+// AddEnvironment creates a deployment environment of the repository without protection rules nor branch policy
+func (repo *Repository) AddEnvironment(name string) *Environment {
+	environment := &Environment{
+		environment:    &github.Environment{Name: github.String(name)},
+		Secrets:        map[string]string{},
+		Variables:      map[string]string{},
+		branchPolicies: map[int64]*github.DeploymentBranchPolicy{},
+	}
+
+	repo.Environments[name] = environment
+
+	return environment
+}
+
+// AddCommit adds a commit to the default branch, the commits are listed in the order they are added so the most recent is added first
+func (repo *Repository) AddCommit(author string, date time.Time) {
+	repo.commits = append(repo.commits, &github.RepositoryCommit{
+		Author: &github.User{Login: github.String(author)},
+		Commit: &github.Commit{Author: &github.CommitAuthor{Date: &github.Timestamp{Time: date}}},
+	})
+}
+
+// AddIssue adds an issue to the repository last updated at a time
+func (repo *Repository) AddIssue(number int, updatedAt time.Time) {
+	repo.issues = append(repo.issues, &github.Issue{Number: github.Int(number), UpdatedAt: &github.Timestamp{Time: updatedAt}})
+}
@@ -0,0 +1,1947 @@
+// Package server provides an in-memory fake of the github rest api endpoints used by github-settings
+package server
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v75/github"
+	"golang.org/x/crypto/nacl/box"
+)
+
+const (
+	maskedSecret = "********"
+	publicKeyID  = "fake-key"
+)
+
+// defaultPerPage is the size of the pages of lists when the request does not set it
+const defaultPerPage = 30
+
+// Epoch is when the resources of the fake repositories were created unless they are written through the server
+// nolint:gochecknoglobals
+var Epoch = time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// InstallationToken is the access token created for every github app installation
+const InstallationToken = "fake-installation-token"
+
+// EnterprisePrefix is the path prefix of the rest api of a github enterprise server, the server answers with and without it
+const EnterprisePrefix = "/api/v3"
+
+// Server is an httptest server emulating the github rest api, it is also served under /api/v3 like a github enterprise server
+type Server struct {
+	*httptest.Server
+
+	mutex sync.Mutex
+	repos map[string]*Repository
+	// rateLimited is the number of requests still answered with a secondary rate limit error
+	rateLimited int
+	// requests are the method and path of every request received
+	requests   []string
+	nextHookID int64
+	nextRepoID int64
+	// nextRulesetID is shared by the repositories like the ids of github
+	nextRulesetID int64
+	// nextPolicyID numbers the deployment branch policies of every environment
+	nextPolicyID int64
+	// accounts maps the ids of the users and teams looked up to their login or slug
+	accounts map[int64]string
+	// personal are the logins of the personal accounts, the other owners are organizations
+	personal map[string]bool
+	// missing are the users, teams and apps answered as not found, every other login or slug exists
+	missing map[string]bool
+	// customRoles maps an org to the base role (read, triage, write, maintain) of each of its custom repository roles
+	customRoles map[string]map[string]string
+	// activeCommitters maps an org to its github advanced security active committers, the other orgs have none
+	activeCommitters map[string]*github.ActiveCommitters
+	// securityManagers maps an org to the slugs of its security manager teams
+	securityManagers map[string][]string
+	// enterpriseVersion is the version of github enterprise server answered by /meta, empty for github.com
+	enterpriseVersion string
+	publicKey         *[32]byte
+	privateKey        *[32]byte
+}
+
+// Repository is the in-memory state of a fake repository
+// It should only be modified before requests are sent to the server, the state kept in go-github types is only reachable through its methods
+type Repository struct {
+	server     *Server
+	repository *github.Repository
+	labels     map[string]*github.Label
+	// branches maps a branch name to its protection, an unprotected branch has a nil protection
+	branches map[string]*github.Protection
+	hooks    map[int64]*github.Hook
+	// hookDeliveries maps a webhook id to its recent deliveries, the most recent first
+	hookDeliveries map[int64][]*github.HookDelivery
+	// Collaborators maps a username to its role name (read, triage, write, maintain, admin or a custom role)
+	Collaborators map[string]string
+	// Teams maps a team slug to its permission (pull, triage, push, maintain, admin or a custom role)
+	Teams map[string]string
+	// Secrets maps an actions secret name to its decrypted value
+	Secrets map[string]string
+	// SecretUpdates maps an actions secret name to when it was last written, secrets without one were written at Epoch
+	SecretUpdates map[string]time.Time
+	// Variables maps an actions variable name to its value
+	Variables map[string]string
+	// DependabotSecrets maps a dependabot secret name to its decrypted value
+	DependabotSecrets map[string]string
+	// DependabotSecretUpdates maps a dependabot secret name to when it was last written, secrets without one were written at Epoch
+	DependabotSecretUpdates map[string]time.Time
+	// Environments maps a deployment environment name to its state
+	Environments map[string]*Environment
+	// Files maps a file path of the default branch to its content, the other branches have no files
+	Files map[string]string
+	// rulesets maps a ruleset id to the ruleset of the repository
+	rulesets map[int64]*github.RepositoryRuleset
+	// statuses maps a commit sha to the latest status of each context
+	statuses map[string]map[string]*github.RepoStatus
+	// commits are the commits of the default branch, the most recent first
+	commits []*github.RepositoryCommit
+	// issues are the issues and pull requests of the repository
+	issues []*github.Issue
+}
+
+// Environment is the in-memory state of a fake deployment environment
+type Environment struct {
+	environment *github.Environment
+	// Secrets maps a secret name of the environment to its decrypted value
+	Secrets map[string]string
+	// SecretUpdates maps a secret name of the environment to when it was last written, secrets without one were written at Epoch
+	SecretUpdates map[string]time.Time
+	// Variables maps a variable name of the environment to its value
+	Variables map[string]string
+	// branchPolicies maps a deployment branch policy id to the policy, they are dropped when custom policies are turned off
+	branchPolicies map[int64]*github.DeploymentBranchPolicy
+}
+
+// NewServer starts a new fake github server
+func NewServer() *Server {
+	publicKey, privateKey, err := box.GenerateKey(rand.Reader)
+
+	if err != nil {
+		panic(err)
+	}
+
+	server := &Server{
+		repos:            map[string]*Repository{},
+		nextHookID:       1,
+		nextRepoID:       1,
+		nextRulesetID:    1,
+		nextPolicyID:     1,
+		accounts:         map[int64]string{},
+		personal:         map[string]bool{},
+		missing:          map[string]bool{},
+		customRoles:      map[string]map[string]string{},
+		activeCommitters: map[string]*github.ActiveCommitters{},
+		securityManagers: map[string][]string{},
+		publicKey:        publicKey,
+		privateKey:       privateKey,
+	}
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("POST /app/installations/{id}/access_tokens", server.createInstallationToken)
+	mux.HandleFunc("GET /orgs/{org}/repos", server.listOrgRepos)
+	mux.HandleFunc("POST /orgs/{org}/repos", server.createOrgRepo)
+	mux.HandleFunc("GET /meta", server.getMeta)
+	mux.HandleFunc("GET /users/{user}", server.getUser)
+	mux.HandleFunc("GET /users/{user}/repos", server.listUserRepos)
+	mux.HandleFunc("GET /orgs/{org}/teams/{slug}", server.getTeam)
+	mux.HandleFunc("GET /apps/{slug}", server.getApp)
+	mux.HandleFunc("GET /orgs/{org}/custom-repository-roles", server.listCustomRoles)
+	mux.HandleFunc("GET /orgs/{org}/settings/billing/advanced-security", server.getActiveCommitters)
+	mux.HandleFunc("GET /orgs/{org}/security-managers", server.listSecurityManagers)
+	mux.HandleFunc("PUT /orgs/{org}/security-managers/teams/{slug}", server.addSecurityManager)
+	mux.HandleFunc("DELETE /orgs/{org}/security-managers/teams/{slug}", server.removeSecurityManager)
+	mux.HandleFunc("POST /repos/{owner}/{repo}/generate", server.withRepo(server.generateRepo))
+	mux.HandleFunc("POST /repos/{owner}/{repo}/branches/{branch}/rename", server.withRepo(server.renameBranch))
+	mux.HandleFunc("GET /repos/{owner}/{repo}", server.withRepo(server.getRepo))
+	mux.HandleFunc("PATCH /repos/{owner}/{repo}", server.withRepo(server.editRepo))
+	mux.HandleFunc("PUT /repos/{owner}/{repo}/topics", server.withRepo(server.replaceTopics))
+	mux.HandleFunc("GET /repos/{owner}/{repo}/labels", server.withRepo(server.listLabels))
+	mux.HandleFunc("POST /repos/{owner}/{repo}/labels", server.withRepo(server.createLabel))
+	mux.HandleFunc("PATCH /repos/{owner}/{repo}/labels/{name}", server.withRepo(server.editLabel))
+	mux.HandleFunc("DELETE /repos/{owner}/{repo}/labels/{name}", server.withRepo(server.deleteLabel))
+	mux.HandleFunc("GET /repos/{owner}/{repo}/branches", server.withRepo(server.listBranches))
+	mux.HandleFunc("GET /repos/{owner}/{repo}/branches/{branch}/protection", server.withRepo(server.getProtection))
+	mux.HandleFunc("PUT /repos/{owner}/{repo}/branches/{branch}/protection", server.withRepo(server.updateProtection))
+	mux.HandleFunc("DELETE /repos/{owner}/{repo}/branches/{branch}/protection", server.withRepo(server.removeProtection))
+	mux.HandleFunc("POST /repos/{owner}/{repo}/branches/{branch}/protection/required_signatures", server.withRepo(server.requireSignatures))
+	mux.HandleFunc("DELETE /repos/{owner}/{repo}/branches/{branch}/protection/required_signatures", server.withRepo(server.optionalSignatures))
+	mux.HandleFunc("GET /repos/{owner}/{repo}/hooks", server.withRepo(server.listHooks))
+	mux.HandleFunc("POST /repos/{owner}/{repo}/hooks", server.withRepo(server.createHook))
+	mux.HandleFunc("PATCH /repos/{owner}/{repo}/hooks/{id}", server.withRepo(server.editHook))
+	mux.HandleFunc("DELETE /repos/{owner}/{repo}/hooks/{id}", server.withRepo(server.deleteHook))
+	mux.HandleFunc("GET /repos/{owner}/{repo}/hooks/{id}/deliveries", server.withRepo(server.listHookDeliveries))
+	mux.HandleFunc("GET /repos/{owner}/{repo}/commits", server.withRepo(server.listCommits))
+	mux.HandleFunc("GET /repos/{owner}/{repo}/issues", server.withRepo(server.listIssues))
+	mux.HandleFunc("GET /repos/{owner}/{repo}/rulesets", server.withRepo(server.listRulesets))
+	mux.HandleFunc("POST /repos/{owner}/{repo}/rulesets", server.withRepo(server.createRuleset))
+	mux.HandleFunc("GET /repos/{owner}/{repo}/rulesets/{id}", server.withRepo(server.getRuleset))
+	mux.HandleFunc("PUT /repos/{owner}/{repo}/rulesets/{id}", server.withRepo(server.updateRuleset))
+	mux.HandleFunc("DELETE /repos/{owner}/{repo}/rulesets/{id}", server.withRepo(server.deleteRuleset))
+	mux.HandleFunc("GET /repos/{owner}/{repo}/collaborators", server.withRepo(server.listCollaborators))
+	mux.HandleFunc("PUT /repos/{owner}/{repo}/collaborators/{user}", server.withRepo(server.addCollaborator))
+	mux.HandleFunc("DELETE /repos/{owner}/{repo}/collaborators/{user}", server.withRepo(server.removeCollaborator))
+	mux.HandleFunc("GET /repos/{owner}/{repo}/invitations", server.withRepo(server.listInvitations))
+	mux.HandleFunc("GET /repos/{owner}/{repo}/teams", server.withRepo(server.listTeams))
+	mux.HandleFunc("GET /orgs/{org}/teams/{slug}/repos/{owner}/{repo}", server.withRepo(server.getTeamRepo))
+	mux.HandleFunc("PUT /orgs/{org}/teams/{slug}/repos/{owner}/{repo}", server.withRepo(server.addTeam))
+	mux.HandleFunc("DELETE /orgs/{org}/teams/{slug}/repos/{owner}/{repo}", server.withRepo(server.removeTeam))
+	mux.HandleFunc("GET /repos/{owner}/{repo}/actions/secrets", server.withRepo(server.listSecrets))
+	mux.HandleFunc("GET /repos/{owner}/{repo}/actions/secrets/public-key", server.withRepo(server.getPublicKey))
+	mux.HandleFunc("PUT /repos/{owner}/{repo}/actions/secrets/{name}", server.withRepo(server.putSecret))
+	mux.HandleFunc("DELETE /repos/{owner}/{repo}/actions/secrets/{name}", server.withRepo(server.deleteSecret))
+	mux.HandleFunc("GET /repos/{owner}/{repo}/dependabot/secrets", server.withRepo(server.listDependabotSecrets))
+	mux.HandleFunc("GET /repos/{owner}/{repo}/dependabot/secrets/public-key", server.withRepo(server.getPublicKey))
+	mux.HandleFunc("PUT /repos/{owner}/{repo}/dependabot/secrets/{name}", server.withRepo(server.putDependabotSecret))
+	mux.HandleFunc("DELETE /repos/{owner}/{repo}/dependabot/secrets/{name}", server.withRepo(server.deleteDependabotSecret))
+	mux.HandleFunc("GET /repos/{owner}/{repo}/actions/variables", server.withRepo(server.listVariables))
+	mux.HandleFunc("POST /repos/{owner}/{repo}/actions/variables", server.withRepo(server.createVariable))
+	mux.HandleFunc("PATCH /repos/{owner}/{repo}/actions/variables/{name}", server.withRepo(server.updateVariable))
+	mux.HandleFunc("DELETE /repos/{owner}/{repo}/actions/variables/{name}", server.withRepo(server.deleteVariable))
+	mux.HandleFunc("GET /repos/{owner}/{repo}/contents/{path...}", server.withRepo(server.getContents))
+	mux.HandleFunc("POST /repos/{owner}/{repo}/statuses/{sha}", server.withRepo(server.createStatus))
+	mux.HandleFunc("GET /repos/{owner}/{repo}/environments", server.withRepo(server.listEnvironments))
+	mux.HandleFunc("PUT /repos/{owner}/{repo}/environments/{env}", server.withRepo(server.putEnvironment))
+	mux.HandleFunc("DELETE /repos/{owner}/{repo}/environments/{env}", server.withRepo(server.deleteEnvironment))
+	mux.HandleFunc("GET /repos/{owner}/{repo}/environments/{env}/deployment-branch-policies", server.withEnvironment(server.listBranchPolicies))
+	mux.HandleFunc("POST /repos/{owner}/{repo}/environments/{env}/deployment-branch-policies", server.withEnvironment(server.createBranchPolicy))
+	mux.HandleFunc("DELETE /repos/{owner}/{repo}/environments/{env}/deployment-branch-policies/{policy}", server.withEnvironment(server.deleteBranchPolicy))
+	mux.HandleFunc("GET /repos/{owner}/{repo}/environments/{env}/variables", server.withEnvironment(server.listEnvironmentVariables))
+	mux.HandleFunc("POST /repos/{owner}/{repo}/environments/{env}/variables", server.withEnvironment(server.createEnvironmentVariable))
+	mux.HandleFunc("PATCH /repos/{owner}/{repo}/environments/{env}/variables/{name}", server.withEnvironment(server.updateEnvironmentVariable))
+	mux.HandleFunc("DELETE /repos/{owner}/{repo}/environments/{env}/variables/{name}", server.withEnvironment(server.deleteEnvironmentVariable))
+	mux.HandleFunc("GET /repositories/{id}/environments/{env}/secrets", server.withEnvironment(server.listEnvironmentSecrets))
+	mux.HandleFunc("GET /repositories/{id}/environments/{env}/secrets/public-key", server.withEnvironment(server.getEnvironmentPublicKey))
+	mux.HandleFunc("PUT /repositories/{id}/environments/{env}/secrets/{name}", server.withEnvironment(server.putEnvironmentSecret))
+	mux.HandleFunc("DELETE /repositories/{id}/environments/{env}/secrets/{name}", server.withEnvironment(server.deleteEnvironmentSecret))
+
+	mux.Handle(EnterprisePrefix+"/", http.StripPrefix(EnterprisePrefix, mux))
+
+	server.Server = httptest.NewServer(server.withRateLimit(mux))
+
+	return server
+}
+
+// BaseURL is the url of the rest api of the fake server, given to the clients with WithBaseURL
+func (server *Server) BaseURL() string {
+	return server.URL + "/"
+}
+
+// AddRepository creates an empty repository with a protected-less main branch
+func (server *Server) AddRepository(owner, name string) *Repository {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	repo := newRepository(owner, name)
+	server.addRepository(repo)
+
+	return repo
+}
+
+// AddPersonalAccount makes a login a personal account, its repositories have no teams and grant write access to every collaborator
+// It must be called before the repositories of the account are added
+func (server *Server) AddPersonalAccount(login string) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	server.personal[login] = true
+}
+
+// RemoveAccount answers the user, team or app of a login or slug as not found
+func (server *Server) RemoveAccount(login string) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	server.missing[login] = true
+}
+
+// SetActiveCommitters sets the github advanced security seats of an org and the logins of the active committers of each of its repositories by full name
+func (server *Server) SetActiveCommitters(org string, maximum, purchased int, repositories map[string][]string) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	committers := &github.ActiveCommitters{MaximumAdvancedSecurityCommitters: maximum, PurchasedAdvancedSecurityCommitters: purchased, Repositories: []*github.RepositoryActiveCommitters{}}
+	logins := map[string]bool{}
+
+	for _, name := range sortedKeys(repositories) {
+		repository := &github.RepositoryActiveCommitters{Name: github.String(name), AdvancedSecurityCommitters: github.Int(len(repositories[name]))}
+
+		for _, login := range repositories[name] {
+			repository.AdvancedSecurityCommittersBreakdown = append(repository.AdvancedSecurityCommittersBreakdown, &github.AdvancedSecurityCommittersBreakdown{UserLogin: github.String(login)})
+			logins[login] = true
+		}
+
+		committers.Repositories = append(committers.Repositories, repository)
+	}
+
+	committers.TotalAdvancedSecurityCommitters = len(logins)
+	committers.TotalCount = len(committers.Repositories)
+	server.activeCommitters[org] = committers
+}
+
+// getActiveCommitters answers the github advanced security active committers of an org, none by default
+func (server *Server) getActiveCommitters(w http.ResponseWriter, r *http.Request) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	committers, ok := server.activeCommitters[r.PathValue("org")]
+
+	if !ok {
+		committers = &github.ActiveCommitters{Repositories: []*github.RepositoryActiveCommitters{}}
+	}
+
+	writeJSON(w, http.StatusOK, committers)
+}
+
+// SecurityManagers returns the slugs of the security manager teams of an org
+func (server *Server) SecurityManagers(org string) []string {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	return append([]string{}, server.securityManagers[org]...)
+}
+
+// SetSecurityManagers sets the slugs of the security manager teams of an org
+func (server *Server) SetSecurityManagers(org string, slugs ...string) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	server.securityManagers[org] = slugs
+}
+
+// listSecurityManagers lists the security manager teams of an org
+func (server *Server) listSecurityManagers(w http.ResponseWriter, r *http.Request) {
+	teams := []*github.Team{}
+
+	for _, slug := range server.SecurityManagers(r.PathValue("org")) {
+		teams = append(teams, &github.Team{ID: github.Int64(server.accountID(slug)), Slug: github.String(slug)})
+	}
+
+	writeJSON(w, http.StatusOK, teams)
+}
+
+// addSecurityManager grants the security manager role to a team of an org, missing teams are not found
+func (server *Server) addSecurityManager(w http.ResponseWriter, r *http.Request) {
+	if server.missingAccount(w, r.PathValue("slug")) {
+		return
+	}
+
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	org, slug := r.PathValue("org"), r.PathValue("slug")
+
+	if !slices.Contains(server.securityManagers[org], slug) {
+		server.securityManagers[org] = append(server.securityManagers[org], slug)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// removeSecurityManager revokes the security manager role of a team of an org
+func (server *Server) removeSecurityManager(w http.ResponseWriter, r *http.Request) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	org, slug := r.PathValue("org"), r.PathValue("slug")
+	server.securityManagers[org] = slices.DeleteFunc(server.securityManagers[org], func(manager string) bool { return manager == slug })
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SetEnterpriseVersion answers /meta like a github enterprise server of the version (ex: 3.10.4)
+func (server *Server) SetEnterpriseVersion(version string) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	server.enterpriseVersion = version
+}
+
+// getMeta answers the installed version of a github enterprise server, github.com has none
+func (server *Server) getMeta(w http.ResponseWriter, r *http.Request) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	meta := map[string]interface{}{"verifiable_password_authentication": false}
+
+	if server.enterpriseVersion != "" {
+		meta["installed_version"] = server.enterpriseVersion
+		w.Header().Set("X-GitHub-Enterprise-Version", server.enterpriseVersion)
+	}
+
+	writeJSON(w, http.StatusOK, meta)
+}
+
+// missingAccount answers not found when the account of the path was removed
+func (server *Server) missingAccount(w http.ResponseWriter, name string) bool {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	if server.missing[name] {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return true
+	}
+
+	return false
+}
+
+// AddCustomRole defines a custom repository role of an org extending a base role (read, triage, write or maintain)
+func (server *Server) AddCustomRole(org, name, baseRole string) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	if server.customRoles[org] == nil {
+		server.customRoles[org] = map[string]string{}
+	}
+
+	server.customRoles[org][name] = baseRole
+}
+
+// addRepository stores a repository with a new id, the mutex must be held
+func (server *Server) addRepository(repo *Repository) {
+	repo.repository.Owner.Type = github.String(server.ownerType(repo.repository.GetOwner().GetLogin()))
+	repo.repository.ID = github.Int64(server.nextRepoID)
+	server.nextRepoID++
+	repo.server = server
+	server.repos[repo.repository.GetFullName()] = repo
+}
+
+func newRepository(owner, name string) *Repository {
+	return &Repository{
+		repository: &github.Repository{
+			Name:          github.String(name),
+			FullName:      github.String(owner + "/" + name),
+			Owner:         &github.User{Login: github.String(owner)},
+			DefaultBranch: github.String("main"),
+			Private:       github.Bool(false),
+			Visibility:    github.String("public"),
+			CreatedAt:     &github.Timestamp{Time: Epoch},
+			HasIssues:     github.Bool(true),
+			HasProjects:   github.Bool(true),
+			HasWiki:       github.Bool(true),
+			HasDownloads:  github.Bool(true),
+			Topics:        []string{},
+		},
+		labels:            map[string]*github.Label{},
+		branches:          map[string]*github.Protection{"main": nil},
+		hooks:             map[int64]*github.Hook{},
+		hookDeliveries:    map[int64][]*github.HookDelivery{},
+		Collaborators:     map[string]string{},
+		Teams:             map[string]string{},
+		Secrets:           map[string]string{},
+		Variables:         map[string]string{},
+		DependabotSecrets: map[string]string{},
+		Environments:      map[string]*Environment{},
+		Files:             map[string]string{},
+		rulesets:          map[int64]*github.RepositoryRuleset{},
+		statuses:          map[string]map[string]*github.RepoStatus{},
+	}
+}
+
+// RateLimit answers the next requests with a secondary rate limit error asking to retry immediately
+func (server *Server) RateLimit(requests int) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	server.rateLimited = requests
+}
+
+func (server *Server) withRateLimit(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.mutex.Lock()
+		server.requests = append(server.requests, r.Method+" "+r.URL.Path)
+		limited := server.rateLimited > 0
+
+		if limited {
+			server.rateLimited--
+		}
+
+		server.mutex.Unlock()
+
+		if limited {
+			w.Header().Set("Retry-After", "0")
+			writeError(w, http.StatusForbidden, "You have exceeded a secondary rate limit")
+
+			return
+		}
+
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// Requests returns the method and path of every request received (ex: GET /repos/acme/api/labels)
+func (server *Server) Requests() []string {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	return append([]string{}, server.requests...)
+}
+
+// Repository returns the state of a repository or nil when it does not exist
+func (server *Server) Repository(owner, name string) *Repository {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	return server.repos[owner+"/"+name]
+}
+
+type repoHandler func(w http.ResponseWriter, r *http.Request, repo *Repository)
+
+// withRepo resolves the repository of the request and serializes access to the server state
+func (server *Server) withRepo(handler repoHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		server.mutex.Lock()
+		defer server.mutex.Unlock()
+
+		repo, ok := server.repos[r.PathValue("owner")+"/"+r.PathValue("repo")]
+
+		if !ok {
+			writeError(w, http.StatusNotFound, "Not Found")
+			return
+		}
+
+		handler(w, r, repo)
+	}
+}
+
+func (server *Server) listOrgRepos(w http.ResponseWriter, r *http.Request) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	if server.personal[r.PathValue("org")] {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+
+	repos := []*github.Repository{}
+
+	for _, fullName := range sortedKeys(server.repos) {
+		repo := server.repos[fullName]
+
+		if repo.repository.GetOwner().GetLogin() == r.PathValue("org") {
+			repos = append(repos, repo.repository)
+		}
+	}
+
+	writeList(w, r, repos)
+}
+
+// createInstallationToken answers any request authenticated with a jwt (three dot separated parts)
+func (server *Server) createInstallationToken(w http.ResponseWriter, r *http.Request) {
+	jwt := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+	if len(strings.Split(jwt, ".")) != 3 {
+		writeError(w, http.StatusUnauthorized, "A JSON web token could not be decoded")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, &github.InstallationToken{
+		Token:     github.String(InstallationToken),
+		ExpiresAt: &github.Timestamp{Time: time.Now().Add(time.Hour)},
+	})
+}
+
+// getUser answers every account as an organization unless it is a personal account or was removed
+func (server *Server) getUser(w http.ResponseWriter, r *http.Request) {
+	if server.missingAccount(w, r.PathValue("user")) {
+		return
+	}
+
+	server.mutex.Lock()
+	ownerType := server.ownerType(r.PathValue("user"))
+	server.mutex.Unlock()
+
+	writeJSON(w, http.StatusOK, &github.User{
+		ID:    github.Int64(server.accountID(r.PathValue("user"))),
+		Login: github.String(r.PathValue("user")),
+		Type:  github.String(ownerType),
+	})
+}
+
+// ownerType returns User for the personal accounts and Organization otherwise, the mutex must be held
+func (server *Server) ownerType(login string) string {
+	if server.personal[login] {
+		return "User"
+	}
+
+	return "Organization"
+}
+
+// listUserRepos lists the public repositories of a personal account
+func (server *Server) listUserRepos(w http.ResponseWriter, r *http.Request) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	if !server.personal[r.PathValue("user")] {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+
+	repos := []*github.Repository{}
+
+	for _, fullName := range sortedKeys(server.repos) {
+		repo := server.repos[fullName]
+
+		if repo.repository.GetOwner().GetLogin() == r.PathValue("user") && !repo.repository.GetPrivate() {
+			repos = append(repos, repo.repository)
+		}
+	}
+
+	writeList(w, r, repos)
+}
+
+// getTeam answers every team slug as an existing team unless it was removed
+func (server *Server) getTeam(w http.ResponseWriter, r *http.Request) {
+	if server.missingAccount(w, r.PathValue("slug")) {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, &github.Team{
+		ID:   github.Int64(server.accountID(r.PathValue("slug"))),
+		Slug: github.String(r.PathValue("slug")),
+	})
+}
+
+func (server *Server) getApp(w http.ResponseWriter, r *http.Request) {
+	if server.missingAccount(w, r.PathValue("slug")) {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, &github.App{
+		ID:   github.Int64(server.accountID(r.PathValue("slug"))),
+		Slug: github.String(r.PathValue("slug")),
+	})
+}
+
+// listCustomRoles lists the custom repository roles of an org, the roles of personal accounts are not found
+func (server *Server) listCustomRoles(w http.ResponseWriter, r *http.Request) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	org := r.PathValue("org")
+
+	if server.personal[org] {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+
+	roles := &github.OrganizationCustomRepoRoles{CustomRepoRoles: []*github.CustomRepoRoles{}}
+
+	for i, name := range sortedKeys(server.customRoles[org]) {
+		roles.CustomRepoRoles = append(roles.CustomRepoRoles, &github.CustomRepoRoles{
+			ID:       github.Int64(int64(i + 1)),
+			Name:     github.String(name),
+			BaseRole: github.String(server.customRoles[org][name]),
+		})
+	}
+
+	roles.TotalCount = github.Int(len(roles.CustomRepoRoles))
+
+	writeJSON(w, http.StatusOK, roles)
+}
+
+// accountID derives a stable id from a login or a slug and remembers it to resolve environment reviewers
+func (server *Server) accountID(name string) int64 {
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(name))
+	id := int64(hash.Sum32())
+
+	server.mutex.Lock()
+	server.accounts[id] = name
+	server.mutex.Unlock()
+
+	return id
+}
+
+func (server *Server) createOrgRepo(w http.ResponseWriter, r *http.Request) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	request := &github.Repository{}
+
+	if !decode(w, r, request) {
+		return
+	}
+
+	owner := r.PathValue("org")
+
+	if _, ok := server.repos[owner+"/"+request.GetName()]; ok {
+		writeError(w, http.StatusUnprocessableEntity, "Repository creation failed")
+		return
+	}
+
+	repo := newRepository(owner, request.GetName())
+	repo.repository.Description = request.Description
+	repo.repository.Homepage = request.Homepage
+	repo.repository.Private = github.Bool(request.GetPrivate())
+
+	// Without an initial commit the repository has no branch
+	if !request.GetAutoInit() {
+		repo.branches = map[string]*github.Protection{}
+	}
+
+	server.addRepository(repo)
+
+	writeJSON(w, http.StatusCreated, repo.repository)
+}
+
+// generateRepo creates a repository with the labels and unprotected branches of a template
+func (server *Server) generateRepo(w http.ResponseWriter, r *http.Request, template *Repository) {
+	request := &github.TemplateRepoRequest{}
+
+	if !decode(w, r, request) {
+		return
+	}
+
+	if _, ok := server.repos[request.GetOwner()+"/"+request.GetName()]; ok {
+		writeError(w, http.StatusUnprocessableEntity, "Repository creation failed")
+		return
+	}
+
+	repo := newRepository(request.GetOwner(), request.GetName())
+	repo.repository.Description = request.Description
+	repo.repository.Private = github.Bool(request.GetPrivate())
+	repo.repository.DefaultBranch = template.repository.DefaultBranch
+	repo.branches = map[string]*github.Protection{template.repository.GetDefaultBranch(): nil}
+
+	for name, label := range template.labels {
+		copied := *label
+		repo.labels[name] = &copied
+	}
+
+	server.addRepository(repo)
+
+	writeJSON(w, http.StatusCreated, repo.repository)
+}
+
+// getRepo answers conditional requests like github, the etag is the hash of the repository
+func (server *Server) getRepo(w http.ResponseWriter, r *http.Request, repo *Repository) {
+	content, err := json.Marshal(repo.repository)
+
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	etag := fmt.Sprintf(`W/"%x"`, sha256.Sum256(content))
+	w.Header().Set("ETag", etag)
+
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, repo.repository)
+}
+
+func (server *Server) editRepo(w http.ResponseWriter, r *http.Request, repo *Repository) {
+	private, visibility := repo.repository.GetPrivate(), repo.repository.GetVisibility()
+
+	if !decode(w, r, repo.repository) {
+		return
+	}
+
+	// Like github the visibility and private flag follow each other, the visibility wins when both change
+	switch {
+	case repo.repository.GetVisibility() != visibility:
+		repo.repository.Private = github.Bool(repo.repository.GetVisibility() != "public")
+	case repo.repository.GetPrivate() != private && repo.repository.GetPrivate():
+		repo.repository.Visibility = github.String("private")
+	case repo.repository.GetPrivate() != private:
+		repo.repository.Visibility = github.String("public")
+	}
+
+	writeJSON(w, http.StatusOK, repo.repository)
+}
+
+func (server *Server) replaceTopics(w http.ResponseWriter, r *http.Request, repo *Repository) {
+	var topics struct {
+		Names []string `json:"names"`
+	}
+
+	if !decode(w, r, &topics) {
+		return
+	}
+
+	repo.repository.Topics = append([]string{}, topics.Names...)
+
+	writeJSON(w, http.StatusOK, topics)
+}
+
+func (server *Server) listLabels(w http.ResponseWriter, r *http.Request, repo *Repository) {
+	labels := make([]*github.Label, 0, len(repo.labels))
+
+	for _, name := range sortedKeys(repo.labels) {
+		labels = append(labels, repo.labels[name])
+	}
+
+	writeList(w, r, labels)
+}
+
+func (server *Server) createLabel(w http.ResponseWriter, r *http.Request, repo *Repository) {
+	label := &github.Label{}
+
+	if !decode(w, r, label) {
+		return
+	}
+
+	if label.GetColor() == "" {
+		label.Color = github.String("ededed")
+	}
+
+	if _, ok := repo.labels[label.GetName()]; ok {
+		writeError(w, http.StatusUnprocessableEntity, "Validation Failed")
+		return
+	}
+
+	repo.labels[label.GetName()] = label
+
+	writeJSON(w, http.StatusCreated, label)
+}
+
+func (server *Server) editLabel(w http.ResponseWriter, r *http.Request, repo *Repository) {
+	label, ok := repo.labels[r.PathValue("name")]
+
+	if !ok {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+
+	if !decode(w, r, label) {
+		return
+	}
+
+	delete(repo.labels, r.PathValue("name"))
+	repo.labels[label.GetName()] = label
+
+	writeJSON(w, http.StatusOK, label)
+}
+
+func (server *Server) deleteLabel(w http.ResponseWriter, r *http.Request, repo *Repository) {
+	if _, ok := repo.labels[r.PathValue("name")]; !ok {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+
+	delete(repo.labels, r.PathValue("name"))
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (server *Server) listBranches(w http.ResponseWriter, r *http.Request, repo *Repository) {
+	branches := make([]*github.Branch, 0, len(repo.branches))
+
+	for _, name := range sortedKeys(repo.branches) {
+		branches = append(branches, &github.Branch{
+			Name:      github.String(name),
+			Protected: github.Bool(repo.branches[name] != nil),
+		})
+	}
+
+	writeList(w, r, branches)
+}
+
+func (server *Server) renameBranch(w http.ResponseWriter, r *http.Request, repo *Repository) {
+	protection, ok := repo.branches[r.PathValue("branch")]
+
+	if !ok {
+		writeError(w, http.StatusNotFound, "Branch not found")
+		return
+	}
+
+	request := &struct {
+		NewName string `json:"new_name"`
+	}{}
+
+	if !decode(w, r, request) {
+		return
+	}
+
+	delete(repo.branches, r.PathValue("branch"))
+	repo.branches[request.NewName] = protection
+
+	if repo.repository.GetDefaultBranch() == r.PathValue("branch") {
+		repo.repository.DefaultBranch = github.String(request.NewName)
+	}
+
+	writeJSON(w, http.StatusCreated, &github.Branch{Name: github.String(request.NewName)})
+}
+
+func (server *Server) getProtection(w http.ResponseWriter, r *http.Request, repo *Repository) {
+	protection, ok := repo.branches[r.PathValue("branch")]
+
+	if !ok {
+		writeError(w, http.StatusNotFound, "Branch not found")
+		return
+	}
+
+	if protection == nil {
+		writeError(w, http.StatusNotFound, "Branch not protected")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, protection)
+}
+
+func (server *Server) updateProtection(w http.ResponseWriter, r *http.Request, repo *Repository) {
+	if _, ok := repo.branches[r.PathValue("branch")]; !ok {
+		writeError(w, http.StatusNotFound, "Branch not found")
+		return
+	}
+
+	request := &github.ProtectionRequest{}
+
+	if !decode(w, r, request) {
+		return
+	}
+
+	protection := &github.Protection{
+		EnforceAdmins:                  &github.AdminEnforcement{Enabled: request.EnforceAdmins},
+		RequireLinearHistory:           &github.RequireLinearHistory{Enabled: request.GetRequireLinearHistory()},
+		AllowForcePushes:               &github.AllowForcePushes{Enabled: request.GetAllowForcePushes()},
+		AllowDeletions:                 &github.AllowDeletions{Enabled: request.GetAllowDeletions()},
+		RequiredConversationResolution: &github.RequiredConversationResolution{Enabled: request.GetRequiredConversationResolution()},
+		RequiredSignatures:             &github.SignaturesProtectedBranch{Enabled: github.Bool(false)},
+	}
+
+	// Signatures are managed by their own endpoint and kept when the protection is replaced
+	if current := repo.branches[r.PathValue("branch")]; current != nil && current.RequiredSignatures != nil {
+		protection.RequiredSignatures = current.RequiredSignatures
+	}
+
+	if request.Restrictions != nil {
+		protection.Restrictions = &github.BranchRestrictions{
+			Users: users(request.Restrictions.Users),
+			Teams: teams(request.Restrictions.Teams),
+			Apps:  apps(request.Restrictions.Apps),
+		}
+	}
+
+	if request.RequiredStatusChecks != nil {
+		protection.RequiredStatusChecks = &github.RequiredStatusChecks{
+			Strict:   request.RequiredStatusChecks.Strict,
+			Contexts: request.RequiredStatusChecks.Contexts,
+		}
+	}
+
+	if request.RequiredPullRequestReviews != nil {
+		protection.RequiredPullRequestReviews = &github.PullRequestReviewsEnforcement{
+			DismissStaleReviews:          request.RequiredPullRequestReviews.DismissStaleReviews,
+			RequireCodeOwnerReviews:      request.RequiredPullRequestReviews.RequireCodeOwnerReviews,
+			RequiredApprovingReviewCount: request.RequiredPullRequestReviews.RequiredApprovingReviewCount,
+		}
+
+		if dismissal := request.RequiredPullRequestReviews.DismissalRestrictionsRequest; dismissal != nil {
+			protection.RequiredPullRequestReviews.DismissalRestrictions = &github.DismissalRestrictions{
+				Users: users(dismissal.GetUsers()),
+				Teams: teams(dismissal.GetTeams()),
+				Apps:  apps(dismissal.GetApps()),
+			}
+		}
+
+		if bypass := request.RequiredPullRequestReviews.BypassPullRequestAllowancesRequest; bypass != nil {
+			protection.RequiredPullRequestReviews.BypassPullRequestAllowances = &github.BypassPullRequestAllowances{
+				Users: users(bypass.Users),
+				Teams: teams(bypass.Teams),
+				Apps:  apps(bypass.Apps),
+			}
+		}
+	}
+
+	repo.branches[r.PathValue("branch")] = protection
+
+	writeJSON(w, http.StatusOK, protection)
+}
+
+func (server *Server) requireSignatures(w http.ResponseWriter, r *http.Request, repo *Repository) {
+	protection := repo.branches[r.PathValue("branch")]
+
+	if protection == nil {
+		writeError(w, http.StatusNotFound, "Branch not protected")
+		return
+	}
+
+	protection.RequiredSignatures = &github.SignaturesProtectedBranch{Enabled: github.Bool(true)}
+
+	writeJSON(w, http.StatusOK, protection.RequiredSignatures)
+}
+
+func (server *Server) optionalSignatures(w http.ResponseWriter, r *http.Request, repo *Repository) {
+	protection := repo.branches[r.PathValue("branch")]
+
+	if protection == nil {
+		writeError(w, http.StatusNotFound, "Branch not protected")
+		return
+	}
+
+	protection.RequiredSignatures = &github.SignaturesProtectedBranch{Enabled: github.Bool(false)}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (server *Server) removeProtection(w http.ResponseWriter, r *http.Request, repo *Repository) {
+	if protection, ok := repo.branches[r.PathValue("branch")]; !ok || protection == nil {
+		writeError(w, http.StatusNotFound, "Branch not protected")
+		return
+	}
+
+	repo.branches[r.PathValue("branch")] = nil
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (server *Server) listHooks(w http.ResponseWriter, r *http.Request, repo *Repository) {
+	ids := make([]int64, 0, len(repo.hooks))
+
+	for id := range repo.hooks {
+		ids = append(ids, id)
+	}
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	hooks := make([]*github.Hook, 0, len(ids))
+
+	for _, id := range ids {
+		hooks = append(hooks, maskHook(repo.hooks[id]))
+	}
+
+	writeList(w, r, hooks)
+}
+
+func (server *Server) createHook(w http.ResponseWriter, r *http.Request, repo *Repository) {
+	hook := &github.Hook{}
+
+	if !decode(w, r, hook) {
+		return
+	}
+
+	if len(hook.Events) == 0 {
+		hook.Events = []string{"push"}
+	}
+
+	if hook.Config != nil && hook.Config.GetContentType() == "" {
+		hook.Config.ContentType = github.String("form")
+	}
+
+	hook.ID = github.Int64(server.nextHookID)
+	server.nextHookID++
+	repo.hooks[hook.GetID()] = hook
+
+	writeJSON(w, http.StatusCreated, maskHook(hook))
+}
+
+func (server *Server) editHook(w http.ResponseWriter, r *http.Request, repo *Repository) {
+	hook, ok := server.hook(w, r, repo)
+
+	if !ok {
+		return
+	}
+
+	if !decode(w, r, hook) {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, maskHook(hook))
+}
+
+func (server *Server) deleteHook(w http.ResponseWriter, r *http.Request, repo *Repository) {
+	hook, ok := server.hook(w, r, repo)
+
+	if !ok {
+		return
+	}
+
+	delete(repo.hooks, hook.GetID())
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// listHookDeliveries lists the first page of the recent deliveries of a webhook, github pages them with a cursor
+func (server *Server) listHookDeliveries(w http.ResponseWriter, r *http.Request, repo *Repository) {
+	hook, ok := server.hook(w, r, repo)
+
+	if !ok {
+		return
+	}
+
+	deliveries := repo.hookDeliveries[hook.GetID()]
+
+	if perPage, err := strconv.Atoi(r.URL.Query().Get("per_page")); err == nil && perPage < len(deliveries) {
+		deliveries = deliveries[:perPage]
+	}
+
+	writeJSON(w, http.StatusOK, deliveries)
+}
+
+// listCommits lists the commits of the default branch authored since the since parameter
+func (server *Server) listCommits(w http.ResponseWriter, r *http.Request, repo *Repository) {
+	since, err := time.Parse(time.RFC3339, r.URL.Query().Get("since"))
+	commits := []*github.RepositoryCommit{}
+
+	for _, commit := range repo.commits {
+		if err != nil || !commit.GetCommit().GetAuthor().GetDate().Before(since) {
+			commits = append(commits, commit)
+		}
+	}
+
+	writeList(w, r, commits)
+}
+
+// listIssues lists the issues and pull requests of a repository, the most recently updated first
+func (server *Server) listIssues(w http.ResponseWriter, r *http.Request, repo *Repository) {
+	issues := append([]*github.Issue{}, repo.issues...)
+
+	sort.SliceStable(issues, func(i, j int) bool { return issues[i].GetUpdatedAt().After(issues[j].GetUpdatedAt().Time) })
+	writeList(w, r, issues)
+}
+
+func (server *Server) hook(w http.ResponseWriter, r *http.Request, repo *Repository) (*github.Hook, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+
+	if err != nil {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return nil, false
+	}
+
+	hook, ok := repo.hooks[id]
+
+	if !ok {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return nil, false
+	}
+
+	return hook, true
+}
+
+// listRulesets lists the rulesets without their conditions and rules like github
+func (server *Server) listRulesets(w http.ResponseWriter, r *http.Request, repo *Repository) {
+	ids := make([]int64, 0, len(repo.rulesets))
+
+	for id := range repo.rulesets {
+		ids = append(ids, id)
+	}
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	rulesets := make([]*github.RepositoryRuleset, 0, len(ids))
+
+	for _, id := range ids {
+		ruleset := *repo.rulesets[id]
+		ruleset.Conditions = nil
+		ruleset.Rules = nil
+		rulesets = append(rulesets, &ruleset)
+	}
+
+	writeList(w, r, rulesets)
+}
+
+func (server *Server) createRuleset(w http.ResponseWriter, r *http.Request, repo *Repository) {
+	ruleset := &github.RepositoryRuleset{}
+
+	if !decode(w, r, ruleset) {
+		return
+	}
+
+	for _, existing := range repo.rulesets {
+		if existing.Name == ruleset.Name {
+			writeError(w, http.StatusUnprocessableEntity, "Name must be unique")
+			return
+		}
+	}
+
+	sourceType := github.RulesetSourceTypeRepository
+	ruleset.ID = github.Int64(server.nextRulesetID)
+	ruleset.SourceType = &sourceType
+	ruleset.Source = repo.repository.GetFullName()
+	server.nextRulesetID++
+	repo.rulesets[ruleset.GetID()] = ruleset
+
+	writeJSON(w, http.StatusCreated, ruleset)
+}
+
+func (server *Server) getRuleset(w http.ResponseWriter, r *http.Request, repo *Repository) {
+	ruleset, ok := server.ruleset(w, r, repo)
+
+	if !ok {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, ruleset)
+}
+
+// updateRuleset replaces the ruleset, github keeps the fields missing from the request but the client sends them all
+// except the bypass actors, they are only sent to change them
+func (server *Server) updateRuleset(w http.ResponseWriter, r *http.Request, repo *Repository) {
+	ruleset, ok := server.ruleset(w, r, repo)
+
+	if !ok {
+		return
+	}
+
+	updated := &github.RepositoryRuleset{}
+
+	if !decode(w, r, updated) {
+		return
+	}
+
+	if updated.BypassActors == nil {
+		updated.BypassActors = ruleset.BypassActors
+	}
+
+	updated.ID = ruleset.ID
+	updated.SourceType = ruleset.SourceType
+	updated.Source = ruleset.Source
+	repo.rulesets[ruleset.GetID()] = updated
+
+	writeJSON(w, http.StatusOK, updated)
+}
+
+func (server *Server) deleteRuleset(w http.ResponseWriter, r *http.Request, repo *Repository) {
+	ruleset, ok := server.ruleset(w, r, repo)
+
+	if !ok {
+		return
+	}
+
+	delete(repo.rulesets, ruleset.GetID())
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (server *Server) ruleset(w http.ResponseWriter, r *http.Request, repo *Repository) (*github.RepositoryRuleset, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+
+	if err != nil {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return nil, false
+	}
+
+	ruleset, ok := repo.rulesets[id]
+
+	if !ok {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return nil, false
+	}
+
+	return ruleset, true
+}
+
+func (server *Server) listCollaborators(w http.ResponseWriter, r *http.Request, repo *Repository) {
+	users := make([]*github.User, 0, len(repo.Collaborators))
+
+	for _, login := range sortedKeys(repo.Collaborators) {
+		users = append(users, &github.User{
+			Login:    github.String(login),
+			RoleName: github.String(repo.Collaborators[login]),
+		})
+	}
+
+	writeList(w, r, users)
+}
+
+// addCollaborator grants access immediately, invitations are not emulated
+func (server *Server) addCollaborator(w http.ResponseWriter, r *http.Request, repo *Repository) {
+	options := &github.RepositoryAddCollaboratorOptions{}
+
+	if !decode(w, r, options) {
+		return
+	}
+
+	role, ok := server.roleName(repo, options.Permission)
+
+	if !ok {
+		writeError(w, http.StatusUnprocessableEntity, "Validation Failed")
+		return
+	}
+
+	repo.Collaborators[r.PathValue("user")] = role
+
+	// The repositories of a personal account grant write access to every collaborator
+	if server.personal[repo.repository.GetOwner().GetLogin()] {
+		repo.Collaborators[r.PathValue("user")] = "write"
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (server *Server) removeCollaborator(w http.ResponseWriter, r *http.Request, repo *Repository) {
+	delete(repo.Collaborators, r.PathValue("user"))
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (server *Server) listInvitations(w http.ResponseWriter, r *http.Request, repo *Repository) {
+	writeList(w, r, []*github.RepositoryInvitation{})
+}
+
+func (server *Server) listTeams(w http.ResponseWriter, r *http.Request, repo *Repository) {
+	if server.personal[repo.repository.GetOwner().GetLogin()] {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+
+	teams := make([]*github.Team, 0, len(repo.Teams))
+
+	// Teams granted a custom role are listed with the permission of its base role
+	for _, slug := range sortedKeys(repo.Teams) {
+		permission := repo.Teams[slug]
+
+		if baseRole, ok := server.customRoles[repo.repository.GetOwner().GetLogin()][permission]; ok {
+			permission = basePermissions[baseRole]
+		}
+
+		teams = append(teams, &github.Team{
+			Slug:       github.String(slug),
+			Permission: github.String(permission),
+		})
+	}
+
+	writeList(w, r, teams)
+}
+
+// getTeamRepo returns the repository with the role name of the team, custom roles included
+func (server *Server) getTeamRepo(w http.ResponseWriter, r *http.Request, repo *Repository) {
+	permission, ok := repo.Teams[r.PathValue("slug")]
+
+	if !ok {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+
+	role, _ := server.roleName(repo, permission)
+	repository := *repo.repository
+	repository.RoleName = github.String(role)
+
+	writeJSON(w, http.StatusOK, &repository)
+}
+
+func (server *Server) addTeam(w http.ResponseWriter, r *http.Request, repo *Repository) {
+	options := &github.TeamAddTeamRepoOptions{}
+
+	if !decode(w, r, options) {
+		return
+	}
+
+	if _, ok := server.roleName(repo, options.Permission); !ok {
+		writeError(w, http.StatusUnprocessableEntity, "Validation Failed")
+		return
+	}
+
+	repo.Teams[r.PathValue("slug")] = options.Permission
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (server *Server) removeTeam(w http.ResponseWriter, r *http.Request, repo *Repository) {
+	delete(repo.Teams, r.PathValue("slug"))
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (server *Server) listSecrets(w http.ResponseWriter, r *http.Request, repo *Repository) {
+	secrets := &github.Secrets{TotalCount: len(repo.Secrets), Secrets: []*github.Secret{}}
+
+	for _, name := range sortedKeys(repo.Secrets) {
+		secrets.Secrets = append(secrets.Secrets, &github.Secret{Name: name, UpdatedAt: secretUpdate(repo.SecretUpdates, name)})
+	}
+
+	writeJSON(w, http.StatusOK, secrets)
+}
+
+// secretUpdate returns when a secret was last written
+func secretUpdate(updates map[string]time.Time, name string) github.Timestamp {
+	if updated, ok := updates[name]; ok {
+		return github.Timestamp{Time: updated}
+	}
+
+	return github.Timestamp{Time: Epoch}
+}
+
+// writeSecret records the decrypted value of a secret and when it was written
+func writeSecret(secrets map[string]string, updates *map[string]time.Time, name, value string) {
+	if *updates == nil {
+		*updates = map[string]time.Time{}
+	}
+
+	secrets[strings.ToUpper(name)] = value
+	(*updates)[strings.ToUpper(name)] = time.Now().UTC()
+}
+
+func (server *Server) getPublicKey(w http.ResponseWriter, r *http.Request, repo *Repository) {
+	writeJSON(w, http.StatusOK, &github.PublicKey{
+		KeyID: github.String(publicKeyID),
+		Key:   github.String(base64.StdEncoding.EncodeToString(server.publicKey[:])),
+	})
+}
+
+// putSecret decrypts the value with the server key so tests can assert on it
+func (server *Server) putSecret(w http.ResponseWriter, r *http.Request, repo *Repository) {
+	request := &github.EncryptedSecret{}
+
+	if !decode(w, r, request) {
+		return
+	}
+
+	value, ok := server.decrypt(request)
+
+	if !ok {
+		writeError(w, http.StatusUnprocessableEntity, "Bad encrypted value")
+		return
+	}
+
+	writeSecret(repo.Secrets, &repo.SecretUpdates, r.PathValue("name"), value)
+
+	w.WriteHeader(http.StatusCreated)
+}
+
+// decrypt opens a secret value sealed with the server key
+func (server *Server) decrypt(request *github.EncryptedSecret) (string, bool) {
+	sealed, err := base64.StdEncoding.DecodeString(request.EncryptedValue)
+
+	if err != nil || request.KeyID != publicKeyID {
+		return "", false
+	}
+
+	value, ok := box.OpenAnonymous(nil, sealed, server.publicKey, server.privateKey)
+
+	return string(value), ok
+}
+
+func (server *Server) deleteSecret(w http.ResponseWriter, r *http.Request, repo *Repository) {
+	delete(repo.Secrets, r.PathValue("name"))
+	delete(repo.SecretUpdates, r.PathValue("name"))
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (server *Server) listDependabotSecrets(w http.ResponseWriter, r *http.Request, repo *Repository) {
+	secrets := &github.Secrets{TotalCount: len(repo.DependabotSecrets), Secrets: []*github.Secret{}}
+
+	for _, name := range sortedKeys(repo.DependabotSecrets) {
+		secrets.Secrets = append(secrets.Secrets, &github.Secret{Name: name, UpdatedAt: secretUpdate(repo.DependabotSecretUpdates, name)})
+	}
+
+	writeJSON(w, http.StatusOK, secrets)
+}
+
+// putDependabotSecret decrypts the value with the server key, dependabot secrets share the key of the actions secrets
+func (server *Server) putDependabotSecret(w http.ResponseWriter, r *http.Request, repo *Repository) {
+	request := &github.EncryptedSecret{}
+
+	if !decode(w, r, request) {
+		return
+	}
+
+	value, ok := server.decrypt(request)
+
+	if !ok {
+		writeError(w, http.StatusUnprocessableEntity, "Bad encrypted value")
+		return
+	}
+
+	writeSecret(repo.DependabotSecrets, &repo.DependabotSecretUpdates, r.PathValue("name"), value)
+
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (server *Server) deleteDependabotSecret(w http.ResponseWriter, r *http.Request, repo *Repository) {
+	delete(repo.DependabotSecrets, r.PathValue("name"))
+	delete(repo.DependabotSecretUpdates, r.PathValue("name"))
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (server *Server) listVariables(w http.ResponseWriter, r *http.Request, repo *Repository) {
+	variables := &github.ActionsVariables{TotalCount: len(repo.Variables), Variables: []*github.ActionsVariable{}}
+
+	for _, name := range sortedKeys(repo.Variables) {
+		variables.Variables = append(variables.Variables, &github.ActionsVariable{Name: name, Value: repo.Variables[name]})
+	}
+
+	writeJSON(w, http.StatusOK, variables)
+}
+
+func (server *Server) createVariable(w http.ResponseWriter, r *http.Request, repo *Repository) {
+	variable := &github.ActionsVariable{}
+
+	if !decode(w, r, variable) {
+		return
+	}
+
+	name := strings.ToUpper(variable.Name)
+
+	if _, ok := repo.Variables[name]; ok {
+		writeError(w, http.StatusConflict, "Already exists")
+		return
+	}
+
+	repo.Variables[name] = variable.Value
+
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (server *Server) updateVariable(w http.ResponseWriter, r *http.Request, repo *Repository) {
+	if _, ok := repo.Variables[r.PathValue("name")]; !ok {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+
+	variable := &github.ActionsVariable{}
+
+	if !decode(w, r, variable) {
+		return
+	}
+
+	repo.Variables[r.PathValue("name")] = variable.Value
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (server *Server) deleteVariable(w http.ResponseWriter, r *http.Request, repo *Repository) {
+	delete(repo.Variables, r.PathValue("name"))
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type environmentHandler func(w http.ResponseWriter, r *http.Request, repo *Repository, environment *Environment)
+
+// withEnvironment resolves the repository, by name or by id, and the environment of the request
+func (server *Server) withEnvironment(handler environmentHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		server.mutex.Lock()
+		defer server.mutex.Unlock()
+
+		repo, ok := server.repos[r.PathValue("owner")+"/"+r.PathValue("repo")]
+
+		if id := r.PathValue("id"); id != "" {
+			repo, ok = server.repoByID(id)
+		}
+
+		if !ok {
+			writeError(w, http.StatusNotFound, "Not Found")
+			return
+		}
+
+		environment, ok := repo.Environments[r.PathValue("env")]
+
+		if !ok {
+			writeError(w, http.StatusNotFound, "Not Found")
+			return
+		}
+
+		handler(w, r, repo, environment)
+	}
+}
+
+func (server *Server) repoByID(id string) (*Repository, bool) {
+	for _, repo := range server.repos {
+		if strconv.FormatInt(repo.repository.GetID(), 10) == id {
+			return repo, true
+		}
+	}
+
+	return nil, false
+}
+
+func (server *Server) listEnvironments(w http.ResponseWriter, r *http.Request, repo *Repository) {
+	environments := &github.EnvResponse{TotalCount: github.Int(len(repo.Environments)), Environments: []*github.Environment{}}
+
+	for _, name := range sortedKeys(repo.Environments) {
+		environments.Environments = append(environments.Environments, repo.Environments[name].environment)
+	}
+
+	writeJSON(w, http.StatusOK, environments)
+}
+
+// putEnvironment creates or replaces the protection rules of an environment, its secrets and variables are kept
+func (server *Server) putEnvironment(w http.ResponseWriter, r *http.Request, repo *Repository) {
+	request := &github.CreateUpdateEnvironment{}
+
+	if !decode(w, r, request) {
+		return
+	}
+
+	name := r.PathValue("env")
+	environment, ok := repo.Environments[name]
+
+	if !ok {
+		environment = &Environment{Secrets: map[string]string{}, Variables: map[string]string{}, branchPolicies: map[int64]*github.DeploymentBranchPolicy{}}
+		repo.Environments[name] = environment
+	}
+
+	environment.environment = &github.Environment{
+		Name:                   github.String(name),
+		DeploymentBranchPolicy: request.DeploymentBranchPolicy,
+		ProtectionRules:        []*github.ProtectionRule{},
+	}
+
+	if !request.GetDeploymentBranchPolicy().GetCustomBranchPolicies() {
+		environment.branchPolicies = map[int64]*github.DeploymentBranchPolicy{}
+	}
+
+	if request.GetWaitTimer() > 0 {
+		environment.environment.ProtectionRules = append(environment.environment.ProtectionRules, &github.ProtectionRule{
+			Type:      github.String("wait_timer"),
+			WaitTimer: request.WaitTimer,
+		})
+	}
+
+	if len(request.Reviewers) != 0 {
+		rule := &github.ProtectionRule{Type: github.String("required_reviewers")}
+
+		for _, reviewer := range request.Reviewers {
+			account := server.accounts[reviewer.GetID()]
+
+			if reviewer.GetType() == "Team" {
+				rule.Reviewers = append(rule.Reviewers, &github.RequiredReviewer{Type: reviewer.Type, Reviewer: &github.Team{ID: reviewer.ID, Slug: github.String(account)}})
+			} else {
+				rule.Reviewers = append(rule.Reviewers, &github.RequiredReviewer{Type: reviewer.Type, Reviewer: &github.User{ID: reviewer.ID, Login: github.String(account)}})
+			}
+		}
+
+		environment.environment.ProtectionRules = append(environment.environment.ProtectionRules, rule)
+	}
+
+	writeJSON(w, http.StatusOK, environment.environment)
+}
+
+func (server *Server) deleteEnvironment(w http.ResponseWriter, r *http.Request, repo *Repository) {
+	delete(repo.Environments, r.PathValue("env"))
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (server *Server) listEnvironmentSecrets(w http.ResponseWriter, r *http.Request, repo *Repository, environment *Environment) {
+	secrets := &github.Secrets{TotalCount: len(environment.Secrets), Secrets: []*github.Secret{}}
+
+	for _, name := range sortedKeys(environment.Secrets) {
+		secrets.Secrets = append(secrets.Secrets, &github.Secret{Name: name, UpdatedAt: secretUpdate(environment.SecretUpdates, name)})
+	}
+
+	writeJSON(w, http.StatusOK, secrets)
+}
+
+func (server *Server) getEnvironmentPublicKey(w http.ResponseWriter, r *http.Request, repo *Repository, environment *Environment) {
+	server.getPublicKey(w, r, repo)
+}
+
+func (server *Server) listBranchPolicies(w http.ResponseWriter, r *http.Request, repo *Repository, environment *Environment) {
+	ids := make([]int64, 0, len(environment.branchPolicies))
+
+	for id := range environment.branchPolicies {
+		ids = append(ids, id)
+	}
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	policies := &github.DeploymentBranchPolicyResponse{TotalCount: github.Int(len(ids)), BranchPolicies: []*github.DeploymentBranchPolicy{}}
+
+	for _, id := range ids {
+		policies.BranchPolicies = append(policies.BranchPolicies, environment.branchPolicies[id])
+	}
+
+	writeJSON(w, http.StatusOK, policies)
+}
+
+func (server *Server) createBranchPolicy(w http.ResponseWriter, r *http.Request, repo *Repository, environment *Environment) {
+	request := &github.DeploymentBranchPolicyRequest{}
+
+	if !decode(w, r, request) {
+		return
+	}
+
+	if !environment.environment.GetDeploymentBranchPolicy().GetCustomBranchPolicies() {
+		writeError(w, http.StatusNotFound, "Custom deployment branch policies are not enabled")
+		return
+	}
+
+	for _, policy := range environment.branchPolicies {
+		if policy.GetName() == request.GetName() && policy.GetType() == request.GetType() {
+			writeError(w, http.StatusConflict, "Already exists")
+			return
+		}
+	}
+
+	policy := &github.DeploymentBranchPolicy{ID: github.Int64(server.nextPolicyID), Name: request.Name, Type: request.Type}
+	server.nextPolicyID++
+	environment.branchPolicies[policy.GetID()] = policy
+
+	writeJSON(w, http.StatusOK, policy)
+}
+
+func (server *Server) deleteBranchPolicy(w http.ResponseWriter, r *http.Request, repo *Repository, environment *Environment) {
+	id, _ := strconv.ParseInt(r.PathValue("policy"), 10, 64)
+
+	if _, ok := environment.branchPolicies[id]; !ok {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+
+	delete(environment.branchPolicies, id)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (server *Server) putEnvironmentSecret(w http.ResponseWriter, r *http.Request, repo *Repository, environment *Environment) {
+	request := &github.EncryptedSecret{}
+
+	if !decode(w, r, request) {
+		return
+	}
+
+	value, ok := server.decrypt(request)
+
+	if !ok {
+		writeError(w, http.StatusUnprocessableEntity, "Bad encrypted value")
+		return
+	}
+
+	writeSecret(environment.Secrets, &environment.SecretUpdates, r.PathValue("name"), value)
+
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (server *Server) deleteEnvironmentSecret(w http.ResponseWriter, r *http.Request, repo *Repository, environment *Environment) {
+	delete(environment.Secrets, r.PathValue("name"))
+	delete(environment.SecretUpdates, r.PathValue("name"))
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (server *Server) listEnvironmentVariables(w http.ResponseWriter, r *http.Request, repo *Repository, environment *Environment) {
+	variables := &github.ActionsVariables{TotalCount: len(environment.Variables), Variables: []*github.ActionsVariable{}}
+
+	for _, name := range sortedKeys(environment.Variables) {
+		variables.Variables = append(variables.Variables, &github.ActionsVariable{Name: name, Value: environment.Variables[name]})
+	}
+
+	writeJSON(w, http.StatusOK, variables)
+}
+
+func (server *Server) createEnvironmentVariable(w http.ResponseWriter, r *http.Request, repo *Repository, environment *Environment) {
+	variable := &github.ActionsVariable{}
+
+	if !decode(w, r, variable) {
+		return
+	}
+
+	name := strings.ToUpper(variable.Name)
+
+	if _, ok := environment.Variables[name]; ok {
+		writeError(w, http.StatusConflict, "Already exists")
+		return
+	}
+
+	environment.Variables[name] = variable.Value
+
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (server *Server) updateEnvironmentVariable(w http.ResponseWriter, r *http.Request, repo *Repository, environment *Environment) {
+	if _, ok := environment.Variables[r.PathValue("name")]; !ok {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+
+	variable := &github.ActionsVariable{}
+
+	if !decode(w, r, variable) {
+		return
+	}
+
+	environment.Variables[r.PathValue("name")] = variable.Value
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (server *Server) deleteEnvironmentVariable(w http.ResponseWriter, r *http.Request, repo *Repository, environment *Environment) {
+	delete(environment.Variables, r.PathValue("name"))
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (server *Server) getContents(w http.ResponseWriter, r *http.Request, repo *Repository) {
+	path := r.PathValue("path")
+	content, ok := repo.Files[path]
+
+	if ref := r.URL.Query().Get("ref"); !ok || (ref != "" && ref != repo.repository.GetDefaultBranch()) {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, &github.RepositoryContent{
+		Type:     github.String("file"),
+		Path:     github.String(path),
+		Size:     github.Int(len(content)),
+		Encoding: github.String("base64"),
+		Content:  github.String(base64.StdEncoding.EncodeToString([]byte(content))),
+	})
+}
+
+func (server *Server) createStatus(w http.ResponseWriter, r *http.Request, repo *Repository) {
+	status := &github.RepoStatus{}
+
+	if !decode(w, r, status) {
+		return
+	}
+
+	sha := r.PathValue("sha")
+
+	if repo.statuses[sha] == nil {
+		repo.statuses[sha] = map[string]*github.RepoStatus{}
+	}
+
+	repo.statuses[sha][status.GetContext()] = status
+
+	writeJSON(w, http.StatusCreated, status)
+}
+
+// nolint:gochecknoglobals
+var roleNames = map[string]string{
+	"pull":     "read",
+	"triage":   "triage",
+	"push":     "write",
+	"maintain": "maintain",
+	"admin":    "admin",
+}
+
+// basePermissions maps the base roles of the custom roles to the permissions teams are listed with
+// nolint:gochecknoglobals
+var basePermissions = map[string]string{
+	"read":     "pull",
+	"triage":   "triage",
+	"write":    "push",
+	"maintain": "maintain",
+}
+
+// roleName returns the role name of a permission, a built-in permission or a custom role of the owner of the repository
+func (server *Server) roleName(repo *Repository, permission string) (string, bool) {
+	if role, ok := roleNames[permission]; ok {
+		return role, true
+	}
+
+	_, ok := server.customRoles[repo.repository.GetOwner().GetLogin()][permission]
+
+	return permission, ok
+}
+
+func users(logins []string) []*github.User {
+	result := []*github.User{}
+
+	for _, login := range logins {
+		result = append(result, &github.User{Login: github.String(login)})
+	}
+
+	return result
+}
+
+func teams(slugs []string) []*github.Team {
+	result := []*github.Team{}
+
+	for _, slug := range slugs {
+		result = append(result, &github.Team{Slug: github.String(slug)})
+	}
+
+	return result
+}
+
+func apps(slugs []string) []*github.App {
+	result := []*github.App{}
+
+	for _, slug := range slugs {
+		result = append(result, &github.App{Slug: github.String(slug)})
+	}
+
+	return result
+}
+
+// maskHook hides the webhook secret like github does
+func maskHook(hook *github.Hook) *github.Hook {
+	masked := *hook
+
+	if hook.Config != nil && hook.Config.GetSecret() != "" {
+		config := *hook.Config
+		config.Secret = github.String(maskedSecret)
+		masked.Config = &config
+	}
+
+	return &masked
+}
+
+func decode(w http.ResponseWriter, r *http.Request, value interface{}) bool {
+	err := json.NewDecoder(r.Body).Decode(value)
+
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Problems parsing JSON")
+		return false
+	}
+
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(value)
+}
+
+// writeList writes a page of a list, selected by the page and per_page parameters, with the link to the next page
+func writeList[T any](w http.ResponseWriter, r *http.Request, items []T) {
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	perPage, err := strconv.Atoi(r.URL.Query().Get("per_page"))
+
+	if err != nil || perPage < 1 {
+		perPage = defaultPerPage
+	}
+
+	start := min((page-1)*perPage, len(items))
+	end := min(start+perPage, len(items))
+
+	if end < len(items) {
+		// The request uri keeps the /api/v3 prefix stripped from the url path
+		next, _ := url.Parse(r.RequestURI)
+		query := next.Query()
+		query.Set("page", strconv.Itoa(page+1))
+		query.Set("per_page", strconv.Itoa(perPage))
+		next.RawQuery = query.Encode()
+
+		w.Header().Set("Link", fmt.Sprintf(`<http://%s%s>; rel="next"`, r.Host, next.String()))
+	}
+
+	writeJSON(w, http.StatusOK, items[start:end])
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"message": message})
+}
+
+func sortedKeys[T any](values map[string]T) []string {
+	keys := make([]string, 0, len(values))
+
+	for key := range values {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}